@@ -0,0 +1,118 @@
+// Package capacity reports how close a schedule is to becoming impossible to
+// staff, so that team leads hear about it before a little more time off makes
+// a rotation unfillable.
+package capacity
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	// Rotations with at most this much slack are reported as tight.
+	TightSlack = 1
+	// How many of the tightest rotations a Report summarizes.
+	TightestCount = 3
+)
+
+// Rotation is the capacity of a single rotation.
+type Rotation struct {
+	Start time.Time
+	End time.Time
+	// The users who may be assigned to the rotation, after applying every
+	// constraint.
+	Available []string
+	// How many more users could become unavailable before the rotation could
+	// not be staffed. Negative if it already cannot be.
+	Slack int
+	Tight bool
+}
+
+type Report struct {
+	Window schedule.TimeRange
+	// Every rotation overlapping Window, in order. Rotations beyond the end of
+	// the schedule are projected from RotationLength.
+	Rotations []Rotation
+	// The rotations with the least slack, least first, then earliest first.
+	Tightest []Rotation
+}
+
+// Analyze reports the capacity of every rotation of s overlapping window.
+func Analyze(s *schedule.Schedule, window schedule.TimeRange) Report {
+	r := Report{Window: window, Rotations: []Rotation{}}
+	add := func(start, end time.Time) {
+		if !end.After(window.Start) || !start.Before(window.End) {
+			return
+		}
+		available := s.AvailableUsers(start)
+		slack := len(available) - s.RequiredUsers()
+		r.Rotations = append(r.Rotations, Rotation{
+			Start: start,
+			End: end,
+			Available: available,
+			Slack: slack,
+			Tight: slack <= TightSlack,
+		})
+	}
+
+	next := s.Start
+	for i, rot := range s.Rotations {
+		end := s.RotationEnd(i)
+		add(rot.Start, end)
+		next = end
+	}
+	for next.Before(window.End) {
+		end := s.NominalEnd(next)
+		add(next, end)
+		next = end
+	}
+
+	r.Tightest = append([]Rotation{}, r.Rotations...)
+	sort.SliceStable(r.Tightest, func(i, j int) bool {
+		return r.Tightest[i].Slack < r.Tightest[j].Slack
+	})
+	if len(r.Tightest) > TightestCount {
+		r.Tightest = r.Tightest[:TightestCount]
+	}
+	return r
+}
+
+// Tight returns the tight rotations in the report.
+func (r Report) Tight() []Rotation {
+	tight := []Rotation{}
+	for _, rot := range r.Rotations {
+		if rot.Tight {
+			tight = append(tight, rot)
+		}
+	}
+	return tight
+}
+
+// String formats the report as a table of rotations followed by a summary of
+// the tightest ones.
+func (r Report) String() string {
+	out := &bytes.Buffer{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "START\tEND\tSLACK\tAVAILABLE\n")
+	for _, rot := range r.Rotations {
+		slack := fmt.Sprintf("%d", rot.Slack)
+		if rot.Tight {
+			slack += " (tight)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", rot.Start.Format(time.RFC3339), rot.End.Format(time.RFC3339), slack, strings.Join(rot.Available, ", "))
+	}
+	w.Flush()
+	if len(r.Tightest) > 0 {
+		fmt.Fprintf(out, "\nTightest rotations:\n")
+		for _, rot := range r.Tightest {
+			fmt.Fprintf(out, "  %s: %d available, slack %d\n", rot.Start.Format(time.RFC3339), len(rot.Available), rot.Slack)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,71 @@
+package capacity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["a", "b", "c", "d"],
+	"Start": "2017-02-15T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"UserEndDates": {
+		"c": "2017-02-20T00:00:00Z",
+		"d": "2017-03-05T00:00:00Z"
+	},
+	"Rotations": [
+		{
+			"Start": "2017-02-01T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		},
+		{
+			"Start": "2017-02-08T10:00:00Z",
+			"Primary": "c",
+			"Secondary": "d"
+		}
+	]
+}`
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2017, month, day, 10, 0, 0, 0, time.UTC)
+}
+
+func TestAnalyze(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Analyze(s, schedule.TimeRange{Start: date(time.February, 10), End: date(time.March, 10)})
+
+	starts := []time.Time{}
+	slack := []int{}
+	for _, rot := range r.Rotations {
+		starts = append(starts, rot.Start)
+		slack = append(slack, rot.Slack)
+	}
+	// The existing rotation overlapping the window, then projected ones as c and
+	// d leave.
+	expectedStarts := []time.Time{date(time.February, 8), date(time.February, 15), date(time.February, 22), date(time.March, 1), date(time.March, 8)}
+	if !reflect.DeepEqual(expectedStarts, starts) {
+		t.Errorf("expected rotations starting at %v, got %v", expectedStarts, starts)
+	}
+	if expected := []int{2, 2, 1, 1, 0}; !reflect.DeepEqual(expected, slack) {
+		t.Errorf("expected slack %v, got %v", expected, slack)
+	}
+	if tight := r.Tight(); len(tight) != 3 {
+		t.Errorf("expected 3 tight rotations, got %v", tight)
+	}
+	if r.Tightest[0].Start != date(time.March, 8) || r.Tightest[1].Start != date(time.February, 22) {
+		t.Errorf("expected the tightest rotations least slack first, got %v", r.Tightest)
+	}
+	if !strings.Contains(r.String(), "2017-03-08T10:00:00Z: 2 available, slack 0") {
+		t.Errorf("summary does not report the tightest rotation:\n%s", r)
+	}
+}
@@ -0,0 +1,209 @@
+// Package client queries a remote oncallator server for who is on call.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 2
+	DefaultCacheTTL = 30 * time.Second
+)
+
+// ErrNoRotation is returned when the server's schedule has no rotation
+// covering the requested time, usually because it needs regenerating.
+var ErrNoRotation = errors.New("no rotation covers the requested time")
+
+// Interface is implemented by Client and by Static, so that code querying the
+// schedule can be tested without a server.
+type Interface interface {
+	// The rotation covering t.
+	OnCallAt(ctx context.Context, t time.Time) (schedule.Rotation, error)
+	// The next n rotations, starting with the current one.
+	Upcoming(ctx context.Context, n int) ([]schedule.Rotation, error)
+	// The full schedule.
+	Schedule(ctx context.Context) (*schedule.Schedule, error)
+}
+
+// Client queries an oncallator server over HTTP. Responses are cached in
+// memory for the cache TTL, and failed requests are retried, since every
+// request is an idempotent GET.
+type Client struct {
+	baseURL string
+	token string
+	http *http.Client
+	retries int
+	backoff time.Duration
+	ttl time.Duration
+
+	mu sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	body []byte
+	expires time.Time
+}
+
+type Option func(*Client)
+
+// WithToken authenticates requests with a bearer token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient replaces the default http.Client, whose timeout is
+// DefaultTimeout.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.http = h
+	}
+}
+
+// WithRetries sets how many times a failed request is retried, waiting
+// backoff, then twice as long, and so on, between attempts.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithCacheTTL sets how long responses are cached. Zero disables caching.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.ttl = ttl
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http: &http.Client{Timeout: DefaultTimeout},
+		retries: DefaultRetries,
+		backoff: 100 * time.Millisecond,
+		ttl: DefaultCacheTTL,
+		cache: map[string]cacheEntry{},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+func (c *Client) OnCallAt(ctx context.Context, t time.Time) (schedule.Rotation, error) {
+	r := schedule.Rotation{}
+	q := url.Values{"at": {t.Format(time.RFC3339)}}
+	body, err := c.get(ctx, "/oncall?" + q.Encode())
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return r, fmt.Errorf("error parsing rotation: %s", err)
+	}
+	return r, nil
+}
+
+func (c *Client) Upcoming(ctx context.Context, n int) ([]schedule.Rotation, error) {
+	rs := []schedule.Rotation{}
+	q := url.Values{"n": {strconv.Itoa(n)}}
+	body, err := c.get(ctx, "/upcoming?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &rs); err != nil {
+		return nil, fmt.Errorf("error parsing rotations: %s", err)
+	}
+	return rs, nil
+}
+
+func (c *Client) Schedule(ctx context.Context) (*schedule.Schedule, error) {
+	body, err := c.get(ctx, "/schedule")
+	if err != nil {
+		return nil, err
+	}
+	return schedule.NewSchedule(body)
+}
+
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.cache[path]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.body, nil
+	}
+
+	var body []byte
+	var err error
+	backoff := c.backoff
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		var retry bool
+		if body, retry, err = c.do(ctx, path); err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.cache[path] = cacheEntry{body: body, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return body, nil
+}
+
+// Make a single request, reporting whether a failure is worth retrying.
+func (c *Client) do(ctx context.Context, path string) ([]byte, bool, error) {
+	req, err := http.NewRequest("GET", c.baseURL + path, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer " + c.token)
+	}
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, false, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, ErrNoRotation
+	case resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("GET %s: %s", path, resp.Status)
+	default:
+		return nil, false, fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["b", "c", "a"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-15T10:00:00Z",
+			"Primary": "c",
+			"Secondary": "a"
+		},
+		{
+			"Start": "2017-02-22T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		}
+	]
+}`
+
+const rotationText = `{"Start": "2017-02-22T10:00:00Z", "Primary": "a", "Secondary": "b"}`
+
+var _ Interface = &Client{}
+var _ Interface = &Static{}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/schedule":
+			fmt.Fprint(w, scheduleText)
+		case "/oncall":
+			if r.URL.Query().Get("at") == "2017-02-23T00:00:00Z" {
+				fmt.Fprint(w, rotationText)
+			} else {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case "/upcoming":
+			fmt.Fprintf(w, "[%s]", rotationText)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL, WithToken("secret"))
+	ctx := context.Background()
+
+	r, err := c.OnCallAt(ctx, time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Primary != "a" || r.Secondary != "b" {
+		t.Errorf("expected a and b, got %s", r)
+	}
+	if _, err := c.OnCallAt(ctx, time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)); err != ErrNoRotation {
+		t.Errorf("expected ErrNoRotation, got %v", err)
+	}
+	rs, err := c.Upcoming(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Primary != "a" {
+		t.Errorf("expected a single rotation with primary a, got %v", rs)
+	}
+	s, err := c.Schedule(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RotationDuration != 7 * 24 * time.Hour || len(s.Rotations) != 2 {
+		t.Errorf("expected the parsed schedule, got %+v", s)
+	}
+
+	if _, err := New(server.URL).Schedule(ctx); err == nil {
+		t.Errorf("expected an unauthenticated request to fail")
+	}
+}
+
+func TestClientRetriesAndCaches(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, rotationText)
+	}))
+	defer server.Close()
+	c := New(server.URL, WithRetries(1, time.Millisecond))
+	at := time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.OnCallAt(context.Background(), at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected one failed and one successful request, got %d requests", n)
+	}
+}
+
+func TestClientGivesUpAfterRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	if _, err := c.Upcoming(context.Background(), 3); err == nil {
+		t.Errorf("expected an error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestStatic(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &Static{Sched: s, Now: time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)}
+	ctx := context.Background()
+	r, err := st.OnCallAt(ctx, time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC))
+	if err != nil || r.Primary != "a" {
+		t.Errorf("expected primary a, got %s (%v)", r, err)
+	}
+	rs, err := st.Upcoming(ctx, 5)
+	if err != nil || len(rs) != 2 || rs[0].Primary != "c" {
+		t.Errorf("expected both rotations from c's, got %v (%v)", rs, err)
+	}
+	if _, err := st.OnCallAt(ctx, time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)); err != ErrNoRotation {
+		t.Errorf("expected ErrNoRotation, got %v", err)
+	}
+}
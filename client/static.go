@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// Static implements Interface from a fixed schedule, for tests of code that
+// queries oncallator.
+type Static struct {
+	Sched *schedule.Schedule
+	// The time Upcoming counts from. Defaults to the current time.
+	Now time.Time
+}
+
+func (s *Static) OnCallAt(ctx context.Context, t time.Time) (schedule.Rotation, error) {
+	i := s.index(t)
+	if i < 0 {
+		return schedule.Rotation{}, ErrNoRotation
+	}
+	return s.Sched.Rotations[i], nil
+}
+
+func (s *Static) Upcoming(ctx context.Context, n int) ([]schedule.Rotation, error) {
+	now := s.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	i := s.index(now)
+	if i < 0 {
+		return nil, ErrNoRotation
+	}
+	end := i + n
+	if end > len(s.Sched.Rotations) {
+		end = len(s.Sched.Rotations)
+	}
+	return append([]schedule.Rotation{}, s.Sched.Rotations[i:end]...), nil
+}
+
+func (s *Static) Schedule(ctx context.Context) (*schedule.Schedule, error) {
+	return s.Sched, nil
+}
+
+// Return the index of the rotation covering t, or -1.
+func (s *Static) index(t time.Time) int {
+	rs := s.Sched.Rotations
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].Start.After(t) {
+			continue
+		}
+		if t.Before(s.Sched.RotationEnd(i)) {
+			return i
+		}
+		break
+	}
+	return -1
+}
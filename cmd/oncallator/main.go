@@ -0,0 +1,1298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/capacity"
+	"github.com/websdev/oncallator/export/ics"
+	"github.com/websdev/oncallator/gcal"
+	"github.com/websdev/oncallator/hooks"
+	"github.com/websdev/oncallator/links"
+	"github.com/websdev/oncallator/notify"
+	"github.com/websdev/oncallator/pagerduty"
+	"github.com/websdev/oncallator/reconcile"
+	"github.com/websdev/oncallator/schedule"
+	"github.com/websdev/oncallator/server"
+	"github.com/websdev/oncallator/terraform"
+	"github.com/urfave/cli"
+)
+
+const (
+	FlagIn = "in"
+	FlagOut = "out"
+	FlagFormat = "format"
+	FlagBase = "base"
+	FlagHead = "head"
+	FlagAt = "at"
+	FlagCheck = "check"
+	FlagJSON = "json"
+	FlagFrom = "from"
+	FlagTo = "to"
+	FlagPagerDutyToken = "pagerduty-token"
+	FlagPagerDutyPrimary = "pagerduty-primary"
+	FlagPagerDutySecondary = "pagerduty-secondary"
+	FlagOpsgenieKey = "opsgenie-key"
+	FlagOpsgeniePrimary = "opsgenie-primary"
+	FlagOpsgenieSecondary = "opsgenie-secondary"
+	FlagProviderUsers = "provider-users"
+	FlagPagerDutyUsers = "pagerduty-users"
+	FlagDryRun = "dry-run"
+	FlagHolidays = "holidays"
+	FlagUntil = "until"
+	FlagGCalToken = "gcal-token"
+	FlagGCalCalendar = "gcal-calendar"
+	FlagGCalEmails = "gcal-emails"
+	FlagLead = "lead"
+	FlagSlackIDs = "slack-ids"
+	FlagTemplate = "template"
+	FlagDMTemplate = "dm-template"
+	FlagWebhook = "webhook"
+	FlagDM = "dm"
+	FlagAddr = "addr"
+	FlagToken = "token"
+	FlagIntegrations = "integrations"
+	FlagName = "name"
+	FlagStrict = "strict"
+	FlagAsOf = "as-of"
+	FlagDiscardFuture = "discard-future"
+	FlagRestartAt = "restart-at"
+
+	FormatSchedule = "schedule"
+	FormatTerraform = "terraform"
+	FormatICS = "ics"
+)
+
+var (
+	inFlag = cli.StringSliceFlag{
+		Name: FlagIn,
+		Usage: `If set, will read the base schedule from this file. Otherwise, reads from stdin.
+
+May be repeated to compose the schedule from several fragments (e.g. users,
+constraints and rotations). The tool-owned rotations fragment should be listed
+last; with the "schedule" format, only its generated fields are written to -out.`,
+	}
+	outFlag = cli.StringFlag{
+		Name: FlagOut,
+		Usage: "If set, will write the output to this file. Otherwise, writes to stdout.",
+	}
+	strictFlag = cli.BoolFlag{
+		Name: FlagStrict,
+		Usage: "Also check for likely mistakes that are otherwise allowed, reporting all of them at once, e.g. duplicate users or a ScheduleFor shorter than RotationLength.",
+	}
+	asOfFlag = cli.StringFlag{
+		Name: FlagAsOf,
+		Usage: "If set, acts as if it were this RFC3339 time rather than now, e.g. to see what regenerating the schedule next Monday would do.",
+	}
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "oncallator"
+	app.Flags = []cli.Flag {
+		inFlag,
+		outFlag,
+		cli.StringFlag{
+			Name: FlagFormat,
+			Usage: `Controls the output format of the schedule.
+
+Allowed values:
+	"schedule" -- will perform schedule generation on the input Schedule and output the updated JSON, or YAML if the input or -out is a .yaml file
+	"terraform" -- will output Terraform pagerduty_schedule layers using the input Schedule
+	"ics" -- will output the input Schedule's rotations as an iCalendar feed`,
+			Value: FormatSchedule,
+		},
+		cli.BoolFlag{
+			Name: FlagCheck,
+			Usage: `Instead of writing the generated schedule, print the changes generation would
+make as JSON and exit non-zero if there are any.`,
+		},
+		asOfFlag,
+	}
+	app.Action = action
+	app.Commands = []cli.Command{
+		{
+			Name: "generate",
+			Usage: "Generate the input Schedule and write it back, printing a summary of what changed",
+			Description: `Exits 1 without writing anything if the schedule cannot be read or
+generated. The summary is printed to stdout, or to stderr when the schedule
+itself is.
+
+If the rotations changed, the schedule's Hooks are then run, except with
+-dry-run. Exits 2 if any of them fails; the schedule is written regardless.
+
+Rotations already scheduled are kept. To schedule afresh, e.g. after the team
+is reshuffled, pass -discard-future, which throws away every rotation after
+the one under way; the summary and diff list what was thrown away.`,
+			Flags: []cli.Flag{
+				inFlag,
+				outFlag,
+				cli.BoolFlag{
+					Name: FlagDryRun,
+					Usage: "Print the generated schedule to stdout instead of writing it to -out.",
+				},
+				cli.BoolFlag{
+					Name: FlagJSON,
+					Usage: "Print the summary as JSON.",
+				},
+				cli.StringFlag{
+					Name: FlagUntil,
+					Usage: "If set, extends the schedule until a rotation covers this RFC3339 time, rather than ScheduleFor from now, e.g. to publish it through the holidays.",
+				},
+				cli.StringFlag{
+					Name: FlagHolidays,
+					Usage: "A JSON file of Holidays to consider alongside the schedule's own. They are not written into the schedule.",
+				},
+				cli.BoolFlag{
+					Name: FlagDiscardFuture,
+					Usage: "Discard the rotations after the one under way and schedule them afresh, starting the round-robin over from Users as written.",
+				},
+				cli.StringFlag{
+					Name: FlagRestartAt,
+					Usage: "With -discard-future, discard every rotation instead and start the schedule over at this RFC3339 time.",
+				},
+				strictFlag,
+				asOfFlag,
+			},
+			Action: generateAction,
+		},
+		{
+			Name: "generate-all",
+			Usage: "Generate every team's schedule in a file of several and write it back",
+			Description: `A team that cannot be generated keeps its schedule as it was, and its
+error is printed to stderr; the rest are written regardless, and the command
+exits 1.`,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: FlagIn,
+					Usage: "The JSON file of the teams' schedules.",
+				},
+				outFlag,
+			},
+			Action: generateAllAction,
+		},
+		{
+			Name: "validate",
+			Usage: "Check that a schedule file is valid, exiting 1 if not, and print suggestions for it",
+			ArgsUsage: "FILE...",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name: FlagJSON,
+					Usage: "Print the result as JSON.",
+				},
+				strictFlag,
+				asOfFlag,
+			},
+			Action: validateAction,
+		},
+		{
+			Name: "who",
+			Usage: "Print who is on call in each tier, exiting 2 if no rotation covers the time",
+			Flags: []cli.Flag{
+				inFlag,
+				cli.StringFlag{
+					Name: FlagAt,
+					Usage: "If set, prints who is on call at this RFC3339 time. Otherwise, uses the current time. Past times are also looked up in the History of elapsed rotations.",
+				},
+				cli.BoolFlag{
+					Name: FlagJSON,
+					Usage: "Print the rotation's span and who is on call in each tier as JSON.",
+				},
+			},
+			Action: whoAction,
+		},
+		{
+			Name: "config",
+			Usage: "Print the effective configuration of the input Schedule, after parsing and defaults",
+			Flags: []cli.Flag{inFlag, outFlag},
+			Action: configAction,
+		},
+		{
+			Name: "migrate",
+			Usage: "Upgrade a schedule file to the current format version in place, reporting what changed",
+			ArgsUsage: "FILE",
+			Action: migrateAction,
+		},
+		{
+			Name: "swap",
+			Usage: "Exchange two users' places in the rotations starting in a span of time, recording the swap in the schedule",
+			ArgsUsage: "USER USER",
+			Flags: []cli.Flag{
+				inFlag,
+				outFlag,
+				cli.StringFlag{
+					Name: FlagFrom,
+					Usage: "The RFC3339 time from which rotations are swapped. Required.",
+				},
+				cli.StringFlag{
+					Name: FlagTo,
+					Usage: "The RFC3339 time before which rotations are swapped. Required.",
+				},
+			},
+			Action: swapAction,
+		},
+		{
+			Name: "explain",
+			Usage: "Describe, as Markdown, how a proposed schedule file edit changes the generated schedule",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: FlagBase,
+					Usage: "The schedule file before the edit.",
+				},
+				cli.StringFlag{
+					Name: FlagHead,
+					Usage: "The schedule file after the edit.",
+				},
+				cli.StringFlag{
+					Name: FlagAt,
+					Usage: "If set, generates both schedules as of this RFC3339 time. Otherwise, uses the current time.",
+				},
+				cli.BoolFlag{
+					Name: FlagJSON,
+					Usage: "Print the changes as JSON instead of Markdown.",
+				},
+				outFlag,
+			},
+			Action: explainAction,
+		},
+		{
+			Name: "reconcile",
+			Usage: "Check that the paging providers agree with the schedule about who is on call now, exiting non-zero if not",
+			Flags: []cli.Flag{
+				inFlag,
+				cli.StringFlag{
+					Name: FlagPagerDutyToken,
+					Usage: "PagerDuty API token. Required to check PagerDuty.",
+					EnvVar: "PAGERDUTY_TOKEN",
+				},
+				cli.StringFlag{
+					Name: FlagPagerDutyPrimary,
+					Usage: "PagerDuty schedule ID of the primary rotation.",
+				},
+				cli.StringFlag{
+					Name: FlagPagerDutySecondary,
+					Usage: "PagerDuty schedule ID of the secondary rotation.",
+				},
+				cli.StringFlag{
+					Name: FlagOpsgenieKey,
+					Usage: "Opsgenie API key. Required to check Opsgenie.",
+					EnvVar: "OPSGENIE_API_KEY",
+				},
+				cli.StringFlag{
+					Name: FlagOpsgeniePrimary,
+					Usage: "Opsgenie schedule ID of the primary rotation.",
+				},
+				cli.StringFlag{
+					Name: FlagOpsgenieSecondary,
+					Usage: "Opsgenie schedule ID of the secondary rotation.",
+				},
+				cli.StringFlag{
+					Name: FlagProviderUsers,
+					Usage: "A JSON file mapping provider user IDs and usernames to schedule user names.",
+				},
+			},
+			Action: reconcileAction,
+		},
+		{
+			Name: "capacity",
+			Usage: "Report how many more users could become unavailable before each rotation could not be staffed",
+			Flags: []cli.Flag{
+				inFlag,
+				outFlag,
+				cli.StringFlag{
+					Name: FlagFrom,
+					Usage: "The RFC3339 start of the window to report on. Defaults to the current time.",
+				},
+				cli.StringFlag{
+					Name: FlagTo,
+					Usage: "The RFC3339 end of the window to report on. Defaults to ScheduleFor after the start.",
+				},
+				cli.BoolFlag{
+					Name: FlagJSON,
+					Usage: "Print the report as JSON instead of a table.",
+				},
+				cli.BoolFlag{
+					Name: FlagCheck,
+					Usage: fmt.Sprintf("Exit non-zero if any rotation has a slack of %d or less, for use as a health check.", capacity.TightSlack),
+				},
+			},
+			Action: capacityAction,
+		},
+		{
+			Name: "stats",
+			Usage: "Report each user's shifts and on-call hours",
+			Flags: []cli.Flag{
+				inFlag,
+				outFlag,
+				cli.StringFlag{
+					Name: FlagFrom,
+					Usage: "The RFC3339 start of the window to report on. Defaults to the start of History, or of the first rotation.",
+				},
+				cli.StringFlag{
+					Name: FlagTo,
+					Usage: "The RFC3339 end of the window to report on. Defaults to the end of the last rotation.",
+				},
+				cli.BoolFlag{
+					Name: FlagJSON,
+					Usage: "Print the report as JSON instead of a table.",
+				},
+			},
+			Action: statsAction,
+		},
+		{
+			Name: "push",
+			Usage: "Push the input Schedule's current and future rotations to PagerDuty as schedule overrides",
+			Flags: []cli.Flag{
+				inFlag,
+				cli.StringFlag{
+					Name: FlagPagerDutyToken,
+					Usage: "PagerDuty API token.",
+					EnvVar: "PAGERDUTY_TOKEN",
+				},
+				cli.StringFlag{
+					Name: FlagPagerDutyPrimary,
+					Usage: "PagerDuty schedule ID to push primaries to.",
+				},
+				cli.StringFlag{
+					Name: FlagPagerDutySecondary,
+					Usage: "PagerDuty schedule ID to push secondaries to.",
+				},
+				cli.StringFlag{
+					Name: FlagPagerDutyUsers,
+					Usage: "A JSON file mapping schedule user names to PagerDuty user IDs.",
+				},
+				cli.BoolFlag{
+					Name: FlagDryRun,
+					Usage: "Print the API calls that would change PagerDuty instead of making them.",
+				},
+			},
+			Action: pushAction,
+		},
+		{
+			Name: "gcal",
+			Usage: "Sync the input Schedule's current and future rotations to a Google Calendar, one event per rotation and tier",
+			Flags: []cli.Flag{
+				inFlag,
+				cli.StringFlag{
+					Name: FlagGCalToken,
+					Usage: "OAuth 2.0 access token with the calendar.events scope.",
+					EnvVar: "GOOGLE_OAUTH_TOKEN",
+				},
+				cli.StringFlag{
+					Name: FlagGCalCalendar,
+					Usage: "ID of the calendar to sync to.",
+				},
+				cli.StringFlag{
+					Name: FlagGCalEmails,
+					Usage: "A JSON file mapping schedule user names to email addresses, to add users to their events as attendees.",
+				},
+				cli.BoolFlag{
+					Name: FlagDryRun,
+					Usage: "Print the API calls that would change the calendar instead of making them.",
+				},
+			},
+			Action: gcalAction,
+		},
+		{
+			Name: "notify",
+			Usage: "Announce the next handoff starting within the lead time, if there is one",
+			Flags: []cli.Flag{
+				inFlag,
+				cli.StringFlag{
+					Name: FlagLead,
+					Value: "24h",
+					Usage: "How far ahead to look for a handoff. Accepts days (1d) and weeks (1w) as well as Go durations.",
+				},
+				cli.StringFlag{
+					Name: FlagSlackIDs,
+					Usage: "A JSON file mapping schedule user names to Slack user IDs, to @mention them.",
+				},
+				cli.StringFlag{
+					Name: FlagTemplate,
+					Usage: "A text/template file for the message, executed with the handoff. Defaults to notify.DefaultTemplate.",
+				},
+				cli.StringFlag{
+					Name: FlagDMTemplate,
+					Usage: "A text/template file for the message to each user taking over, executed with the handoff and their tier. Defaults to notify.DefaultDMTemplate.",
+				},
+				cli.StringFlag{
+					Name: FlagWebhook,
+					Usage: "A Slack incoming webhook URL to post the message to. Otherwise, prints it.",
+					EnvVar: "SLACK_WEBHOOK_URL",
+				},
+				cli.BoolFlag{
+					Name: FlagDM,
+					Usage: "Also print the message to each user taking over, prefixed with their name.",
+				},
+			},
+			Action: notifyAction,
+		},
+		{
+			Name: "serve",
+			Usage: "Serve the schedule over HTTP, for the client package to query, and regenerate it on POST /generate",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name: FlagIn,
+					Usage: "The JSON schedule file to serve. Generation replaces it.",
+				},
+				cli.StringFlag{
+					Name: FlagAddr,
+					Value: ":8080",
+					Usage: "The address to listen on.",
+				},
+				cli.StringFlag{
+					Name: FlagToken,
+					Usage: "If set, requests must carry this bearer token.",
+					EnvVar: "ONCALLATOR_TOKEN",
+				},
+				cli.StringFlag{
+					Name: FlagIntegrations,
+					Usage: `A JSON integrations config file. Its "Links" section, with a "Secret" and "BaseURL", enables the signed links of GET /link.`,
+				},
+			},
+			Action: serveAction,
+		},
+		{
+			Name: "terraform",
+			Usage: "Write the input Schedule's rotations as HCL pagerduty_schedule resources for the PagerDuty Terraform provider",
+			Flags: []cli.Flag{
+				inFlag,
+				outFlag,
+				cli.StringFlag{
+					Name: FlagPagerDutyUsers,
+					Usage: "A JSON file mapping schedule user names to PagerDuty user IDs. Required.",
+				},
+				cli.StringFlag{
+					Name: FlagName,
+					Value: terraform.DefaultName,
+					Usage: "The prefix of resource and PagerDuty schedule names.",
+				},
+			},
+			Action: terraformAction,
+		},
+	}
+
+	app.Run(os.Args)
+}
+
+func action(ctx *cli.Context) error {
+	in := ctx.StringSlice(FlagIn)
+	opts, err := parseOptions(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := readSchedule(in, opts...)
+	if err != nil {
+		return err
+	}
+	// TODO(brb): This feels gross. Can we find a way to make Schedule.Generate()
+	// idempotent?
+	if ctx.String(FlagFormat) == FormatSchedule {
+		ns, err := s.Generate()
+		if err != nil {
+			return err
+		}
+		if ctx.Bool(FlagCheck) {
+			return check(s, ns)
+		}
+		s = ns
+		for _, l := range s.Lint() {
+			fmt.Fprintf(os.Stderr, "suggestion: %s\n", l)
+		}
+		return writeSchedule(s, in, ctx.String(FlagOut))
+	}
+	out, err := output(ctx.String(FlagFormat), s)
+	if err != nil {
+		return err
+	}
+	return write(ctx.String(FlagOut), out)
+}
+
+func generateAction(ctx *cli.Context) error {
+	in := ctx.StringSlice(FlagIn)
+	opts, err := parseOptions(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	s, err := readSchedule(in, opts...)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	own := s.Holidays
+	if path := ctx.String(FlagHolidays); path != "" {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		holidays, err := schedule.ParseHolidays(text)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		s.Holidays = append(append([]schedule.Holiday{}, own...), holidays...)
+	}
+	var ns *schedule.Schedule
+	var diff schedule.Diff
+	if ctx.String(FlagRestartAt) != "" && !ctx.Bool(FlagDiscardFuture) {
+		return cli.NewExitError(fmt.Sprintf("-%s requires -%s", FlagRestartAt, FlagDiscardFuture), 1)
+	}
+	if ctx.Bool(FlagDiscardFuture) {
+		if ctx.String(FlagUntil) != "" {
+			return cli.NewExitError(fmt.Sprintf("-%s cannot be used with -%s", FlagUntil, FlagDiscardFuture), 1)
+		}
+		var restart time.Time
+		if text := ctx.String(FlagRestartAt); text != "" {
+			if restart, err = time.Parse(time.RFC3339, text); err != nil {
+				return cli.NewExitError(fmt.Sprintf("error parsing -%s: %s", FlagRestartAt, err), 1)
+			}
+		}
+		ns, diff, err = s.GenerateFresh(restart)
+	} else if until := ctx.String(FlagUntil); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("error parsing -%s: %s", FlagUntil, err), 1)
+		}
+		ns, err = s.Extend(t)
+	} else {
+		ns, err = s.Generate()
+	}
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("%s; nothing was written", err), 1)
+	}
+	// Generation can itself produce a mistake, e.g. with an override that
+	// assigns one user to two tiers.
+	warnings := []schedule.Problem{}
+	if ctx.Bool(FlagStrict) {
+		if warnings, err = ns.ValidateStrict(); err != nil {
+			return cli.NewExitError(fmt.Sprintf("generated schedule: %s; nothing was written", err), 1)
+		}
+	}
+	if !ctx.Bool(FlagDiscardFuture) {
+		diff = schedule.NewDiff(s, ns)
+	}
+	ns.Holidays = own
+	out := ctx.String(FlagOut)
+	if ctx.Bool(FlagDryRun) {
+		out = ""
+	}
+	if err := writeSchedule(ns, in, out); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	var hookErr error
+	if !ctx.Bool(FlagDryRun) {
+		hookErr = (&hooks.Runner{}).Run(context.Background(), s, ns, diff)
+	}
+	// The summary goes to stderr when the schedule itself is on stdout.
+	w := os.Stdout
+	if out == "" {
+		w = os.Stderr
+	}
+	summary := summarize(ns, diff, s.Warnings())
+	if ctx.Bool(FlagJSON) {
+		if err := json.NewEncoder(w).Encode(summary); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(w, summary)
+		fmt.Fprint(w, diff)
+		for _, p := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", p)
+		}
+		for _, w := range summary.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+		for _, l := range summary.Suggestions {
+			fmt.Fprintf(os.Stderr, "suggestion: %s\n", l)
+		}
+	}
+	if hookErr != nil {
+		return cli.NewExitError(fmt.Sprintf("the schedule was written, but %s", hookErr), 2)
+	}
+	return nil
+}
+
+// The options to read the schedule with, given the flags.
+func parseOptions(ctx *cli.Context) ([]schedule.ParseOption, error) {
+	opts := []schedule.ParseOption{}
+	if ctx.Bool(FlagStrict) {
+		opts = append(opts, schedule.Strict())
+	}
+	if asOf := ctx.String(FlagAsOf); asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing -%s: %s", FlagAsOf, err)
+		}
+		opts = append(opts, schedule.WithNow(t))
+	}
+	return opts, nil
+}
+
+func generateAllAction(ctx *cli.Context) error {
+	in := ctx.String(FlagIn)
+	if in == "" {
+		return fmt.Errorf("-%s is required", FlagIn)
+	}
+	text, err := ioutil.ReadFile(in)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	ss, err := schedule.NewScheduleSet(text)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	ns, genErr := ss.GenerateAll()
+	text, err = json.MarshalIndent(ns, "", "  ")
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if err := write(ctx.String(FlagOut), text); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if errs, ok := genErr.(schedule.TeamErrors); ok {
+		for _, team := range ss.Names() {
+			if err := errs[team]; err != nil {
+				fmt.Fprintf(os.Stderr, "team %s: %s\n", team, err)
+			}
+		}
+		return cli.NewExitError(fmt.Sprintf("%d of %d teams could not be generated", len(errs), len(ss.Teams)), 1)
+	}
+	return nil
+}
+
+// What generate did to the schedule.
+type generateSummary struct {
+	// Whether any rotation changed.
+	Changed bool
+	// The rotations discarded by -discard-future, the rotations generated, the
+	// elapsed rotations dropped, and the tiers of kept rotations given to
+	// someone else.
+	Discarded int
+	Added int
+	Truncated int
+	Reassigned int
+	// The rotations in the generated schedule.
+	Rotations int
+	Suggestions []string
+	// Signs of mistaken hand edits to the rotations as read, e.g. for CI to
+	// fail on.
+	Warnings []schedule.Warning
+}
+
+func (g generateSummary) String() string {
+	text := fmt.Sprintf("added %d rotations, truncated %d and reassigned %d tiers; %d rotations are scheduled", g.Added, g.Truncated, g.Reassigned, g.Rotations)
+	if g.Discarded > 0 {
+		// Discarding is never routine, so it leads.
+		return fmt.Sprintf("DISCARDED %d rotations that had not started; %s", g.Discarded, text)
+	}
+	return text
+}
+
+func summarize(ns *schedule.Schedule, d schedule.Diff, warnings []schedule.Warning) generateSummary {
+	return generateSummary{
+		Changed: !d.Empty(),
+		Discarded: len(d.Discarded),
+		Added: len(d.Added),
+		Truncated: len(d.Truncated),
+		Reassigned: len(d.Reassigned),
+		Rotations: len(ns.Rotations),
+		Suggestions: ns.Lint(),
+		Warnings: warnings,
+	}
+}
+
+func validateAction(ctx *cli.Context) error {
+	result := struct {
+		Valid bool
+		Error string `json:",omitempty"`
+		Suggestions []string
+	}{Suggestions: []string{}}
+	opts, err := parseOptions(ctx)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	s, err := readSchedule(ctx.Args(), opts...)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Valid = true
+		result.Suggestions = s.Lint()
+	}
+	if ctx.Bool(FlagJSON) {
+		if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+			return err
+		}
+	} else {
+		for _, l := range result.Suggestions {
+			fmt.Printf("suggestion: %s\n", l)
+		}
+		if result.Valid {
+			fmt.Println("ok")
+		}
+	}
+	if !result.Valid {
+		return cli.NewExitError(result.Error, 1)
+	}
+	return nil
+}
+
+func swapAction(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("swap takes exactly 2 users")
+	}
+	times := []time.Time{}
+	for _, f := range []string{FlagFrom, FlagTo} {
+		if ctx.String(f) == "" {
+			return fmt.Errorf("-%s is required", f)
+		}
+		t, err := time.Parse(time.RFC3339, ctx.String(f))
+		if err != nil {
+			return fmt.Errorf("error parsing -%s: %s", f, err)
+		}
+		times = append(times, t)
+	}
+	in := ctx.StringSlice(FlagIn)
+	s, err := readSchedule(in)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	ns, err := s.Swap(ctx.Args()[0], ctx.Args()[1], times[0], times[1])
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("%s; nothing was written", err), 1)
+	}
+	if err := writeSchedule(ns, in, ctx.String(FlagOut)); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	return nil
+}
+
+func whoAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	at := time.Now()
+	if a := ctx.String(FlagAt); a != "" {
+		if at, err = time.Parse(time.RFC3339, a); err != nil {
+			return cli.NewExitError(fmt.Sprintf("error parsing -%s: %s", FlagAt, err), 1)
+		}
+	}
+	r, err := s.At(at, schedule.SearchHistory)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("at %s: %s", at.Format(time.RFC3339), err), 2)
+	}
+	assigned := s.Assigned(r)
+	if ctx.Bool(FlagJSON) {
+		onCall := map[string]string{}
+		for i, tier := range s.Tiers() {
+			onCall[tier] = assigned[i]
+		}
+		return json.NewEncoder(os.Stdout).Encode(struct {
+			At time.Time
+			Start time.Time
+			End time.Time
+			OnCall map[string]string
+		}{at, r.Start, r.End, onCall})
+	}
+	for i, tier := range s.Tiers() {
+		fmt.Printf("%s: %s\n", tier, assigned[i])
+	}
+	return nil
+}
+
+func configAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(s.Effective(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return write(ctx.String(FlagOut), out)
+}
+
+func migrateAction(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("migrate takes exactly 1 schedule file")
+	}
+	in := ctx.Args().First()
+	text, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	migrated, changes, err := schedule.Migrate(text)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s is already at format version %d\n", in, schedule.CurrentFormatVersion)
+		return nil
+	}
+	s, err := schedule.NewSchedule(migrated)
+	if err != nil {
+		return err
+	}
+	out, err := s.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(in, out, 0660); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Printf("%s: %s\n", in, c)
+	}
+	return nil
+}
+
+func explainAction(ctx *cli.Context) error {
+	if ctx.String(FlagBase) == "" || ctx.String(FlagHead) == "" {
+		return fmt.Errorf("explain requires both -%s and -%s", FlagBase, FlagHead)
+	}
+	base, err := ioutil.ReadFile(ctx.String(FlagBase))
+	if err != nil {
+		return err
+	}
+	head, err := ioutil.ReadFile(ctx.String(FlagHead))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if at := ctx.String(FlagAt); at != "" {
+		if now, err = time.Parse(time.RFC3339, at); err != nil {
+			return fmt.Errorf("error parsing -%s: %s", FlagAt, err)
+		}
+	}
+	if ctx.Bool(FlagJSON) {
+		changes, err := schedule.ExplainChanges(base, head, now)
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		return write(ctx.String(FlagOut), out)
+	}
+	out, err := schedule.Explain(base, head, now)
+	if err != nil {
+		return err
+	}
+	return write(ctx.String(FlagOut), []byte(out))
+}
+
+func capacityAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	window := schedule.TimeRange{Start: time.Now()}
+	if from := ctx.String(FlagFrom); from != "" {
+		if window.Start, err = time.Parse(time.RFC3339, from); err != nil {
+			return fmt.Errorf("error parsing -%s: %s", FlagFrom, err)
+		}
+	}
+	window.End = window.Start.Add(s.ScheduleForDuration)
+	if to := ctx.String(FlagTo); to != "" {
+		if window.End, err = time.Parse(time.RFC3339, to); err != nil {
+			return fmt.Errorf("error parsing -%s: %s", FlagTo, err)
+		}
+	}
+	r := capacity.Analyze(s, window)
+	out := []byte(r.String())
+	if ctx.Bool(FlagJSON) {
+		if out, err = json.MarshalIndent(r, "", "  "); err != nil {
+			return err
+		}
+	}
+	if err := write(ctx.String(FlagOut), out); err != nil {
+		return err
+	}
+	if tight := r.Tight(); ctx.Bool(FlagCheck) && len(tight) > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d rotations have a slack of %d or less", len(tight), capacity.TightSlack), 1)
+	}
+	return nil
+}
+
+func statsAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	window := s.Stats().Window
+	if from := ctx.String(FlagFrom); from != "" {
+		if window.Start, err = time.Parse(time.RFC3339, from); err != nil {
+			return fmt.Errorf("error parsing -%s: %s", FlagFrom, err)
+		}
+	}
+	if to := ctx.String(FlagTo); to != "" {
+		if window.End, err = time.Parse(time.RFC3339, to); err != nil {
+			return fmt.Errorf("error parsing -%s: %s", FlagTo, err)
+		}
+	}
+	st := s.Stats(window.Start, window.End)
+	out := []byte(st.String())
+	if ctx.Bool(FlagJSON) {
+		if out, err = json.MarshalIndent(st, "", "  "); err != nil {
+			return err
+		}
+	}
+	return write(ctx.String(FlagOut), out)
+}
+
+func pushAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	if ctx.String(FlagPagerDutyToken) == "" {
+		return fmt.Errorf("-%s is required", FlagPagerDutyToken)
+	}
+	if ctx.String(FlagPagerDutyPrimary) == "" && ctx.String(FlagPagerDutySecondary) == "" {
+		return fmt.Errorf("no schedules to push to; set -%s or -%s", FlagPagerDutyPrimary, FlagPagerDutySecondary)
+	}
+	users := map[string]string{}
+	if f := ctx.String(FlagPagerDutyUsers); f != "" {
+		text, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(text, &users); err != nil {
+			return fmt.Errorf("error parsing %s: %s", f, err)
+		}
+	}
+	now := time.Now()
+	for _, target := range []struct{ tier, scheduleID string }{
+		{schedule.TierPrimary, ctx.String(FlagPagerDutyPrimary)},
+		{schedule.TierSecondary, ctx.String(FlagPagerDutySecondary)},
+	} {
+		if target.scheduleID == "" {
+			continue
+		}
+		c := &pagerduty.Client{
+			Token: ctx.String(FlagPagerDutyToken),
+			ScheduleID: target.scheduleID,
+			Tier: target.tier,
+			Users: users,
+		}
+		if ctx.Bool(FlagDryRun) {
+			c.DryRun = os.Stdout
+		}
+		r, err := c.Push(context.Background(), s, now)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d overrides created, %d deleted, %d unchanged\n", target.tier, r.Created, r.Deleted, r.Unchanged)
+	}
+	return nil
+}
+
+func gcalAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	if ctx.String(FlagGCalToken) == "" {
+		return fmt.Errorf("-%s is required", FlagGCalToken)
+	}
+	if ctx.String(FlagGCalCalendar) == "" {
+		return fmt.Errorf("-%s is required", FlagGCalCalendar)
+	}
+	emails := map[string]string{}
+	if f := ctx.String(FlagGCalEmails); f != "" {
+		text, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(text, &emails); err != nil {
+			return fmt.Errorf("error parsing %s: %s", f, err)
+		}
+	}
+	c := &gcal.Client{
+		Token: ctx.String(FlagGCalToken),
+		CalendarID: ctx.String(FlagGCalCalendar),
+		Emails: emails,
+	}
+	if ctx.Bool(FlagDryRun) {
+		c.DryRun = os.Stdout
+	}
+	r, err := c.Sync(context.Background(), s, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d events created, %d updated, %d deleted, %d unchanged\n", r.Created, r.Updated, r.Deleted, r.Unchanged)
+	return nil
+}
+
+func notifyAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	lead, err := schedule.ParseDuration(ctx.String(FlagLead))
+	if err != nil {
+		return fmt.Errorf("error parsing -%s: %s", FlagLead, err)
+	}
+	n := &notify.Notifier{SlackIDs: map[string]string{}}
+	if f := ctx.String(FlagSlackIDs); f != "" {
+		text, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(text, &n.SlackIDs); err != nil {
+			return fmt.Errorf("error parsing %s: %s", f, err)
+		}
+	}
+	for _, t := range []struct {
+		flag string
+		template *string
+	}{{FlagTemplate, &n.Template}, {FlagDMTemplate, &n.DMTemplate}} {
+		if f := ctx.String(t.flag); f != "" {
+			text, err := ioutil.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			*t.template = string(text)
+		}
+	}
+	h, ok := notify.NextHandoff(s, time.Now(), lead)
+	if !ok {
+		return nil
+	}
+	m, err := n.Message(h)
+	if err != nil {
+		return err
+	}
+	if url := ctx.String(FlagWebhook); url != "" {
+		if err := (&notify.Webhook{URL: url}).Post(context.Background(), m); err != nil {
+			return fmt.Errorf("error posting to -%s: %s", FlagWebhook, err)
+		}
+	} else {
+		fmt.Println(m)
+	}
+	if ctx.Bool(FlagDM) {
+		dms, err := n.DMs(h)
+		if err != nil {
+			return err
+		}
+		users := []string{}
+		for u := range dms {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		for _, u := range users {
+			fmt.Printf("%s: %s\n", u, dms[u])
+		}
+	}
+	return nil
+}
+
+func serveAction(ctx *cli.Context) error {
+	if ctx.String(FlagIn) == "" {
+		return fmt.Errorf("-%s is required", FlagIn)
+	}
+	storage := server.FileStorage{Path: ctx.String(FlagIn)}
+	if _, err := storage.Load(); err != nil {
+		return err
+	}
+	srv := &server.Server{Storage: storage, Token: ctx.String(FlagToken)}
+	if f := ctx.String(FlagIntegrations); f != "" {
+		text, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		config := integrations{}
+		if err := json.Unmarshal(text, &config); err != nil {
+			return fmt.Errorf("error parsing %s: %s", f, err)
+		}
+		if config.Links != nil {
+			if config.Links.Secret == "" {
+				return fmt.Errorf("%s: Links must have a Secret", f)
+			}
+			srv.Links = &links.Signer{Secret: []byte(config.Links.Secret), BaseURL: config.Links.BaseURL, Nonces: &links.MemoryNonces{}}
+		}
+	}
+	return http.ListenAndServe(ctx.String(FlagAddr), srv)
+}
+
+// The integrations config read from -integrations, holding the settings and
+// secrets of integrations that are kept out of the schedule file.
+type integrations struct {
+	Links *links.Config
+}
+
+func terraformAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	f := ctx.String(FlagPagerDutyUsers)
+	if f == "" {
+		return fmt.Errorf("-%s is required", FlagPagerDutyUsers)
+	}
+	text, err := ioutil.ReadFile(f)
+	if err != nil {
+		return err
+	}
+	users := map[string]string{}
+	if err := json.Unmarshal(text, &users); err != nil {
+		return fmt.Errorf("error parsing %s: %s", f, err)
+	}
+	out := &bytes.Buffer{}
+	if err := terraform.WriteHCL(out, s, terraform.HCLConfig{Users: users, Name: ctx.String(FlagName)}); err != nil {
+		return err
+	}
+	return write(ctx.String(FlagOut), bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+}
+
+// Print the changes generation made to s as JSON, and fail if there are any.
+func check(s, ns *schedule.Schedule) error {
+	changes := schedule.Changes(s, ns, schedule.SourceGenerate)
+	out, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := write("", out); err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return cli.NewExitError(fmt.Sprintf("generation would make %d changes", len(changes)), 1)
+	}
+	return nil
+}
+
+func reconcileAction(ctx *cli.Context) error {
+	s, err := readSchedule(ctx.StringSlice(FlagIn))
+	if err != nil {
+		return err
+	}
+	users := map[string]string{}
+	if f := ctx.String(FlagProviderUsers); f != "" {
+		text, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(text, &users); err != nil {
+			return fmt.Errorf("error parsing %s: %s", f, err)
+		}
+	}
+	providers := []reconcile.Provider{}
+	if ctx.String(FlagPagerDutyToken) != "" {
+		providers = append(providers, &reconcile.PagerDuty{
+			Token: ctx.String(FlagPagerDutyToken),
+			PrimaryScheduleID: ctx.String(FlagPagerDutyPrimary),
+			SecondaryScheduleID: ctx.String(FlagPagerDutySecondary),
+			Users: users,
+		})
+	}
+	if ctx.String(FlagOpsgenieKey) != "" {
+		providers = append(providers, &reconcile.Opsgenie{
+			APIKey: ctx.String(FlagOpsgenieKey),
+			PrimaryScheduleID: ctx.String(FlagOpsgeniePrimary),
+			SecondaryScheduleID: ctx.String(FlagOpsgenieSecondary),
+			Users: users,
+		})
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("no providers configured; set -%s or -%s", FlagPagerDutyToken, FlagOpsgenieKey)
+	}
+
+	mismatches, err := reconcile.Run(context.Background(), s, providers)
+	for _, m := range mismatches {
+		fmt.Println(m)
+	}
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if len(mismatches) > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d providers disagree with the schedule", len(mismatches)), 2)
+	}
+	return nil
+}
+
+func readSchedule(in []string, opts ...schedule.ParseOption) (*schedule.Schedule, error) {
+	if len(in) > 1 {
+		text, err := schedule.MergeComposite(in...)
+		if err != nil {
+			return nil, err
+		}
+		return schedule.NewSchedule(text, opts...)
+	}
+	text := []byte{}
+	if len(in) == 0 {
+		t, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		text = t
+	} else {
+		t, err := ioutil.ReadFile(in[0])
+		if err != nil {
+			return nil, err
+		}
+		text = t
+	}
+	if len(in) == 1 && isYAML(in[0]) {
+		return schedule.NewScheduleYAML(text, opts...)
+	}
+	return schedule.NewSchedule(text, opts...)
+}
+
+// Write a generated schedule to out, or stdout if it is not set: as fragments
+// if it was read from several, as YAML for a YAML input or out, and otherwise
+// as JSON.
+func writeSchedule(s *schedule.Schedule, in []string, out string) error {
+	if len(in) > 1 && out != "" {
+		return schedule.SaveComposite(s, out)
+	}
+	if isYAML(out) || out == "" && len(in) == 1 && isYAML(in[0]) {
+		text := &bytes.Buffer{}
+		if err := s.WriteYAML(text); err != nil {
+			return err
+		}
+		return write(out, text.Bytes())
+	}
+	text, err := output(FormatSchedule, s)
+	if err != nil {
+		return err
+	}
+	return write(out, text)
+}
+
+// Whether the file at path is YAML rather than JSON, judging by its name.
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func output(format string, s *schedule.Schedule) ([]byte, error) {
+	switch format {
+	case FormatSchedule:
+		return s.Marshal()
+	case FormatTerraform:
+		return json.MarshalIndent(terraform.NewLayers(s), "", "  ")
+	case FormatICS:
+		out := &bytes.Buffer{}
+		err := ics.Write(out, s, ics.Config{})
+		return out.Bytes(), err
+	default:
+		return []byte{}, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+func write(out string, text []byte) error {
+	if out == "" {
+		_, err := fmt.Println(string(bytes.TrimSuffix(text, []byte("\n"))))
+		return err
+	} else {
+		return ioutil.WriteFile(out, text, 0660)
+	}
+}
@@ -0,0 +1,126 @@
+// Package grafana turns the schedule's rotations into the on-call shift
+// payloads Grafana OnCall's API and Terraform provider take, one per rotation
+// and tier.
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// The types of shift Shifts can export rotations as.
+const (
+	// Shifts that take precedence over the rest of a schedule's shifts. This
+	// is the default.
+	TypeOverride = "override"
+	// One-off shifts, for a schedule made up of nothing else.
+	TypeSingleEvent = "single_event"
+)
+
+// The layout Grafana OnCall writes shift starts in, without an offset: they
+// are in the shift's TimeZone, which Shifts always sets to UTC.
+const startLayout = "2006-01-02T15:04:05"
+
+// A Shift is the body of a request to create an on-call shift:
+// POST /api/v1/on_call_shifts/, or PUT to /api/v1/on_call_shifts/{id} to
+// replace one pushed before.
+type Shift struct {
+	// Derived from the tier and start of the rotation, so that the shift for a
+	// rotation keeps its name when the schedule is regenerated.
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TeamID string `json:"team_id,omitempty"`
+	TimeZone string `json:"time_zone"`
+	Start string `json:"start"`
+	// In seconds.
+	Duration int64 `json:"duration"`
+	Users []string `json:"users"`
+}
+
+type Config struct {
+	// Maps schedule user names to Grafana OnCall user IDs. Every user with a
+	// rotation must have an entry.
+	Users map[string]string
+	// TypeOverride, the default, or TypeSingleEvent.
+	Type string
+	// The Grafana OnCall team the shifts belong to, if any.
+	TeamID string
+}
+
+// Shifts returns a Shift for each rotation of s and each tier it fills,
+// ordered by start and then by tier. Each lasts from the rotation's start to
+// its end, so a rotation edited by hand to run longer or shorter than
+// RotationLength keeps its length. Nothing is returned if any user is missing
+// an ID, or if any rotation is schedule.Unassigned.
+func Shifts(s *schedule.Schedule, c Config) ([]Shift, error) {
+	kind := c.Type
+	if kind == "" {
+		kind = TypeOverride
+	}
+	if kind != TypeOverride && kind != TypeSingleEvent {
+		return nil, fmt.Errorf("unknown shift type %q: must be %q or %q", kind, TypeOverride, TypeSingleEvent)
+	}
+	shifts := []Shift{}
+	missing := map[string]bool{}
+	unassigned := []string{}
+	for i, r := range s.Rotations {
+		if r.Primary == schedule.Unassigned {
+			unassigned = append(unassigned, r.Start.Format(time.RFC3339))
+			continue
+		}
+		for j, user := range s.Assigned(r) {
+			if user == "" {
+				// The rest of the Grafana OnCall schedule covers the unfilled
+				// tier.
+				continue
+			}
+			id, ok := c.Users[user]
+			if !ok {
+				missing[user] = true
+				continue
+			}
+			shifts = append(shifts, Shift{
+				Name: fmt.Sprintf("oncallator-%s-%s", s.Tiers()[j], r.Start.UTC().Format("20060102T150405Z")),
+				Type: kind,
+				TeamID: c.TeamID,
+				TimeZone: "UTC",
+				Start: r.Start.UTC().Format(startLayout),
+				Duration: int64(s.RotationEnd(i).Sub(r.Start) / time.Second),
+				Users: []string{id},
+			})
+		}
+	}
+	if len(unassigned) > 0 {
+		return nil, fmt.Errorf("nobody is assigned to the rotations starting at: %s", strings.Join(unassigned, ", "))
+	}
+	if len(missing) > 0 {
+		users := []string{}
+		for u := range missing {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		return nil, fmt.Errorf("no Grafana OnCall user ID for users: %s", strings.Join(users, ", "))
+	}
+	return shifts, nil
+}
+
+// Write writes the Shifts of s as a JSON array, one payload per element, e.g.
+// for piping each into curl.
+func Write(w io.Writer, s *schedule.Schedule, c Config) error {
+	shifts, err := Shifts(s, c)
+	if err != nil {
+		return err
+	}
+	text, err := json.MarshalIndent(shifts, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(text))
+	return err
+}
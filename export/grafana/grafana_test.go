@@ -0,0 +1,97 @@
+package grafana
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// The second rotation was extended by hand to nine days, and the third
+// shortened to match.
+const scheduleText = `
+{
+	"Users": ["carol", "alice", "bob"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-08T10:00:00Z",
+			"End": "2017-02-15T10:00:00Z",
+			"Primary": "bob",
+			"Secondary": "carol"
+		},
+		{
+			"Start": "2017-02-15T10:00:00Z",
+			"End": "2017-02-24T10:00:00Z",
+			"Primary": "carol",
+			"Secondary": ""
+		},
+		{
+			"Start": "2017-02-24T10:00:00Z",
+			"End": "2017-03-01T10:00:00Z",
+			"Primary": "alice",
+			"Secondary": "bob"
+		}
+	]
+}`
+
+var config = Config{
+	Users: map[string]string{
+		"alice": "U4DNY931HHJS5",
+		"bob": "U8Z3XGC6BR2KQ",
+		"carol": "UQ2WN7JD5VF1T",
+	},
+	TeamID: "TI73TDU19W48J",
+}
+
+func testSchedule(t *testing.T) *schedule.Schedule {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWriteGolden(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := Write(out, testSchedule(t), config); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "shifts.json")
+	if *update {
+		if err := ioutil.WriteFile(golden, out.Bytes(), 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, out.Bytes()) {
+		t.Errorf("Grafana OnCall export does not match %s\nExpected:\n%s\n---\nGot:\n%s\n", golden, expected, out.Bytes())
+	}
+}
+
+func TestShiftsErrors(t *testing.T) {
+	_, err := Shifts(testSchedule(t), Config{Users: map[string]string{"alice": "U4DNY931HHJS5"}})
+	if err == nil || !strings.Contains(err.Error(), "bob, carol") {
+		t.Errorf("expected an error naming bob and carol, got %v", err)
+	}
+	s := testSchedule(t)
+	s.Rotations[1].Primary = schedule.Unassigned
+	if _, err := Shifts(s, config); err == nil || !strings.Contains(err.Error(), "2017-02-15T10:00:00Z") {
+		t.Errorf("expected an error naming the unassigned rotation, got %v", err)
+	}
+	if _, err := Shifts(testSchedule(t), Config{Users: config.Users, Type: "rolling_users"}); err == nil || !strings.Contains(err.Error(), `unknown shift type "rolling_users"`) {
+		t.Errorf("expected an error for an unknown type, got %v", err)
+	}
+}
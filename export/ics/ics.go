@@ -0,0 +1,195 @@
+// Package ics writes the schedule as an RFC 5545 iCalendar feed, so that
+// people can subscribe to who is on call from their calendar.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	prodID = "-//websdev//oncallator//EN"
+	// The most octets a content line may have before it is folded.
+	lineLength = 75
+
+	dateTimeFormat = "20060102T150405"
+	utcFormat = "20060102T150405Z"
+)
+
+// Used for DTSTAMP; replaced in tests.
+var now = time.Now
+
+type Config struct {
+	// The tiers to write events for, e.g. just schedule.TierPrimary. Each tier
+	// gets its own event per rotation. If empty, every tier of the schedule is
+	// written.
+	Tiers []string
+}
+
+// Write writes a calendar with one event per rotation and tier, summarised as
+// e.g. "On-call primary: alice". Tiers a rotation leaves unfilled get no
+// event.
+//
+// Each event's UID is derived from its tier and start, so a regenerated
+// calendar replaces the events of an earlier one rather than duplicating them,
+// even if who is on call changed. If the schedule has a Timezone, events are
+// written in it, with a VTIMEZONE describing its offsets over the rotations;
+// otherwise they are written in UTC.
+func Write(w io.Writer, s *schedule.Schedule, c Config) error {
+	tiers := s.Tiers()
+	if len(c.Tiers) > 0 {
+		for _, t := range c.Tiers {
+			if indexOf(tiers, t) < 0 {
+				return fmt.Errorf("unknown tier %s (the schedule's tiers are %s)", t, strings.Join(tiers, ", "))
+			}
+		}
+		tiers = c.Tiers
+	}
+
+	loc := location(s)
+	b := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(b, name + ":" + value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	if loc != nil && len(s.Rotations) > 0 {
+		writeTimezone(b, loc, s.Rotations[0].Start, s.RotationEnd(len(s.Rotations) - 1))
+	}
+	stamp := now().UTC().Format(utcFormat)
+	for i, r := range s.Rotations {
+		assigned := s.Assigned(r)
+		for _, tier := range tiers {
+			user := assigned[indexOf(s.Tiers(), tier)]
+			if user == "" {
+				continue
+			}
+			line("BEGIN", "VEVENT")
+			line("UID", uid(r.Start, tier))
+			line("DTSTAMP", stamp)
+			writeLine(b, "DTSTART" + dateTime(r.Start, loc))
+			writeLine(b, "DTEND" + dateTime(s.RotationEnd(i), loc))
+			line("SUMMARY", escape(fmt.Sprintf("On-call %s: %s", tier, user)))
+			if r.Notes != "" {
+				line("DESCRIPTION", escape(r.Notes))
+			}
+			line("TRANSP", "TRANSPARENT")
+			line("END", "VEVENT")
+		}
+	}
+	line("END", "VCALENDAR")
+	return b.Flush()
+}
+
+// The location of the schedule's Timezone, or nil if it has none.
+func location(s *schedule.Schedule) *time.Location {
+	if s.Timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		// NewSchedule has already loaded it successfully.
+		return nil
+	}
+	return loc
+}
+
+func uid(start time.Time, tier string) string {
+	return fmt.Sprintf("%s-%s@oncallator", start.UTC().Format(utcFormat), tier)
+}
+
+// Format t as the value of a DATE-TIME property, including the separator:
+// local time with a TZID parameter in loc, or UTC without one.
+func dateTime(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		return ":" + t.UTC().Format(utcFormat)
+	}
+	return fmt.Sprintf(";TZID=%s:%s", loc.String(), t.In(loc).Format(dateTimeFormat))
+}
+
+// Write a VTIMEZONE for loc with an observance for each offset it uses
+// between from and to.
+func writeTimezone(b *bufio.Writer, loc *time.Location, from, to time.Time) {
+	writeLine(b, "BEGIN:VTIMEZONE")
+	writeLine(b, "TZID:" + loc.String())
+	t := from.In(loc)
+	for {
+		name, offset := t.Zone()
+		start, end := t.ZoneBounds()
+		component := "STANDARD"
+		if t.IsDST() {
+			component = "DAYLIGHT"
+		}
+		// An observance's DTSTART is the local time it starts at, in the
+		// offset before it.
+		before := offset
+		onset := "19700101T000000"
+		if !start.IsZero() {
+			_, before = start.Add(-time.Second).In(loc).Zone()
+			onset = start.UTC().Add(time.Duration(before) * time.Second).Format(dateTimeFormat)
+		}
+		writeLine(b, "BEGIN:" + component)
+		writeLine(b, "DTSTART:" + onset)
+		writeLine(b, "TZOFFSETFROM:" + utcOffset(before))
+		writeLine(b, "TZOFFSETTO:" + utcOffset(offset))
+		writeLine(b, "TZNAME:" + escape(name))
+		writeLine(b, "END:" + component)
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		t = end.In(loc)
+	}
+	writeLine(b, "END:VTIMEZONE")
+}
+
+// Format an offset in seconds east of UTC as a UTC-OFFSET, e.g. -0500.
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	text := fmt.Sprintf("%s%02d%02d", sign, seconds / 3600, seconds % 3600 / 60)
+	if seconds % 60 != 0 {
+		text += fmt.Sprintf("%02d", seconds % 60)
+	}
+	return text
+}
+
+// Escape a TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// Write a content line, folded so that no line is longer than lineLength
+// octets. Lines are only folded between UTF-8 characters.
+func writeLine(b *bufio.Writer, l string) {
+	limit := lineLength
+	for len(l) > limit {
+		cut := limit
+		for cut > 0 && l[cut] & 0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(l[:cut] + "\r\n ")
+		l = l[cut:]
+		// The leading space of a continuation line counts towards its length.
+		limit = lineLength - 1
+	}
+	b.WriteString(l + "\r\n")
+}
+
+func indexOf(list []string, s string) int {
+	for i, x := range list {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,263 @@
+package ics
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Rotations across the start of daylight saving time in New York, on
+// 2017-03-12.
+const scheduleText = `
+{
+	"Users": ["carol", "alice", "bob"],
+	"Start": "2017-03-15T10:00:00-04:00",
+	"Timezone": "America/New_York",
+	"RotationLength": "1w",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-03-01T10:00:00-05:00",
+			"End": "2017-03-08T10:00:00-05:00",
+			"Primary": "alice",
+			"Secondary": "bob"
+		},
+		{
+			"Start": "2017-03-08T10:00:00-05:00",
+			"End": "2017-03-15T10:00:00-04:00",
+			"Primary": "bob",
+			"Secondary": "carol",
+			"Notes": "swapped with carol; back on the 15th"
+		},
+		{
+			"Start": "2017-03-15T10:00:00-04:00",
+			"End": "2017-03-22T10:00:00-04:00",
+			"Primary": "carol",
+			"Secondary": ""
+		}
+	]
+}`
+
+func init() {
+	now = func() time.Time {
+		return time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// An event read back from a calendar.
+type event struct {
+	props map[string]string
+	params map[string]string
+}
+
+// Parse the events of a calendar, unfolding lines and checking that each is
+// CRLF-terminated, no longer than 75 octets, and inside balanced BEGIN and
+// END lines.
+func parse(t *testing.T, text string) []event {
+	if !strings.HasSuffix(text, "\r\n") {
+		t.Fatalf("calendar does not end with CRLF")
+	}
+	raw := strings.Split(strings.TrimSuffix(text, "\r\n"), "\r\n")
+	lines := []string{}
+	for _, l := range raw {
+		if len(l) > lineLength {
+			t.Errorf("line longer than %d octets: %q", lineLength, l)
+		}
+		if strings.HasPrefix(l, " ") {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	events := []event{}
+	stack := []string{}
+	for _, l := range lines {
+		colon := strings.Index(l, ":")
+		if colon < 0 {
+			t.Fatalf("line without a value: %q", l)
+		}
+		name, value := l[:colon], l[colon+1:]
+		param := ""
+		if semi := strings.Index(name, ";"); semi >= 0 {
+			name, param = name[:semi], name[semi+1:]
+		}
+		switch name {
+		case "BEGIN":
+			stack = append(stack, value)
+			if value == "VEVENT" {
+				events = append(events, event{map[string]string{}, map[string]string{}})
+			}
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != value {
+				t.Fatalf("unbalanced END:%s in %v", value, stack)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) > 0 && stack[len(stack)-1] == "VEVENT" {
+				events[len(events)-1].props[name] = value
+				events[len(events)-1].params[name] = param
+			}
+		}
+	}
+	if len(stack) != 0 {
+		t.Fatalf("unterminated components %v", stack)
+	}
+	return events
+}
+
+// Read back a DATE-TIME property of e.
+func (e event) time(t *testing.T, name string) time.Time {
+	value, param := e.props[name], e.params[name]
+	if param == "" {
+		parsed, err := time.Parse(utcFormat, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	if !strings.HasPrefix(param, "TZID=") {
+		t.Fatalf("unexpected parameter %s on %s", param, name)
+	}
+	loc, err := time.LoadLocation(strings.TrimPrefix(param, "TZID="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := time.ParseInLocation(dateTimeFormat, value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func write(t *testing.T, text string, c Config) string {
+	s, err := schedule.NewSchedule([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := Write(out, s, c); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestWrite(t *testing.T) {
+	got := write(t, scheduleText, Config{})
+	golden := filepath.Join("testdata", "new-york.ics")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestWriteRoundTripsBoundaries(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := parse(t, write(t, scheduleText, Config{Tiers: []string{schedule.TierPrimary}}))
+	if len(events) != len(s.Rotations) {
+		t.Fatalf("expected %d events, got %d", len(s.Rotations), len(events))
+	}
+	for i, e := range events {
+		if start := e.time(t, "DTSTART"); !start.Equal(s.Rotations[i].Start) {
+			t.Errorf("event %d: expected start %s, got %s", i, s.Rotations[i].Start, start)
+		}
+		if end := e.time(t, "DTEND"); !end.Equal(s.RotationEnd(i)) {
+			t.Errorf("event %d: expected end %s, got %s", i, s.RotationEnd(i), end)
+		}
+		if e.params["DTSTART"] != "TZID=America/New_York" {
+			t.Errorf("event %d: expected the schedule's zone, got %q", i, e.params["DTSTART"])
+		}
+		expected := "On-call primary: " + s.Rotations[i].Primary
+		if e.props["SUMMARY"] != expected {
+			t.Errorf("event %d: expected summary %q, got %q", i, expected, e.props["SUMMARY"])
+		}
+	}
+}
+
+func TestWriteUTCWithoutTimezone(t *testing.T) {
+	text := strings.Replace(scheduleText, `"Timezone": "America/New_York",`, "", 1)
+	out := write(t, text, Config{Tiers: []string{schedule.TierSecondary}})
+	if strings.Contains(out, "VTIMEZONE") || strings.Contains(out, "TZID") {
+		t.Errorf("expected no time zone without a Timezone, got:\n%s", out)
+	}
+	events := parse(t, out)
+	// The last rotation has no secondary.
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	expected := time.Date(2017, time.March, 8, 15, 0, 0, 0, time.UTC)
+	if start := events[1].time(t, "DTSTART"); !start.Equal(expected) {
+		t.Errorf("expected start %s, got %s", expected, start)
+	}
+}
+
+func TestWriteUIDsStable(t *testing.T) {
+	before := parse(t, write(t, scheduleText, Config{}))
+	// Someone else takes the first rotation.
+	changed := strings.Replace(scheduleText, `"Primary": "alice"`, `"Primary": "dave"`, 1)
+	after := parse(t, write(t, changed, Config{}))
+	if len(before) != len(after) {
+		t.Fatalf("expected %d events, got %d", len(before), len(after))
+	}
+	seen := map[string]bool{}
+	for i := range before {
+		uid := before[i].props["UID"]
+		if seen[uid] {
+			t.Errorf("UID %s is used by more than one event", uid)
+		}
+		seen[uid] = true
+		if after[i].props["UID"] != uid {
+			t.Errorf("event %d: expected UID %s to be kept, got %s", i, uid, after[i].props["UID"])
+		}
+	}
+	if after[0].props["SUMMARY"] != "On-call primary: dave" {
+		t.Errorf("expected the changed primary, got %q", after[0].props["SUMMARY"])
+	}
+}
+
+func TestWriteUnknownTier(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Write(&bytes.Buffer{}, s, Config{Tiers: []string{"tertiary"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown tier tertiary") {
+		t.Errorf("expected an error about the unknown tier, got %v", err)
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := bufio.NewWriter(b)
+	long := "SUMMARY:" + strings.Repeat("é", 60)
+	writeLine(w, long)
+	w.Flush()
+	unfolded := strings.Replace(strings.TrimSuffix(b.String(), "\r\n"), "\r\n ", "", -1)
+	if unfolded != long {
+		t.Errorf("expected folding to be undone by unfolding, got %q", unfolded)
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(l) > lineLength {
+			t.Errorf("line longer than %d octets: %q", lineLength, l)
+		}
+	}
+}
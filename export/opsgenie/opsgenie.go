@@ -0,0 +1,111 @@
+// Package opsgenie turns the schedule's rotations into the payloads Opsgenie's
+// schedule override API expects, one per rotation and tier.
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// An Override is the body of a request to create or update a schedule
+// override: POST /v2/schedules/{id}/overrides, or PUT to
+// /v2/schedules/{id}/overrides/{alias} to replace one pushed before.
+type Override struct {
+	// Derived from the tier and start of the rotation, so that the override
+	// for a rotation keeps its alias when the schedule is regenerated.
+	Alias string `json:"alias"`
+	User Participant `json:"user"`
+	StartDate time.Time `json:"startDate"`
+	EndDate time.Time `json:"endDate"`
+	Rotations []RotationRef `json:"rotations"`
+}
+
+type Participant struct {
+	Type string `json:"type"`
+	Username string `json:"username"`
+}
+
+type RotationRef struct {
+	Name string `json:"name"`
+}
+
+type Config struct {
+	// Maps schedule user names to Opsgenie usernames, which are usually email
+	// addresses. Every user with a rotation must have an entry.
+	Usernames map[string]string
+	// Maps each tier to the name of the Opsgenie rotation it is kept in, since
+	// Opsgenie models each tier as a separate rotation. A tier missing from
+	// the map uses its own name.
+	Rotations map[string]string
+}
+
+// Overrides returns an Override for each rotation of s and each tier it
+// fills, ordered by start and then by tier. Nothing is returned if any user
+// is missing a username, or if any rotation is schedule.Unassigned.
+func Overrides(s *schedule.Schedule, c Config) ([]Override, error) {
+	overrides := []Override{}
+	missing := map[string]bool{}
+	unassigned := []string{}
+	for i, r := range s.Rotations {
+		if r.Primary == schedule.Unassigned {
+			unassigned = append(unassigned, r.Start.Format(time.RFC3339))
+			continue
+		}
+		for j, user := range s.Assigned(r) {
+			if user == "" {
+				// Opsgenie's own rotation covers the unfilled tier.
+				continue
+			}
+			tier := s.Tiers()[j]
+			username, ok := c.Usernames[user]
+			if !ok {
+				missing[user] = true
+				continue
+			}
+			rotation := tier
+			if name, ok := c.Rotations[tier]; ok {
+				rotation = name
+			}
+			overrides = append(overrides, Override{
+				Alias: fmt.Sprintf("oncallator-%s-%s", tier, r.Start.UTC().Format("20060102T150405Z")),
+				User: Participant{Type: "user", Username: username},
+				StartDate: r.Start,
+				EndDate: s.RotationEnd(i),
+				Rotations: []RotationRef{{Name: rotation}},
+			})
+		}
+	}
+	if len(unassigned) > 0 {
+		return nil, fmt.Errorf("nobody is assigned to the rotations starting at: %s", strings.Join(unassigned, ", "))
+	}
+	if len(missing) > 0 {
+		users := []string{}
+		for u := range missing {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		return nil, fmt.Errorf("no Opsgenie username for users: %s", strings.Join(users, ", "))
+	}
+	return overrides, nil
+}
+
+// Write writes the Overrides of s as a JSON array, one payload per element,
+// e.g. for piping each into curl.
+func Write(w io.Writer, s *schedule.Schedule, c Config) error {
+	overrides, err := Overrides(s, c)
+	if err != nil {
+		return err
+	}
+	text, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(text))
+	return err
+}
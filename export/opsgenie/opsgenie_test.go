@@ -0,0 +1,125 @@
+package opsgenie
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+const scheduleText = `
+{
+	"Users": ["carol", "alice", "bob"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-08T10:00:00Z",
+			"End": "2017-02-15T10:00:00Z",
+			"Primary": "bob",
+			"Secondary": "carol"
+		},
+		{
+			"Start": "2017-02-15T10:00:00Z",
+			"End": "2017-02-22T10:00:00Z",
+			"Primary": "carol",
+			"Secondary": ""
+		},
+		{
+			"Start": "2017-02-22T10:00:00Z",
+			"End": "2017-03-01T10:00:00Z",
+			"Primary": "alice",
+			"Secondary": "bob"
+		}
+	]
+}`
+
+var config = Config{
+	Usernames: map[string]string{
+		"alice": "alice@example.com",
+		"bob": "bob@example.com",
+		"carol": "carol@example.com",
+	},
+	Rotations: map[string]string{
+		schedule.TierPrimary: "Primary rotation",
+	},
+}
+
+func testSchedule(t *testing.T) *schedule.Schedule {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWriteGolden(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := Write(out, testSchedule(t), config); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "overrides.json")
+	if *update {
+		if err := ioutil.WriteFile(golden, out.Bytes(), 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, out.Bytes()) {
+		t.Errorf("Opsgenie export does not match %s\nExpected:\n%s\n---\nGot:\n%s\n", golden, expected, out.Bytes())
+	}
+}
+
+func TestOverridesKeepAliasesAcrossRegeneration(t *testing.T) {
+	before, err := Overrides(testSchedule(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testSchedule(t)
+	s.Rotations[0].Primary = "alice"
+	after, err := Overrides(s, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0].Alias != after[0].Alias {
+		t.Errorf("expected the alias %s to be kept, got %s", before[0].Alias, after[0].Alias)
+	}
+	if after[0].User.Username != "alice@example.com" {
+		t.Errorf("expected the override to be for alice, got %s", after[0].User.Username)
+	}
+	seen := map[string]bool{}
+	for _, o := range before {
+		if seen[o.Alias] {
+			t.Errorf("alias %s is used by more than one override", o.Alias)
+		}
+		seen[o.Alias] = true
+	}
+}
+
+func TestOverridesMissingUsername(t *testing.T) {
+	_, err := Overrides(testSchedule(t), Config{Usernames: map[string]string{"alice": "alice@example.com"}})
+	if err == nil || !strings.Contains(err.Error(), "bob, carol") {
+		t.Errorf("expected an error naming bob and carol, got %v", err)
+	}
+}
+
+func TestOverridesUnassigned(t *testing.T) {
+	s := testSchedule(t)
+	s.Rotations[1].Primary = schedule.Unassigned
+	s.Rotations[1].Secondary = schedule.Unassigned
+	_, err := Overrides(s, config)
+	if err == nil || !strings.Contains(err.Error(), "2017-02-15T10:00:00Z") {
+		t.Errorf("expected an error naming the unassigned rotation, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package payroll writes on-call assignments as a fixed-width positional text
+// file for payroll ingestion of on-call allowances.
+package payroll
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	RolePrimary = "P"
+	RoleSecondary = "S"
+
+	dateFormat = "20060102"
+)
+
+// Widths holds the width in characters of each field of a record. Text fields
+// are left-aligned and padded with spaces, and numeric fields are right-aligned
+// and padded with zeros. A value too long for its field is an error, since
+// truncating it could pay the wrong employee or the wrong amount.
+type Widths struct {
+	EmployeeID int
+	Start int
+	End int
+	Role int
+	Hours int
+}
+
+var DefaultWidths = Widths{
+	EmployeeID: 10,
+	Start: 8,
+	End: 8,
+	Role: 1,
+	Hours: 4,
+}
+
+type Config struct {
+	// Maps schedule user names to employee IDs. Every user with a shift in the
+	// exported month must have an entry.
+	EmployeeIDs map[string]string
+	// The zero Widths means DefaultWidths.
+	Widths Widths
+}
+
+// Write writes one record per user and role for every rotation overlapping
+// month, clipped to the month. Periods are written as the first and last day
+// of the (clipped) shift, and hours are whole hours.
+//
+// Rotations from an archive of elapsed rotations may be passed in history;
+// they are combined with the schedule's live Rotations. Nothing is written if
+// any user is missing an employee ID, or if any rotation in the month is
+// schedule.Unassigned.
+func Write(w io.Writer, s *schedule.Schedule, history []schedule.Rotation, month time.Time, c Config) error {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	to := from.AddDate(0, 1, 0)
+	widths := c.Widths
+	if widths == (Widths{}) {
+		widths = DefaultWidths
+	}
+
+	rotations := append(append([]schedule.Rotation{}, history...), s.Rotations...)
+	lines := []string{}
+	missing := map[string]bool{}
+	unassigned := []string{}
+	for i, r := range rotations {
+		end := r.End
+		if end.IsZero() {
+			end = s.NominalEnd(r.Start)
+			if i + 1 < len(rotations) {
+				end = rotations[i+1].Start
+			} else if len(s.Rotations) > 0 {
+				end = s.RotationEnd(len(s.Rotations) - 1)
+			}
+		}
+		start := r.Start
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !start.Before(end) {
+			continue
+		}
+		if r.Primary == schedule.Unassigned {
+			unassigned = append(unassigned, r.Start.Format(time.RFC3339))
+			continue
+		}
+		for _, a := range []struct{ user, role string }{
+			{r.Primary, RolePrimary},
+			{r.Secondary, RoleSecondary},
+		} {
+			if a.user == "" {
+				// The tier is unfilled, so nobody is owed an allowance.
+				continue
+			}
+			id, ok := c.EmployeeIDs[a.user]
+			if !ok {
+				missing[a.user] = true
+				continue
+			}
+			l, err := record(widths, id, start, end, a.role)
+			if err != nil {
+				return fmt.Errorf("%s's record for the rotation starting at %s: %s", a.user, r.Start.Format(time.RFC3339), err)
+			}
+			lines = append(lines, l)
+		}
+	}
+	if len(unassigned) > 0 {
+		return fmt.Errorf("nobody is assigned to the rotations starting at: %s", strings.Join(unassigned, ", "))
+	}
+	if len(missing) > 0 {
+		users := []string{}
+		for u := range missing {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		return fmt.Errorf("no employee ID for users: %s", strings.Join(users, ", "))
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func record(widths Widths, id string, start, end time.Time, role string) (string, error) {
+	hours := int(end.Sub(start).Hours())
+	// The period end is the last day of the shift, not the day it hands off.
+	last := end.Add(-time.Nanosecond).In(start.Location())
+	l := ""
+	for _, f := range []struct {
+		name, value string
+		width int
+	}{
+		{"employee ID", id, widths.EmployeeID},
+		{"start", start.Format(dateFormat), widths.Start},
+		{"end", last.Format(dateFormat), widths.End},
+		{"role", role, widths.Role},
+	} {
+		t, err := text(f.value, f.width)
+		if err != nil {
+			return "", fmt.Errorf("%s %s", f.name, err)
+		}
+		l += t
+	}
+	n, err := number(hours, widths.Hours)
+	if err != nil {
+		return "", fmt.Errorf("hours %s", err)
+	}
+	return l + n, nil
+}
+
+func text(s string, width int) (string, error) {
+	if len(s) > width {
+		return "", fmt.Errorf("%q does not fit in %d characters", s, width)
+	}
+	return s + strings.Repeat(" ", width - len(s)), nil
+}
+
+func number(n, width int) (string, error) {
+	s := fmt.Sprintf("%0*d", width, n)
+	if len(s) > width {
+		return "", fmt.Errorf("%d does not fit in %d digits", n, width)
+	}
+	return s, nil
+}
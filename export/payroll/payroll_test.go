@@ -0,0 +1,171 @@
+package payroll
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+const scheduleText = `
+{
+	"Users": ["carol", "alice", "bob"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-08T10:00:00Z",
+			"Primary": "bob",
+			"Secondary": "carol"
+		},
+		{
+			"Start": "2017-02-15T10:00:00Z",
+			"Primary": "carol",
+			"Secondary": "alice"
+		},
+		{
+			"Start": "2017-02-22T10:00:00Z",
+			"Primary": "alice",
+			"Secondary": "bob"
+		}
+	]
+}`
+
+var (
+	history = []schedule.Rotation{
+		{
+			Start: time.Date(2017, time.January, 25, 10, 0, 0, 0, time.UTC),
+			Primary: "carol",
+			Secondary: "alice",
+		},
+		{
+			Start: time.Date(2017, time.February, 1, 10, 0, 0, 0, time.UTC),
+			Primary: "alice",
+			Secondary: "bob",
+		},
+	}
+	employeeIDs = map[string]string{
+		"alice": "E1001",
+		"bob": "E1002",
+		"carol": "E1003",
+	}
+)
+
+func TestWriteGolden(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	c := Config{EmployeeIDs: employeeIDs, Widths: DefaultWidths}
+	if err := Write(out, s, history, time.Date(2017, time.February, 1, 0, 0, 0, 0, time.UTC), c); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "2017-02.txt")
+	if *update {
+		if err := ioutil.WriteFile(golden, out.Bytes(), 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, out.Bytes()) {
+		t.Errorf("payroll export does not match %s\nExpected:\n%s\n---\nGot:\n%s\n", golden, expected, out.Bytes())
+	}
+}
+
+func TestWriteMissingEmployeeID(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	c := Config{EmployeeIDs: map[string]string{"alice": "E1001"}, Widths: DefaultWidths}
+	err = Write(out, s, history, time.Date(2017, time.February, 1, 0, 0, 0, 0, time.UTC), c)
+	if err == nil || !strings.Contains(err.Error(), "bob, carol") {
+		t.Errorf("expected an error naming bob and carol, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be written, got:\n%s", out.String())
+	}
+}
+
+func TestWriteUnassigned(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Rotations[1].Primary = schedule.Unassigned
+	s.Rotations[1].Secondary = schedule.Unassigned
+	out := &bytes.Buffer{}
+	c := Config{EmployeeIDs: employeeIDs, Widths: DefaultWidths}
+	err = Write(out, s, history, time.Date(2017, time.February, 1, 0, 0, 0, 0, time.UTC), c)
+	if err == nil || !strings.Contains(err.Error(), "2017-02-15T10:00:00Z") {
+		t.Errorf("expected an error naming the unassigned rotation, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be written, got:\n%s", out.String())
+	}
+}
+
+func TestWriteDefaultWidths(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	month := time.Date(2017, time.February, 1, 0, 0, 0, 0, time.UTC)
+	expected, got := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := Write(expected, s, history, month, Config{EmployeeIDs: employeeIDs, Widths: DefaultWidths}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(got, s, history, month, Config{EmployeeIDs: employeeIDs}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected.Bytes(), got.Bytes()) {
+		t.Errorf("expected zero Widths to mean DefaultWidths\nExpected:\n%s\n---\nGot:\n%s\n", expected, got)
+	}
+}
+
+func TestWriteValueTooLong(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	widths := DefaultWidths
+	widths.EmployeeID = 4
+	c := Config{EmployeeIDs: employeeIDs, Widths: widths}
+	err = Write(out, s, history, time.Date(2017, time.February, 1, 0, 0, 0, 0, time.UTC), c)
+	if err == nil || !strings.Contains(err.Error(), `employee ID "E100`) {
+		t.Errorf("expected an error for an employee ID too long for its field, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be written, got:\n%s", out.String())
+	}
+}
+
+func TestFieldPadding(t *testing.T) {
+	if got, err := text("E1001", 8); err != nil || got != "E1001   " {
+		t.Errorf("expected text to be padded, got %q, %v", got, err)
+	}
+	if _, err := text("E100123456", 8); err == nil {
+		t.Errorf("expected an error for text too long for its field")
+	}
+	if got, err := number(42, 4); err != nil || got != "0042" {
+		t.Errorf("expected number to be zero-padded, got %q, %v", got, err)
+	}
+	if _, err := number(12345, 4); err == nil {
+		t.Errorf("expected an error for a number too long for its field")
+	}
+}
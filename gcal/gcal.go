@@ -0,0 +1,352 @@
+// Package gcal syncs generated rotations to a Google Calendar, one event per
+// rotation and tier, so that everyone can see who is on call alongside the
+// rest of their week.
+package gcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	DefaultURL = "https://www.googleapis.com/calendar/v3"
+	// How many times a request rate limited with a 429 is retried.
+	MaxRetries = 5
+	// The private extended property marking the events Sync owns, as "true".
+	ManagedProperty = "oncallator"
+	// The private extended property holding the key of each event Sync owns.
+	KeyProperty = "oncallatorKey"
+
+	pageSize = 250
+)
+
+// Used to wait out rate limits; replaced in tests.
+var sleep = time.Sleep
+
+// A Client syncs the rotations of a schedule to a Google Calendar.
+//
+// The client owns the events of the calendar marked with ManagedProperty. Each
+// is keyed by the start and tier of the rotation it shows, so running Sync
+// again updates the events in place rather than duplicating them, and deletes
+// those of rotations that no longer exist. Other events are left alone.
+type Client struct {
+	// An OAuth 2.0 access token with the calendar.events scope. If empty,
+	// Client must authorize requests itself, as one from golang.org/x/oauth2
+	// does.
+	Token string
+	CalendarID string
+	// Maps schedule user names to email addresses. A user with an entry is
+	// added to their events as an attendee; one without is only named in the
+	// title.
+	Emails map[string]string
+	// If set, the changes Sync would make are written here as the API calls
+	// that would make them, and the calendar is left as it is. The existing
+	// events are still listed to work out the changes.
+	DryRun io.Writer
+	// Defaults to DefaultURL.
+	BaseURL string
+	// Defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+// An event of the calendar, as Google lists it or as Sync wants it.
+type event struct {
+	ID string `json:"id,omitempty"`
+	Summary string `json:"summary"`
+	Start eventTime `json:"start"`
+	End eventTime `json:"end"`
+	Attendees []attendee `json:"attendees,omitempty"`
+	ExtendedProperties struct {
+		Private map[string]string `json:"private,omitempty"`
+	} `json:"extendedProperties"`
+}
+
+type eventTime struct {
+	DateTime time.Time `json:"dateTime"`
+}
+
+type attendee struct {
+	Email string `json:"email"`
+}
+
+func (e event) key() string {
+	return e.ExtendedProperties.Private[KeyProperty]
+}
+
+func (e event) String() string {
+	return fmt.Sprintf("%s from %s to %s", e.Summary, e.Start.DateTime.Format(time.RFC3339), e.End.DateTime.Format(time.RFC3339))
+}
+
+// Whether e shows the same thing as o, ignoring IDs.
+func (e event) matches(o event) bool {
+	if e.Summary != o.Summary || !e.Start.DateTime.Equal(o.Start.DateTime) || !e.End.DateTime.Equal(o.End.DateTime) || len(e.Attendees) != len(o.Attendees) {
+		return false
+	}
+	for i := range e.Attendees {
+		if !strings.EqualFold(e.Attendees[i].Email, o.Attendees[i].Email) {
+			return false
+		}
+	}
+	return true
+}
+
+// Key returns the key of the event for the given tier of the rotation
+// starting at start.
+func Key(start time.Time, tier string) string {
+	return start.UTC().Format("20060102T150405Z") + "-" + tier
+}
+
+// What Sync changed, or would change in a dry run.
+type Result struct {
+	Created int
+	Updated int
+	Deleted int
+	Unchanged int
+}
+
+// Sync makes the events of the calendar match every tier of the rotations of
+// s that end after from, titled e.g. "On-call primary: alice". Tiers left
+// unfilled, or filled by schedule.Unassigned, get no event. Events for
+// rotations starting at or after the first synced one whose key is no longer
+// wanted are deleted.
+func (c *Client) Sync(ctx context.Context, s *schedule.Schedule, from time.Time) (Result, error) {
+	wanted := c.wanted(s, from)
+	if len(wanted) == 0 {
+		return Result{}, nil
+	}
+	existing, err := c.list(ctx, from)
+	if err != nil {
+		return Result{}, fmt.Errorf("error listing events of calendar %s: %s", c.CalendarID, err)
+	}
+
+	byKey := map[string]event{}
+	stale := []event{}
+	for _, e := range existing {
+		if _, ok := byKey[e.key()]; ok {
+			// A duplicate, e.g. from an earlier sync that was interrupted.
+			stale = append(stale, e)
+			continue
+		}
+		byKey[e.key()] = e
+	}
+	result := Result{}
+	keep := map[string]bool{}
+	for _, e := range wanted {
+		keep[e.key()] = true
+	}
+	for _, e := range existing {
+		if !keep[e.key()] && !e.Start.DateTime.Before(wanted[0].Start.DateTime) && byKey[e.key()].ID == e.ID {
+			stale = append(stale, e)
+		}
+	}
+	for _, e := range stale {
+		if err := c.delete(ctx, e); err != nil {
+			return result, fmt.Errorf("error deleting stale event %s (%s): %s", e.ID, e, err)
+		}
+		result.Deleted++
+	}
+	for _, e := range wanted {
+		old, ok := byKey[e.key()]
+		switch {
+		case !ok:
+			if err := c.create(ctx, e); err != nil {
+				return result, fmt.Errorf("error creating the event %s: %s", e, err)
+			}
+			result.Created++
+		case !old.matches(e):
+			e.ID = old.ID
+			if err := c.update(ctx, e); err != nil {
+				return result, fmt.Errorf("error updating event %s to %s: %s", old.ID, e, err)
+			}
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+	return result, nil
+}
+
+// Return the events wanted for the rotations of s ending after from, in
+// order.
+func (c *Client) wanted(s *schedule.Schedule, from time.Time) []event {
+	wanted := []event{}
+	tiers := s.Tiers()
+	for i, r := range s.Rotations {
+		end := s.RotationEnd(i)
+		if !end.After(from) {
+			continue
+		}
+		for tier, user := range s.Assigned(r) {
+			if user == "" || user == schedule.Unassigned {
+				continue
+			}
+			e := event{
+				Summary: fmt.Sprintf("On-call %s: %s", tiers[tier], user),
+				Start: eventTime{r.Start},
+				End: eventTime{end},
+			}
+			if email, ok := c.Emails[user]; ok {
+				e.Attendees = []attendee{{Email: email}}
+			}
+			e.ExtendedProperties.Private = map[string]string{
+				ManagedProperty: "true",
+				KeyProperty: Key(r.Start, tiers[tier]),
+			}
+			wanted = append(wanted, e)
+		}
+	}
+	return wanted
+}
+
+// List the events of the calendar Sync owns ending after from,
+// following pagination.
+func (c *Client) list(ctx context.Context, from time.Time) ([]event, error) {
+	events := []event{}
+	for token := ""; ; {
+		q := url.Values{}
+		q.Set("timeMin", from.Format(time.RFC3339))
+		q.Set("privateExtendedProperty", ManagedProperty + "=true")
+		q.Set("singleEvents", "true")
+		q.Set("maxResults", strconv.Itoa(pageSize))
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		body := struct {
+			Items []event `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+		if err := c.do(ctx, "GET", c.eventsPath() + "?" + q.Encode(), nil, &body); err != nil {
+			return nil, err
+		}
+		for _, e := range body.Items {
+			if e.key() != "" {
+				events = append(events, e)
+			}
+		}
+		if body.NextPageToken == "" {
+			return events, nil
+		}
+		token = body.NextPageToken
+	}
+}
+
+func (c *Client) create(ctx context.Context, e event) error {
+	if c.DryRun != nil {
+		return c.dryRun("POST", c.eventsPath(), e)
+	}
+	return c.do(ctx, "POST", c.eventsPath(), e, nil)
+}
+
+func (c *Client) update(ctx context.Context, e event) error {
+	path := c.eventsPath() + "/" + url.PathEscape(e.ID)
+	if c.DryRun != nil {
+		return c.dryRun("PUT", path, e)
+	}
+	return c.do(ctx, "PUT", path, e, nil)
+}
+
+func (c *Client) delete(ctx context.Context, e event) error {
+	path := c.eventsPath() + "/" + url.PathEscape(e.ID)
+	if c.DryRun != nil {
+		_, err := fmt.Fprintf(c.DryRun, "DELETE %s # %s\n", path, e)
+		return err
+	}
+	return c.do(ctx, "DELETE", path, nil, nil)
+}
+
+func (c *Client) dryRun(method, path string, e event) error {
+	text, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.DryRun, "%s %s %s # %s\n", method, path, text, e)
+	return err
+}
+
+func (c *Client) eventsPath() string {
+	return "/calendars/" + url.PathEscape(c.CalendarID) + "/events"
+}
+
+// Make a request of the API, retrying while it is rate limited, and decode
+// the response into v unless it is nil.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultURL
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	text := []byte{}
+	if body != nil {
+		t, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		text = t
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, base + path, bytes.NewReader(text))
+		if err != nil {
+			return err
+		}
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer " + c.Token)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < MaxRetries {
+			resp.Body.Close()
+			sleep(retryAfter(resp, attempt))
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return responseError(resp)
+		}
+		if v == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+}
+
+// How long to wait before retrying a rate limited request: as long as Google
+// asks, or otherwise a second, doubling with each attempt.
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Second << uint(attempt)
+}
+
+// Describe an unsuccessful response, including the error Google gives.
+func responseError(resp *http.Response) error {
+	body := struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	text, _ := ioutil.ReadAll(resp.Body)
+	if json.Unmarshal(text, &body) != nil || body.Error.Message == "" {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return fmt.Errorf("unexpected response %s: %s", resp.Status, body.Error.Message)
+}
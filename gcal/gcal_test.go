@@ -0,0 +1,275 @@
+package gcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["b", "c", "a"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-22T10:00:00Z",
+			"End": "2017-03-01T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		},
+		{
+			"Start": "2017-03-01T10:00:00Z",
+			"End": "2017-03-08T10:00:00Z",
+			"Primary": "b",
+			"Secondary": "c"
+		}
+	]
+}`
+
+var (
+	emails = map[string]string{"a": "a@example.com", "b": "b@example.com"}
+	from = time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC)
+)
+
+// A fake Google Calendar's events API.
+type fakeCalendar struct {
+	events map[string]event
+	nextID int
+	// Events are listed this many at a time, whatever the maximum asked for.
+	pageSize int
+	requests []string
+}
+
+func newFakeCalendar() *fakeCalendar {
+	return &fakeCalendar{events: map[string]event{}, pageSize: 250}
+}
+
+func (f *fakeCalendar) add(e event) {
+	f.nextID++
+	e.ID = fmt.Sprintf("ev%d", f.nextID)
+	f.events[e.ID] = e
+}
+
+// The events, ordered by start, as "start/end summary [attendees]".
+func (f *fakeCalendar) list() []string {
+	list := []string{}
+	for _, e := range f.events {
+		attendees := []string{}
+		for _, a := range e.Attendees {
+			attendees = append(attendees, a.Email)
+		}
+		list = append(list, fmt.Sprintf("%s/%s %s %v", e.Start.DateTime.UTC().Format(time.RFC3339), e.End.DateTime.UTC().Format(time.RFC3339), e.Summary, attendees))
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (f *fakeCalendar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method + " " + r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error": {"code": 401, "message": "Invalid Credentials"}}`)
+		return
+	}
+	const path = "/calendars/team@example.com/events"
+	switch {
+	case r.Method == "GET" && r.URL.Path == path:
+		timeMin, _ := time.Parse(time.RFC3339, r.URL.Query().Get("timeMin"))
+		property := strings.SplitN(r.URL.Query().Get("privateExtendedProperty"), "=", 2)
+		matching := []event{}
+		for _, e := range f.events {
+			if e.End.DateTime.After(timeMin) && e.ExtendedProperties.Private[property[0]] == property[1] {
+				matching = append(matching, e)
+			}
+		}
+		sort.Slice(matching, func(i, j int) bool {
+			return matching[i].ID < matching[j].ID
+		})
+		offset := 0
+		fmt.Sscan(r.URL.Query().Get("pageToken"), &offset)
+		page := matching[offset:]
+		next := ""
+		if len(page) > f.pageSize {
+			page = page[:f.pageSize]
+			next = fmt.Sprint(offset + f.pageSize)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": page, "nextPageToken": next})
+	case r.Method == "POST" && r.URL.Path == path:
+		e := event{}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.add(e)
+		json.NewEncoder(w).Encode(e)
+	case (r.Method == "PUT" || r.Method == "DELETE") && strings.HasPrefix(r.URL.Path, path + "/"):
+		id := strings.TrimPrefix(r.URL.Path, path + "/")
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "DELETE" {
+			delete(f.events, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		e := event{}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		e.ID = id
+		f.events[id] = e
+		json.NewEncoder(w).Encode(e)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testSchedule(t *testing.T, text string) *schedule.Schedule {
+	s, err := schedule.NewSchedule([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func sync(t *testing.T, f *fakeCalendar, c Client, text string) Result {
+	server := httptest.NewServer(f)
+	defer server.Close()
+	c.Token = "secret"
+	c.CalendarID = "team@example.com"
+	c.Emails = emails
+	c.BaseURL = server.URL
+	result, err := c.Sync(context.Background(), testSchedule(t, text), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func expectEvents(t *testing.T, f *fakeCalendar, expected []string) {
+	t.Helper()
+	if got := f.list(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected events:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestSync(t *testing.T) {
+	f := newFakeCalendar()
+	result := sync(t, f, Client{}, scheduleText)
+	if result != (Result{Created: 4}) {
+		t.Errorf("expected 4 events created, got %+v", result)
+	}
+	expectEvents(t, f, []string{
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z On-call primary: a [a@example.com]",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z On-call secondary: b [b@example.com]",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z On-call primary: b [b@example.com]",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z On-call secondary: c []",
+	})
+	keys := []string{}
+	for _, e := range f.events {
+		keys = append(keys, e.key())
+	}
+	sort.Strings(keys)
+	expected := []string{"20170222T100000Z-primary", "20170222T100000Z-secondary", "20170301T100000Z-primary", "20170301T100000Z-secondary"}
+	if strings.Join(keys, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected keys %v, got %v", expected, keys)
+	}
+}
+
+func TestSyncIsIdempotent(t *testing.T) {
+	f := newFakeCalendar()
+	sync(t, f, Client{}, scheduleText)
+	f.requests = nil
+	result := sync(t, f, Client{}, scheduleText)
+	if result != (Result{Unchanged: 4}) {
+		t.Errorf("expected every event to be left alone, got %+v", result)
+	}
+	for _, r := range f.requests {
+		if !strings.HasPrefix(r, "GET ") {
+			t.Errorf("expected only the events to be listed, got %s", r)
+		}
+	}
+}
+
+func TestSyncAfterRegeneration(t *testing.T) {
+	f := newFakeCalendar()
+	sync(t, f, Client{}, scheduleText)
+	// An event the client does not own is left alone.
+	f.add(event{Summary: "Offsite", Start: eventTime{time.Date(2017, time.March, 2, 0, 0, 0, 0, time.UTC)}, End: eventTime{time.Date(2017, time.March, 3, 0, 0, 0, 0, time.UTC)}})
+	// c takes the second primary, and the secondary of the second rotation is
+	// left unfilled.
+	regenerated := strings.Replace(scheduleText, `"Primary": "b",
+			"Secondary": "c"`, `"Primary": "c"`, 1)
+	result := sync(t, f, Client{}, regenerated)
+	if result != (Result{Updated: 1, Deleted: 1, Unchanged: 2}) {
+		t.Errorf("expected one event updated and one deleted, got %+v", result)
+	}
+	expectEvents(t, f, []string{
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z On-call primary: a [a@example.com]",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z On-call secondary: b [b@example.com]",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z On-call primary: c []",
+		"2017-03-02T00:00:00Z/2017-03-03T00:00:00Z Offsite []",
+	})
+}
+
+func TestSyncDeletesDuplicates(t *testing.T) {
+	f := newFakeCalendar()
+	sync(t, f, Client{}, scheduleText)
+	for _, e := range f.events {
+		if e.Summary == "On-call primary: b" {
+			f.add(e)
+			break
+		}
+	}
+	f.pageSize = 2
+	result := sync(t, f, Client{}, scheduleText)
+	if result != (Result{Deleted: 1, Unchanged: 4}) {
+		t.Errorf("expected the duplicate to be found across pages and deleted, got %+v", result)
+	}
+	if len(f.events) != 4 {
+		t.Errorf("expected 4 events, got %v", f.list())
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	f := newFakeCalendar()
+	sync(t, f, Client{}, scheduleText)
+	regenerated := strings.Replace(scheduleText, `"Secondary": "c"`, `"Secondary": "a"`, 1)
+	out := &bytes.Buffer{}
+	result := sync(t, f, Client{DryRun: out}, regenerated)
+	if result != (Result{Updated: 1, Unchanged: 3}) {
+		t.Errorf("expected the last event to be updated, got %+v", result)
+	}
+	if !strings.Contains(strings.Join(f.list(), "\n"), "On-call secondary: c") {
+		t.Errorf("expected the dry run to leave the events alone, got %v", f.list())
+	}
+	expected := `PUT /calendars/team@example.com/events/ev4 {"id":"ev4","summary":"On-call secondary: a","start":{"dateTime":"2017-03-01T10:00:00Z"},"end":{"dateTime":"2017-03-08T10:00:00Z"},"attendees":[{"email":"a@example.com"}],"extendedProperties":{"private":{"oncallator":"true","oncallatorKey":"20170301T100000Z-secondary"}}} # On-call secondary: a from 2017-03-01T10:00:00Z to 2017-03-08T10:00:00Z
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestSyncAPIError(t *testing.T) {
+	server := httptest.NewServer(newFakeCalendar())
+	defer server.Close()
+	c := Client{Token: "expired", CalendarID: "team@example.com", BaseURL: server.URL}
+	_, err := c.Sync(context.Background(), testSchedule(t, scheduleText), from)
+	expected := "error listing events of calendar team@example.com: unexpected response 401 Unauthorized: Invalid Credentials"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package hooks runs the Hooks of a generated schedule, such as committing it
+// or posting the changes to chat, whenever Generate changed its rotations.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// A Payload is what each hook is given: POSTed as JSON to a URL, or written as
+// JSON to a command's stdin.
+type Payload struct {
+	// The schedule as generated.
+	Schedule *schedule.Schedule
+	// The Diff rendered as text, e.g. for a commit message.
+	Summary string
+	// The changes Generate made, as in the output of oncallator --check, and
+	// the version of their serialized form.
+	ChangeSchemaVersion int
+	Changes []schedule.Change
+}
+
+// Runs a command with stdin, returning its combined output; replaced in tests.
+var execCommand = func(ctx context.Context, command []string, stdin []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	return cmd.CombinedOutput()
+}
+
+// A Runner runs hooks.
+type Runner struct {
+	// Defaults to http.DefaultClient. Each hook's Timeout applies whatever
+	// the client's own.
+	Client *http.Client
+}
+
+// Run runs each of the Hooks of ns, the schedule generated from s with diff,
+// in turn, unless diff is Empty. A hook that fails or runs past its Timeout does
+// not stop the rest; the failures are returned together. Run neither reads
+// nor changes ns beyond encoding it, so a failing hook cannot affect the
+// schedule written.
+func (r *Runner) Run(ctx context.Context, s, ns *schedule.Schedule, diff schedule.Diff) error {
+	if diff.Empty() || len(ns.Hooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Payload{
+		Schedule: ns,
+		Summary: diff.String(),
+		ChangeSchemaVersion: schedule.ChangeSchemaVersion,
+		Changes: schedule.Changes(s, ns, schedule.SourceGenerate),
+	})
+	if err != nil {
+		return err
+	}
+	failures := []string{}
+	for _, h := range ns.Hooks {
+		if err := r.run(ctx, h, payload); err != nil {
+			failures = append(failures, fmt.Sprintf("hook %s failed: %s", h, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, h schedule.Hook, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, h.TimeoutDuration())
+	defer cancel()
+	if len(h.Command) > 0 {
+		output, err := execCommand(ctx, h.Command, payload)
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", h.TimeoutDuration())
+		}
+		if err != nil {
+			if text := strings.TrimSpace(string(output)); text != "" {
+				return fmt.Errorf("%s: %s", err, text)
+			}
+			return err
+		}
+		return nil
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", h.TimeoutDuration())
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		text, _ := ioutil.ReadAll(resp.Body)
+		if msg := strings.TrimSpace(string(text)); msg != "" {
+			return fmt.Errorf("unexpected response %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["a", "b", "c"],
+	"Start": "2017-02-01T10:00:00Z",
+	"RotationLength": "1w",
+	"ScheduleFor": "2w"
+}`
+
+// Generate the schedule with hooks, returning it as written and as generated,
+// with its diff.
+func generate(t *testing.T, hooks ...schedule.Hook) (*schedule.Schedule, *schedule.Schedule, schedule.Diff) {
+	s, err := schedule.NewSchedule([]byte(scheduleText), schedule.WithNow(time.Date(2017, time.February, 1, 10, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Hooks = hooks
+	ns, diff, err := s.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, ns, diff
+}
+
+// Replace execCommand for the test, recording the commands run and their
+// stdin, and answering with run.
+func fakeExec(t *testing.T, run func(ctx context.Context) ([]byte, error)) *[]string {
+	ran := &[]string{}
+	old := execCommand
+	execCommand = func(ctx context.Context, command []string, stdin []byte) ([]byte, error) {
+		*ran = append(*ran, strings.Join(command, " "), string(stdin))
+		return run(ctx)
+	}
+	t.Cleanup(func() {
+		execCommand = old
+	})
+	return ran
+}
+
+func TestRun(t *testing.T) {
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		text, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Type") + " " + string(text))
+	}))
+	defer server.Close()
+	ran := fakeExec(t, func(context.Context) ([]byte, error) {
+		return nil, nil
+	})
+	s, ns, diff := generate(t, schedule.Hook{URL: server.URL}, schedule.Hook{Command: []string{"git", "commit", "-a", "-F", "-"}})
+	if err := (&Runner{}).Run(context.Background(), s, ns, diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 1 || !strings.HasPrefix(bodies[0], "application/json {") {
+		t.Fatalf("expected the payload to be POSTed as JSON, got %q", bodies)
+	}
+	payload := struct {
+		Schedule struct {
+			Users []string
+			Rotations []schedule.Rotation
+		}
+		Summary string
+		ChangeSchemaVersion int
+		Changes []schedule.Change
+	}{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(bodies[0], "application/json ")), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Schedule.Rotations) != 3 || payload.Summary != diff.String() {
+		t.Errorf("expected the generated schedule and its summary, got %+v", payload)
+	}
+	// A primary and a secondary added for each rotation.
+	changes := schedule.Changes(s, ns, schedule.SourceGenerate)
+	if payload.ChangeSchemaVersion != schedule.ChangeSchemaVersion || len(payload.Changes) != 7 || !reflect.DeepEqual(changesJSON(t, changes), changesJSON(t, payload.Changes)) {
+		t.Errorf("expected the changes %+v, got %+v", changes, payload.Changes)
+	}
+	for _, c := range payload.Changes[1:] {
+		if c.Kind != schedule.ChangeAdded || c.RotationID == "" {
+			t.Errorf("expected each rotation to be added under its ID, got %+v", c)
+		}
+	}
+	if len(*ran) != 2 || (*ran)[0] != "git commit -a -F -" || (*ran)[1] != strings.TrimPrefix(bodies[0], "application/json ") {
+		t.Errorf("expected the command to be given the same payload on stdin, got %q", *ran)
+	}
+}
+
+// Return changes as JSON, as hooks are given them.
+func changesJSON(t *testing.T, changes []schedule.Change) string {
+	text, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(text)
+}
+
+func TestRunWithoutChanges(t *testing.T) {
+	ran := fakeExec(t, func(context.Context) ([]byte, error) {
+		return nil, nil
+	})
+	_, ns, _ := generate(t, schedule.Hook{Command: []string{"true"}})
+	again, diff, err := ns.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected regenerating to change nothing, got %s", diff)
+	}
+	if err := (&Runner{}).Run(context.Background(), ns, again, diff); err != nil || len(*ran) != 0 {
+		t.Errorf("expected no hooks to run, got %q (%v)", *ran, err)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("sync job is down\n"))
+	}))
+	defer server.Close()
+	ran := fakeExec(t, func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	s, ns, diff := generate(t,
+		schedule.Hook{Name: "sync", URL: server.URL},
+		schedule.Hook{Command: []string{"sleep", "60"}, Timeout: "10ms"},
+	)
+	rotations := append([]schedule.Rotation{}, ns.Rotations...)
+	err := (&Runner{}).Run(context.Background(), s, ns, diff)
+	expected := "hook sync failed: unexpected response 500 Internal Server Error: sync job is down; hook sleep 60 failed: timed out after 10ms"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+	if len(*ran) != 2 {
+		t.Errorf("expected the command to run after the webhook failed, got %q", *ran)
+	}
+	if !reflect.DeepEqual(rotations, ns.Rotations) {
+		t.Errorf("expected failing hooks to leave the schedule alone, got %+v", ns.Rotations)
+	}
+}
@@ -0,0 +1,84 @@
+package importers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/pagerduty"
+	"github.com/websdev/oncallator/schedule"
+)
+
+// FromPagerDuty builds a schedule from who was on call for primary's PagerDuty
+// schedule from since until until, with one rotation per entry, and the
+// secondary of each taken from whoever was on call for secondary's schedule
+// as it starts. secondary may be nil for a schedule with no secondary.
+//
+// Each client's Users, which maps schedule user names to PagerDuty user IDs
+// for pushing, is read the other way to name the PagerDuty users on call; a
+// user may be mapped by their PagerDuty name instead of their ID. As with
+// FromSheet, RotationLength is inferred and the result can be passed straight
+// to Generate to carry on from the last entry.
+//
+// Entries that are spaced irregularly, leave nobody on call, or during which
+// the secondary changes are imported as they are and reported in the returned
+// warnings.
+func FromPagerDuty(ctx context.Context, primary, secondary *pagerduty.Client, since, until time.Time) (*schedule.Schedule, []string, error) {
+	entries, err := primary.Entries(ctx, since, until)
+	if err != nil {
+		return nil, nil, err
+	}
+	var secondaries []pagerduty.Entry
+	if secondary != nil {
+		if secondaries, err = secondary.Entries(ctx, since, until); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	warnings := []string{}
+	rotations := []schedule.Rotation{}
+	missing := map[string]bool{}
+	name := func(c *pagerduty.Client, e pagerduty.Entry) string {
+		for u, id := range c.Users {
+			if id == e.UserID || id == e.UserName {
+				return u
+			}
+		}
+		missing[fmt.Sprintf("%s (%s)", e.UserName, e.UserID)] = true
+		return ""
+	}
+	for i, e := range entries {
+		if i > 0 && e.Start.After(entries[i-1].End) {
+			warnings = append(warnings, fmt.Sprintf("nobody was on call from %s to %s", entries[i-1].End.Format(time.RFC3339), e.Start.Format(time.RFC3339)))
+		}
+		r := schedule.Rotation{Start: e.Start, Primary: name(primary, e)}
+		if secondary != nil {
+			on := -1
+			for j, s := range secondaries {
+				if !s.Start.After(e.Start) && s.End.After(e.Start) {
+					on = j
+				} else if s.Start.After(e.Start) && s.Start.Before(e.End) {
+					warnings = append(warnings, fmt.Sprintf("the secondary changes during the rotation starting at %s; only whoever was on call as it started is imported", e.Start.Format(time.RFC3339)))
+					break
+				}
+			}
+			if on < 0 {
+				warnings = append(warnings, fmt.Sprintf("rotation starting at %s has no secondary", e.Start.Format(time.RFC3339)))
+			} else {
+				r.Secondary = name(secondary, secondaries[on])
+			}
+		}
+		rotations = append(rotations, r)
+	}
+	if len(missing) > 0 {
+		users := []string{}
+		for u := range missing {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		return nil, nil, fmt.Errorf("no schedule user for PagerDuty users: %s", strings.Join(users, ", "))
+	}
+	return fromRotations("PagerDuty schedule " + primary.ScheduleID, rotations, secondary != nil, warnings)
+}
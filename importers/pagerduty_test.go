@@ -0,0 +1,86 @@
+package importers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/pagerduty"
+)
+
+// Serve the recorded responses in testdata for the schedules PPRIMRY and
+// PSECOND.
+func pagerDutyServer() *httptest.Server {
+	fixtures := map[string]string{
+		"/schedules/PPRIMRY": "pagerduty-primary.json",
+		"/schedules/PSECOND": "pagerduty-secondary.json",
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture, ok := fixtures[r.URL.Path]
+		if !ok || r.Header.Get("Authorization") != "Token token=secret" || r.URL.Query().Get("since") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", fixture))
+	}))
+}
+
+func TestFromPagerDuty(t *testing.T) {
+	server := pagerDutyServer()
+	defer server.Close()
+	users := map[string]string{"alice": "Alice Smith", "bob": "PBOB123", "carol": "PCAROL1"}
+	client := func(id string) *pagerduty.Client {
+		return &pagerduty.Client{Token: "secret", ScheduleID: id, Users: users, BaseURL: server.URL}
+	}
+	since := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	s, warnings, err := FromPagerDuty(context.Background(), client("PPRIMRY"), client("PSECOND"), since, since.AddDate(0, 0, 28))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2024-01-01T09:00:00Z/2024-01-08T09:00:00Z alice bob",
+		"2024-01-08T09:00:00Z/2024-01-15T09:00:00Z bob carol",
+		"2024-01-15T09:00:00Z/2024-01-22T09:00:00Z carol alice",
+		"2024-01-22T09:00:00Z/2024-01-29T09:00:00Z alice bob",
+	}
+	got := []string{}
+	for _, r := range s.Rotations {
+		got = append(got, r.String())
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected rotations\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if expected := []string{"bob", "carol", "alice"}; !reflect.DeepEqual(expected, s.Users) || s.RotationLength != "168h0m0s" {
+		t.Errorf("expected users %v weekly, got %v every %s", expected, s.Users, s.RotationLength)
+	}
+	if expected := []string{"the secondary changes during the rotation starting at 2024-01-22T09:00:00Z; only whoever was on call as it started is imported"}; !reflect.DeepEqual(expected, warnings) {
+		t.Errorf("expected warnings %q, got %q", expected, warnings)
+	}
+
+	// Generating carries on from the last entry.
+	s.SetNow(since.AddDate(0, 0, 28))
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := ns.At(since.AddDate(0, 0, 28))
+	if err != nil || next.Primary != "bob" || next.Secondary != "carol" {
+		t.Errorf("expected bob and carol to be on call next, got %+v (%v)", next, err)
+	}
+}
+
+func TestFromPagerDutyMissingUsers(t *testing.T) {
+	server := pagerDutyServer()
+	defer server.Close()
+	c := &pagerduty.Client{Token: "secret", ScheduleID: "PPRIMRY", Users: map[string]string{"bob": "PBOB123"}, BaseURL: server.URL}
+	since := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	_, _, err := FromPagerDuty(context.Background(), c, nil, since, since.AddDate(0, 0, 28))
+	if err == nil || err.Error() != "no schedule user for PagerDuty users: Alice Smith (PALICE1), Carol Smith (PCAROL1)" {
+		t.Errorf("expected an error naming the unmapped users, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package importers builds schedules from rotas kept in other tools, so teams
+// can move to oncallator without losing their history.
+package importers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// ScheduleForRotations is how many rotations ahead an imported schedule is set
+// to be generated. Edit ScheduleFor in the result to change it.
+const ScheduleForRotations = 4
+
+// A ColumnMapping says which columns of a spreadsheet hold what. Columns are
+// named by their header, ignoring case and surrounding space.
+type ColumnMapping struct {
+	// The column holding the date or time each rotation starts, e.g. "Week of".
+	Start string
+	// The format of Start, as for time.Parse, e.g. "2006-01-02".
+	StartLayout string
+	Primary string
+	// The column holding the secondary (backup), if there is one.
+	Secondary string
+}
+
+// FromSheet builds a schedule from a CSV export of a spreadsheet rota with a
+// header row and one row per rotation, in order. Start times are read in zone.
+//
+// RotationLength is inferred from the most common spacing between rows, and
+// Users lists everyone named, in order of first appearance, rotated so that
+// whoever follows the last imported primary is primary next. The result can be
+// passed straight to Generate to carry on from the last row.
+//
+// Rows that are spaced irregularly or leave a tier empty are imported as they
+// are and reported in the returned warnings.
+func FromSheet(r io.Reader, mapping ColumnMapping, zone *time.Location) (*schedule.Schedule, []string, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading sheet: %s", err)
+	}
+	if len(records) < 3 {
+		return nil, nil, fmt.Errorf("sheet must have a header row and at least 2 rotations to infer RotationLength from")
+	}
+	columns := map[string]int{}
+	for i, h := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	column := func(name string) (int, error) {
+		i, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("sheet has no %q column (columns are %s)", name, strings.Join(records[0], ", "))
+		}
+		return i, nil
+	}
+	startCol, err := column(mapping.Start)
+	if err != nil {
+		return nil, nil, err
+	}
+	primaryCol, err := column(mapping.Primary)
+	if err != nil {
+		return nil, nil, err
+	}
+	secondaryCol := -1
+	if mapping.Secondary != "" {
+		if secondaryCol, err = column(mapping.Secondary); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	warnings := []string{}
+	rotations := []schedule.Rotation{}
+	for n, record := range records[1:] {
+		// Rows are numbered as in the spreadsheet, counting the header.
+		row := n + 2
+		cell := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		start, err := time.ParseInLocation(mapping.StartLayout, cell(startCol), zone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s in row %d: %s", mapping.Start, row, err)
+		}
+		if len(rotations) > 0 && !start.After(rotations[len(rotations)-1].Start) {
+			return nil, nil, fmt.Errorf("row %d starts at %s, not after the row before it", row, start.Format(time.RFC3339))
+		}
+		rot := schedule.Rotation{
+			Start: start,
+			Primary: cell(primaryCol),
+			Secondary: cell(secondaryCol),
+		}
+		if rot.Primary == "" {
+			warnings = append(warnings, fmt.Sprintf("row %d has no primary", row))
+		}
+		if secondaryCol >= 0 && rot.Secondary == "" {
+			warnings = append(warnings, fmt.Sprintf("row %d has no secondary", row))
+		}
+		rotations = append(rotations, rot)
+	}
+	return fromRotations("sheet", rotations, secondaryCol >= 0, warnings)
+}
+
+// Build a schedule from the rotations imported from source, as FromSheet
+// describes, adding to the warnings about them. secondary is whether source
+// has a secondary tier at all.
+func fromRotations(source string, rotations []schedule.Rotation, secondary bool, warnings []string) (*schedule.Schedule, []string, error) {
+	if len(rotations) < 2 {
+		return nil, nil, fmt.Errorf("%s must have at least 2 rotations to infer RotationLength from", source)
+	}
+	users := []string{}
+	seen := map[string]bool{}
+	for _, r := range rotations {
+		for _, u := range []string{r.Primary, r.Secondary} {
+			if u != "" && !seen[u] {
+				seen[u] = true
+				users = append(users, u)
+			}
+		}
+	}
+	if len(users) == 0 {
+		return nil, nil, fmt.Errorf("%s names no users", source)
+	}
+
+	length := commonSpacing(rotations)
+	for i := 1; i < len(rotations); i++ {
+		d := rotations[i].Start.Sub(rotations[i-1].Start)
+		// Rows at the same local time either side of a daylight saving change
+		// are an hour more or less apart, which is not worth a warning.
+		if diff := d - length; diff != 0 && (diff > time.Hour || diff < -time.Hour) {
+			warnings = append(warnings, fmt.Sprintf("rotation starting at %s lasts %s rather than %s", rotations[i-1].Start.Format(time.RFC3339), d, length))
+		}
+	}
+
+	last := rotations[len(rotations)-1]
+	in := schedule.Input{
+		FormatVersion: schedule.CurrentFormatVersion,
+		Users: schedule.UsersNamed(nextUsers(users, last.Primary)),
+		Start: rotations[0].Start,
+		RotationLength: length.String(),
+		ScheduleFor: (ScheduleForRotations * length).String(),
+		AllowTierCollapse: !secondary || len(users) < 2,
+		Rotations: rotations,
+	}
+	text, err := json.Marshal(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := schedule.NewSchedule(text)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Each row runs until the next, and the last for RotationLength.
+	for i := range s.Rotations {
+		s.Rotations[i].End = s.RotationEnd(i)
+	}
+	return s, warnings, nil
+}
+
+// Return the most common time between consecutive rotations, preferring the
+// earliest seen on a tie.
+func commonSpacing(rotations []schedule.Rotation) time.Duration {
+	counts := map[time.Duration]int{}
+	var common time.Duration
+	for i := 1; i < len(rotations); i++ {
+		d := rotations[i].Start.Sub(rotations[i-1].Start)
+		counts[d]++
+		if counts[d] > counts[common] {
+			common = d
+		}
+	}
+	return common
+}
+
+// Return users rotated so that the user after last comes first.
+func nextUsers(users []string, last string) []string {
+	for i, u := range users {
+		if u == last {
+			return append(append([]string{}, users[i+1:]...), users[:i+1]...)
+		}
+	}
+	return users
+}
@@ -0,0 +1,122 @@
+package importers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+func importFixture(t *testing.T, name string, mapping ColumnMapping, zone *time.Location) ([]string, string, []string) {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, warnings, err := FromSheet(f, mapping, zone)
+	if err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	return s.Users, s.RotationLength, warnings
+}
+
+func TestFromSheet(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		fixture string
+		mapping ColumnMapping
+		zone *time.Location
+		users []string
+		length string
+		warnings []string
+	}{
+		{
+			fixture: "weekly.csv",
+			mapping: ColumnMapping{Start: "Week of", StartLayout: "2006-01-02", Primary: "Primary", Secondary: "Backup"},
+			zone: time.UTC,
+			users: []string{"bob", "carol", "alice"},
+			length: "168h0m0s",
+			warnings: []string{},
+		},
+		{
+			// A Google Sheets export, across a daylight saving change.
+			fixture: "google-sheets.csv",
+			mapping: ColumnMapping{Start: "date", StartLayout: "1/2/2006", Primary: "on call", Secondary: "secondary"},
+			zone: warsaw,
+			users: []string{"frank", "dmitri", "erin"},
+			length: "336h0m0s",
+			warnings: []string{"row 4 has no secondary"},
+		},
+		{
+			fixture: "daily.csv",
+			mapping: ColumnMapping{Start: "Day", StartLayout: "2006-01-02", Primary: "Person"},
+			zone: time.UTC,
+			users: []string{"gus", "hana"},
+			length: "24h0m0s",
+			warnings: []string{},
+		},
+	} {
+		users, length, warnings := importFixture(t, c.fixture, c.mapping, c.zone)
+		if !reflect.DeepEqual(c.users, users) {
+			t.Errorf("%s: expected users %v, got %v", c.fixture, c.users, users)
+		}
+		if c.length != length {
+			t.Errorf("%s: expected RotationLength %s, got %s", c.fixture, c.length, length)
+		}
+		if !reflect.DeepEqual(c.warnings, warnings) {
+			t.Errorf("%s: expected warnings %q, got %q", c.fixture, c.warnings, warnings)
+		}
+	}
+}
+
+func TestFromSheetIrregularRows(t *testing.T) {
+	// Weekly rotations, one of which was stretched to two weeks.
+	mapping := ColumnMapping{Start: "Date", StartLayout: "1/2/2006", Primary: "On call"}
+	text := "Date,On call\n3/6/2017,a\n3/13/2017,b\n3/27/2017,a\n4/3/2017,b\n"
+	s, warnings, err := FromSheet(strings.NewReader(text), mapping, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RotationLength != "168h0m0s" {
+		t.Errorf("expected weekly rotations, got %s", s.RotationLength)
+	}
+	expected := []string{"rotation starting at 2017-03-13T00:00:00Z lasts 336h0m0s rather than 168h0m0s"}
+	if !reflect.DeepEqual(expected, warnings) {
+		t.Errorf("expected warnings %q, got %q", expected, warnings)
+	}
+}
+
+func TestFromSheetExtends(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "weekly.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, _, err := FromSheet(f, ColumnMapping{Start: "Week of", StartLayout: "2006-01-02", Primary: "Primary", Secondary: "Backup"}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetNow(time.Date(2017, time.January, 24, 0, 0, 0, 0, time.UTC))
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The round-robin carries on from the last imported row.
+	last := s.Rotations[len(s.Rotations)-1]
+	var next schedule.Rotation
+	for i, r := range ns.Rotations {
+		if r.Start.Equal(last.Start) {
+			next = ns.Rotations[i+1]
+		}
+	}
+	if !next.Start.Equal(time.Date(2017, time.January, 30, 0, 0, 0, 0, time.UTC)) || next.Primary != "bob" {
+		t.Errorf("expected bob to follow the last imported rotation on 2017-01-30, got %s", next)
+	}
+}
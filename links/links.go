@@ -0,0 +1,208 @@
+// Package links creates and verifies signed, expiring, single-use URLs that let
+// a user act on a rotation (e.g. acknowledge it) with one click from a
+// notification, without logging in.
+package links
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionAcknowledge Action = "acknowledge"
+	ActionSwap Action = "swap"
+)
+
+var (
+	ErrBadSignature = errors.New("link signature is invalid")
+	ErrExpired = errors.New("link has expired")
+	ErrUsed = errors.New("link has already been used")
+	ErrNoSecret = errors.New("no secret configured; refusing to sign or verify links that anyone could forge")
+)
+
+// Claims are what a link authorizes: User taking Action on a rotation.
+type Claims struct {
+	RotationID string
+	User string
+	Action Action
+	Expires time.Time
+	// A random value making each link unique, so that it can only be used once.
+	Nonce string
+	// For ActionSwap, the user to swap with, and the end of the span of
+	// rotations swapped, which starts with the rotation.
+	With string
+	Until time.Time
+}
+
+// A NonceStore remembers the nonces of links that have been used.
+type NonceStore interface {
+	// Use marks nonce as used, returning false if it already was. The nonce
+	// only needs to be remembered until expires, after which the link is
+	// rejected anyway.
+	Use(nonce string, expires time.Time) bool
+}
+
+// Config is how links are configured in the integrations config, which keeps
+// secrets out of the schedule file.
+type Config struct {
+	// The secret links are signed with.
+	Secret string
+	// The URL of the endpoint that handles links.
+	BaseURL string
+}
+
+// A Signer creates and verifies links. Its Secret must be kept private, since
+// anyone holding it can create links, and must not be empty.
+type Signer struct {
+	Secret []byte
+	// The URL of the endpoint that handles links. Claims are added as query
+	// parameters.
+	BaseURL string
+	Nonces NonceStore
+
+	// Used to check expiry in a test-friendly way.
+	now func() time.Time
+}
+
+func (s *Signer) currentTime() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// URL returns a link for user to take action on a rotation, valid for ttl.
+func (s *Signer) URL(rotationID, user string, action Action, ttl time.Duration) (string, error) {
+	return s.link(Claims{RotationID: rotationID, User: user, Action: action}, ttl)
+}
+
+// SwapURL returns a link for user to swap places with another user in the
+// rotations from the one with rotationID until until, valid for ttl.
+func (s *Signer) SwapURL(rotationID, user, with string, until time.Time, ttl time.Duration) (string, error) {
+	return s.link(Claims{RotationID: rotationID, User: user, Action: ActionSwap, With: with, Until: until.Truncate(time.Second)}, ttl)
+}
+
+func (s *Signer) link(c Claims, ttl time.Duration) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", ErrNoSecret
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	c.Expires = s.currentTime().Add(ttl).Truncate(time.Second)
+	c.Nonce = hex.EncodeToString(nonce)
+	q := url.Values{}
+	q.Set("rotation", c.RotationID)
+	q.Set("user", c.User)
+	q.Set("action", string(c.Action))
+	q.Set("expires", strconv.FormatInt(c.Expires.Unix(), 10))
+	q.Set("nonce", c.Nonce)
+	if c.Action == ActionSwap {
+		q.Set("with", c.With)
+		q.Set("until", strconv.FormatInt(c.Until.Unix(), 10))
+	}
+	q.Set("sig", s.sign(c))
+	sep := "?"
+	if strings.Contains(s.BaseURL, "?") {
+		sep = "&"
+	}
+	return s.BaseURL + sep + q.Encode(), nil
+}
+
+// Verify checks the query parameters of a link and returns its claims. A link
+// verifies successfully at most once.
+func (s *Signer) Verify(q url.Values) (Claims, error) {
+	if len(s.Secret) == 0 {
+		return Claims{}, ErrNoSecret
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil {
+		return Claims{}, ErrBadSignature
+	}
+	c := Claims{
+		RotationID: q.Get("rotation"),
+		User: q.Get("user"),
+		Action: Action(q.Get("action")),
+		Expires: time.Unix(expires, 0),
+		Nonce: q.Get("nonce"),
+		With: q.Get("with"),
+	}
+	if until := q.Get("until"); until != "" {
+		u, err := strconv.ParseInt(until, 10, 64)
+		if err != nil {
+			return Claims{}, ErrBadSignature
+		}
+		c.Until = time.Unix(u, 0)
+	}
+	if !hmac.Equal([]byte(s.sign(c)), []byte(q.Get("sig"))) {
+		return Claims{}, ErrBadSignature
+	}
+	if !s.currentTime().Before(c.Expires) {
+		return Claims{}, ErrExpired
+	}
+	if s.Nonces == nil {
+		return Claims{}, fmt.Errorf("no nonce store configured; refusing a link that could be replayed")
+	}
+	if !s.Nonces.Use(c.Nonce, c.Expires) {
+		return Claims{}, ErrUsed
+	}
+	return c, nil
+}
+
+func (s *Signer) sign(c Claims) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	// Every field is length-prefixed so that values cannot be shifted between
+	// fields without changing the signature.
+	until := ""
+	if !c.Until.IsZero() {
+		until = strconv.FormatInt(c.Until.Unix(), 10)
+	}
+	for _, f := range []string{"v1", c.RotationID, c.User, string(c.Action), strconv.FormatInt(c.Expires.Unix(), 10), c.Nonce, c.With, until} {
+		fmt.Fprintf(mac, "%d:%s", len(f), f)
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// MemoryNonces is an in-memory NonceStore, suitable for a single server.
+type MemoryNonces struct {
+	mu sync.Mutex
+	used map[string]time.Time
+
+	// Used to forget expired nonces in a test-friendly way.
+	now func() time.Time
+}
+
+func (m *MemoryNonces) Use(nonce string, expires time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.used == nil {
+		m.used = map[string]time.Time{}
+	}
+	now := time.Now()
+	if m.now != nil {
+		now = m.now()
+	}
+	for n, e := range m.used {
+		if now.After(e) {
+			delete(m.used, n)
+		}
+	}
+	if _, ok := m.used[nonce]; ok {
+		return false
+	}
+	m.used[nonce] = expires
+	return true
+}
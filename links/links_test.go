@@ -0,0 +1,131 @@
+package links
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2017, time.February, 22, 9, 0, 0, 0, time.UTC)
+
+func newSigner() *Signer {
+	return &Signer{
+		Secret: []byte("secret"),
+		BaseURL: "https://oncall.example.com/action",
+		Nonces: &MemoryNonces{now: func() time.Time { return now }},
+		now: func() time.Time { return now },
+	}
+}
+
+func link(t *testing.T, s *Signer) url.Values {
+	u, err := s.URL("2017-02-22T10:00:00Z", "alice", ActionAcknowledge, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query()
+}
+
+func TestVerify(t *testing.T) {
+	s := newSigner()
+	c, err := s.Verify(link(t, s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RotationID != "2017-02-22T10:00:00Z" || c.User != "alice" || c.Action != ActionAcknowledge {
+		t.Errorf("unexpected claims %+v", c)
+	}
+	if !c.Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected link to expire at %s, got %s", now.Add(time.Hour), c.Expires)
+	}
+}
+
+func TestVerifySingleUse(t *testing.T) {
+	s := newSigner()
+	q := link(t, s)
+	if _, err := s.Verify(q); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(q); err != ErrUsed {
+		t.Errorf("expected ErrUsed, got %v", err)
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	s := newSigner()
+	q := link(t, s)
+	s.now = func() time.Time { return now.Add(time.Hour) }
+	if _, err := s.Verify(q); err != ErrExpired {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	s := newSigner()
+	for param, value := range map[string]string{
+		"rotation": "2017-03-01T10:00:00Z",
+		"user": "mallory",
+		"action": string(ActionSwap),
+		"expires": "9999999999",
+		"nonce": "00",
+		"sig": "forged",
+	} {
+		q := link(t, s)
+		q.Set(param, value)
+		if _, err := s.Verify(q); err != ErrBadSignature {
+			t.Errorf("tampering with %s: expected ErrBadSignature, got %v", param, err)
+		}
+	}
+
+	other := newSigner()
+	other.Secret = []byte("other")
+	if _, err := s.Verify(link(t, other)); err != ErrBadSignature {
+		t.Errorf("expected a link signed with another secret to be rejected, got %v", err)
+	}
+}
+
+func TestVerifySwap(t *testing.T) {
+	s := newSigner()
+	until := now.Add(14 * 24 * time.Hour)
+	u, err := s.SwapURL("2017-02-22T10:00:00Z", "alice", "bob", until, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := parsed.Query()
+	for param, value := range map[string]string{"with": "mallory", "until": "9999999999"} {
+		tampered := url.Values{}
+		for k, v := range q {
+			tampered[k] = v
+		}
+		tampered.Set(param, value)
+		if _, err := s.Verify(tampered); err != ErrBadSignature {
+			t.Errorf("tampering with %s: expected ErrBadSignature, got %v", param, err)
+		}
+	}
+	c, err := s.Verify(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Action != ActionSwap || c.With != "bob" || !c.Until.Equal(until) {
+		t.Errorf("unexpected claims %+v", c)
+	}
+}
+
+func TestNoSecret(t *testing.T) {
+	s := newSigner()
+	q := link(t, s)
+	s.Secret = nil
+	if _, err := s.URL("2017-02-22T10:00:00Z", "alice", ActionAcknowledge, time.Hour); err != ErrNoSecret {
+		t.Errorf("expected URL to return ErrNoSecret, got %v", err)
+	}
+	if _, err := s.Verify(q); err != ErrNoSecret {
+		t.Errorf("expected Verify to return ErrNoSecret, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/websdev/oncallator/metrics"
+	"github.com/websdev/oncallator/schedule"
+)
+
+// Serve the metrics of a schedule for Prometheus to scrape.
+func Example() {
+	text, err := ioutil.ReadFile("schedule.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := schedule.NewSchedule(text)
+	if err != nil {
+		log.Fatal(err)
+	}
+	http.Handle("/metrics", metrics.New(s, time.Now))
+	log.Fatal(http.ListenAndServe(":9090", nil))
+}
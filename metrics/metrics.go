@@ -0,0 +1,165 @@
+// Package metrics exposes the on-call state of a schedule as Prometheus
+// metrics, for alerting on nobody being on call or the schedule running out.
+//
+// A Collector is an http.Handler serving the metrics in Prometheus's text
+// exposition format, so it can be scraped without depending on the Prometheus
+// client library:
+//
+//	oncall_active_rotation                  1 if a rotation covers now, else 0
+//	oncall_current{role,user}               1 for each user on call now
+//	oncall_rotation_seconds_remaining       until the active rotation ends
+//	oncall_schedule_horizon_seconds         until the last rotation ends
+//	oncall_upcoming_primary_shifts{user}    rotations yet to start with user
+//	                                        as primary
+//
+// oncall_rotation_seconds_remaining is left out when no rotation is active;
+// alert on oncall_active_rotation == 0 instead.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// A Metric is a family of samples sharing a name.
+type Metric struct {
+	Name string
+	Help string
+	Samples []Sample
+}
+
+// A Sample is one value of a metric, with its labels in order.
+type Sample struct {
+	Labels []Label
+	Value float64
+}
+
+type Label struct {
+	Name string
+	Value string
+}
+
+// A Collector reports the metrics of a schedule as of its clock.
+type Collector struct {
+	// Defaults to time.Now.
+	Clock func() time.Time
+
+	mu sync.Mutex
+	s *schedule.Schedule
+}
+
+// New returns a Collector reporting on s as of clock, or the current time if
+// clock is nil.
+func New(s *schedule.Schedule, clock func() time.Time) *Collector {
+	return &Collector{Clock: clock, s: s}
+}
+
+// Set replaces the schedule reported on, e.g. after regenerating it.
+func (c *Collector) Set(s *schedule.Schedule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.s = s
+}
+
+// Collect returns the metrics of the schedule now, in the order listed in the
+// package documentation, each with its samples ordered by label.
+func (c *Collector) Collect() []Metric {
+	c.mu.Lock()
+	s := c.s
+	c.mu.Unlock()
+	now := time.Now()
+	if c.Clock != nil {
+		now = c.Clock()
+	}
+
+	active := Metric{Name: "oncall_active_rotation", Help: "Whether a rotation covers the current time."}
+	current := Metric{Name: "oncall_current", Help: "Whether the user is on call in the role now."}
+	remaining := Metric{Name: "oncall_rotation_seconds_remaining", Help: "Seconds until the active rotation ends."}
+	horizon := Metric{Name: "oncall_schedule_horizon_seconds", Help: "Seconds until the last rotation of the schedule ends."}
+	upcoming := Metric{Name: "oncall_upcoming_primary_shifts", Help: "Rotations yet to start with the user as primary."}
+
+	r, err := s.At(now)
+	if err != nil {
+		active.Samples = []Sample{{Value: 0}}
+	} else {
+		active.Samples = []Sample{{Value: 1}}
+		tiers := s.Tiers()
+		for i, u := range s.Assigned(r) {
+			if u != "" {
+				current.Samples = append(current.Samples, Sample{Labels: []Label{{"role", tiers[i]}, {"user", u}}, Value: 1})
+			}
+		}
+		for i := range s.Rotations {
+			if !s.Rotations[i].Start.After(now) && s.RotationEnd(i).After(now) {
+				remaining.Samples = []Sample{{Value: s.RotationEnd(i).Sub(now).Seconds()}}
+			}
+		}
+	}
+	if n := len(s.Rotations); n > 0 {
+		horizon.Samples = []Sample{{Value: s.RotationEnd(n-1).Sub(now).Seconds()}}
+	} else {
+		horizon.Samples = []Sample{{Value: 0}}
+	}
+
+	shifts := map[string]int{}
+	// Everyone who can be primary is listed, so that a user with none is 0
+	// rather than missing.
+	for _, u := range append(append([]string{}, s.Users...), s.PrimaryUsers...) {
+		shifts[u] = 0
+	}
+	for _, r := range s.Rotations {
+		if u := s.Assigned(r)[0]; r.Start.After(now) && !r.Continues && u != "" {
+			shifts[u]++
+		}
+	}
+	for u, n := range shifts {
+		upcoming.Samples = append(upcoming.Samples, Sample{Labels: []Label{{"user", u}}, Value: float64(n)})
+	}
+
+	metrics := []Metric{active, current, remaining, horizon, upcoming}
+	for _, m := range metrics {
+		sort.Slice(m.Samples, func(i, j int) bool {
+			return labelString(m.Samples[i].Labels) < labelString(m.Samples[j].Labels)
+		})
+	}
+	return metrics
+}
+
+// WriteTo writes the metrics in Prometheus's text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	out := &bytes.Buffer{}
+	for _, m := range c.Collect() {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s gauge\n", m.Name, m.Help, m.Name)
+		for _, s := range m.Samples {
+			fmt.Fprintf(out, "%s%s %s\n", m.Name, labelString(s.Labels), strconv.FormatFloat(s.Value, 'g', -1, 64))
+		}
+	}
+	return out.WriteTo(w)
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// Render labels as {name="value",...}, or "" if there are none.
+func labelString(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := []string{}
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, l.Name, escape.Replace(l.Value)))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["a", "b", "c"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-03-01T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		},
+		{
+			"Start": "2017-03-08T10:00:00Z",
+			"Primary": "b",
+			"Secondary": "c"
+		},
+		{
+			"Start": "2017-03-15T10:00:00Z",
+			"End": "2017-03-22T10:00:00Z",
+			"Primary": "b",
+			"Secondary": "a"
+		}
+	]
+}`
+
+func testCollector(t *testing.T, now time.Time) *Collector {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(s, func() time.Time { return now })
+}
+
+func scrape(c *Collector) string {
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	c := testCollector(t, time.Date(2017, time.March, 7, 10, 0, 0, 0, time.UTC))
+	expected := `# HELP oncall_active_rotation Whether a rotation covers the current time.
+# TYPE oncall_active_rotation gauge
+oncall_active_rotation 1
+# HELP oncall_current Whether the user is on call in the role now.
+# TYPE oncall_current gauge
+oncall_current{role="primary",user="a"} 1
+oncall_current{role="secondary",user="b"} 1
+# HELP oncall_rotation_seconds_remaining Seconds until the active rotation ends.
+# TYPE oncall_rotation_seconds_remaining gauge
+oncall_rotation_seconds_remaining 86400
+# HELP oncall_schedule_horizon_seconds Seconds until the last rotation of the schedule ends.
+# TYPE oncall_schedule_horizon_seconds gauge
+oncall_schedule_horizon_seconds 1.296e+06
+# HELP oncall_upcoming_primary_shifts Rotations yet to start with the user as primary.
+# TYPE oncall_upcoming_primary_shifts gauge
+oncall_upcoming_primary_shifts{user="a"} 0
+oncall_upcoming_primary_shifts{user="b"} 2
+oncall_upcoming_primary_shifts{user="c"} 0
+`
+	if got := scrape(c); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestMetricsWithoutActiveRotation(t *testing.T) {
+	c := testCollector(t, time.Date(2017, time.March, 23, 10, 0, 0, 0, time.UTC))
+	got := scrape(c)
+	if !strings.Contains(got, "\noncall_active_rotation 0\n") {
+		t.Errorf("expected no active rotation to be reported, got:\n%s", got)
+	}
+	if strings.Contains(got, "oncall_current{") || strings.Contains(got, "\noncall_rotation_seconds_remaining ") {
+		t.Errorf("expected nobody to be reported on call, got:\n%s", got)
+	}
+	if !strings.Contains(got, "\noncall_schedule_horizon_seconds -86400\n") {
+		t.Errorf("expected the horizon to have passed, got:\n%s", got)
+	}
+}
+
+func TestLabelsEscaped(t *testing.T) {
+	if got := labelString([]Label{{"user", "a \"b\"\\"}}); got != `{user="a \"b\"\\"}` {
+		t.Errorf("unexpected labels %s", got)
+	}
+}
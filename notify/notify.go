@@ -0,0 +1,207 @@
+// Package notify reminds a team of upcoming handoffs, as a message for a
+// channel and a direct message for each user taking over, and posts them to a
+// Slack incoming webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	// The default template of the message for a channel, given a Handoff.
+	DefaultTemplate = `Reminder: from {{.Start.Format "Mon Jan 2 15:04 MST"}}, {{range $i, $a := .Assignments}}{{if $i}}, {{end}}{{mention $a.User}} is {{$a.Tier}}{{end}}.`
+	// The default template of the message for each user taking over, given a
+	// DM.
+	DefaultDMTemplate = `It's your turn next: you're {{.Tier}} on call from {{.Start.Format "Mon Jan 2 15:04 MST"}} to {{.End.Format "Mon Jan 2 15:04 MST"}}.`
+)
+
+// An Assignment is the user filling a tier.
+type Assignment struct {
+	Tier string
+	User string
+}
+
+// A Handoff is the start of a rotation whose users differ from those of the
+// rotation before it.
+type Handoff struct {
+	Start time.Time
+	// The RotationEnd of the rotation starting.
+	End time.Time
+	// The users taking over, by tier. Tiers left unfilled are left out.
+	Assignments []Assignment
+	// The users handing over, by tier, or none for the first rotation.
+	Previous []Assignment
+}
+
+// Key returns a key identifying h, for a caller to remember which handoffs it
+// has already announced.
+func (h Handoff) Key() string {
+	return h.Start.UTC().Format(time.RFC3339)
+}
+
+// A DM is a user taking over a tier at a handoff.
+type DM struct {
+	Handoff
+	Assignment
+}
+
+// NextHandoff returns the first handoff of s starting at or after now and no
+// more than lead after it. Since it depends only on the rotations, repeated
+// calls within the lead time before a handoff return the same one, so that a
+// job run every hour with a lead of a day announces each handoff on every run
+// in the day before it, and can use its Key to announce it just once.
+func NextHandoff(s *schedule.Schedule, now time.Time, lead time.Duration) (Handoff, bool) {
+	previous := []Assignment{}
+	for i, r := range s.Rotations {
+		assignments := assignmentsOf(s, r)
+		if !r.Start.Before(now) && !r.Start.After(now.Add(lead)) && !sameAssignments(previous, assignments) {
+			return Handoff{Start: r.Start, End: s.RotationEnd(i), Assignments: assignments, Previous: previous}, true
+		}
+		previous = assignments
+	}
+	return Handoff{}, false
+}
+
+func assignmentsOf(s *schedule.Schedule, r schedule.Rotation) []Assignment {
+	assignments := []Assignment{}
+	tiers := s.Tiers()
+	for i, u := range s.Assigned(r) {
+		if u != "" {
+			assignments = append(assignments, Assignment{Tier: tiers[i], User: u})
+		}
+	}
+	return assignments
+}
+
+func sameAssignments(a, b []Assignment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// A Notifier formats the messages announcing handoffs.
+type Notifier struct {
+	// Maps schedule user names to Slack user IDs, so that users are mentioned
+	// as <@U123>. A user without an entry is mentioned as @name.
+	SlackIDs map[string]string
+	// The text/template of the message for a channel, executed with a Handoff.
+	// Defaults to DefaultTemplate.
+	Template string
+	// The text/template of the message for each user taking over, executed
+	// with a DM. Defaults to DefaultDMTemplate.
+	DMTemplate string
+}
+
+// Message returns the message announcing h to a channel.
+func (n *Notifier) Message(h Handoff) (string, error) {
+	text := n.Template
+	if text == "" {
+		text = DefaultTemplate
+	}
+	return n.execute(text, h)
+}
+
+// DMs returns the message for each user taking over a tier at h, by user:
+// those not already filling the same tier before it.
+func (n *Notifier) DMs(h Handoff) (map[string]string, error) {
+	text := n.DMTemplate
+	if text == "" {
+		text = DefaultDMTemplate
+	}
+	dms := map[string]string{}
+	for _, a := range h.Assignments {
+		if a.User == schedule.Unassigned || containsAssignment(h.Previous, a) {
+			continue
+		}
+		m, err := n.execute(text, DM{h, a})
+		if err != nil {
+			return nil, err
+		}
+		dms[a.User] = m
+	}
+	return dms, nil
+}
+
+func containsAssignment(as []Assignment, a Assignment) bool {
+	for _, b := range as {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Notifier) execute(text string, data interface{}) (string, error) {
+	t, err := template.New("").Funcs(template.FuncMap{"mention": n.mention}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %s", err)
+	}
+	out := &bytes.Buffer{}
+	if err := t.Execute(out, data); err != nil {
+		return "", fmt.Errorf("error executing template: %s", err)
+	}
+	return out.String(), nil
+}
+
+// Mention user in Slack's markup.
+func (n *Notifier) mention(user string) string {
+	if id, ok := n.SlackIDs[user]; ok {
+		return "<@" + id + ">"
+	}
+	return "@" + user
+}
+
+// A Webhook posts messages to a Slack incoming webhook.
+type Webhook struct {
+	URL string
+	// Defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+// Post posts text to the webhook's channel.
+func (w *Webhook) Post(ctx context.Context, text string) error {
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Slack explains the error, e.g. "invalid_payload", in plain text.
+		text, _ := ioutil.ReadAll(resp.Body)
+		if msg := strings.TrimSpace(string(text)); msg != "" {
+			return fmt.Errorf("unexpected response %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["a", "b", "c"],
+	"Start": "2017-03-06T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-27T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		},
+		{
+			"Start": "2017-03-06T10:00:00Z",
+			"Primary": "b",
+			"Secondary": "c"
+		},
+		{
+			"Start": "2017-03-08T10:00:00Z",
+			"Primary": "b",
+			"Secondary": "c"
+		},
+		{
+			"Start": "2017-03-13T10:00:00Z",
+			"End": "2017-03-20T10:00:00Z",
+			"Primary": "c",
+			"Secondary": "b"
+		}
+	]
+}`
+
+func testSchedule(t *testing.T) *schedule.Schedule {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func at(month time.Month, day, hour int) time.Time {
+	return time.Date(2017, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestNextHandoff(t *testing.T) {
+	s := testSchedule(t)
+	expected := Handoff{
+		Start: at(time.March, 6, 10),
+		End: at(time.March, 8, 10),
+		Assignments: []Assignment{{"primary", "b"}, {"secondary", "c"}},
+		Previous: []Assignment{{"primary", "a"}, {"secondary", "b"}},
+	}
+	// Every run in the day before the handoff finds the same one.
+	for _, now := range []time.Time{at(time.March, 5, 10), at(time.March, 5, 20), at(time.March, 6, 10)} {
+		h, ok := NextHandoff(s, now, 24 * time.Hour)
+		if !ok || !reflect.DeepEqual(expected, h) {
+			t.Errorf("at %s: expected %+v, got %+v", now, expected, h)
+		}
+		if h.Key() != "2017-03-06T10:00:00Z" {
+			t.Errorf("at %s: unexpected key %s", now, h.Key())
+		}
+	}
+	if h, ok := NextHandoff(s, at(time.March, 5, 9), 24 * time.Hour); ok {
+		t.Errorf("expected no handoff more than a day ahead, got %+v", h)
+	}
+	// The start of the third rotation, which changes nobody, is no handoff.
+	if h, ok := NextHandoff(s, at(time.March, 7, 10), 2 * 24 * time.Hour); ok {
+		t.Errorf("expected no handoff where the users stay the same, got %+v", h)
+	}
+	if h, ok := NextHandoff(s, at(time.March, 7, 10), 7 * 24 * time.Hour); !ok || h.Start != at(time.March, 13, 10) {
+		t.Errorf("expected the handoff to c, got %+v", h)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	h, _ := NextHandoff(testSchedule(t), at(time.March, 6, 0), 24 * time.Hour)
+	n := &Notifier{SlackIDs: map[string]string{"b": "U2"}}
+	m, err := n.Message(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Reminder: from Mon Mar 6 10:00 UTC, <@U2> is primary, @c is secondary."; m != expected {
+		t.Errorf("expected %q, got %q", expected, m)
+	}
+
+	n.Template = `{{range .Assignments}}{{.Tier}}={{mention .User}} {{end}}`
+	if m, err = n.Message(h); err != nil || m != "primary=<@U2> secondary=@c " {
+		t.Errorf("expected the template to be overridden, got %q, %v", m, err)
+	}
+	n.Template = `{{.Nope}}`
+	if _, err := n.Message(h); err == nil || !strings.Contains(err.Error(), "error executing template") {
+		t.Errorf("expected a template error, got %v", err)
+	}
+}
+
+func TestDMs(t *testing.T) {
+	h, _ := NextHandoff(testSchedule(t), at(time.March, 13, 0), 24 * time.Hour)
+	dms, err := (&Notifier{}).DMs(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b and c swap tiers, so both are told.
+	expected := map[string]string{
+		"b": "It's your turn next: you're secondary on call from Mon Mar 13 10:00 UTC to Mon Mar 20 10:00 UTC.",
+		"c": "It's your turn next: you're primary on call from Mon Mar 13 10:00 UTC to Mon Mar 20 10:00 UTC.",
+	}
+	if !reflect.DeepEqual(expected, dms) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, dms)
+	}
+
+	// b moves up to primary, and c joins as secondary.
+	h, _ = NextHandoff(testSchedule(t), at(time.March, 6, 0), 24 * time.Hour)
+	if dms, _ = (&Notifier{}).DMs(h); len(dms) != 2 {
+		t.Errorf("expected b and c to be told, got %v", dms)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	posted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Text string `json:"text"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Text == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid_payload"))
+			return
+		}
+		posted = append(posted, body.Text)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	w := &Webhook{URL: server.URL}
+	if err := w.Post(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(posted, []string{"hello"}) {
+		t.Errorf("expected the message to be posted, got %v", posted)
+	}
+	if err := w.Post(context.Background(), ""); err == nil || err.Error() != "unexpected response 400 Bad Request: invalid_payload" {
+		t.Errorf("expected Slack's error, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package pagerduty
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// An Entry is a span of time in which one user is on call for a PagerDuty
+// schedule, after its layers and overrides are combined.
+type Entry struct {
+	Start time.Time
+	End time.Time
+	// The PagerDuty user's ID and name.
+	UserID string
+	UserName string
+}
+
+// Entries returns who was, or will be, on call for the client's schedule
+// from since until until, in order, as PagerDuty renders it. Entries under way
+// at either end are cut short to the window.
+func (c *Client) Entries(ctx context.Context, since, until time.Time) ([]Entry, error) {
+	q := url.Values{}
+	q.Set("since", since.Format(time.RFC3339))
+	q.Set("until", until.Format(time.RFC3339))
+	q.Set("time_zone", "UTC")
+	body := struct {
+		Schedule struct {
+			FinalSchedule struct {
+				RenderedScheduleEntries []struct {
+					Start time.Time `json:"start"`
+					End time.Time `json:"end"`
+					User struct {
+						ID string `json:"id"`
+						Summary string `json:"summary"`
+					} `json:"user"`
+				} `json:"rendered_schedule_entries"`
+			} `json:"final_schedule"`
+		} `json:"schedule"`
+	}{}
+	if err := c.do(ctx, "GET", "/schedules/" + url.PathEscape(c.ScheduleID) + "?" + q.Encode(), nil, &body); err != nil {
+		return nil, fmt.Errorf("error reading schedule %s: %s", c.ScheduleID, err)
+	}
+	entries := []Entry{}
+	for _, e := range body.Schedule.FinalSchedule.RenderedScheduleEntries {
+		entries = append(entries, Entry{Start: e.Start, End: e.End, UserID: e.User.ID, UserName: e.User.Summary})
+	}
+	return entries, nil
+}
@@ -0,0 +1,350 @@
+// Package pagerduty pushes generated rotations to a PagerDuty schedule as
+// overrides, so that nobody has to copy them in by hand, and reads who is on
+// call for one, so that a schedule kept there can be imported.
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	DefaultURL = "https://api.pagerduty.com"
+	// How many times a request rate limited with a 429 is retried.
+	MaxRetries = 5
+
+	pageSize = 100
+)
+
+// Used to wait out rate limits; replaced in tests.
+var sleep = time.Sleep
+
+// A Client pushes one tier of a schedule's rotations to a PagerDuty schedule.
+//
+// The client owns the overrides of the PagerDuty schedule that start during
+// the rotations it pushes: any of them that does not match a rotation is
+// deleted. Overrides cannot be edited, so one whose user changed
+// is deleted and created again.
+type Client struct {
+	Token string
+	ScheduleID string
+	// The tier pushed to the schedule. Defaults to schedule.TierPrimary.
+	Tier string
+	// Maps schedule user names to PagerDuty user IDs. Every user assigned to
+	// a pushed rotation must have an entry.
+	Users map[string]string
+	// If set, the changes Push would make are written here as the API calls
+	// that would make them, and the schedule is left as it is. The existing
+	// overrides are still listed to work out the changes.
+	DryRun io.Writer
+	// Defaults to DefaultURL.
+	BaseURL string
+	// Defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+// An override of the schedule, as PagerDuty lists it or as Push wants it.
+type override struct {
+	ID string `json:"id,omitempty"`
+	Start time.Time `json:"start"`
+	End time.Time `json:"end"`
+	User userReference `json:"user"`
+
+	// The schedule user the override is wanted for, or "" for one listed by
+	// PagerDuty.
+	name string
+}
+
+type userReference struct {
+	ID string `json:"id"`
+	Type string `json:"type"`
+}
+
+func (o override) String() string {
+	who := o.User.ID
+	if o.name != "" {
+		who = fmt.Sprintf("%s (%s)", o.name, o.User.ID)
+	}
+	return fmt.Sprintf("%s from %s to %s", who, o.Start.Format(time.RFC3339), o.End.Format(time.RFC3339))
+}
+
+// What Push changed, or would change in a dry run.
+type Result struct {
+	Created int
+	Deleted int
+	Unchanged int
+}
+
+// Push makes the overrides of the PagerDuty schedule match the client's tier
+// of every rotation of s that ends after from. Rotations leaving the tier
+// unfilled, or filled by schedule.Unassigned, get no override, and
+// consecutive rotations with the same user are pushed as one override.
+// Running Push again after a regeneration only changes the overrides that
+// differ.
+func (c *Client) Push(ctx context.Context, s *schedule.Schedule, from time.Time) (Result, error) {
+	wanted, err := c.wanted(s, from)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(wanted) == 0 {
+		return Result{}, nil
+	}
+	existing, err := c.list(ctx, wanted[0].Start, wanted[len(wanted)-1].End)
+	if err != nil {
+		return Result{}, fmt.Errorf("error listing overrides of schedule %s: %s", c.ScheduleID, err)
+	}
+
+	key := func(o override) string {
+		return fmt.Sprintf("%d %d %s", o.Start.Unix(), o.End.Unix(), o.User.ID)
+	}
+	byKey := map[string]string{}
+	for _, o := range existing {
+		if _, ok := byKey[key(o)]; !ok {
+			byKey[key(o)] = o.ID
+		}
+	}
+	result := Result{}
+	keep := map[string]bool{}
+	create := []override{}
+	for _, o := range wanted {
+		if id, ok := byKey[key(o)]; ok && !keep[id] {
+			keep[id] = true
+			result.Unchanged++
+			continue
+		}
+		create = append(create, o)
+	}
+	// Stale overrides, and duplicates of kept ones, e.g. from an earlier push
+	// that was interrupted, are deleted first so they never overlap the new
+	// ones.
+	for _, o := range existing {
+		if keep[o.ID] {
+			continue
+		}
+		if err := c.delete(ctx, o); err != nil {
+			return result, fmt.Errorf("error deleting stale override %s of %s: %s", o.ID, o, err)
+		}
+		result.Deleted++
+	}
+	for _, o := range create {
+		if err := c.create(ctx, o); err != nil {
+			return result, fmt.Errorf("error creating the override of the %s rotation for %s: %s", c.tier(), o, err)
+		}
+		result.Created++
+	}
+	return result, nil
+}
+
+func (c *Client) tier() string {
+	if c.Tier == "" {
+		return schedule.TierPrimary
+	}
+	return c.Tier
+}
+
+// Return the overrides wanted for the rotations of s ending after from, in
+// order.
+func (c *Client) wanted(s *schedule.Schedule, from time.Time) ([]override, error) {
+	tier := -1
+	for i, t := range s.Tiers() {
+		if t == c.tier() {
+			tier = i
+		}
+	}
+	if tier < 0 {
+		return nil, fmt.Errorf("unknown tier %s (the schedule's tiers are %s)", c.tier(), strings.Join(s.Tiers(), ", "))
+	}
+	wanted := []override{}
+	missing := map[string]bool{}
+	for i, r := range s.Rotations {
+		end := s.RotationEnd(i)
+		if !end.After(from) {
+			continue
+		}
+		user := s.Assigned(r)[tier]
+		if user == "" || user == schedule.Unassigned {
+			continue
+		}
+		id, ok := c.Users[user]
+		if !ok {
+			missing[user] = true
+			continue
+		}
+		if n := len(wanted); n > 0 && wanted[n-1].name == user && wanted[n-1].End.Equal(r.Start) {
+			wanted[n-1].End = end
+			continue
+		}
+		wanted = append(wanted, override{
+			Start: r.Start,
+			End: end,
+			User: userReference{ID: id, Type: "user_reference"},
+			name: user,
+		})
+	}
+	if len(missing) > 0 {
+		users := []string{}
+		for u := range missing {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		return nil, fmt.Errorf("no PagerDuty user ID for users: %s", strings.Join(users, ", "))
+	}
+	return wanted, nil
+}
+
+// List the overrides of the schedule starting in [since, until), following
+// pagination.
+func (c *Client) list(ctx context.Context, since, until time.Time) ([]override, error) {
+	overrides := []override{}
+	for offset := 0; ; {
+		q := url.Values{}
+		q.Set("since", since.Format(time.RFC3339))
+		q.Set("until", until.Format(time.RFC3339))
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("limit", strconv.Itoa(pageSize))
+		body := struct {
+			Overrides []override `json:"overrides"`
+			More bool `json:"more"`
+		}{}
+		if err := c.do(ctx, "GET", c.overridesPath() + "?" + q.Encode(), nil, &body); err != nil {
+			return nil, err
+		}
+		for _, o := range body.Overrides {
+			// Overrides already under way when the window starts are not ours.
+			if !o.Start.Before(since) {
+				overrides = append(overrides, o)
+			}
+		}
+		if !body.More || len(body.Overrides) == 0 {
+			return overrides, nil
+		}
+		offset += len(body.Overrides)
+	}
+}
+
+func (c *Client) create(ctx context.Context, o override) error {
+	body := struct {
+		Overrides []override `json:"overrides"`
+	}{[]override{o}}
+	if c.DryRun != nil {
+		text, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.DryRun, "POST %s %s # %s\n", c.overridesPath(), text, o)
+		return err
+	}
+	// PagerDuty answers 201 with the status of each override created.
+	created := []struct {
+		Status int `json:"status"`
+		Errors []string `json:"errors"`
+	}{}
+	if err := c.do(ctx, "POST", c.overridesPath(), body, &created); err != nil {
+		return err
+	}
+	for _, r := range created {
+		if len(r.Errors) > 0 {
+			return fmt.Errorf("%s", strings.Join(r.Errors, "; "))
+		}
+	}
+	return nil
+}
+
+func (c *Client) delete(ctx context.Context, o override) error {
+	path := c.overridesPath() + "/" + url.PathEscape(o.ID)
+	if c.DryRun != nil {
+		_, err := fmt.Fprintf(c.DryRun, "DELETE %s # %s\n", path, o)
+		return err
+	}
+	return c.do(ctx, "DELETE", path, nil, nil)
+}
+
+func (c *Client) overridesPath() string {
+	return "/schedules/" + url.PathEscape(c.ScheduleID) + "/overrides"
+}
+
+// Make a request of the API, retrying while it is rate limited, and decode
+// the response into v unless it is nil.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultURL
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	text := []byte{}
+	if body != nil {
+		t, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		text = t
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, base + path, bytes.NewReader(text))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Token token=" + c.Token)
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < MaxRetries {
+			resp.Body.Close()
+			sleep(retryAfter(resp, attempt))
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return responseError(resp)
+		}
+		if v == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+}
+
+// How long to wait before retrying a rate limited request: as long as
+// PagerDuty asks, or otherwise a second, doubling with each attempt.
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Second << uint(attempt)
+}
+
+// Describe an unsuccessful response, including the error PagerDuty gives.
+func responseError(resp *http.Response) error {
+	body := struct {
+		Error struct {
+			Message string `json:"message"`
+			Errors []string `json:"errors"`
+		} `json:"error"`
+	}{}
+	text, _ := ioutil.ReadAll(resp.Body)
+	if json.Unmarshal(text, &body) != nil || body.Error.Message == "" {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	details := append([]string{body.Error.Message}, body.Error.Errors...)
+	return fmt.Errorf("unexpected response %s: %s", resp.Status, strings.Join(details, "; "))
+}
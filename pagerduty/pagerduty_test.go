@@ -0,0 +1,324 @@
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["b", "c", "a"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-15T10:00:00Z",
+			"End": "2017-02-22T10:00:00Z",
+			"Primary": "c",
+			"Secondary": "a"
+		},
+		{
+			"Start": "2017-02-22T10:00:00Z",
+			"End": "2017-03-01T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		},
+		{
+			"Start": "2017-03-01T10:00:00Z",
+			"End": "2017-03-08T10:00:00Z",
+			"Primary": "b",
+			"Secondary": "c"
+		}
+	]
+}`
+
+var (
+	users = map[string]string{"a": "PA", "b": "PB", "c": "PC"}
+	from = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+)
+
+// A fake PagerDuty schedule's overrides API.
+type fakeSchedule struct {
+	overrides map[string]override
+	nextID int
+	// Overrides are listed this many at a time, whatever the limit asked for.
+	pageSize int
+	// This many requests are answered 429 before any succeed.
+	rateLimited int
+	// If set, creating an override fails with this message.
+	createError string
+	requests []string
+}
+
+func newFakeSchedule() *fakeSchedule {
+	return &fakeSchedule{overrides: map[string]override{}, pageSize: 100}
+}
+
+func (f *fakeSchedule) add(start, end time.Time, user string) {
+	f.nextID++
+	id := fmt.Sprintf("Q%d", f.nextID)
+	f.overrides[id] = override{ID: id, Start: start, End: end, User: userReference{ID: user, Type: "user_reference"}}
+}
+
+// The overrides, ordered by start, as "start/end user".
+func (f *fakeSchedule) list() []string {
+	list := []string{}
+	for _, o := range f.overrides {
+		list = append(list, fmt.Sprintf("%s/%s %s", o.Start.UTC().Format(time.RFC3339), o.End.UTC().Format(time.RFC3339), o.User.ID))
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (f *fakeSchedule) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method + " " + r.URL.Path)
+	if f.rateLimited > 0 {
+		f.rateLimited--
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	if r.Header.Get("Authorization") != "Token token=secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const path = "/schedules/PSCHED/overrides"
+	switch {
+	case r.Method == "GET" && r.URL.Path == path:
+		since, _ := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+		until, _ := time.Parse(time.RFC3339, r.URL.Query().Get("until"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		matching := []override{}
+		for _, o := range f.overrides {
+			if o.End.After(since) && o.Start.Before(until) {
+				matching = append(matching, o)
+			}
+		}
+		sort.Slice(matching, func(i, j int) bool {
+			return matching[i].ID < matching[j].ID
+		})
+		page := matching[offset:]
+		more := len(page) > f.pageSize
+		if more {
+			page = page[:f.pageSize]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"overrides": page, "more": more})
+	case r.Method == "POST" && r.URL.Path == path:
+		if f.createError != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error": {"message": "Invalid Input Provided", "errors": [%q]}}`, f.createError)
+			return
+		}
+		body := struct {
+			Overrides []override `json:"overrides"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, o := range body.Overrides {
+			f.add(o.Start, o.End, o.User.ID)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `[{"status": 201}]`)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, path + "/"):
+		id := strings.TrimPrefix(r.URL.Path, path + "/")
+		if _, ok := f.overrides[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.overrides, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testSchedule(t *testing.T, text string) *schedule.Schedule {
+	s, err := schedule.NewSchedule([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func push(t *testing.T, f *fakeSchedule, c Client, text string) Result {
+	server := httptest.NewServer(f)
+	defer server.Close()
+	c.Token = "secret"
+	c.ScheduleID = "PSCHED"
+	c.Users = users
+	c.BaseURL = server.URL
+	result, err := c.Push(context.Background(), testSchedule(t, text), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestPush(t *testing.T) {
+	f := newFakeSchedule()
+	result := push(t, f, Client{}, scheduleText)
+	if result != (Result{Created: 3}) {
+		t.Errorf("expected 3 overrides created, got %+v", result)
+	}
+	expected := []string{
+		"2017-02-15T10:00:00Z/2017-02-22T10:00:00Z PC",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z PA",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z PB",
+	}
+	if got := f.list(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected overrides:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPushSecondary(t *testing.T) {
+	f := newFakeSchedule()
+	push(t, f, Client{Tier: schedule.TierSecondary}, scheduleText)
+	expected := []string{
+		"2017-02-15T10:00:00Z/2017-02-22T10:00:00Z PA",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z PB",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z PC",
+	}
+	if got := f.list(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected overrides:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPushIsIdempotent(t *testing.T) {
+	f := newFakeSchedule()
+	push(t, f, Client{}, scheduleText)
+	f.requests = nil
+	result := push(t, f, Client{}, scheduleText)
+	if result != (Result{Unchanged: 3}) {
+		t.Errorf("expected every override to be left alone, got %+v", result)
+	}
+	for _, r := range f.requests {
+		if !strings.HasPrefix(r, "GET ") {
+			t.Errorf("expected only the overrides to be listed, got %s", r)
+		}
+	}
+}
+
+func TestPushReplacesStaleOverrides(t *testing.T) {
+	f := newFakeSchedule()
+	push(t, f, Client{}, scheduleText)
+	// A duplicate left by an interrupted push.
+	f.add(time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC), time.Date(2017, time.March, 8, 10, 0, 0, 0, time.UTC), "PB")
+	// An override already under way before the pushed rotations is left alone.
+	f.add(time.Date(2017, time.February, 14, 0, 0, 0, 0, time.UTC), time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC), "PC")
+	// a and c swap the first two rotations.
+	regenerated := strings.Replace(strings.Replace(scheduleText, `"Primary": "c"`, `"Primary": "x"`, 1), `"Primary": "a"`, `"Primary": "c"`, 1)
+	regenerated = strings.Replace(regenerated, `"Primary": "x"`, `"Primary": "a"`, 1)
+	result := push(t, f, Client{}, regenerated)
+	if result != (Result{Created: 2, Deleted: 3, Unchanged: 1}) {
+		t.Errorf("expected 2 overrides replaced and a duplicate deleted, got %+v", result)
+	}
+	expected := []string{
+		"2017-02-14T00:00:00Z/2017-02-16T00:00:00Z PC",
+		"2017-02-15T10:00:00Z/2017-02-22T10:00:00Z PA",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z PC",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z PB",
+	}
+	if got := f.list(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected overrides:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPushMergesConsecutiveRotations(t *testing.T) {
+	f := newFakeSchedule()
+	text := strings.Replace(scheduleText, `"Primary": "a"`, `"Primary": "c"`, 1)
+	result := push(t, f, Client{}, text)
+	if result != (Result{Created: 2}) {
+		t.Errorf("expected 2 overrides created, got %+v", result)
+	}
+	expected := []string{
+		"2017-02-15T10:00:00Z/2017-03-01T10:00:00Z PC",
+		"2017-03-01T10:00:00Z/2017-03-08T10:00:00Z PB",
+	}
+	if got := f.list(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected overrides:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPushPaginates(t *testing.T) {
+	f := newFakeSchedule()
+	push(t, f, Client{}, scheduleText)
+	f.pageSize = 1
+	f.requests = nil
+	result := push(t, f, Client{}, scheduleText)
+	if result != (Result{Unchanged: 3}) {
+		t.Errorf("expected every override to be found across pages, got %+v", result)
+	}
+	if len(f.requests) != 3 {
+		t.Errorf("expected 3 pages to be listed, got %v", f.requests)
+	}
+}
+
+func TestPushRetriesRateLimited(t *testing.T) {
+	slept := []time.Duration{}
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+	f := newFakeSchedule()
+	f.rateLimited = 2
+	result := push(t, f, Client{}, scheduleText)
+	if result != (Result{Created: 3}) {
+		t.Errorf("expected 3 overrides created, got %+v", result)
+	}
+	if len(slept) != 2 || slept[0] != 3 * time.Second || slept[1] != 3 * time.Second {
+		t.Errorf("expected to wait as long as Retry-After asked twice, waited %v", slept)
+	}
+}
+
+func TestPushDryRun(t *testing.T) {
+	f := newFakeSchedule()
+	push(t, f, Client{}, scheduleText)
+	regenerated := strings.Replace(scheduleText, `"Primary": "b"`, `"Primary": "c"`, 1)
+	out := &bytes.Buffer{}
+	result := push(t, f, Client{DryRun: out}, regenerated)
+	if result != (Result{Created: 1, Deleted: 1, Unchanged: 2}) {
+		t.Errorf("expected the last override to be replaced, got %+v", result)
+	}
+	if len(f.list()) != 3 || !strings.HasSuffix(f.list()[2], " PB") {
+		t.Errorf("expected the dry run to leave the overrides alone, got %v", f.list())
+	}
+	expected := `DELETE /schedules/PSCHED/overrides/Q3 # PB from 2017-03-01T10:00:00Z to 2017-03-08T10:00:00Z
+POST /schedules/PSCHED/overrides {"overrides":[{"start":"2017-03-01T10:00:00Z","end":"2017-03-08T10:00:00Z","user":{"id":"PC","type":"user_reference"}}]} # c (PC) from 2017-03-01T10:00:00Z to 2017-03-08T10:00:00Z
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestPushAPIError(t *testing.T) {
+	f := newFakeSchedule()
+	f.createError = "Override must end after its start"
+	server := httptest.NewServer(f)
+	defer server.Close()
+	c := Client{Token: "secret", ScheduleID: "PSCHED", Users: users, BaseURL: server.URL}
+	_, err := c.Push(context.Background(), testSchedule(t, scheduleText), from)
+	expected := "error creating the override of the primary rotation for c (PC) from 2017-02-15T10:00:00Z to 2017-02-22T10:00:00Z: unexpected response 400 Bad Request: Invalid Input Provided; Override must end after its start"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestPushMissingUsers(t *testing.T) {
+	c := Client{Users: map[string]string{"a": "PA"}}
+	_, err := c.Push(context.Background(), testSchedule(t, scheduleText), from)
+	if err == nil || err.Error() != "no PagerDuty user ID for users: b, c" {
+		t.Errorf("expected an error naming the missing users, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const DefaultOpsgenieURL = "https://api.opsgenie.com"
+
+// Opsgenie asks Opsgenie who is on call for a primary and a secondary
+// schedule.
+type Opsgenie struct {
+	APIKey string
+	PrimaryScheduleID string
+	SecondaryScheduleID string
+	// Maps Opsgenie usernames to schedule user names. Users missing from the
+	// map are reported by their Opsgenie username.
+	Users map[string]string
+	// Defaults to DefaultOpsgenieURL.
+	BaseURL string
+	// Defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+func (o *Opsgenie) Name() string {
+	return "opsgenie"
+}
+
+func (o *Opsgenie) CurrentOnCall(ctx context.Context) (string, string, error) {
+	primary, err := o.onCall(ctx, o.PrimaryScheduleID)
+	if err != nil {
+		return "", "", err
+	}
+	secondary, err := o.onCall(ctx, o.SecondaryScheduleID)
+	if err != nil {
+		return "", "", err
+	}
+	return primary, secondary, nil
+}
+
+func (o *Opsgenie) onCall(ctx context.Context, scheduleID string) (string, error) {
+	if scheduleID == "" {
+		return "", nil
+	}
+	base := o.BaseURL
+	if base == "" {
+		base = DefaultOpsgenieURL
+	}
+	req, err := http.NewRequest("GET", base + "/v2/schedules/" + url.PathEscape(scheduleID) + "/on-calls?flat=true", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "GenieKey " + o.APIKey)
+
+	body := struct {
+		Data struct {
+			OnCallRecipients []string `json:"onCallRecipients"`
+		} `json:"data"`
+	}{}
+	if err := getJSON(ctx, o.Client, req, &body); err != nil {
+		return "", fmt.Errorf("schedule %s: %s", scheduleID, err)
+	}
+	if len(body.Data.OnCallRecipients) == 0 {
+		return "", nil
+	}
+	u := body.Data.OnCallRecipients[0]
+	if name, ok := o.Users[u]; ok {
+		return name, nil
+	}
+	return u, nil
+}
@@ -0,0 +1,97 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const DefaultPagerDutyURL = "https://api.pagerduty.com"
+
+// PagerDuty asks PagerDuty who is on call for a primary and a secondary
+// schedule.
+type PagerDuty struct {
+	Token string
+	PrimaryScheduleID string
+	SecondaryScheduleID string
+	// Maps PagerDuty user IDs to schedule user names. Users missing from the
+	// map are reported by their PagerDuty name.
+	Users map[string]string
+	// Defaults to DefaultPagerDutyURL.
+	BaseURL string
+	// Defaults to an http.Client with a 30s timeout.
+	Client *http.Client
+}
+
+func (p *PagerDuty) Name() string {
+	return "pagerduty"
+}
+
+func (p *PagerDuty) CurrentOnCall(ctx context.Context) (string, string, error) {
+	primary, err := p.onCall(ctx, p.PrimaryScheduleID)
+	if err != nil {
+		return "", "", err
+	}
+	secondary, err := p.onCall(ctx, p.SecondaryScheduleID)
+	if err != nil {
+		return "", "", err
+	}
+	return primary, secondary, nil
+}
+
+func (p *PagerDuty) onCall(ctx context.Context, scheduleID string) (string, error) {
+	if scheduleID == "" {
+		return "", nil
+	}
+	base := p.BaseURL
+	if base == "" {
+		base = DefaultPagerDutyURL
+	}
+	q := url.Values{}
+	q.Set("schedule_ids[]", scheduleID)
+	q.Set("earliest", "true")
+	req, err := http.NewRequest("GET", base + "/oncalls?" + q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Token token=" + p.Token)
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+
+	body := struct {
+		OnCalls []struct {
+			User struct {
+				ID string `json:"id"`
+				Summary string `json:"summary"`
+			} `json:"user"`
+		} `json:"oncalls"`
+	}{}
+	if err := getJSON(ctx, p.Client, req, &body); err != nil {
+		return "", fmt.Errorf("schedule %s: %s", scheduleID, err)
+	}
+	if len(body.OnCalls) == 0 {
+		return "", nil
+	}
+	u := body.OnCalls[0].User
+	if name, ok := p.Users[u.ID]; ok {
+		return name, nil
+	}
+	return u.Summary, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, req *http.Request, v interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
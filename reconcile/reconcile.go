@@ -0,0 +1,88 @@
+// Package reconcile compares who oncallator thinks is on call with who the
+// paging providers think is on call, so that drift between them can be caught
+// and fixed.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// A Provider is a paging system that can report who it currently has on call.
+type Provider interface {
+	// A short name identifying the provider in reports, e.g. "pagerduty".
+	Name() string
+	CurrentOnCall(ctx context.Context) (primary, secondary string, err error)
+}
+
+// An OnCall is one side's answer to who is on call.
+type OnCall struct {
+	Primary string
+	Secondary string
+}
+
+// A Mismatch is a provider that disagrees with the schedule.
+type Mismatch struct {
+	Provider string
+	At time.Time
+	Schedule OnCall
+	ProviderOnCall OnCall
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s disagrees with the schedule at %s: schedule has %s/%s, %s has %s/%s",
+		m.Provider, m.At.Format(time.RFC3339),
+		m.Schedule.Primary, m.Schedule.Secondary,
+		m.Provider, m.ProviderOnCall.Primary, m.ProviderOnCall.Secondary)
+}
+
+// Used to find the current rotation in a test-friendly way.
+var now = time.Now
+
+// Run asks every provider who is on call and returns a Mismatch for each one
+// that disagrees with the rotation active in s. Providers that fail to answer
+// do not stop the others being checked; their errors are returned together
+// after the mismatches that were found.
+func Run(ctx context.Context, s *schedule.Schedule, providers []Provider) ([]Mismatch, error) {
+	at := now()
+	expected, err := current(s, at)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := []Mismatch{}
+	failures := []string{}
+	for _, p := range providers {
+		primary, secondary, err := p.CurrentOnCall(ctx)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", p.Name(), err))
+			continue
+		}
+		actual := OnCall{Primary: primary, Secondary: secondary}
+		if actual != expected {
+			mismatches = append(mismatches, Mismatch{
+				Provider: p.Name(),
+				At: at,
+				Schedule: expected,
+				ProviderOnCall: actual,
+			})
+		}
+	}
+	if len(failures) > 0 {
+		return mismatches, fmt.Errorf("error asking providers who is on call: %s", strings.Join(failures, "; "))
+	}
+	return mismatches, nil
+}
+
+// Return the assignments of the rotation in s active at t.
+func current(s *schedule.Schedule, t time.Time) (OnCall, error) {
+	r, err := s.At(t)
+	if err != nil {
+		return OnCall{}, fmt.Errorf("no rotation in the schedule covers %s; regenerate it", t.Format(time.RFC3339))
+	}
+	return OnCall{Primary: r.Primary, Secondary: r.Secondary}, nil
+}
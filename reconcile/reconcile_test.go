@@ -0,0 +1,177 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const scheduleText = `
+{
+	"Users": ["b", "c", "a"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{
+			"Start": "2017-02-15T10:00:00Z",
+			"Primary": "c",
+			"Secondary": "a"
+		},
+		{
+			"Start": "2017-02-22T10:00:00Z",
+			"Primary": "a",
+			"Secondary": "b"
+		}
+	]
+}`
+
+type fakeProvider struct {
+	name string
+	primary string
+	secondary string
+	err error
+}
+
+func (f fakeProvider) Name() string {
+	return f.name
+}
+
+func (f fakeProvider) CurrentOnCall(ctx context.Context) (string, string, error) {
+	return f.primary, f.secondary, f.err
+}
+
+func setNow(t time.Time) func() {
+	now = func() time.Time { return t }
+	return func() { now = time.Now }
+}
+
+func testSchedule(t *testing.T) *schedule.Schedule {
+	s, err := schedule.NewSchedule([]byte(scheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRun(t *testing.T) {
+	at := time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC)
+	defer setNow(at)()
+	mismatches, err := Run(context.Background(), testSchedule(t), []Provider{
+		fakeProvider{name: "agrees", primary: "a", secondary: "b"},
+		fakeProvider{name: "drifted", primary: "c", secondary: "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Mismatch{
+		{
+			Provider: "drifted",
+			At: at,
+			Schedule: OnCall{Primary: "a", Secondary: "b"},
+			ProviderOnCall: OnCall{Primary: "c", Secondary: "b"},
+		},
+	}
+	if !reflect.DeepEqual(expected, mismatches) {
+		t.Errorf("mismatches do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, mismatches)
+	}
+}
+
+func TestRunProviderError(t *testing.T) {
+	defer setNow(time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC))()
+	mismatches, err := Run(context.Background(), testSchedule(t), []Provider{
+		fakeProvider{name: "broken", err: fmt.Errorf("boom")},
+		fakeProvider{name: "drifted", primary: "c", secondary: "b"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken: boom") {
+		t.Errorf("expected the broken provider's error, got %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Provider != "drifted" {
+		t.Errorf("expected the other provider to still be checked, got %+v", mismatches)
+	}
+}
+
+func TestRunOutsideSchedule(t *testing.T) {
+	defer setNow(time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC))()
+	if _, err := Run(context.Background(), testSchedule(t), nil); err == nil {
+		t.Errorf("expected an error when no rotation covers now")
+	}
+}
+
+func TestPagerDuty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token token=secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("schedule_ids[]") {
+		case "PRIMARY":
+			fmt.Fprint(w, `{"oncalls": [{"user": {"id": "PA", "summary": "Alice A"}}]}`)
+		case "SECONDARY":
+			fmt.Fprint(w, `{"oncalls": [{"user": {"id": "PB", "summary": "Bob B"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &PagerDuty{
+		Token: "secret",
+		PrimaryScheduleID: "PRIMARY",
+		SecondaryScheduleID: "SECONDARY",
+		Users: map[string]string{"PA": "a"},
+		BaseURL: server.URL,
+	}
+	primary, secondary, err := p.CurrentOnCall(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary != "a" || secondary != "Bob B" {
+		t.Errorf("expected a and Bob B, got %s and %s", primary, secondary)
+	}
+
+	p.Token = "wrong"
+	if _, _, err := p.CurrentOnCall(context.Background()); err == nil {
+		t.Errorf("expected an error with the wrong token")
+	}
+}
+
+func TestOpsgenie(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/schedules/primary/on-calls":
+			fmt.Fprint(w, `{"data": {"onCallRecipients": ["alice@example.com"]}}`)
+		case "/v2/schedules/secondary/on-calls":
+			fmt.Fprint(w, `{"data": {"onCallRecipients": ["bob@example.com"]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	o := &Opsgenie{
+		APIKey: "secret",
+		PrimaryScheduleID: "primary",
+		SecondaryScheduleID: "secondary",
+		Users: map[string]string{"alice@example.com": "a", "bob@example.com": "b"},
+		BaseURL: server.URL,
+	}
+	primary, secondary, err := o.CurrentOnCall(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary != "a" || secondary != "b" {
+		t.Errorf("expected a and b, got %s and %s", primary, secondary)
+	}
+}
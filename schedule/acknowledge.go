@@ -0,0 +1,39 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// RotationByID returns the index in Rotations of the rotation with ID id, or
+// -1 if there is none.
+func (s *Schedule) RotationByID(id string) int {
+	for i, r := range s.Rotations {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Acknowledge returns a copy of s with user recorded as having acknowledged the
+// rotation with ID id. It is an error if there is no such rotation, or user is
+// not on call in it. Acknowledging a rotation again changes nothing.
+func (s *Schedule) Acknowledge(id, user string) (*Schedule, error) {
+	i := s.RotationByID(id)
+	if i < 0 {
+		return nil, fmt.Errorf("no rotation has ID %s", id)
+	}
+	r := s.Rotations[i]
+	if user == "" || !containsUser(s.Assigned(r), user) {
+		return nil, fmt.Errorf("%s is not on call in the rotation starting at %s", user, r.Start.Format(time.RFC3339))
+	}
+	ns := *s
+	ns.Rotations = append([]Rotation{}, s.Rotations...)
+	if !containsUser(r.Acknowledged, user) {
+		r = r.clone()
+		r.Acknowledged = append(r.Acknowledged, user)
+		ns.Rotations[i] = r
+	}
+	return &ns, nil
+}
@@ -0,0 +1,49 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAcknowledge(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := s.Rotations[1].ID
+	ns, err := s.Acknowledge(id, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := ns.Acknowledge(id, "c"); err != nil || !reflect.DeepEqual(again.Rotations[1].Acknowledged, []string{"c"}) {
+		t.Errorf("expected acknowledging again to change nothing, got %v, %v", again, err)
+	}
+	if len(s.Rotations[1].Acknowledged) != 0 {
+		t.Errorf("expected the original schedule to be left as it was, got %v", s.Rotations[1])
+	}
+
+	// The acknowledgment is kept when the schedule is regenerated.
+	ns.now = day(3)
+	again, err := ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := again.RotationByID(id); i < 0 || !reflect.DeepEqual(again.Rotations[i].Acknowledged, []string{"c"}) {
+		t.Errorf("expected c's acknowledgment to be kept, got %v", again.Rotations)
+	}
+
+	for _, c := range []struct {
+		id, user string
+		expected string
+	}{
+		{"nope", "c", "no rotation has ID nope"},
+		{id, "a", "a is not on call in the rotation starting at 2017-02-08T10:00:00Z"},
+	} {
+		if _, err := s.Acknowledge(c.id, c.user); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("expected an error containing %q, got %v", c.expected, err)
+		}
+	}
+}
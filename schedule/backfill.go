@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backfill reconstructs the rotations that would have preceded the schedule,
+// back to and including the rotation covering from, assuming the current Users
+// order and RotationLength had always applied. The round-robin is run in
+// reverse from the first rotation in Rotations (or from Start and Users[0] if
+// there are none), so the result lines up with it. With separate PrimaryUsers
+// and SecondaryUsers, each pool is run in reverse from its own user in the
+// first rotation. The rotations are returned in chronological order and the
+// schedule itself is not modified.
+func (s *Schedule) Backfill(from time.Time) ([]Rotation, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if len(s.Windows) > 0 {
+		return nil, fmt.Errorf("cannot backfill a schedule with Windows")
+	}
+	if len(s.Regions) > 0 {
+		return nil, fmt.Errorf("cannot backfill a schedule with Regions")
+	}
+
+	users := s.primaryPool()
+	anchor, p := s.Start, 0
+	if len(s.Rotations) > 0 {
+		first := s.Rotations[0]
+		anchor, p = first.Start, indexOf(users, first.Primary)
+		if p < 0 {
+			return nil, fmt.Errorf("cannot backfill: first rotation's primary %s is not in Users", first.Primary)
+		}
+	}
+	secondaries, q := s.secondaryPool(), 0
+	if s.separatePools() && len(s.Rotations) > 0 {
+		if q = indexOf(secondaries, s.Rotations[0].Secondary); q < 0 {
+			return nil, fmt.Errorf("cannot backfill: first rotation's secondary %s is not in SecondaryUsers", s.Rotations[0].Secondary)
+		}
+	}
+
+	// Walk back until a rotation starts at or before from. With a Timezone,
+	// daylight saving changes can make that one more or fewer than
+	// numRotations.
+	rs := make([]Rotation, 0, numRotations(from, anchor, s.RotationDuration) + 1)
+	for k := 1; anchor.After(from) && (len(rs) == 0 || rs[len(rs)-1].Start.After(from)); k++ {
+		i := mod(p - k, len(users))
+		r := Rotation{Start: s.rotationsAfter(anchor, -k), End: anchor}
+		if len(rs) > 0 {
+			r.End = rs[len(rs)-1].Start
+		}
+		if s.separatePools() {
+			r = s.withTierUser(r, s.tiers()[0], users[i])
+			if secondary := secondaries[mod(q - k, len(secondaries))]; secondary != users[i] {
+				r = s.withTierUser(r, s.tiers()[1], secondary)
+			}
+			rs = append(rs, r)
+			continue
+		}
+		for j, tier := range s.tiers() {
+			if j >= len(users) {
+				// The highest tiers are left unfilled, as AllowTierCollapse
+				// requires.
+				break
+			}
+			r = s.withTierUser(r, tier, users[(i + j) % len(users)])
+		}
+		rs = append(rs, r)
+	}
+	// The rotations were added newest first.
+	for i, j := 0, len(rs) - 1; i < j; i, j = i + 1, j - 1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
+	return rs, nil
+}
+
+// Return the position of u in users, or -1 if it is not there.
+func indexOf(users []string, u string) int {
+	for i, v := range users {
+		if v == u {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return a modulo b, in the range [0, b).
+func mod(a, b int) int {
+	return ((a % b) + b) % b
+}
@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	filled := FilledSchedule()
+	from := time.Date(2017, time.January, 15, 0, 0, 0, 0, time.UTC)
+	rs, err := filled.Backfill(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2017-01-11T10:00:00Z/2017-01-18T10:00:00Z a b",
+		"2017-01-18T10:00:00Z/2017-01-25T10:00:00Z b c",
+		"2017-01-25T10:00:00Z/2017-02-01T10:00:00Z c a",
+	}
+	if len(rs) != len(expected) {
+		t.Fatalf("expected %d rotations, got %v", len(expected), rs)
+	}
+	for i, r := range rs {
+		if r.String() != expected[i] {
+			t.Errorf("rotation %d: expected %s, got %s", i, expected[i], r)
+		}
+	}
+	if !reflect.DeepEqual(FilledSchedule(), filled) {
+		t.Errorf("Backfill modified the schedule")
+	}
+}
+
+func TestBackfillThenGenerateReproducesSeed(t *testing.T) {
+	filled := FilledSchedule()
+	rs, err := filled.Backfill(time.Date(2016, time.June, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Generate forward from the oldest backfilled rotation, as if the schedule
+	// had been seeded with it.
+	seed := EmptySchedule()
+	seed.Start = rs[0].Start
+	seed.Users = filled.Users
+	for seed.Users[0] != rs[0].Primary {
+		seed.Users = rotateUsers(seed.Users, 1)
+	}
+	seed.now = rs[0].Start
+	seed.ScheduleForDuration = filled.Rotations[0].Start.Sub(rs[0].Start)
+	s, err := seed.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(withIDs(rs), s.Rotations[:len(rs)]) {
+		t.Errorf("generated rotations do not match backfill\nExpected:\n%+v\n---\nGot:\n%+v\n", rs, s.Rotations[:len(rs)])
+	}
+	if got := s.Rotations[len(rs)]; !reflect.DeepEqual(withIDs(filled.Rotations)[0], got) {
+		t.Errorf("expected seed rotation %s, got %s", filled.Rotations[0], got)
+	}
+}
+
+func TestBackfillFromStart(t *testing.T) {
+	empty := EmptySchedule()
+	rs, err := empty.Backfill(Start.Add(-7 * 24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].String() != "2017-01-25T10:00:00Z/2017-02-01T10:00:00Z c a" {
+		t.Errorf("expected a single rotation before Start, got %v", rs)
+	}
+}
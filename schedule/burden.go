@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A WeightedRange is a window of time whose on-call hours count for more (or
+// less) than usual, e.g. a release week at 2 or a holiday freeze at 0.5.
+type WeightedRange struct {
+	Name string `json:",omitempty"`
+	Start time.Time
+	End time.Time
+	Weight float64
+}
+
+// Burden returns the weighted hours of on-call between start and end. Every
+// hour counts 1, plus Weight-1 for each burden window covering it, so windows
+// are purely additive: overlapping windows both apply, and a rotation's burden
+// is the sum of its hours and its overlap with each window.
+func (s *Schedule) Burden(start, end time.Time) float64 {
+	burden := end.Sub(start).Hours()
+	for _, w := range s.BurdenWindows {
+		burden += (w.Weight - 1) * overlap(start, end, w.Start, w.End).Hours()
+	}
+	return burden
+}
+
+// How much of [aStart, aEnd) overlaps [bStart, bEnd).
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	if bStart.After(aStart) {
+		aStart = bStart
+	}
+	if bEnd.Before(aEnd) {
+		aEnd = bEnd
+	}
+	if !aEnd.After(aStart) {
+		return 0
+	}
+	return aEnd.Sub(aStart)
+}
+
+// A UserBurden is the weighted on-call hours of one user.
+type UserBurden struct {
+	User string
+	Primary float64
+	Secondary float64
+}
+
+// BurdenByUser returns the weighted hours each user spends on call across
+// Rotations, ordered by user.
+func (s *Schedule) BurdenByUser() []UserBurden {
+	byUser := map[string]*UserBurden{}
+	get := func(u string) *UserBurden {
+		if byUser[u] == nil {
+			byUser[u] = &UserBurden{User: u}
+		}
+		return byUser[u]
+	}
+	for i, r := range s.Rotations {
+		b := s.Burden(r.Start, s.RotationEnd(i))
+		if r.Primary != "" {
+			get(r.Primary).Primary += b
+		}
+		if r.Secondary != "" {
+			get(r.Secondary).Secondary += b
+		}
+	}
+	burdens := []UserBurden{}
+	for _, b := range byUser {
+		burdens = append(burdens, *b)
+	}
+	sort.Slice(burdens, func(i, j int) bool {
+		return burdens[i].User < burdens[j].User
+	})
+	return burdens
+}
+
+func validateBurdenWindows(ws []WeightedRange) error {
+	for _, w := range ws {
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("burden window %s must end after it starts (got %s to %s)", w.Name, w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339))
+		}
+		if w.Weight < 0 {
+			return fmt.Errorf("burden window %s cannot have negative weight (got %g)", w.Name, w.Weight)
+		}
+	}
+	return nil
+}
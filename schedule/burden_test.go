@@ -0,0 +1,54 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBurdenPartialOverlaps(t *testing.T) {
+	s := FilledSchedule()
+	day := 24 * time.Hour
+	feb8 := time.Date(2017, time.February, 8, 10, 0, 0, 0, time.UTC)
+	s.BurdenWindows = []WeightedRange{
+		// Covers the last two days of the first rotation and the first day of
+		// the second.
+		{Name: "release", Start: feb8.Add(-2 * day), End: feb8.Add(day), Weight: 2},
+		// Overlaps the release window for its last day.
+		{Name: "freeze", Start: feb8, End: feb8.Add(2 * day), Weight: 0.5},
+	}
+
+	// 168h, plus 48h extra for the release.
+	if b := s.Burden(Start, feb8); b != 168 + 48 {
+		t.Errorf("expected burden %g for the first rotation, got %g", 168.0 + 48, b)
+	}
+	// 168h, plus 24h extra for the release, minus 24h for half of the two
+	// freeze days.
+	if b := s.Burden(feb8, feb8.Add(7 * day)); b != 168 + 24 - 24 {
+		t.Errorf("expected burden %g for the second rotation, got %g", 168.0, b)
+	}
+	if b := s.Burden(feb8.Add(7 * day), feb8.Add(14 * day)); b != 168 {
+		t.Errorf("expected an unweighted rotation to count its hours, got %g", b)
+	}
+
+	expected := []UserBurden{
+		{User: "a", Primary: 216 + 168, Secondary: 168},
+		{User: "b", Primary: 168, Secondary: 216 + 168},
+		{User: "c", Primary: 168, Secondary: 168},
+	}
+	if burdens := s.BurdenByUser(); !reflect.DeepEqual(expected, burdens) {
+		t.Errorf("burdens do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, burdens)
+	}
+}
+
+func TestValidateBurdenWindows(t *testing.T) {
+	s := FilledSchedule()
+	s.BurdenWindows = []WeightedRange{{Name: "backwards", Start: Start, End: Start, Weight: 2}}
+	if err := s.Validate(); err == nil {
+		t.Errorf("expected an empty window to be rejected")
+	}
+	s.BurdenWindows = []WeightedRange{{Name: "negative", Start: Start, End: Start.Add(time.Hour), Weight: -1}}
+	if err := s.Validate(); err == nil {
+		t.Errorf("expected a negative weight to be rejected")
+	}
+}
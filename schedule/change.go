@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"time"
+)
+
+// ChangeSchemaVersion is the version of the serialized form of Change. It must
+// be bumped whenever the set of serialized fields changes, so that consumers of
+// the CLI, HTTP and webhook output can tell.
+const ChangeSchemaVersion = 1
+
+type ChangeKind string
+
+const (
+	// A rotation, or one tier of it, was added.
+	ChangeAdded ChangeKind = "added"
+	// A rotation, or one tier of it, was removed.
+	ChangeRemoved ChangeKind = "removed"
+	// The user assigned to one tier of a rotation changed.
+	ChangeReassigned ChangeKind = "reassigned"
+	ChangeUserAdded ChangeKind = "user_added"
+	ChangeUserRemoved ChangeKind = "user_removed"
+	// The end of the last rotation moved.
+	ChangeHorizon ChangeKind = "horizon"
+)
+
+const (
+	// The change was made by Generate.
+	SourceGenerate = "generate"
+	// The change was made by editing the schedule document.
+	SourceEdit = "edit"
+)
+
+type Window struct {
+	Start time.Time `json:"start"`
+	End time.Time `json:"end"`
+}
+
+// A Change is a single difference between two versions of a schedule. It is
+// the one representation of schedule changes shared by every consumer, so its
+// JSON field names are stable; see ChangeSchemaVersion.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// The ID of the rotation changed, for rotation changes. A rotation without
+	// an ID, e.g. one written by hand and not yet generated, is identified by
+	// its Window alone.
+	RotationID string `json:"rotation_id,omitempty"`
+	// The tier changed, for rotation changes.
+	Tier string `json:"tier,omitempty"`
+	// The schedule field changed, e.g. Users or Rotations.
+	Field string `json:"field"`
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+	// The time covered by the rotation changed, for rotation changes.
+	Window *Window `json:"window,omitempty"`
+	// What made the change, e.g. SourceGenerate.
+	Source string `json:"source,omitempty"`
+}
+
+// Changes returns the differences from base to head: users removed and added,
+// then any change to the horizon, then changes to rotations in chronological
+// order with each rotation's tiers lowest first. source is recorded on every
+// change.
+func Changes(base, head *Schedule, source string) []Change {
+	changes := []Change{}
+	added, removed := diffUsers(base.Users, head.Users)
+	for _, u := range removed {
+		changes = append(changes, Change{Kind: ChangeUserRemoved, Field: "Users", Old: u, Source: source})
+	}
+	for _, u := range added {
+		changes = append(changes, Change{Kind: ChangeUserAdded, Field: "Users", New: u, Source: source})
+	}
+	if bh, hh := base.horizon(), head.horizon(); !bh.Equal(hh) {
+		changes = append(changes, Change{
+			Kind: ChangeHorizon,
+			Field: "Rotations",
+			Old: bh.Format(time.RFC3339),
+			New: hh.Format(time.RFC3339),
+			Source: source,
+		})
+	}
+	return append(changes, rotationChanges(base, head, source)...)
+}
+
+// Return a change for every tier of every rotation whose assignment differs
+// between base and head, matching rotations by start time.
+func rotationChanges(base, head *Schedule, source string) []Change {
+	changes := []Change{}
+	emit := func(s *Schedule, i int, old, new Rotation) {
+		r := s.Rotations[i]
+		w := &Window{Start: r.Start, End: s.RotationEnd(i)}
+		for _, tier := range head.tiers() {
+			t := struct{ tier, old, new string }{tier, base.tierUser(old, tier), head.tierUser(new, tier)}
+			if t.old == t.new {
+				continue
+			}
+			kind := ChangeReassigned
+			if t.old == "" {
+				kind = ChangeAdded
+			} else if t.new == "" {
+				kind = ChangeRemoved
+			}
+			changes = append(changes, Change{
+				Kind: kind,
+				RotationID: r.ID,
+				Tier: t.tier,
+				Field: "Rotations",
+				Old: t.old,
+				New: t.new,
+				Window: w,
+				Source: source,
+			})
+		}
+	}
+
+	inHead := map[time.Time]bool{}
+	for _, r := range head.Rotations {
+		inHead[r.Start.UTC()] = true
+	}
+	byStart := map[time.Time]Rotation{}
+	for _, r := range base.Rotations {
+		byStart[r.Start.UTC()] = r
+	}
+	bi := 0
+	emitRemoved := func(before time.Time) {
+		// Rotations only in base are interleaved by start time so that the
+		// changes stay in chronological order.
+		for ; bi < len(base.Rotations) && (before.IsZero() || base.Rotations[bi].Start.Before(before)); bi++ {
+			if !inHead[base.Rotations[bi].Start.UTC()] {
+				emit(base, bi, base.Rotations[bi], Rotation{})
+			}
+		}
+	}
+	for i, r := range head.Rotations {
+		emitRemoved(r.Start)
+		emit(head, i, byStart[r.Start.UTC()], r)
+	}
+	emitRemoved(time.Time{})
+	return changes
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The serialized fields of Change at each ChangeSchemaVersion. If this test
+// fails, bump ChangeSchemaVersion and add the new field set here.
+var changeSchemas = map[int][]string{
+	1: {"field", "kind", "new", "old", "rotation_id", "source", "tier", "window"},
+}
+
+func TestChangeSchemaCompatibility(t *testing.T) {
+	fields := []string{}
+	typ := reflect.TypeOf(Change{})
+	for i := 0; i < typ.NumField(); i++ {
+		fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+	}
+	sort.Strings(fields)
+	expected, ok := changeSchemas[ChangeSchemaVersion]
+	if !ok {
+		t.Fatalf("no field set recorded for ChangeSchemaVersion %d", ChangeSchemaVersion)
+	}
+	if !reflect.DeepEqual(expected, fields) {
+		t.Errorf("Change's serialized fields changed without bumping ChangeSchemaVersion\nExpected:\n%v\n---\nGot:\n%v\n", expected, fields)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	base := FilledSchedule()
+	base.Rotations = withIDs(base.Rotations)
+	head := FilledSchedule()
+	head.Rotations = withIDs(head.Rotations)
+	head.Users = []string{"b", "c", "a", "d"}
+	head.Rotations[2].Primary = "d"
+	head.Rotations = append(head.Rotations, Rotation{
+		ID: "added",
+		Start: time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC),
+		Primary: "b",
+		Secondary: "c",
+	})
+	changes := Changes(base, head, SourceEdit)
+
+	week := 7 * 24 * time.Hour
+	feb15 := time.Date(2017, time.February, 15, 10, 0, 0, 0, time.UTC)
+	mar1 := time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+	expected := []Change{
+		{Kind: ChangeUserAdded, Field: "Users", New: "d", Source: SourceEdit},
+		{Kind: ChangeHorizon, Field: "Rotations", Old: "2017-03-01T10:00:00Z", New: "2017-03-08T10:00:00Z", Source: SourceEdit},
+		{Kind: ChangeReassigned, RotationID: head.Rotations[2].ID, Tier: TierPrimary, Field: "Rotations", Old: "c", New: "d", Window: &Window{Start: feb15, End: feb15.Add(week)}, Source: SourceEdit},
+		{Kind: ChangeAdded, RotationID: "added", Tier: TierPrimary, Field: "Rotations", New: "b", Window: &Window{Start: mar1, End: mar1.Add(week)}, Source: SourceEdit},
+		{Kind: ChangeAdded, RotationID: "added", Tier: TierSecondary, Field: "Rotations", New: "c", Window: &Window{Start: mar1, End: mar1.Add(week)}, Source: SourceEdit},
+	}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Errorf("changes do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, changes)
+	}
+
+	if changes := Changes(base, base, SourceEdit); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
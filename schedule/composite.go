@@ -0,0 +1,206 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// generatedFields are the top-level fields Generate writes, those of Input
+// tagged schedule:"generated". When a schedule is split across several
+// fragments, these are the only fields SaveComposite writes back, and the only
+// fields where a later fragment may override an earlier one instead of
+// conflicting with it.
+var generatedFields = func() []string {
+	fields := []string{}
+	t := reflect.TypeOf(Input{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("schedule") == "generated" {
+			fields = append(fields, t.Field(i).Name)
+		}
+	}
+	return fields
+}()
+
+// orderedFields are the lists of users whose membership is written by hand
+// but whose order Generate keeps, as where each pool is next taken from.
+// SaveComposite writes them with the generated fields, and when merging, a
+// later fragment reorders them rather than conflicting, without adding or
+// removing anybody.
+var orderedFields = []string{"Users", "PrimaryUsers", "SecondaryUsers"}
+
+func isOrdered(field string) bool {
+	for _, f := range orderedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isGenerated(field string) bool {
+	for _, f := range generatedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadComposite reads a schedule that has been split across several files
+// (e.g. users.json, constraints.json and rotations.json) and merges them into a
+// single Schedule.
+//
+// Fragments are merged in the order given. Objects are merged key by key.
+// Scalars and lists must agree across every fragment that sets them, otherwise
+// an error naming both files is returned. The exceptions are the fields
+// Generate writes, such as Start, Rotations and ShiftCounts, for which the last
+// fragment that sets them wins, and the lists of users, which a later fragment
+// may reorder. So the tool-owned rotations file should be listed last.
+func LoadComposite(paths ...string) (*Schedule, error) {
+	text, err := MergeComposite(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchedule(text)
+}
+
+// MergeComposite merges the fragments of a schedule as LoadComposite does,
+// returning the merged schedule as JSON for NewSchedule, e.g. to read it with
+// options.
+func MergeComposite(paths ...string) ([]byte, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("must provide at least 1 schedule fragment")
+	}
+	merged := map[string]interface{}{}
+	owners := map[string]string{}
+	for _, p := range paths {
+		text, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		fragment := map[string]interface{}{}
+		if err := json.Unmarshal(standardize(text), &fragment); err != nil {
+			return nil, fmt.Errorf("error parsing schedule fragment %s: %s", p, locateJSON(text, err))
+		}
+		for k, v := range fragment {
+			if isGenerated(k) {
+				merged[k] = v
+				owners[k] = p
+				continue
+			}
+			if existing, ok := merged[k]; ok && isOrdered(k) {
+				merged[k] = reorderUsers(existing, v)
+				continue
+			}
+			if err := mergeValue(merged, k, v, k, p, owners); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// mergeValue merges v into dst[k]. path is the dotted path of k from the root
+// of the document, and owners records which fragment set each path so that
+// conflicts can name both files.
+func mergeValue(dst map[string]interface{}, k string, v interface{}, path, file string, owners map[string]string) error {
+	existing, ok := dst[k]
+	if !ok {
+		dst[k] = v
+		owners[path] = file
+		return nil
+	}
+	em, eok := existing.(map[string]interface{})
+	vm, vok := v.(map[string]interface{})
+	if eok && vok {
+		for ck, cv := range vm {
+			if err := mergeValue(em, ck, cv, path+"."+ck, file, owners); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(existing, v) {
+		return fmt.Errorf("conflicting values for %s in %s and %s", path, owners[path], file)
+	}
+	return nil
+}
+
+// Return the users in existing, with their details, in the order they appear
+// in order. Users that order does not name keep their place after those it does.
+// Either may not be a list, in which case existing is returned for NewSchedule
+// to reject.
+func reorderUsers(existing, order interface{}) interface{} {
+	users, ok := existing.([]interface{})
+	names, nok := order.([]interface{})
+	if !ok || !nok {
+		return existing
+	}
+	position := map[string]int{}
+	for i, n := range names {
+		position[userName(n)] = i
+	}
+	sorted := append([]interface{}{}, users...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		pi, iok := position[userName(sorted[i])]
+		pj, jok := position[userName(sorted[j])]
+		if !iok || !jok {
+			return iok && !jok
+		}
+		return pi < pj
+	})
+	return sorted
+}
+
+// Return the name of a user as written, either a name or an object with a
+// Name.
+func userName(u interface{}) string {
+	switch u := u.(type) {
+	case string:
+		return u
+	case map[string]interface{}:
+		name, _ := u["Name"].(string)
+		return name
+	}
+	return ""
+}
+
+// SaveComposite writes the fields of s that Generate writes, such as Start,
+// Rotations and ShiftCounts, and the order of its users to path, which should
+// be the tool-owned fragment passed last to LoadComposite. A generated field s
+// leaves empty is removed from the file. Any other fields already in that file are preserved, and no
+// other fragment is touched.
+func SaveComposite(s *Schedule, path string) error {
+	fragment := map[string]interface{}{}
+	if text, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(standardize(text), &fragment); err != nil {
+			return fmt.Errorf("error parsing schedule fragment %s: %s", path, locateJSON(text, err))
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	text, err := json.Marshal(s.Input())
+	if err != nil {
+		return err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(text, &fields); err != nil {
+		return err
+	}
+	for _, f := range append(generatedFields, orderedFields...) {
+		if v, ok := fields[f]; ok {
+			fragment[f] = v
+		} else {
+			delete(fragment, f)
+		}
+	}
+	text, err = json.MarshalIndent(fragment, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, text, 0660)
+}
@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFragments(t *testing.T, fragments map[string]string) string {
+	dir, err := ioutil.TempDir("", "composite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range fragments {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadComposite(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"users.json": `{"Users": ["b", "c", "a"]}`,
+		"constraints.json": `{"RotationLength": "168h", "ScheduleFor": "504h", "Start": "2017-01-01T10:00:00Z"}`,
+		"rotations.json": FilledScheduleText,
+	})
+	defer os.RemoveAll(dir)
+
+	s, err := LoadComposite(
+		filepath.Join(dir, "users.json"),
+		filepath.Join(dir, "constraints.json"),
+		filepath.Join(dir, "rotations.json"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := FilledSchedule()
+	if !reflect.DeepEqual(expected, s) {
+		t.Errorf("composite schedule does not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, s)
+	}
+}
+
+func TestLoadCompositeConflict(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"a.json": `{"Users": ["a", "b"], "RotationLength": "168h", "ScheduleFor": "504h"}`,
+		"b.json": `{"RotationLength": "24h"}`,
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := LoadComposite(filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json"))
+	if err == nil {
+		t.Fatal("expected conflicting RotationLength to fail")
+	}
+	if !strings.Contains(err.Error(), "RotationLength") {
+		t.Errorf("error does not name the conflicting field: %s", err)
+	}
+}
+
+func TestSaveCompositeLeavesOtherFragments(t *testing.T) {
+	users := `{"Users": ["a", "b", "c"]}`
+	dir := writeFragments(t, map[string]string{
+		"users.json": users,
+		"rotations.json": `{"RotationLength": "168h", "ScheduleFor": "504h", "Start": "2017-02-01T10:00:00Z"}`,
+	})
+	defer os.RemoveAll(dir)
+
+	s, err := LoadComposite(filepath.Join(dir, "users.json"), filepath.Join(dir, "rotations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveComposite(ns, filepath.Join(dir, "rotations.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := ioutil.ReadFile(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != users {
+		t.Errorf("users fragment was modified: %s", text)
+	}
+	reloaded, err := LoadComposite(filepath.Join(dir, "users.json"), filepath.Join(dir, "rotations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ns.Rotations, reloaded.Rotations) {
+		t.Errorf("saved rotations do not round-trip\nExpected:\n%+v\n---\nGot:\n%+v\n", ns.Rotations, reloaded.Rotations)
+	}
+}
+
+func TestSaveCompositeRoundTrip(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"users.json": `{"Users": ["a", "b", "c"]}`,
+		"constraints.json": `{"RotationLength": "168h", "ScheduleFor": "504h", "KeepHistory": true, "Order": "shuffled"}`,
+		"rotations.json": `{"Start": "2017-02-01T10:00:00Z"}`,
+	})
+	defer os.RemoveAll(dir)
+	paths := []string{filepath.Join(dir, "users.json"), filepath.Join(dir, "constraints.json"), filepath.Join(dir, "rotations.json")}
+
+	s, err := LoadComposite(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Regenerate later, so that rotations are dropped and counted.
+	ns.now = day(23)
+	if ns, err = ns.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveComposite(ns, paths[2]); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadComposite(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ns.ShiftCounts) == 0 || len(ns.History) == 0 || ns.Provenance == nil || ns.Seed == 0 {
+		t.Fatalf("expected Generate to fill in every generated field, got %+v", ns.Input())
+	}
+	expected, err := json.Marshal(ns.Input())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(reloaded.Input())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, got) {
+		t.Errorf("saved schedule does not round-trip\nExpected:\n%s\n---\nGot:\n%s\n", expected, got)
+	}
+
+	// Users are reordered by the saved fragment, but who they are is still
+	// up to the others.
+	if err := ioutil.WriteFile(paths[0], []byte(`{"Users": ["a", "b", "c", "d"]}`), 0660); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err = LoadComposite(paths...); err != nil {
+		t.Fatal(err)
+	}
+	if users := append(ns.Users, "d"); !reflect.DeepEqual(users, reloaded.Users) {
+		t.Errorf("expected Users %v, got %v", users, reloaded.Users)
+	}
+}
@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Conflict is a time a user is on call in two schedules at once, e.g. as
+// primary for two teams in the same week.
+type Conflict struct {
+	// The user's name in the first schedule.
+	User string
+	// The positions of the two schedules in those checked, and their names in
+	// a ScheduleSet, if they were checked as one.
+	Schedules [2]int
+	Teams [2]string `json:",omitempty"`
+	// The tier the user holds in each schedule.
+	Tiers [2]string
+	// When the user is on call in both.
+	Window TimeRange
+}
+
+func (c Conflict) String() string {
+	names := []string{}
+	for i := range c.Schedules {
+		name := fmt.Sprintf("schedule %d", c.Schedules[i])
+		if c.Teams[i] != "" {
+			name = "team " + c.Teams[i]
+		}
+		names = append(names, fmt.Sprintf("%s in %s", c.Tiers[i], name))
+	}
+	return fmt.Sprintf("%s is %s from %s to %s", c.User, strings.Join(names, " and "), c.Window.Start.Format(time.RFC3339), c.Window.End.Format(time.RFC3339))
+}
+
+// A shift of a user, as CheckConflicts compares them.
+type conflictShift struct {
+	user string
+	email string
+	tier string
+	TimeRange
+}
+
+// CheckConflicts returns the times a user is on call in two of schedules at
+// once, whatever the tiers, for every pair of schedules the user is in,
+// ordered by when they start. Users are matched by name, ignoring case, or by
+// the Email in their details if both schedules give one. A conflict that runs
+// across the rotations of either schedule is reported once, over its whole
+// window.
+func CheckConflicts(schedules []*Schedule) []Conflict {
+	shifts := make([][]conflictShift, len(schedules))
+	for i, s := range schedules {
+		for j, r := range s.Rotations {
+			for _, tier := range s.tiers() {
+				u := s.tierUser(r, tier)
+				if u == "" || u == Unassigned {
+					continue
+				}
+				details, _ := s.User(u)
+				shifts[i] = append(shifts[i], conflictShift{
+					user: u,
+					email: details.Email,
+					tier: tier,
+					TimeRange: TimeRange{Start: r.Start, End: s.RotationEnd(j)},
+				})
+			}
+		}
+	}
+
+	conflicts := []Conflict{}
+	for i := range shifts {
+		for k := i + 1; k < len(shifts); k++ {
+			found := []Conflict{}
+			for _, a := range shifts[i] {
+				for _, b := range shifts[k] {
+					if !sameUser(a, b) || !a.Start.Before(b.End) || !b.Start.Before(a.End) {
+						continue
+					}
+					window := a.TimeRange
+					if b.Start.After(window.Start) {
+						window.Start = b.Start
+					}
+					if b.End.Before(window.End) {
+						window.End = b.End
+					}
+					found = append(found, Conflict{User: a.user, Schedules: [2]int{i, k}, Tiers: [2]string{a.tier, b.tier}, Window: window})
+				}
+			}
+			conflicts = append(conflicts, mergeConflicts(found)...)
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Window.Start.Before(conflicts[j].Window.Start)
+	})
+	return conflicts
+}
+
+// Conflicts returns the times a user is on call for two teams of ss at once,
+// as CheckConflicts does, with the teams named.
+func (ss *ScheduleSet) Conflicts() []Conflict {
+	names := ss.Names()
+	schedules := make([]*Schedule, len(names))
+	for i, team := range names {
+		schedules[i] = ss.Teams[team]
+	}
+	conflicts := CheckConflicts(schedules)
+	for i, c := range conflicts {
+		conflicts[i].Teams = [2]string{names[c.Schedules[0]], names[c.Schedules[1]]}
+	}
+	return conflicts
+}
+
+func sameUser(a, b conflictShift) bool {
+	if a.email != "" && b.email != "" {
+		return strings.EqualFold(a.email, b.email)
+	}
+	return strings.EqualFold(a.user, b.user)
+}
+
+// Return conflicts, between the same pair of schedules, with those of the
+// same user and tiers whose windows meet joined into one.
+func mergeConflicts(conflicts []Conflict) []Conflict {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Window.Start.Before(conflicts[j].Window.Start)
+	})
+	merged := []Conflict{}
+	for _, c := range conflicts {
+		joined := false
+		for i := range merged {
+			m := &merged[i]
+			if strings.EqualFold(m.User, c.User) && m.Tiers == c.Tiers && !c.Window.Start.After(m.Window.End) && !c.Window.End.Before(m.Window.Start) {
+				if c.Window.End.After(m.Window.End) {
+					m.Window.End = c.Window.End
+				}
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
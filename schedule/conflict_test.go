@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckConflicts(t *testing.T) {
+	filled := FilledSchedule()
+	other := EmptySchedule()
+	other.Users = []string{"x", "A", "C", "y"}
+	other.Rotations = withEnds(day(20), []Rotation{
+		{Start: day(5), Primary: "x", Secondary: "A"},
+		{Start: day(9), Primary: "C", Secondary: "y"},
+		{Start: day(12), Primary: "C", Secondary: "x", Continues: true},
+	})
+	expected := []Conflict{
+		{User: "a", Schedules: [2]int{0, 1}, Tiers: [2]string{TierPrimary, TierSecondary}, Window: TimeRange{day(5), day(8)}},
+		// c is on call in both across a rotation of either, so reported once.
+		{User: "c", Schedules: [2]int{0, 1}, Tiers: [2]string{TierSecondary, TierPrimary}, Window: TimeRange{day(9), day(15)}},
+		{User: "c", Schedules: [2]int{0, 1}, Tiers: [2]string{TierPrimary, TierPrimary}, Window: TimeRange{day(15), day(20)}},
+	}
+	if got := CheckConflicts([]*Schedule{filled, other}); !reflect.DeepEqual(expected, got) {
+		t.Errorf("conflicts do not match expected\nExpected:\n%v\n---\nGot:\n%v\n", expected, got)
+	}
+	if got := CheckConflicts([]*Schedule{filled, EmptySchedule()}); len(got) != 0 {
+		t.Errorf("expected no conflicts with a schedule without rotations, got %v", got)
+	}
+}
+
+func TestCheckConflictsByEmail(t *testing.T) {
+	filled := FilledSchedule()
+	filled.userDetails = map[string]User{"b": {Name: "b", Email: "bea@example.com"}}
+	other := EmptySchedule()
+	other.Users = []string{"bea", "x"}
+	other.userDetails = map[string]User{"bea": {Name: "bea", Email: "Bea@example.com"}}
+	other.Rotations = withEnds(day(3), []Rotation{{Start: day(2), Primary: "bea", Secondary: "x"}})
+	got := CheckConflicts([]*Schedule{filled, other})
+	if len(got) != 1 || got[0].User != "b" || got[0].Window != (TimeRange{day(2), day(3)}) {
+		t.Errorf("expected b and bea to be matched by email, got %v", got)
+	}
+}
+
+func TestScheduleSetConflicts(t *testing.T) {
+	ss, err := NewScheduleSet([]byte(`{
+		"ScheduleFor": "3w",
+		"RotationLength": "1w",
+		"Start": "2017-02-01T10:00:00Z",
+		"Teams": {
+			"search": {"Users": ["a", "d"], "Rotations": [{"Start": "2017-02-08T10:00:00Z", "End": "2017-02-15T10:00:00Z", "Primary": "d", "Secondary": "A"}]},
+			"payments": {"Users": ["a", "b", "c"], "Rotations": [{"Start": "2017-02-01T10:00:00Z", "End": "2017-02-10T10:00:00Z", "Primary": "a", "Secondary": "b"}]}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ss.Conflicts()
+	if len(got) != 1 {
+		t.Fatalf("expected a conflict, got %v", got)
+	}
+	if expected := "a is primary in team payments and secondary in team search from 2017-02-08T10:00:00Z to 2017-02-10T10:00:00Z"; got[0].String() != expected {
+		t.Errorf("expected %q, got %q", expected, got[0].String())
+	}
+}
@@ -0,0 +1,135 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Diff summarises what Generate did to the rotations of a schedule.
+type Diff struct {
+	// Rotations GenerateFresh discarded before they ended, to be scheduled
+	// afresh.
+	Discarded []Rotation
+	// Elapsed rotations Generate dropped.
+	Truncated []Rotation
+	// Rotations Generate added.
+	Added []Rotation
+	// Changes to the tiers of rotations Generate kept, e.g. because a user's
+	// end date or unavailability moved them.
+	Reassigned []Change
+	// Pinned rotations Generate kept as written while adding rotations before
+	// them.
+	Pinned []Rotation
+
+	// The DisplayTimezone of the generated schedule, which String renders
+	// rotations in.
+	location *time.Location
+}
+
+// GenerateWithDiff is Generate, also returning the Diff from s to the
+// generated schedule.
+func (s *Schedule) GenerateWithDiff() (*Schedule, Diff, error) {
+	ns, err := s.Generate()
+	if err != nil {
+		return nil, Diff{}, err
+	}
+	return ns, NewDiff(s, ns), nil
+}
+
+// NewDiff returns the Diff from base to head, matching rotations by start
+// time.
+func NewDiff(base, head *Schedule) Diff {
+	d := Diff{location: head.displayLocation}
+	inBase := map[time.Time]bool{}
+	for _, r := range base.Rotations {
+		inBase[r.Start.UTC()] = true
+	}
+	inHead := map[time.Time]bool{}
+	for _, r := range head.Rotations {
+		inHead[r.Start.UTC()] = true
+		if !inBase[r.Start.UTC()] {
+			d.Added = append(d.Added, r)
+		}
+	}
+	for _, r := range base.Rotations {
+		if !inHead[r.Start.UTC()] {
+			d.Truncated = append(d.Truncated, r)
+		}
+	}
+	for _, r := range head.Rotations {
+		if r.Pinned && !r.Continues && inBase[r.Start.UTC()] && len(d.Added) > 0 && d.Added[0].Start.Before(r.Start) {
+			d.Pinned = append(d.Pinned, r)
+		}
+	}
+	for _, c := range rotationChanges(base, head, SourceGenerate) {
+		if t := c.Window.Start.UTC(); inBase[t] && inHead[t] {
+			d.Reassigned = append(d.Reassigned, c)
+		}
+	}
+	return d
+}
+
+// Empty returns whether Generate left every rotation as it was. Other fields,
+// such as the order of Users, may still have changed.
+func (d Diff) Empty() bool {
+	return len(d.Discarded) == 0 && len(d.Truncated) == 0 && len(d.Added) == 0 && len(d.Reassigned) == 0
+}
+
+// String renders d for a commit message or chat post: a line for each kind
+// of change, followed by the rotations or tiers it affected, with any notes,
+// such as the holidays a rotation covers.
+func (d Diff) String() string {
+	if d.Empty() {
+		return "No changes to the rotations.\n"
+	}
+	b := &strings.Builder{}
+	rotations := func(verb string, rs []Rotation) {
+		if len(rs) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "%s %s:\n", verb, plural(len(rs), "rotation"))
+		for _, r := range rs {
+			if r.Notes != "" {
+				fmt.Fprintf(b, "  %s (%s)\n", r.Format(d.location, time.RFC3339), r.Notes)
+				continue
+			}
+			fmt.Fprintf(b, "  %s\n", r.Format(d.location, time.RFC3339))
+		}
+	}
+	if len(d.Discarded) > 0 {
+		fmt.Fprintf(b, "Discarded %s that had not started, to schedule afresh:\n", plural(len(d.Discarded), "rotation"))
+		for _, r := range d.Discarded {
+			fmt.Fprintf(b, "  %s\n", r.Format(d.location, time.RFC3339))
+		}
+	}
+	rotations("Truncated", d.Truncated)
+	rotations("Added", d.Added)
+	if len(d.Pinned) > 0 {
+		fmt.Fprintf(b, "Added rotations around %s, kept as written:\n", plural(len(d.Pinned), "pinned rotation"))
+		for _, r := range d.Pinned {
+			fmt.Fprintf(b, "  %s\n", r.Format(d.location, time.RFC3339))
+		}
+	}
+	if len(d.Reassigned) > 0 {
+		fmt.Fprintf(b, "Reassigned %s:\n", plural(len(d.Reassigned), "tier"))
+		for _, c := range d.Reassigned {
+			fmt.Fprintf(b, "  %s %s: %s -> %s\n", formatTime(c.Window.Start, d.location, time.RFC3339), c.Tier, orNobody(c.Old), orNobody(c.New))
+		}
+	}
+	return b.String()
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func orNobody(u string) string {
+	if u == "" {
+		return "nobody"
+	}
+	return u
+}
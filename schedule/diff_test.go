@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateWithDiff(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	filled.Overrides = []Override{{
+		Start: time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC),
+		End: time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC),
+		Role: TierSecondary,
+		User: "c",
+	}}
+	ns, d, err := filled.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Truncated) != 1 || !d.Truncated[0].Start.Equal(Start) {
+		t.Errorf("expected the first rotation to be truncated, got %v", d.Truncated)
+	}
+	if len(d.Added) != len(ns.Rotations) - 3 {
+		t.Errorf("expected %d rotations added, got %v", len(ns.Rotations) - 3, d.Added)
+	}
+	if len(d.Reassigned) != 1 || d.Reassigned[0].Old != "b" || d.Reassigned[0].New != "c" {
+		t.Errorf("expected the overridden secondary to be reassigned, got %+v", d.Reassigned)
+	}
+	expected := `Truncated 1 rotation:
+  2017-02-01T10:00:00Z/2017-02-08T10:00:00Z a b
+Added 3 rotations:
+  2017-03-01T10:00:00Z/2017-03-08T10:00:00Z b c
+  2017-03-08T10:00:00Z/2017-03-15T10:00:00Z c a
+  2017-03-15T10:00:00Z/2017-03-22T10:00:00Z a b
+Reassigned 1 tier:
+  2017-02-22T10:00:00Z secondary: b -> c
+`
+	if d.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, d.String())
+	}
+	if d.Empty() {
+		t.Errorf("expected the diff not to be empty")
+	}
+}
+
+func TestGenerateWithDiffEmpty(t *testing.T) {
+	empty := EmptySchedule()
+	empty.now = Start
+	s, err := empty.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, d, err := s.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("expected regenerating to change nothing, got:\n%s", d)
+	}
+	if d.String() != "No changes to the rotations.\n" {
+		t.Errorf("unexpected rendering of an empty diff: %q", d.String())
+	}
+}
@@ -0,0 +1,39 @@
+package schedule
+
+import (
+	"strings"
+	"time"
+)
+
+// FormatRotation renders r as String does, with its times in DisplayTimezone
+// if it is set.
+func (s Schedule) FormatRotation(r Rotation) string {
+	return r.Format(s.displayLocation, time.RFC3339)
+}
+
+// Return t in loc, or its own location if loc is nil, laid out by layout. If
+// layout shows no UTC offset, one is added after times a daylight saving
+// change makes ambiguous: wall-clock times the clocks going back repeat, and
+// those just after the clocks going forward skipped the hour before them,
+// which is where a handoff at a skipped time lands.
+func formatTime(t time.Time, loc *time.Location, layout string) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	text := t.Format(layout)
+	if strings.Contains(layout, "Z07") || strings.Contains(layout, "-07") {
+		return text
+	}
+	if len(wallClockInstants(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Location())) > 1 || clocksWentForward(t) {
+		text += " (" + t.Format("-07:00") + ")"
+	}
+	return text
+}
+
+// Return whether the clocks went forward within the hour before t, so that
+// the wall-clock times just before it were skipped.
+func clocksWentForward(t time.Time) bool {
+	_, before := t.Add(-time.Hour).Zone()
+	_, after := t.Zone()
+	return after > before
+}
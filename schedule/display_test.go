@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotationFormat(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Rotation{Start: time.Date(2017, time.February, 1, 14, 0, 0, 0, time.UTC), End: time.Date(2017, time.February, 8, 14, 0, 0, 0, time.UTC), Primary: "a", Secondary: "b"}
+	for _, c := range []struct {
+		loc *time.Location
+		layout string
+		expected string
+	}{
+		{nil, time.RFC3339, "2017-02-01T14:00:00Z/2017-02-08T14:00:00Z a b"},
+		{newYork, time.RFC3339, "2017-02-01T09:00:00-05:00/2017-02-08T09:00:00-05:00 a b"},
+		{london, "Mon 2 Jan 15:04", "Wed 1 Feb 14:00/Wed 8 Feb 14:00 a b"},
+	} {
+		if got := r.Format(c.loc, c.layout); got != c.expected {
+			t.Errorf("expected %q in %v, got %q", c.expected, c.loc, got)
+		}
+	}
+	if got := r.String(); got != r.Format(nil, time.RFC3339) {
+		t.Errorf("expected String to be Format without a location, got %q", got)
+	}
+}
+
+func TestRotationFormatDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const layout = "2006-01-02 15:04"
+	for _, c := range []struct {
+		name string
+		start, end time.Time
+		expected string
+	}{
+		{
+			// The clocks go back at 02:00 EDT on 2017-11-05, so 01:30 happens
+			// twice: the handoff is at the first, and the next at the second.
+			"repeated",
+			time.Date(2017, time.November, 5, 5, 30, 0, 0, time.UTC),
+			time.Date(2017, time.November, 5, 6, 30, 0, 0, time.UTC),
+			"2017-11-05 01:30 (-04:00)/2017-11-05 01:30 (-05:00) a b",
+		},
+		{
+			// The clocks go forward at 02:00 EST on 2017-03-12, so a handoff at
+			// 02:30 lands at 03:30.
+			"skipped",
+			time.Date(2017, time.March, 5, 7, 30, 0, 0, time.UTC),
+			time.Date(2017, time.March, 12, 7, 30, 0, 0, time.UTC),
+			"2017-03-05 02:30/2017-03-12 03:30 (-04:00) a b",
+		},
+		{
+			"clear of the change",
+			time.Date(2017, time.March, 12, 13, 0, 0, 0, time.UTC),
+			time.Date(2017, time.March, 19, 13, 0, 0, 0, time.UTC),
+			"2017-03-12 09:00/2017-03-19 09:00 a b",
+		},
+	} {
+		r := Rotation{Start: c.start, End: c.end, Primary: "a", Secondary: "b"}
+		if got := r.Format(newYork, layout); got != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, got)
+		}
+		// A layout with an offset is unambiguous already.
+		if got := r.Format(newYork, time.RFC3339); strings.Contains(got, "(") {
+			t.Errorf("%s: expected no offset added to RFC3339, got %q", c.name, got)
+		}
+	}
+}
+
+func TestDisplayTimezone(t *testing.T) {
+	s, err := NewSchedule([]byte(strings.Replace(newYorkScheduleText, `"Users"`, `"DisplayTimezone": "Europe/London", "Users"`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = time.Date(2017, time.March, 1, 14, 0, 0, 0, time.UTC)
+	ns, d, err := s.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := ns.FormatRotation(ns.Rotations[0]), "2017-03-01T14:00:00Z/2017-03-08T14:00:00Z a b"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	// Europe/London starts daylight saving on 2017-03-26.
+	if got, expected := ns.FormatRotation(ns.Rotations[3]), "2017-03-22T13:00:00Z/2017-03-29T14:00:00+01:00 a b"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if !strings.Contains(d.String(), "  2017-03-01T14:00:00Z/2017-03-08T14:00:00Z a b\n") {
+		t.Errorf("expected the diff in DisplayTimezone, got:\n%s", d)
+	}
+
+	// Only rendering changes: the schedule is still written in Timezone.
+	if got := ns.Rotations[0].String(); got != "2017-03-01T09:00:00-05:00/2017-03-08T09:00:00-05:00 a b" {
+		t.Errorf("expected the rotation to keep Timezone, got %q", got)
+	}
+	b, err := ns.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := string(b); !strings.Contains(text, `"2017-03-01T09:00:00-05:00"`) || !strings.Contains(text, `"DisplayTimezone": "Europe/London"`) {
+		t.Errorf("expected times written in Timezone and DisplayTimezone kept, got:\n%s", text)
+	}
+}
@@ -0,0 +1,70 @@
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Day = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// The units ParseDuration accepts, for error messages.
+const durationUnits = `"w", "d", "h", "m", "s", "ms", "us" (or "µs") and "ns"`
+
+var durationSegment = regexp.MustCompile(`^([0-9]*\.?[0-9]*)([^0-9.]+)`)
+
+// ParseDuration parses a duration like time.ParseDuration, but also accepts
+// the units "d" (24 hours) and "w" (7 days), alone or combined with the others,
+// e.g. "2w", "10d" or "1w3d12h". Every string time.ParseDuration accepts is
+// parsed exactly as it would be.
+func ParseDuration(s string) (time.Duration, error) {
+	if !strings.ContainsAny(s, "dw") {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: accepted units are %s", s, durationUnits)
+		}
+		return d, nil
+	}
+
+	rest, neg := s, false
+	if strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		neg = rest[0] == '-'
+		rest = rest[1:]
+	}
+	var total time.Duration
+	for rest != "" {
+		m := durationSegment.FindStringSubmatch(rest)
+		if m == nil || m[1] == "" || m[1] == "." {
+			return 0, fmt.Errorf("invalid duration %q: accepted units are %s", s, durationUnits)
+		}
+		var d time.Duration
+		switch m[2] {
+		case "w", "d":
+			n, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: accepted units are %s", s, durationUnits)
+			}
+			unit := Day
+			if m[2] == "w" {
+				unit = Week
+			}
+			d = time.Duration(n * float64(unit))
+		default:
+			var err error
+			if d, err = time.ParseDuration(m[0]); err != nil {
+				return 0, fmt.Errorf("invalid duration %q: accepted units are %s", s, durationUnits)
+			}
+		}
+		total += d
+		rest = rest[len(m[0]):]
+	}
+	if neg {
+		total = -total
+	}
+	return total, nil
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		expected time.Duration
+	}{
+		{"168h", Week},
+		{"1h30m", 90 * time.Minute},
+		{"0", 0},
+		{"1w", Week},
+		{"2w", 2 * Week},
+		{"10d", 10 * Day},
+		{"1w3d", Week + 3 * Day},
+		{"1w3d12h30m", Week + 3 * Day + 12 * time.Hour + 30 * time.Minute},
+		{"1.5d", 36 * time.Hour},
+		{"-2d", -2 * Day},
+	} {
+		d, err := ParseDuration(c.text)
+		if err != nil {
+			t.Errorf("%q: %s", c.text, err)
+			continue
+		}
+		if d != c.expected {
+			t.Errorf("%q: expected %s, got %s", c.text, c.expected, d)
+		}
+	}
+}
+
+func TestParseDurationInvalid(t *testing.T) {
+	for _, text := range []string{"", "w", "1x", "1wd", "d1", "1w 3d", "1.2.3d"} {
+		if _, err := ParseDuration(text); err == nil || !strings.Contains(err.Error(), `"w", "d", "h"`) {
+			t.Errorf("%q: expected an error listing the accepted units, got %v", text, err)
+		}
+	}
+}
+
+func TestNewScheduleWeeksAndDays(t *testing.T) {
+	s, err := NewSchedule([]byte(`{"Users": ["a", "b"], "Start": "2017-02-01T10:00:00Z", "RotationLength": "1w", "ScheduleFor": "3w"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RotationDuration != Week || s.ScheduleForDuration != 3 * Week {
+		t.Errorf("expected 1w and 3w, got %s and %s", s.RotationDuration, s.ScheduleForDuration)
+	}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The generated schedule keeps the lengths as written.
+	if ns.RotationLength != "1w" || ns.ScheduleFor != "3w" {
+		t.Errorf("expected 1w and 3w to be preserved, got %q and %q", ns.RotationLength, ns.ScheduleFor)
+	}
+
+	_, err = NewSchedule([]byte(`{"Users": ["a", "b"], "RotationLength": "1fortnight", "ScheduleFor": "3w"}`))
+	if err == nil || !strings.HasPrefix(err.Error(), "error parsing RotationLength: ") {
+		t.Errorf("expected an error naming RotationLength, got %v", err)
+	}
+}
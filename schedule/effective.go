@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"time"
+)
+
+// An EffectiveDuration shows a duration field both as written in the schedule
+// (or defaulted) and as parsed.
+type EffectiveDuration struct {
+	Text string
+	Parsed string
+	Seconds int64
+}
+
+func effectiveDuration(text string, d time.Duration) EffectiveDuration {
+	if text == "" {
+		text = d.String()
+	}
+	return EffectiveDuration{
+		Text: text,
+		Parsed: d.String(),
+		Seconds: int64(d.Seconds()),
+	}
+}
+
+// EffectiveConfig is the configuration a Schedule resolves to once its fields
+// have been parsed and defaults filled in. It is meant for display, so callers
+// can see what the tool actually understood from a schedule file.
+type EffectiveConfig struct {
+	Users []string
+	Start time.Time
+	Timezone string
+	RotationLength EffectiveDuration
+	// SecondaryRotationLength, or RotationLength if it is not set.
+	SecondaryRotationLength EffectiveDuration
+	ScheduleFor EffectiveDuration
+	HandoffGrace EffectiveDuration
+	// MaxRotations, or DefaultMaxRotations if it is not set.
+	MaxRotations int
+	// The current time according to the schedule's clock, and how far a
+	// Generate run at that time would extend the schedule.
+	Now time.Time
+	HorizonEnd time.Time
+	// The Generate run that last changed the rotations, if any.
+	Provenance *Provenance `json:",omitempty"`
+}
+
+// Effective returns the effective configuration of s.
+func (s *Schedule) Effective() EffectiveConfig {
+	now := s.currentTime()
+	timezone := s.Timezone
+	if timezone == "" {
+		timezone = s.Start.Location().String()
+	}
+	secondary := effectiveDuration(s.RotationLength, s.RotationDuration)
+	if s.SecondaryRotationLength != "" {
+		secondary = effectiveDuration(s.SecondaryRotationLength, s.SecondaryRotationDuration)
+	}
+	maxRotations := s.MaxRotations
+	if maxRotations == 0 {
+		maxRotations = DefaultMaxRotations
+	}
+	return EffectiveConfig{
+		Users: s.Users,
+		Start: s.Start,
+		Timezone: timezone,
+		RotationLength: effectiveDuration(s.RotationLength, s.RotationDuration),
+		SecondaryRotationLength: secondary,
+		ScheduleFor: effectiveDuration(s.ScheduleFor, s.ScheduleForDuration),
+		HandoffGrace: effectiveDuration(s.HandoffGrace, s.HandoffGraceDuration),
+		MaxRotations: maxRotations,
+		Now: now,
+		HorizonEnd: now.Add(s.ScheduleForDuration),
+		Provenance: s.Provenance,
+	}
+}
@@ -0,0 +1,31 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEffective(t *testing.T) {
+	s, err := NewSchedule([]byte(EmptyScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = Start
+	expected := EffectiveConfig{
+		Users: []string{"a", "b", "c"},
+		Start: Start,
+		Timezone: "UTC",
+		RotationLength: EffectiveDuration{Text: "168h", Parsed: "168h0m0s", Seconds: 604800},
+		// The secondary hands off with the primary by default.
+		SecondaryRotationLength: EffectiveDuration{Text: "168h", Parsed: "168h0m0s", Seconds: 604800},
+		ScheduleFor: EffectiveDuration{Text: "504h", Parsed: "504h0m0s", Seconds: 1814400},
+		HandoffGrace: EffectiveDuration{Text: "1h0m0s", Parsed: "1h0m0s", Seconds: 3600},
+		MaxRotations: DefaultMaxRotations,
+		Now: Start,
+		HorizonEnd: time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC),
+	}
+	if e := s.Effective(); !reflect.DeepEqual(expected, e) {
+		t.Errorf("effective config does not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, e)
+	}
+}
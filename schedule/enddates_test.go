@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateSkipsUsersAfterEndDate(t *testing.T) {
+	empty := EmptySchedule()
+	empty.now = Start
+	// b finishes after the second rotation has started.
+	end := time.Date(2017, time.February, 10, 0, 0, 0, 0, time.UTC)
+	empty.UserEndDates = map[string]time.Time{"b": end}
+	s, err := empty.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2017-02-01T10:00:00Z/2017-02-08T10:00:00Z a b",
+		"2017-02-08T10:00:00Z/2017-02-15T10:00:00Z b c",
+		"2017-02-15T10:00:00Z/2017-02-22T10:00:00Z c a",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z a c",
+	}
+	if len(s.Rotations) != len(expected) {
+		t.Fatalf("expected %d rotations, got %v", len(expected), s.Rotations)
+	}
+	for i, r := range s.Rotations {
+		if r.String() != expected[i] {
+			t.Errorf("rotation %d: expected %s, got %s", i, expected[i], r)
+		}
+	}
+}
+
+func TestGenerateNoEligibleUsers(t *testing.T) {
+	empty := EmptySchedule()
+	empty.now = Start
+	end := time.Date(2017, time.February, 10, 0, 0, 0, 0, time.UTC)
+	empty.UserEndDates = map[string]time.Time{"a": end, "b": end, "c": end}
+	if _, err := empty.Generate(); err == nil || !strings.Contains(err.Error(), "2017-02-15T10:00:00Z") {
+		t.Errorf("expected an error naming the uncovered rotation, got %v", err)
+	}
+}
+
+func TestValidateRotationAfterEndDate(t *testing.T) {
+	filled := FilledSchedule()
+	filled.UserEndDates = map[string]time.Time{"c": time.Date(2017, time.February, 12, 0, 0, 0, 0, time.UTC)}
+	err := filled.Validate()
+	if err == nil || !strings.Contains(err.Error(), "2017-02-15T10:00:00Z names c") {
+		t.Errorf("expected an error naming c's rotation after their end date, got %v", err)
+	}
+}
+
+func TestLintSuggestsRemovingEndedUsers(t *testing.T) {
+	filled := FilledSchedule()
+	filled.UserEndDates = map[string]time.Time{"c": time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC)}
+	filled.now = time.Date(2017, time.February, 22, 12, 0, 0, 0, time.UTC)
+	suggestions := filled.Lint()
+	if len(suggestions) != 1 || !strings.HasPrefix(suggestions[0], "c reached their end date") {
+		t.Errorf("expected a suggestion to remove c, got %v", suggestions)
+	}
+
+	// Before c's end date, there is nothing to suggest.
+	filled.now = time.Date(2017, time.February, 21, 12, 0, 0, 0, time.UTC)
+	if suggestions := filled.Lint(); len(suggestions) != 0 {
+		t.Errorf("expected no suggestions, got %v", suggestions)
+	}
+}
+
+func TestOverrideStraddlingEndDate(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	// c's turn as secondary starts before their end date, and an override
+	// splits it so that a segment after the end date still names them.
+	s.UserEndDates = map[string]time.Time{"c": day(11)}
+	s.Overrides = []Override{{Start: day(9), End: day(12), Role: TierPrimary, User: "a"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ns.Rotations[3]; !r.Start.Equal(day(12)) || r.Secondary != "c" || !r.Continues {
+		t.Fatalf("expected c to finish their turn after the override, got %v", r)
+	}
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSchedule(text); err != nil {
+		t.Errorf("expected the generated schedule to load, got %v", err)
+	}
+}
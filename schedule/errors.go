@@ -0,0 +1,55 @@
+package schedule
+
+import (
+	"errors"
+)
+
+var (
+	// Returned by Validate for a schedule with nobody to assign.
+	ErrNoUsers = errors.New("must provide at least 1 user")
+	// Returned by Validate for a RotationLength of zero or less.
+	ErrNonPositiveRotation = errors.New("cannot have nonpositive RotationLength")
+	// Returned by Generate when nobody can take a rotation and
+	// AllowUnassigned is not set.
+	ErrNoneAvailable = errors.New("no users are available")
+	// Returned by Generate when the schedule would have more rotations than
+	// MaxRotations allows.
+	ErrTooManyRotations = errors.New("too many rotations")
+)
+
+// A ParseError is a schedule document, or a field of one, that cannot be
+// read, e.g. a RotationLength that is not a duration. It is returned by
+// NewSchedule and NewScheduleYAML.
+type ParseError struct {
+	// What was being done, e.g. "parsing" or "loading".
+	Op string
+	// The field that cannot be read, or "schedule" for the document itself.
+	Field string
+	// The field's value as written, if it was read that far.
+	Value string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "error " + e.Op + " " + e.Field + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// A ValidationError is a schedule that was read but is not valid. Err says
+// why, and may itself be one of the errors above, e.g. ErrNoUsers, so that
+// errors.Is finds it. Validate returns one, as do NewSchedule and Generate,
+// which validate the schedule first.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
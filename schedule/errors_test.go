@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		field string
+		value string
+		message string
+	}{
+		{`{"Users": [`, "schedule", "", "error parsing schedule: unexpected end of JSON input"},
+		{strings.Replace(EmptyScheduleText, `"168h"`, `"often"`, 1), "RotationLength", "often", "error parsing RotationLength: "},
+		{strings.Replace(EmptyScheduleText, `"504h"`, `"later"`, 1), "ScheduleFor", "later", "error parsing ScheduleFor: "},
+		{strings.Replace(EmptyScheduleText, `"Users"`, `"Timezone": "Mars/Olympus", "Users"`, 1), "Timezone", "Mars/Olympus", "error loading Timezone: "},
+		{strings.Replace(EmptyScheduleText, `"Users"`, `"DisplayTimezone": "Mars/Olympus", "Users"`, 1), "DisplayTimezone", "Mars/Olympus", "error loading DisplayTimezone: "},
+	} {
+		_, err := NewSchedule([]byte(c.text))
+		pe := &ParseError{}
+		if !errors.As(err, &pe) || pe.Field != c.field || pe.Value != c.value {
+			t.Errorf("expected a ParseError for %s with value %q, got %#v", c.field, c.value, err)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), c.message) {
+			t.Errorf("expected error starting %q, got %q", c.message, err)
+		}
+		if errors.As(err, new(*ValidationError)) {
+			t.Errorf("expected %q not to be a ValidationError", err)
+		}
+	}
+	if _, err := NewScheduleYAML([]byte("Users: [a\n")); !errors.As(err, new(*ParseError)) {
+		t.Errorf("expected malformed YAML to be a ParseError, got %#v", err)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	noUsers := strings.Replace(EmptyScheduleText, `["a", "b", "c"]`, `[]`, 1)
+	_, err := NewSchedule([]byte(noUsers))
+	if !errors.Is(err, ErrNoUsers) || err.Error() != "must provide at least 1 user" {
+		t.Errorf("expected ErrNoUsers, got %#v", err)
+	}
+	if !errors.As(err, new(*ValidationError)) {
+		t.Errorf("expected a ValidationError, got %#v", err)
+	}
+
+	s := EmptySchedule()
+	s.RotationDuration = -Week
+	err = s.Validate()
+	if !errors.Is(err, ErrNonPositiveRotation) || err.Error() != "cannot have nonpositive RotationLength (got -168h0m0s)" {
+		t.Errorf("expected ErrNonPositiveRotation, got %v", err)
+	}
+	if _, err := s.Generate(); !errors.Is(err, ErrNonPositiveRotation) {
+		t.Errorf("expected Generate to return ErrNonPositiveRotation, got %v", err)
+	}
+
+	// Strict validation wraps Validate's error among its problems.
+	if _, err := NewSchedule([]byte(noUsers), Strict()); !errors.Is(err, ErrNoUsers) || !errors.As(err, new(*ValidationError)) {
+		t.Errorf("expected strict validation to find ErrNoUsers, got %#v", err)
+	}
+}
+
+func TestErrNoneAvailable(t *testing.T) {
+	s := EmptySchedule()
+	s.now = s.Start
+	for _, u := range s.Users {
+		s.Unavailable = append(s.Unavailable, Unavailability{User: u, Start: day(1), End: day(30)})
+	}
+	_, err := s.Generate()
+	if !errors.Is(err, ErrNoneAvailable) || !strings.HasPrefix(err.Error(), "no users are available for the rotation starting at 2017-02-01T10:00:00Z") {
+		t.Errorf("expected ErrNoneAvailable, got %v", err)
+	}
+}
+
+func TestParseErrorsLocateBrokenFiles(t *testing.T) {
+	for _, c := range []struct {
+		file string
+		field string
+		expected string
+	}{
+		{"bad-rotation-start.json", "Rotations[2].Start", "error parsing Rotations[2].Start: line 9, column 13: parsing time \"2017-02-31T10:00:00Z\": day out of range"},
+		{"bad-end-date.json", "UserEndDates.b", "error parsing UserEndDates.b: line 4, column 24: parsing time \"March 1st\""},
+		{"bad-rotation-type.json", "Rotations[0].Secondary", "error parsing Rotations[0].Secondary: line 7, column 66: json: cannot unmarshal array into Go value of type string (got [\"b\", \"c\"])"},
+		{"bad-user.json", "Users[1]", "error parsing Users[1]: line 2, column 17: a user must have a Name"},
+		{"bad-duration.json", "RotationLength", "error parsing RotationLength: "},
+		{"missing-comma.json", "schedule", "error parsing schedule: line 4, column 2: invalid character '\"' after object key:value pair"},
+	} {
+		text, err := ioutil.ReadFile(filepath.Join("testdata", "broken", c.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewSchedule(text)
+		pe := &ParseError{}
+		if !errors.As(err, &pe) || pe.Field != c.field {
+			t.Errorf("%s: expected a ParseError for %s, got %v", c.file, c.field, err)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error starting %q, got %q", c.file, c.expected, err)
+		}
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	text, err := ioutil.ReadFile(filepath.Join("testdata", "broken", "unknown-field.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ignored by default, since newer documents may have fields this version
+	// does not know.
+	if _, err := NewSchedule(text); err != nil {
+		t.Errorf("expected unknown fields to be ignored by default, got %v", err)
+	}
+	s, err := NewSchedule(text, Strict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	warning := "Rotations[0].Primray at line 7, column 37 is not a schedule field, so is ignored; check its spelling, or whether it needs a newer oncallator"
+	if lint := s.Lint(); len(lint) == 0 || lint[0] != warning {
+		t.Errorf("expected a warning about Primray, got %v", lint)
+	}
+	_, err = NewSchedule(text, DisallowUnknownFields())
+	pe := &ParseError{}
+	if !errors.As(err, &pe) || pe.Field != "Rotations[0].Primray" || err.Error() != "error parsing Rotations[0].Primray: unknown field at line 7, column 37" {
+		t.Errorf("expected an error for Primray, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Explain describes the effect of changing a schedule document from base to
+// head, as Markdown suitable for posting as a pull request comment. Both
+// documents are generated with now as their clock so that only the edit, and
+// not the passage of time, shows up in the result.
+func Explain(base, head []byte, now time.Time) (string, error) {
+	b, h, err := generateBoth(base, head, now)
+	if err != nil {
+		return "", err
+	}
+	return explainChanges(h, Changes(b, h, SourceEdit)), nil
+}
+
+// ExplainChanges returns the changes Explain describes.
+func ExplainChanges(base, head []byte, now time.Time) ([]Change, error) {
+	b, h, err := generateBoth(base, head, now)
+	if err != nil {
+		return nil, err
+	}
+	return Changes(b, h, SourceEdit), nil
+}
+
+func generateBoth(base, head []byte, now time.Time) (*Schedule, *Schedule, error) {
+	b, err := generateAt(base, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("base: %s", err)
+	}
+	h, err := generateAt(head, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("head: %s", err)
+	}
+	return b, h, nil
+}
+
+func generateAt(text []byte, now time.Time) (*Schedule, error) {
+	s, err := NewSchedule(text)
+	if err != nil {
+		return nil, err
+	}
+	s.now = now
+	return s.Generate()
+}
+
+// Render changes as Markdown. Rotation changes are shown as a table with a row
+// per rotation, where unchanged tiers show head's user.
+func explainChanges(head *Schedule, changes []Change) string {
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "### Schedule changes\n\n")
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "No changes to the generated schedule.\n")
+		return out.String()
+	}
+
+	users := func(kind ChangeKind, label string) {
+		us := []string{}
+		for _, c := range changes {
+			if c.Kind == kind {
+				us = append(us, c.Old + c.New)
+			}
+		}
+		if len(us) > 0 {
+			fmt.Fprintf(out, "**%s:** %s\n\n", label, markdownList(us))
+		}
+	}
+	users(ChangeUserAdded, "Users added")
+	users(ChangeUserRemoved, "Users removed")
+	for _, c := range changes {
+		if c.Kind == ChangeHorizon {
+			fmt.Fprintf(out, "**Horizon:** %s → %s\n\n", c.Old, c.New)
+		}
+	}
+
+	byStart := map[time.Time]Rotation{}
+	for _, r := range head.Rotations {
+		byStart[r.Start.UTC()] = r
+	}
+	rows := 0
+	for i := 0; i < len(changes); {
+		if changes[i].Window == nil {
+			i++
+			continue
+		}
+		if rows == 0 {
+			fmt.Fprintf(out, "| Start |")
+			for _, tier := range head.tiers() {
+				fmt.Fprintf(out, " %s |", strings.ToUpper(tier[:1]) + tier[1:])
+			}
+			fmt.Fprintf(out, "\n|---|%s\n", strings.Repeat("---|", len(head.tiers())))
+		}
+		rows++
+		start := changes[i].Window.Start
+		r := byStart[start.UTC()]
+		cells := map[string]string{}
+		for _, tier := range head.tiers() {
+			cells[tier] = head.tierUser(r, tier)
+		}
+		for ; i < len(changes) && changes[i].Window != nil && changes[i].Window.Start.Equal(start); i++ {
+			cells[changes[i].Tier] = changeCell(changes[i].Old, changes[i].New)
+		}
+		fmt.Fprintf(out, "| %s |", formatTime(start, head.displayLocation, time.RFC3339))
+		for _, tier := range head.tiers() {
+			fmt.Fprintf(out, " %s |", cells[tier])
+		}
+		fmt.Fprintf(out, "\n")
+	}
+	if rows > 0 {
+		fmt.Fprintf(out, "\n")
+	}
+	return out.String()
+}
+
+// Show a tier's change as the old user struck through followed by the new one.
+func changeCell(old, new string) string {
+	if old == "" {
+		old = "—"
+	}
+	if new == "" {
+		new = "—"
+	}
+	return fmt.Sprintf("~~%s~~ → %s", old, new)
+}
+
+// The end of the last generated rotation.
+func (s *Schedule) horizon() time.Time {
+	if len(s.Rotations) == 0 {
+		return s.Start
+	}
+	return s.RotationEnd(len(s.Rotations) - 1)
+}
+
+// Return the users in head but not base, and in base but not head, each in the
+// order they are listed.
+func diffUsers(base, head []string) ([]string, []string) {
+	in := func(u string, us []string) bool {
+		for _, o := range us {
+			if o == u {
+				return true
+			}
+		}
+		return false
+	}
+	added, removed := []string{}, []string{}
+	for _, u := range head {
+		if !in(u, base) {
+			added = append(added, u)
+		}
+	}
+	for _, u := range base {
+		if !in(u, head) {
+			removed = append(removed, u)
+		}
+	}
+	return added, removed
+}
+
+func markdownList(us []string) string {
+	out := ""
+	for i, u := range us {
+		if i > 0 {
+			out += ", "
+		}
+		out += "`" + u + "`"
+	}
+	return out
+}
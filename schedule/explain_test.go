@@ -0,0 +1,47 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	head := strings.Replace(FilledScheduleText, `"Users": ["b", "c", "a"]`, `"Users": ["b", "c", "a", "d"]`, 1)
+	head = strings.Replace(head, `"Primary": "c"`, `"Primary": "d"`, 1)
+	out, err := Explain([]byte(FilledScheduleText), []byte(head), Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "### Schedule changes\n\n" +
+		"**Users added:** `d`\n\n" +
+		"| Start | Primary | Secondary |\n" +
+		"|---|---|---|\n" +
+		"| 2017-02-15T10:00:00Z | ~~c~~ → d | a |\n\n"
+	if out != expected {
+		t.Errorf("explanation does not match expected\nExpected:\n%s\n---\nGot:\n%s\n", expected, out)
+	}
+}
+
+func TestExplainHorizon(t *testing.T) {
+	head := strings.Replace(FilledScheduleText, `"ScheduleFor": "504h"`, `"ScheduleFor": "672h"`, 1)
+	out, err := Explain([]byte(FilledScheduleText), []byte(head), Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "**Horizon:** 2017-03-01T10:00:00Z → 2017-03-08T10:00:00Z") {
+		t.Errorf("expected a horizon change, got:\n%s", out)
+	}
+	if !strings.Contains(out, "| 2017-03-01T10:00:00Z | ~~—~~ → b | ~~—~~ → c |") {
+		t.Errorf("expected an added rotation, got:\n%s", out)
+	}
+}
+
+func TestExplainNoChanges(t *testing.T) {
+	out, err := Explain([]byte(FilledScheduleText), []byte(FilledScheduleText), Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "No changes") {
+		t.Errorf("expected no changes, got:\n%s", out)
+	}
+}
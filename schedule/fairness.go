@@ -0,0 +1,162 @@
+package schedule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The ways Generate can choose each rotation's primary.
+const (
+	// Take users in turn from Users. This is the default.
+	FairnessRoundRobin = "round-robin"
+	// Take the available user with the fewest weighted hours as primary so
+	// far, counting both ShiftCounts and the rotations in the schedule. Users
+	// added late, or back from time off, are given extra turns until they
+	// catch up. Hours are divided by each user's Weights.
+	FairnessBalanced = "balanced"
+)
+
+// A ShiftCount is how much of the on-call a user has done in rotations that
+// have elapsed and been dropped from a schedule. Hours are weighted by
+// BurdenWindows.
+type ShiftCount struct {
+	Primary int `json:",omitempty"`
+	Secondary int `json:",omitempty"`
+	PrimaryHours float64 `json:",omitempty"`
+	SecondaryHours float64 `json:",omitempty"`
+	// Rotations shadowed as one of Trainees.
+	Shadow int `json:",omitempty"`
+}
+
+func validateFairness(fairness string) error {
+	switch fairness {
+	case "", FairnessRoundRobin, FairnessBalanced:
+		return nil
+	}
+	return fmt.Errorf("unknown Fairness %q: must be %q or %q", fairness, FairnessRoundRobin, FairnessBalanced)
+}
+
+func copyShiftCounts(counts map[string]ShiftCount) map[string]ShiftCount {
+	if counts == nil {
+		return nil
+	}
+	c := map[string]ShiftCount{}
+	for u, n := range counts {
+		c[u] = n
+	}
+	return c
+}
+
+// Truncate the elapsed rotations of s, as for truncate, and return the
+// rotations that are kept, without overrides. Shifts in the rotations that are
+// dropped are added to ns.ShiftCounts.
+func (ns *Schedule) truncateCounting(s *Schedule) []Rotation {
+	return ns.dropCounting(s, truncate(s.unapplyOverrides(s.Rotations), ns.now))
+}
+
+// Add the shifts in the rotations of s before kept, a suffix of them without
+// overrides, to ns.ShiftCounts, and return kept. Shifts are counted per
+// rotation as generated, and hours per rotation as overridden, so they follow
+// whoever was actually on call. If kept is empty, every rotation is counted.
+func (ns *Schedule) dropCounting(s *Schedule, kept []Rotation) []Rotation {
+	dropped := func(r Rotation) bool {
+		return len(kept) == 0 || r.Start.Before(kept[0].Start)
+	}
+	for _, r := range s.unapplyOverrides(s.Rotations) {
+		if !dropped(r) {
+			break
+		}
+		if !r.SecondaryHandoff {
+			ns.countShift(r.Primary, func(c *ShiftCount) { c.Primary++ })
+		}
+		ns.countShift(r.Secondary, func(c *ShiftCount) { c.Secondary++ })
+		ns.countShift(r.Shadow, func(c *ShiftCount) { c.Shadow++ })
+	}
+	for i, r := range s.Rotations {
+		if !dropped(r) {
+			break
+		}
+		hours := s.Burden(r.Start, s.RotationEnd(i))
+		ns.countShift(r.Primary, func(c *ShiftCount) { c.PrimaryHours += hours })
+		ns.countShift(r.Secondary, func(c *ShiftCount) { c.SecondaryHours += hours })
+	}
+	return kept
+}
+
+func (s *Schedule) countShift(u string, add func(c *ShiftCount)) {
+	if u == "" || u == Unassigned {
+		return
+	}
+	if s.ShiftCounts == nil {
+		s.ShiftCounts = map[string]ShiftCount{}
+	}
+	c := s.ShiftCounts[u]
+	add(&c)
+	s.ShiftCounts[u] = c
+}
+
+// Return the weighted hours each user has been primary for, across
+// ShiftCounts and Rotations.
+func (s *Schedule) primaryHours() map[string]float64 {
+	hours := map[string]float64{}
+	for u, c := range s.ShiftCounts {
+		hours[u] = c.PrimaryHours
+	}
+	for i, r := range s.Rotations {
+		hours[r.Primary] += s.Burden(r.Start, s.RotationEnd(i))
+	}
+	return hours
+}
+
+// Return the position of the available user in users who has been primary for
+// the fewest weighted hours, relative to their Weights. Ties go to whoever the
+// round-robin reaches first from position p.
+func (s *Schedule) leastLoaded(users []string, p int) int {
+	hours := s.primaryHours()
+	return s.best(users, p, func(u string) float64 {
+		w := s.weight(u)
+		if w == 0 {
+			return math.Inf(1)
+		}
+		return hours[u] / w
+	})
+}
+
+// Return u's share of the primary shifts relative to other users: their
+// entry in Weights, or 1.
+func (s *Schedule) weight(u string) float64 {
+	if w, ok := s.Weights[u]; ok {
+		return w
+	}
+	return 1
+}
+
+func (s Schedule) validateWeights() error {
+	if len(s.Weights) == 0 {
+		return nil
+	}
+	if s.Fairness != FairnessBalanced {
+		return fmt.Errorf("Weights can only be used with Fairness %q, which balances load between users", FairnessBalanced)
+	}
+	users := map[string]bool{}
+	for _, u := range s.allUsers() {
+		users[u] = true
+	}
+	// Check users in order, so that the error does not depend on map order.
+	weighted := []string{}
+	for u := range s.Weights {
+		weighted = append(weighted, u)
+	}
+	sort.Strings(weighted)
+	for _, u := range weighted {
+		w := s.Weights[u]
+		if !users[u] {
+			return fmt.Errorf("Weights gives a weight for %s, who is not among the users to schedule", u)
+		}
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("Weights gives %s a weight of %v; weights must be 0 or more", u, w)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Generate a schedule every week for weeks weeks, round-tripping it through
+// JSON each time, and return the last one.
+func regenerateWeekly(t *testing.T, s *Schedule, weeks int) *Schedule {
+	for i := 0; i < weeks; i++ {
+		text, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err := NewSchedule(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reloaded.now = s.now.Add(Week)
+		if s, err = reloaded.Generate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// The spread between the most and fewest primary shifts counted for users.
+func primarySpread(s *Schedule) int {
+	min, max := -1, 0
+	for _, u := range s.Users {
+		n := s.ShiftCounts[u].Primary
+		if min < 0 || n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return max - min
+}
+
+func TestFairnessLateUserCatchesUp(t *testing.T) {
+	for _, test := range []struct {
+		fairness string
+		spread int
+	}{
+		// d never makes up the turns they missed.
+		{FairnessRoundRobin, 3},
+		// d takes extra turns until they are level with everyone else.
+		{FairnessBalanced, 0},
+	} {
+		s := EmptySchedule()
+		s.Fairness = test.fairness
+		s.now = Start
+		s, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s = regenerateWeekly(t, s, 7)
+		s.Users = append(s.Users, "d")
+		s = regenerateWeekly(t, s, 18)
+		if spread := primarySpread(s); spread != test.spread {
+			t.Errorf("%s: expected a spread of %d primary shifts, got %d: %+v", test.fairness, test.spread, spread, s.ShiftCounts)
+		}
+	}
+}
+
+func TestShiftCountsFollowOverrides(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(1), End: day(2), Role: TierPrimary, User: "c"}}
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = day(16)
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a's first rotation elapsed, with c covering its first day.
+	if c := ns.ShiftCounts["a"]; c.Primary != 1 || c.PrimaryHours != 6 * 24 {
+		t.Errorf("expected a to have 1 primary shift of 144 hours, got %+v", c)
+	}
+	if c := ns.ShiftCounts["c"]; c.Primary != 0 || c.PrimaryHours != 24 {
+		t.Errorf("expected c to have 24 primary hours but no shift, got %+v", c)
+	}
+}
+
+func TestValidateFairness(t *testing.T) {
+	s := EmptySchedule()
+	s.Fairness = "random"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), `unknown Fairness "random"`) {
+		t.Errorf("expected an error about the unknown mode, got %v", err)
+	}
+}
+
+func TestWeightsScalePrimaryShifts(t *testing.T) {
+	s := EmptySchedule()
+	s.Fairness = FairnessBalanced
+	s.Weights = map[string]float64{"c": 0.5}
+	s.now = Start
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Regenerating weekly keeps the balance through truncation, as the counts
+	// that drive it are written back into the schedule.
+	s = regenerateWeekly(t, s, 50)
+	a, b, c := s.ShiftCounts["a"].Primary, s.ShiftCounts["b"].Primary, s.ShiftCounts["c"].Primary
+	if a < 19 || a > 20 || b < 19 || b > 20 || c != 10 {
+		t.Errorf("expected c to have half the primary shifts of a and b, got a %d, b %d, c %d", a, b, c)
+	}
+}
+
+func TestValidateWeights(t *testing.T) {
+	for _, c := range []struct {
+		fairness string
+		weights map[string]float64
+		err string
+	}{
+		{FairnessRoundRobin, map[string]float64{"a": 0.5}, "Weights can only be used with Fairness"},
+		{FairnessBalanced, map[string]float64{"a": -1}, "weights must be 0 or more"},
+		{FairnessBalanced, map[string]float64{"z": 1}, "z, who is not among the users to schedule"},
+	} {
+		s := EmptySchedule()
+		s.Fairness, s.Weights = c.fairness, c.weights
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%v: expected error %q, got %v", c.weights, c.err, err)
+		}
+	}
+}
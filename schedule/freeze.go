@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+func (s Schedule) validateFreezes() error {
+	if len(s.Freezes) == 0 {
+		return nil
+	}
+	for _, f := range s.Freezes {
+		if !f.Start.Before(f.End) {
+			return fmt.Errorf("freeze from %s must end after it starts (ends %s)", f.Start.Format(time.RFC3339), f.End.Format(time.RFC3339))
+		}
+	}
+	sorted := sortedPauses(s.Freezes)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Start.Before(sorted[i-1].End) {
+			return fmt.Errorf("freeze from %s overlaps the freeze from %s", sorted[i].Start.Format(time.RFC3339), sorted[i-1].Start.Format(time.RFC3339))
+		}
+	}
+	for _, f := range sorted {
+		for _, other := range []struct {
+			name string
+			ranges []TimeRange
+		}{
+			{"pause", s.Pauses},
+			{"gap", s.Gaps},
+		} {
+			for _, r := range other.ranges {
+				if f.Start.Before(r.End) && f.End.After(r.Start) {
+					return fmt.Errorf("freeze from %s overlaps the %s from %s", f.Start.Format(time.RFC3339), other.name, r.Start.Format(time.RFC3339))
+				}
+			}
+		}
+	}
+	if s.FreezeUser == "" {
+		return nil
+	}
+	primary := s.tiers()[0]
+	for _, o := range s.Overrides {
+		if o.Role != primary {
+			continue
+		}
+		for _, f := range sorted {
+			if o.Start.Before(f.End) && o.End.After(f.Start) {
+				return fmt.Errorf("%s overlaps the freeze from %s, which FreezeUser covers", o, f.Start.Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// Return Freezes in order, with any that abut merged, so that back-to-back
+// freezes are covered as one.
+func mergedFreezes(freezes []TimeRange) []TimeRange {
+	merged := []TimeRange{}
+	for _, f := range sortedPauses(freezes) {
+		if n := len(merged); n > 0 && !f.Start.After(merged[n-1].End) {
+			if f.End.After(merged[n-1].End) {
+				merged[n-1].End = f.End
+			}
+			continue
+		}
+		merged = append(merged, f)
+	}
+	return merged
+}
+
+// Return Overrides along with one putting FreezeUser on the primary tier for
+// each freeze, if FreezeUser is set. The freeze overrides are never written
+// to Overrides, and Generate works out the rotations under them again each
+// time, so they stay the same however often the schedule is regenerated.
+func (s Schedule) effectiveOverrides() []Override {
+	if s.FreezeUser == "" || len(s.Freezes) == 0 {
+		return s.Overrides
+	}
+	overrides := append(s.Overrides[:0:0], s.Overrides...)
+	for _, f := range mergedFreezes(s.Freezes) {
+		overrides = append(overrides, Override{Start: f.Start, End: f.End, Role: s.tiers()[0], User: s.FreezeUser, Force: true})
+	}
+	return overrides
+}
+
+// Whether ranges includes r.
+func containsRange(ranges []TimeRange, r TimeRange) bool {
+	for _, v := range ranges {
+		if v.Start.Equal(r.Start) && v.End.Equal(r.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateFreeze(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Freezes = []TimeRange{{Start: day(10), End: day(17)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b stays on through the freeze, and everyone after them moves back.
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "b", Secondary: "c", Notes: "extended to cover the freeze from 2017-02-10T10:00:00Z to 2017-02-17T10:00:00Z"},
+		{Start: day(22), Primary: "c", Secondary: "a"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+}
+
+func TestGenerateFreezeUser(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.FreezeUser = "z"
+	// Back-to-back freezes are covered as one.
+	s.Freezes = []TimeRange{{Start: day(12), End: day(17)}, {Start: day(10), End: day(12)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b's rotation is split around the freeze, and b finishes their turn
+	// after it.
+	expected := []string{
+		"2017-02-01T10:00:00Z/2017-02-08T10:00:00Z a b",
+		"2017-02-08T10:00:00Z/2017-02-10T10:00:00Z b c",
+		"2017-02-10T10:00:00Z/2017-02-17T10:00:00Z z c",
+		"2017-02-17T10:00:00Z/2017-02-22T10:00:00Z b c",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z c a",
+	}
+	got := []string{}
+	for _, r := range ns.Rotations {
+		got = append(got, r.String())
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("rotations do not match expected\nExpected:\n%s\n---\nGot:\n%s\n", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if ns.Rotations[2].Overridden[TierPrimary] != "b" || !ns.Rotations[3].Continues {
+		t.Errorf("expected z to override b for the freeze only, got %+v", ns.Rotations[2:4])
+	}
+	if len(ns.Overrides) != 0 {
+		t.Errorf("expected the freeze not to be written as an override, got %v", ns.Overrides)
+	}
+
+	// Regenerating during and after the freeze changes nothing.
+	for _, now := range []int{11, 13, 16, 18} {
+		ns.now = day(now)
+		again, err := ns.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range again.Rotations {
+			if r.Start.Before(day(22)) && !containsRotation(ns.Rotations, r) {
+				t.Errorf("regenerating on day %d changed the rotations to %+v", now, again.Rotations)
+				break
+			}
+		}
+	}
+}
+
+func containsRotation(rs []Rotation, r Rotation) bool {
+	for _, v := range rs {
+		if reflect.DeepEqual(v, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateFreezes(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		edit func(s *Schedule)
+		expected string
+	}{
+		{"backwards", func(s *Schedule) {
+			s.Freezes = []TimeRange{{Start: day(10), End: day(10)}}
+		}, "freeze from 2017-02-10T10:00:00Z must end after it starts"},
+		{"overlapping", func(s *Schedule) {
+			s.Freezes = []TimeRange{{Start: day(10), End: day(15)}, {Start: day(12), End: day(20)}}
+		}, "freeze from 2017-02-12T10:00:00Z overlaps the freeze from 2017-02-10T10:00:00Z"},
+		{"pause", func(s *Schedule) {
+			s.Freezes = []TimeRange{{Start: day(10), End: day(15)}}
+			s.Pauses = []TimeRange{{Start: day(14), End: day(16)}}
+		}, "freeze from 2017-02-10T10:00:00Z overlaps the pause from 2017-02-14T10:00:00Z"},
+		{"override", func(s *Schedule) {
+			s.Freezes = []TimeRange{{Start: day(10), End: day(15)}}
+			s.FreezeUser = "c"
+			s.Overrides = []Override{{Start: day(14), End: day(16), Role: TierPrimary, User: "a"}}
+		}, "overlaps the freeze from 2017-02-10T10:00:00Z, which FreezeUser covers"},
+	} {
+		s := EmptySchedule()
+		c.edit(s)
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestFreezeUserAfterEndDate(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.FreezeUser = "a"
+	s.Freezes = []TimeRange{{Start: day(10), End: day(17)}}
+	// c's turn as secondary starts before their end date, and resumes after
+	// the freeze.
+	s.UserEndDates = map[string]time.Time{"c": day(12)}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := false
+	for _, r := range ns.Rotations {
+		resumed = resumed || (r.Start.Equal(day(17)) && r.Secondary == "c")
+	}
+	if !resumed {
+		t.Fatalf("expected c to finish their turn after the freeze, got %v", ns.Rotations)
+	}
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSchedule(text); err != nil {
+		t.Errorf("expected the generated schedule to load, got %v", err)
+	}
+}
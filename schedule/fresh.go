@@ -0,0 +1,75 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GenerateFresh is GenerateWithDiff, but first discards the rotations after
+// the one under way, and schedules the rest of the horizon afresh with the
+// round-robin started over from Users (or PrimaryUsers and SecondaryUsers) as
+// written, e.g. after the team has been reshuffled. The rotation under way is
+// kept exactly as it is. If start is not zero, every rotation is discarded
+// instead and the schedule starts over at start.
+//
+// Generate never discards a rotation that has not ended, so a schedule can
+// only be regenerated afresh by calling GenerateFresh. The rotations discarded
+// are listed in Discarded of the Diff returned, and the rotations in their
+// place in Added. Rotations that are pinned, and schedules with Windows or
+// Regions, which keep their own orders, cannot be regenerated afresh.
+func (s *Schedule) GenerateFresh(start time.Time) (*Schedule, Diff, error) {
+	ns, err := s.generate(time.Time{}, &start)
+	if err != nil {
+		return nil, Diff{}, err
+	}
+	started := s.startedBy(ns.now)
+	head := *s
+	head.Rotations = s.Rotations[:started]
+	d := NewDiff(&head, ns)
+	d.Discarded = s.Rotations[started:]
+	return ns, d, nil
+}
+
+// Return how many of the rotations of s have started by t, including the
+// rest of the rotation under way, which an override may have split.
+func (s Schedule) startedBy(t time.Time) int {
+	n := sort.Search(len(s.Rotations), func(i int) bool {
+		return s.Rotations[i].Start.After(t)
+	})
+	for n < len(s.Rotations) && s.Rotations[n].Continues {
+		n++
+	}
+	return n
+}
+
+// Return s with the rotations after the one under way discarded, or if start
+// is not zero, with every rotation discarded and Start set to start. The
+// shifts in rotations discarded that have started are counted in
+// ns.ShiftCounts and History as if they had been truncated.
+func (ns *Schedule) discardFuture(s *Schedule, start time.Time) (*Schedule, error) {
+	if len(s.Windows) > 0 || len(s.Regions) > 0 {
+		return nil, fmt.Errorf("cannot generate a schedule with Windows or Regions afresh")
+	}
+	started := s.startedBy(ns.now)
+	for _, r := range s.Rotations[started:] {
+		if r.Pinned {
+			return nil, fmt.Errorf("cannot generate afresh over the pinned rotation starting at %s; unpin or remove it first", r.Start.Format(time.RFC3339))
+		}
+	}
+	head := *s
+	head.Rotations = s.Rotations[:started]
+	// The secondary's turns start over along with its round-robin.
+	ns.SecondaryStart = time.Time{}
+	if start.IsZero() {
+		if started == 0 && len(s.Rotations) > 0 {
+			head.Start = s.Rotations[0].Start
+		}
+		return &head, nil
+	}
+	ns.dropCounting(&head, nil)
+	ns.archive(&head, nil)
+	head.History = ns.History
+	head.Start, head.Rotations = start, nil
+	return &head, nil
+}
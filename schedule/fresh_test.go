@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateFresh(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	// The team is reshuffled, so c is to be followed by a rather than b.
+	filled.Users = []string{"a", "c", "b"}
+	ns, d, err := filled.GenerateFresh(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := filled.Rotations[2]
+	var kept *Rotation
+	for i, r := range ns.Rotations {
+		if r.Start.Equal(active.Start) {
+			kept = &ns.Rotations[i]
+		}
+	}
+	if kept == nil || kept.Primary != active.Primary || kept.Secondary != active.Secondary || !kept.End.Equal(active.End) {
+		t.Fatalf("expected the rotation under way to be kept as it was, got %v", ns.Rotations)
+	}
+	var primaries []string
+	for _, r := range ns.Rotations {
+		primaries = append(primaries, r.Primary)
+	}
+	if got := strings.Join(primaries, " "); got != "b c a c b a" {
+		t.Errorf("expected the round-robin to start over from Users after the rotation under way, got %s", got)
+	}
+	if len(d.Discarded) != 1 || !d.Discarded[0].Start.Equal(filled.Rotations[3].Start) {
+		t.Errorf("expected the rotation after the one under way to be discarded, got %v", d.Discarded)
+	}
+	if len(d.Reassigned) != 0 {
+		t.Errorf("expected nothing kept to be reassigned, got %+v", d.Reassigned)
+	}
+	expected := `Discarded 1 rotation that had not started, to schedule afresh:
+  2017-02-22T10:00:00Z/2017-03-01T10:00:00Z a b
+Truncated 1 rotation:
+  2017-02-01T10:00:00Z/2017-02-08T10:00:00Z a b
+Added 4 rotations:
+  2017-02-22T10:00:00Z/2017-03-01T10:00:00Z a c
+  2017-03-01T10:00:00Z/2017-03-08T10:00:00Z c b
+  2017-03-08T10:00:00Z/2017-03-15T10:00:00Z b a
+  2017-03-15T10:00:00Z/2017-03-22T10:00:00Z a c
+`
+	if d.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, d.String())
+	}
+
+	// Generating again carries on from the fresh order, discarding nothing.
+	again, d, err := ns.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Discarded) != 0 || len(again.Rotations) != len(ns.Rotations) {
+		t.Errorf("expected regenerating to keep the fresh rotations, got:\n%s", d)
+	}
+}
+
+func TestGenerateFreshKeepsActiveRotation(t *testing.T) {
+	for _, now := range []time.Time{
+		Start,
+		Start.Add(time.Hour),
+		day(10),
+		day(20),
+	} {
+		s := EmptySchedule()
+		s.now = Start
+		s, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.now = now
+		s.Users = []string{"c", "b", "a"}
+		ns, _, err := s.GenerateFresh(time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		active := s.Rotations[s.startedBy(now)-1]
+		found := false
+		for _, r := range ns.Rotations {
+			if r.Start.Equal(active.Start) {
+				found = true
+				if r.Primary != active.Primary || r.Secondary != active.Secondary {
+					t.Errorf("at %s, expected the rotation under way to keep %s and %s, got %s and %s", now, active.Primary, active.Secondary, r.Primary, r.Secondary)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("at %s, expected the rotation under way to be kept, got %v", now, ns.Rotations)
+		}
+	}
+}
+
+func TestGenerateFreshStartOver(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	restart := time.Date(2017, time.February, 20, 10, 0, 0, 0, time.UTC)
+	ns, d, err := filled.GenerateFresh(restart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ns.Rotations[0].Start.Equal(restart) || ns.Rotations[0].Primary != "b" {
+		t.Errorf("expected the schedule to start over at %s with b, got %v", restart, ns.Rotations)
+	}
+	if len(d.Discarded) != 1 || len(d.Truncated) != 3 {
+		t.Errorf("expected 1 rotation discarded and 3 truncated, got:\n%s", d)
+	}
+	if ns.ShiftCounts["c"].Primary != 1 {
+		t.Errorf("expected the rotations started to be counted, got %+v", ns.ShiftCounts)
+	}
+}
+
+func TestGenerateFreshErrors(t *testing.T) {
+	pinned := FilledSchedule()
+	pinned.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	pinned.Rotations[3].Pinned = true
+	if _, _, err := pinned.GenerateFresh(time.Time{}); err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("expected an error discarding a pinned rotation, got %v", err)
+	}
+
+	windows := WindowsSchedule(t)
+	if _, _, err := windows.GenerateFresh(time.Time{}); err == nil || !strings.Contains(err.Error(), "Windows") {
+		t.Errorf("expected an error with Windows, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// The note recorded on every rotation covering a gap.
+const unstaffedNote = "nobody is on call during the gap"
+
+func (s Schedule) validateGaps() error {
+	if len(s.Gaps) == 0 {
+		return nil
+	}
+	sorted := sortedPauses(s.Gaps)
+	for i, g := range sorted {
+		if !g.Start.Before(g.End) {
+			return fmt.Errorf("gap from %s must end after it starts (ends %s)", g.Start.Format(time.RFC3339), g.End.Format(time.RFC3339))
+		}
+		if i > 0 && g.Start.Before(sorted[i-1].End) {
+			return fmt.Errorf("gap from %s overlaps the gap from %s", g.Start.Format(time.RFC3339), sorted[i-1].Start.Format(time.RFC3339))
+		}
+	}
+	for _, o := range s.Overrides {
+		for _, g := range sorted {
+			if o.Start.Before(g.End) && o.End.After(g.Start) {
+				return fmt.Errorf("%s falls in the gap from %s to %s, when nobody is on call; shorten the gap instead", o, g.Start.Format(time.RFC3339), g.End.Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// Generate refuses gaps that leave nobody on call for the whole of the time
+// it would schedule, from now to end, since the schedule would then say
+// nothing at all. This depends on the clock and so is checked separately from
+// Validate.
+func checkGapsLeaveStaffed(gaps []TimeRange, now, end time.Time) error {
+	covered := now
+	for _, g := range sortedPauses(gaps) {
+		if !g.Start.After(covered) && g.End.After(covered) {
+			covered = g.End
+		}
+	}
+	if len(gaps) > 0 && !covered.Before(end) {
+		return fmt.Errorf("Gaps leave nobody on call from %s to %s, the whole time being scheduled; end a gap sooner or schedule further ahead", now.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Return the gap that t falls in, or nil if it is in none.
+func (s Schedule) gapAt(t time.Time) *TimeRange {
+	for i, g := range s.Gaps {
+		if !t.Before(g.Start) && t.Before(g.End) {
+			return &s.Gaps[i]
+		}
+	}
+	return nil
+}
+
+// Return the first gap starting after start and before end, which cuts short
+// a rotation from start to end, or nil if there is none.
+func (s Schedule) gapDuring(start, end time.Time) *TimeRange {
+	var first *TimeRange
+	for i, g := range s.Gaps {
+		if g.Start.After(start) && g.Start.Before(end) && (first == nil || g.Start.Before(first.Start)) {
+			first = &s.Gaps[i]
+		}
+	}
+	return first
+}
+
+// Return end, the end of a rotation starting at start, cut short to where a
+// gap begins, and the note explaining why, which is "" if it was not cut
+// short. A rotation starting in a gap lasts until the gap ends.
+func (s Schedule) endForGaps(start, end time.Time) (time.Time, string) {
+	if g := s.gapAt(start); g != nil {
+		return g.End, ""
+	}
+	if g := s.gapDuring(start, end); g != nil {
+		return g.Start, fmt.Sprintf("shortened to end as the gap starting at %s begins", g.Start.Format(time.RFC3339))
+	}
+	return end, ""
+}
+
+// The note recorded on a rotation starting at start that explains why it is
+// shorter than RotationLength, or "" if it is not.
+func (s Schedule) gapNote(start time.Time) string {
+	if len(s.Gaps) == 0 {
+		return ""
+	}
+	_, note := s.endForGaps(start, s.ungappedEnd(start))
+	return note
+}
+
+// Add the rotation covering the gap that s.Start falls in, with nobody on any
+// tier. The round-robin does not advance past it.
+func (s *Schedule) addUnstaffed(end time.Time) {
+	s.Rotations = append(s.Rotations, Rotation{Start: s.Start, End: end, Notes: unstaffedNote, Unstaffed: true})
+	s.Start = end
+}
@@ -0,0 +1,113 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGap(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Gaps = []TimeRange{{Start: day(10), End: day(17)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b's rotation ends as the gap starts, and c takes the next turn after it,
+	// as though the gap were not there.
+	expected := withEnds(day(31), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "b", Secondary: "c", Notes: "shortened to end as the gap starting at 2017-02-10T10:00:00Z begins"},
+		{Start: day(10), Notes: unstaffedNote, Unstaffed: true},
+		{Start: day(17), Primary: "c", Secondary: "a"},
+		{Start: day(24), Primary: "a", Secondary: "b"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	if !reflect.DeepEqual([]string{"b", "c", "a"}, ns.Users) {
+		t.Errorf("expected b to be primary next, got %v", ns.Users)
+	}
+
+	r, err := ns.At(day(12))
+	if err != nil || !r.Unstaffed || len(ns.Assigned(r)) != 2 || ns.Assigned(r)[0] != "" {
+		t.Errorf("expected the gap to be unstaffed, got %+v (%v)", r, err)
+	}
+	if !strings.HasSuffix(r.String(), " unstaffed") {
+		t.Errorf("expected %q to say it is unstaffed", r)
+	}
+}
+
+func TestGenerateGapElapsed(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Gaps = []TimeRange{{Start: day(29), End: day(36)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Regenerating once the gap, beyond the rotations generated, has passed
+	// does not count it as anybody's turn.
+	ns.now = day(40)
+	ns, err = ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := EmptySchedule()
+	again.now = Start
+	again.Gaps = s.Gaps
+	again.ScheduleForDuration = 10 * Week
+	want, err := again.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ns.At(day(40))
+	expected, _ := want.At(day(40))
+	if got.Primary != expected.Primary {
+		t.Errorf("expected %s to be primary after the gap, as when scheduled ahead, got %s", expected.Primary, got.Primary)
+	}
+}
+
+func TestValidateGaps(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		edit func(s *Schedule)
+		expected string
+	}{
+		{"backwards", func(s *Schedule) {
+			s.Gaps = []TimeRange{{Start: day(10), End: day(9)}}
+		}, "gap from 2017-02-10T10:00:00Z must end after it starts"},
+		{"overlapping", func(s *Schedule) {
+			s.Gaps = []TimeRange{{Start: day(10), End: day(15)}, {Start: day(12), End: day(20)}}
+		}, "gap from 2017-02-12T10:00:00Z overlaps the gap from 2017-02-10T10:00:00Z"},
+		{"override", func(s *Schedule) {
+			s.Gaps = []TimeRange{{Start: day(10), End: day(15)}}
+			s.Overrides = []Override{{Start: day(14), End: day(16), Role: TierPrimary, User: "a"}}
+		}, "falls in the gap from 2017-02-10T10:00:00Z to 2017-02-15T10:00:00Z"},
+		{"windows", func(s *Schedule) {
+			s.RotationLength, s.RotationDuration = "", 0
+			s.Windows = []WeekWindow{{Name: "all", Start: "Monday 09:00", End: "Monday 09:00", Users: []string{"a", "b"}}}
+			s.Gaps = []TimeRange{{Start: day(10), End: day(15)}}
+		}, "Gaps cannot be used with Windows"},
+	} {
+		s := EmptySchedule()
+		c.edit(s)
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestGenerateRefusesGapOverEverything(t *testing.T) {
+	s := EmptySchedule()
+	s.now = day(5)
+	s.Gaps = []TimeRange{{Start: day(1), End: day(20)}, {Start: day(20), End: day(40)}}
+	if _, err := s.Generate(); err == nil || !strings.Contains(err.Error(), "leave nobody on call from 2017-02-05T10:00:00Z to 2017-02-26T10:00:00Z") {
+		t.Errorf("expected an error for gaps covering the whole schedule, got %v", err)
+	}
+	s.Gaps = s.Gaps[:1]
+	if _, err := s.Generate(); err != nil {
+		t.Errorf("expected a gap ending before the horizon to be allowed, got %v", err)
+	}
+}
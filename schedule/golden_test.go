@@ -0,0 +1,15 @@
+package schedule_test
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/websdev/oncallator/schedule/goldentest"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGolden(t *testing.T) {
+	goldentest.Run(t, filepath.Join("testdata", "golden"), *update)
+}
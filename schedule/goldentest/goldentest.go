@@ -0,0 +1,208 @@
+// Package goldentest runs directory-driven golden tests of schedule
+// generation. Each subdirectory of a suite is one case containing:
+//
+//	input.json      the schedule to generate from
+//	now.txt         the RFC3339 time to generate at
+//	mutations.txt   optional edits to apply to the input before generating
+//	expected.json   the expected generated schedule, or
+//	error.txt       the expected error if generation should fail
+//	warnings.txt    optional expected Lint suggestions, one per line
+//
+// Mutations are applied in order, one per line. Blank lines and lines starting
+// with # are ignored. The supported commands are:
+//
+//	set INDEX primary|secondary USER   hand edit Rotations[INDEX]
+//	end-date USER RFC3339              set USER's end date
+//
+// The package is exported so that users embedding oncallator can keep golden
+// tests against their own schedules in the same style.
+package goldentest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const (
+	InputFile = "input.json"
+	NowFile = "now.txt"
+	MutationsFile = "mutations.txt"
+	ExpectedFile = "expected.json"
+	ErrorFile = "error.txt"
+	WarningsFile = "warnings.txt"
+)
+
+// Run runs every case in dir as a subtest of t. If update is set, the expected
+// files are rewritten from the actual results instead of compared with them.
+func Run(t *testing.T, dir string, update bool) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(dir, e.Name())
+		t.Run(e.Name(), func(t *testing.T) {
+			RunCase(t, caseDir, update)
+		})
+	}
+}
+
+// RunCase runs the single case in dir.
+func RunCase(t *testing.T, dir string, update bool) {
+	output, errText, warnings, err := Generate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if update {
+		os.Remove(filepath.Join(dir, ExpectedFile))
+		os.Remove(filepath.Join(dir, ErrorFile))
+		os.Remove(filepath.Join(dir, WarningsFile))
+		if errText != "" {
+			writeFile(t, filepath.Join(dir, ErrorFile), []byte(errText))
+		} else {
+			writeFile(t, filepath.Join(dir, ExpectedFile), output)
+		}
+		if warnings != "" {
+			writeFile(t, filepath.Join(dir, WarningsFile), []byte(warnings))
+		}
+		return
+	}
+
+	if errText != "" {
+		compare(t, filepath.Join(dir, ErrorFile), []byte(errText))
+	} else {
+		compare(t, filepath.Join(dir, ExpectedFile), output)
+	}
+	compare(t, filepath.Join(dir, WarningsFile), []byte(warnings))
+}
+
+// Generate runs the case in dir and returns the generated schedule, the
+// generation error text if it failed, and the Lint suggestions, formatted as
+// they are stored in the case's expected files. The returned error is only for
+// problems with the case itself, such as a malformed input or mutation.
+func Generate(dir string) ([]byte, string, string, error) {
+	input, err := ioutil.ReadFile(filepath.Join(dir, InputFile))
+	if err != nil {
+		return nil, "", "", err
+	}
+	s, err := schedule.NewSchedule(input)
+	if err != nil {
+		return nil, "", "", err
+	}
+	nowText, err := ioutil.ReadFile(filepath.Join(dir, NowFile))
+	if err != nil {
+		return nil, "", "", err
+	}
+	now, err := time.Parse(time.RFC3339, strings.TrimSpace(string(nowText)))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("error parsing %s: %s", NowFile, err)
+	}
+	s.SetNow(now)
+	if err := applyMutations(s, filepath.Join(dir, MutationsFile)); err != nil {
+		return nil, "", "", err
+	}
+
+	ns, err := s.Generate()
+	if err != nil {
+		return nil, err.Error() + "\n", "", nil
+	}
+	output, err := json.MarshalIndent(ns, "", "  ")
+	if err != nil {
+		return nil, "", "", err
+	}
+	output = append(output, '\n')
+	warnings := ""
+	for _, w := range ns.Lint() {
+		warnings += w + "\n"
+	}
+	return output, "", warnings, nil
+}
+
+func applyMutations(s *schedule.Schedule, path string) error {
+	text, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := applyMutation(s, fields); err != nil {
+			return fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func applyMutation(s *schedule.Schedule, fields []string) error {
+	switch fields[0] {
+	case "set":
+		if len(fields) != 4 {
+			return fmt.Errorf("usage: set INDEX primary|secondary USER")
+		}
+		i, err := strconv.Atoi(fields[1])
+		if err != nil || i < 0 || i >= len(s.Rotations) {
+			return fmt.Errorf("no rotation at index %s", fields[1])
+		}
+		switch fields[2] {
+		case schedule.TierPrimary:
+			s.Rotations[i].Primary = fields[3]
+		case schedule.TierSecondary:
+			s.Rotations[i].Secondary = fields[3]
+		default:
+			return fmt.Errorf("unknown tier %s", fields[2])
+		}
+	case "end-date":
+		if len(fields) != 3 {
+			return fmt.Errorf("usage: end-date USER RFC3339")
+		}
+		end, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return err
+		}
+		if s.UserEndDates == nil {
+			s.UserEndDates = map[string]time.Time{}
+		}
+		s.UserEndDates[fields[1]] = end
+	default:
+		return fmt.Errorf("unknown mutation %s", fields[0])
+	}
+	return nil
+}
+
+func compare(t *testing.T, path string, actual []byte) {
+	expected, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		expected = []byte{}
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("%s does not match\nExpected:\n%s\n---\nGot:\n%s\n", path, expected, actual)
+	}
+}
+
+func writeFile(t *testing.T, path string, text []byte) {
+	if err := ioutil.WriteFile(path, text, 0660); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"time"
+)
+
+// DefaultHandoffGrace is used when a schedule does not set HandoffGrace.
+const DefaultHandoffGrace = time.Hour
+
+const (
+	TierPrimary = "primary"
+	TierSecondary = "secondary"
+)
+
+// A HandoffEvent describes responsibility for a tier passing from one user to
+// another. Notifiers should message both Outgoing and Incoming, either of which
+// is empty if the tier was or becomes unfilled.
+type HandoffEvent struct {
+	Tier string
+	Outgoing string
+	Incoming string
+	At time.Time
+}
+
+// HandoffNotifications returns the handoffs occurring within HandoffGrace of
+// now, in the order they occur, with the handoffs for the same rotation
+// ordered by tier, lowest first. A tier whose user does not change across a
+// rotation boundary has no handoff.
+func (s *Schedule) HandoffNotifications(now time.Time) []HandoffEvent {
+	events := []HandoffEvent{}
+	for i := 1; i < len(s.Rotations); i++ {
+		prev, next := s.Rotations[i-1], s.Rotations[i]
+		if d := next.Start.Sub(now); d > s.HandoffGraceDuration || d < -s.HandoffGraceDuration {
+			continue
+		}
+		for _, tier := range s.tiers() {
+			if outgoing, incoming := s.tierUser(prev, tier), s.tierUser(next, tier); outgoing != incoming {
+				events = append(events, HandoffEvent{
+					Tier: tier,
+					Outgoing: outgoing,
+					Incoming: incoming,
+					At: next.Start,
+				})
+			}
+		}
+	}
+	return events
+}
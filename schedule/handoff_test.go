@@ -0,0 +1,43 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandoffNotifications(t *testing.T) {
+	s := FilledSchedule()
+	handoff := time.Date(2017, time.February, 8, 10, 0, 0, 0, time.UTC)
+	expected := []HandoffEvent{
+		{Tier: TierPrimary, Outgoing: "a", Incoming: "b", At: handoff},
+		{Tier: TierSecondary, Outgoing: "b", Incoming: "c", At: handoff},
+	}
+	for _, now := range []time.Time{
+		handoff,
+		handoff.Add(-DefaultHandoffGrace),
+		handoff.Add(DefaultHandoffGrace),
+	} {
+		if events := s.HandoffNotifications(now); !reflect.DeepEqual(expected, events) {
+			t.Errorf("handoffs at %s do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", now, expected, events)
+		}
+	}
+}
+
+func TestHandoffNotificationsOutsideGrace(t *testing.T) {
+	s := FilledSchedule()
+	now := time.Date(2017, time.February, 8, 10, 0, 0, 0, time.UTC).Add(DefaultHandoffGrace + time.Minute)
+	if events := s.HandoffNotifications(now); len(events) != 0 {
+		t.Errorf("expected no handoffs at %s, got %+v", now, events)
+	}
+}
+
+func TestHandoffNotificationsConfigurableGrace(t *testing.T) {
+	s := FilledSchedule()
+	s.HandoffGraceDuration = 24 * time.Hour
+	now := time.Date(2017, time.February, 7, 12, 0, 0, 0, time.UTC)
+	events := s.HandoffNotifications(now)
+	if len(events) != 2 || events[0].Outgoing != "a" || events[0].Incoming != "b" {
+		t.Errorf("expected primary handoff from a to b, got %+v", events)
+	}
+}
@@ -0,0 +1,127 @@
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var handoffTimeFormat = regexp.MustCompile(`^([01][0-9]|2[0-3]):([0-5][0-9])$`)
+
+// Parse a HandoffTime into hours and minutes.
+func parseHandoffTime(text string) (int, int, error) {
+	m := handoffTimeFormat.FindStringSubmatch(text)
+	if m == nil {
+		return 0, 0, fmt.Errorf("error parsing HandoffTime: must be HH:MM on a 24-hour clock (got %q)", text)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	return hour, min, nil
+}
+
+// Return the first HandoffTime at or after t, in the schedule's Timezone (or
+// t's location, without one). If HandoffTime is not set, t is returned as is.
+func (s Schedule) snapToHandoff(t time.Time) time.Time {
+	if s.HandoffTime == "" {
+		return t
+	}
+	hour, min, err := parseHandoffTime(s.HandoffTime)
+	if err != nil {
+		// Validate rejects this before any rotation is computed.
+		return t
+	}
+	loc := s.location
+	if loc == nil {
+		loc = t.Location()
+	}
+	local := t.In(loc)
+	snapped := wallClock(local.Year(), local.Month(), local.Day(), hour, min, loc)
+	if snapped.Before(t) {
+		snapped = wallClock(local.Year(), local.Month(), local.Day() + 1, hour, min, loc)
+	}
+	return snapped
+}
+
+// Return the instants at which the wall clock in loc reads the given time on
+// the given day: none if a daylight saving change skips it, two if one repeats
+// it, and otherwise one.
+func wallClockInstants(year int, month time.Month, day, hour, min int, loc *time.Location) []time.Time {
+	naive := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	instants := []time.Time{}
+	seen := map[int]bool{}
+	// Any offset in force around the wall time is in force a day either side
+	// of it, as no zone changes offset twice in a day.
+	for _, near := range []time.Time{naive.Add(-Day), naive.Add(Day)} {
+		_, offset := near.In(loc).Zone()
+		if seen[offset] {
+			continue
+		}
+		seen[offset] = true
+		t := naive.Add(-time.Duration(offset) * time.Second).In(loc)
+		if _, o := t.Zone(); o == offset {
+			instants = append(instants, t)
+		}
+	}
+	if len(instants) == 2 && instants[1].Before(instants[0]) {
+		instants[0], instants[1] = instants[1], instants[0]
+	}
+	return instants
+}
+
+// Return the instant a handoff at the given wall-clock time on the given day
+// happens. Go leaves the choice unspecified for times a daylight saving change
+// skips or repeats, so it is made explicit here: a skipped time is moved
+// forward by the length of the gap (e.g. 02:30 becomes 03:30), and a repeated
+// time is its first occurrence, before the clocks go back.
+func wallClock(year int, month time.Month, day, hour, min int, loc *time.Location) time.Time {
+	if instants := wallClockInstants(year, month, day, hour, min, loc); len(instants) > 0 {
+		return instants[0]
+	}
+	naive := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	_, before := naive.Add(-Day).In(loc).Zone()
+	return naive.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+// Return a warning if, within a year of now, a daylight saving change skips or
+// repeats HandoffTime, since handoffs at such times surprise people. The
+// warning suggests the nearest later time on the same minute that is clear of
+// every such change.
+func (s Schedule) handoffTimeDSTWarning(now time.Time) string {
+	if s.HandoffTime == "" {
+		return ""
+	}
+	hour, min, err := parseHandoffTime(s.HandoffTime)
+	if err != nil {
+		return ""
+	}
+	loc := s.location
+	if loc == nil {
+		loc = s.Start.Location()
+	}
+	clear := func(hour int) (time.Time, int) {
+		local := now.In(loc)
+		for d := 0; d <= 366; d++ {
+			day := time.Date(local.Year(), local.Month(), local.Day() + d, 0, 0, 0, 0, time.UTC)
+			if n := len(wallClockInstants(day.Year(), day.Month(), day.Day(), hour, min, loc)); n != 1 {
+				return day, n
+			}
+		}
+		return time.Time{}, 1
+	}
+	day, n := clear(hour)
+	if n == 1 {
+		return ""
+	}
+	problem := "skips"
+	if n == 2 {
+		problem = "repeats"
+	}
+	warning := fmt.Sprintf("the daylight saving change on %s %s HandoffTime %s in %s, so which rotation covers the hour around it is easy to misread", day.Format("2006-01-02"), problem, s.HandoffTime, loc)
+	for h := 1; h < 24; h++ {
+		if _, n := clear((hour + h) % 24); n == 1 {
+			return fmt.Sprintf("%s; consider handing off at %02d:%02d instead", warning, (hour + h) % 24, min)
+		}
+	}
+	return warning
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateHandoffTime(t *testing.T) {
+	for _, text := range []string{"9:30", "24:00", "09:60", "09:30:00", "noon"} {
+		s := EmptySchedule()
+		s.HandoffTime = text
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "HH:MM") {
+			t.Errorf("%q: expected an error describing HH:MM, got %v", text, err)
+		}
+	}
+}
+
+func TestHandoffTimeSnapsForward(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2017, time.February, day, hour, min, 0, 0, time.UTC)
+	}
+	for _, c := range []struct {
+		name string
+		start time.Time
+		length time.Duration
+		expected []time.Time
+	}{
+		{"already at the handoff time", at(1, 9, 30), Week, []time.Time{at(1, 9, 30), at(8, 9, 30)}},
+		{"before the handoff time", at(1, 8, 0), Week, []time.Time{at(1, 9, 30), at(8, 9, 30)}},
+		{"after the handoff time", at(1, 10, 0), Week, []time.Time{at(2, 9, 30), at(9, 9, 30)}},
+		{"midnight", at(1, 0, 0), Week, []time.Time{at(1, 9, 30), at(8, 9, 30)}},
+		// A 36h rotation ends at 21:30 the next day, and is rounded up to the
+		// following morning's handoff.
+		{"36h rotation", at(1, 9, 30), 36 * time.Hour, []time.Time{at(1, 9, 30), at(3, 9, 30), at(5, 9, 30)}},
+	} {
+		s := EmptySchedule()
+		s.now = c.start
+		s.Start = c.start
+		s.RotationDuration = c.length
+		s.ScheduleForDuration = time.Duration(len(c.expected) - 1) * c.length
+		s.HandoffTime = "09:30"
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range c.expected {
+			if !ns.Rotations[i].Start.Equal(e) {
+				t.Errorf("%s: expected rotation %d to start at %s, got %s", c.name, i, e.Format(time.RFC3339), ns.Rotations[i].Start.Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+// A hand-edited rotation starting at midnight ends at midnight, and the next
+// rotation starts at the handoff time later that morning, not the one before.
+func TestHandoffTimeSnapsMidnightBoundaryForward(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC)
+	s.Rotations[3].Start = time.Date(2017, time.February, 22, 0, 0, 0, 0, time.UTC)
+	s.Rotations[2].End = s.Rotations[3].Start
+	s.HandoffTime = "09:30"
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2017, time.March, 1, 9, 30, 0, 0, time.UTC)
+	if next := ns.Rotations[covering(ns, s.Rotations[3].Start) + 1]; !next.Start.Equal(expected) {
+		t.Errorf("expected the next rotation to start at %s, got %s", expected.Format(time.RFC3339), next)
+	}
+}
+
+// Handoffs at times that daylight saving changes skip or repeat resolve to a
+// single, documented instant, and a rotation covers the absolute time up to the
+// next one.
+func TestHandoffTimeDST(t *testing.T) {
+	for _, c := range []struct {
+		zone string
+		// The day of the change, and the handoff time.
+		day string
+		handoff string
+		// The instant the handoff resolves to on that day.
+		expected string
+	}{
+		// Clocks skip 02:00-03:00: handoffs move forward by an hour.
+		{"America/Los_Angeles", "2017-03-12", "02:00", "2017-03-12T03:00:00-07:00"},
+		{"America/Los_Angeles", "2017-03-12", "02:30", "2017-03-12T03:30:00-07:00"},
+		{"Europe/Warsaw", "2017-03-26", "02:00", "2017-03-26T03:00:00+02:00"},
+		{"Europe/Warsaw", "2017-03-26", "02:30", "2017-03-26T03:30:00+02:00"},
+		// Los Angeles repeats 01:00-02:00, so these are unaffected.
+		{"America/Los_Angeles", "2017-11-05", "02:00", "2017-11-05T02:00:00-08:00"},
+		{"America/Los_Angeles", "2017-11-05", "02:30", "2017-11-05T02:30:00-08:00"},
+		// Warsaw repeats 02:00-03:00: handoffs happen the first time round.
+		{"Europe/Warsaw", "2017-10-29", "02:00", "2017-10-29T02:00:00+02:00"},
+		{"Europe/Warsaw", "2017-10-29", "02:30", "2017-10-29T02:30:00+02:00"},
+	} {
+		name := c.zone + " " + c.day + " " + c.handoff
+		loc, err := time.LoadLocation(c.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		day, err := time.ParseInLocation("2006-01-02", c.day, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := EmptySchedule()
+		s.Timezone = c.zone
+		s.location = loc
+		s.HandoffTime = c.handoff
+		s.RotationDuration = Day
+		s.ScheduleForDuration = 2 * Day
+		s.Start = day.AddDate(0, 0, -1)
+		s.now = s.Start
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		handoff := ns.Rotations[1]
+		if got := handoff.Start.Format(time.RFC3339); got != c.expected {
+			t.Errorf("%s: expected the handoff at %s, got %s", name, c.expected, got)
+		}
+		// The day after, the handoff is back at the configured time.
+		if got := ns.Rotations[2].Start.In(loc).Format("15:04"); got != c.handoff {
+			t.Errorf("%s: expected the next handoff at %s, got %s", name, c.handoff, got)
+		}
+		// Pages just either side of the handoff instant belong to the
+		// outgoing and incoming rotations.
+		if covering(ns, handoff.Start.Add(-time.Minute)) != 0 || covering(ns, handoff.Start) != 1 || covering(ns, handoff.Start.Add(59 * time.Minute)) != 1 {
+			t.Errorf("%s: rotations do not cover absolute time either side of the handoff", name)
+		}
+
+		s.now = day.AddDate(0, -1, 0)
+		warned := false
+		for _, l := range s.Lint() {
+			warned = warned || strings.Contains(l, "HandoffTime " + c.handoff)
+		}
+		if !warned {
+			t.Errorf("%s: expected a warning about the handoff time, got %v", name, s.Lint())
+		}
+	}
+}
+
+func TestHandoffTimeDSTSuggestsSaferTime(t *testing.T) {
+	s := EmptySchedule()
+	s.Timezone = "Europe/Warsaw"
+	s.location, _ = time.LoadLocation(s.Timezone)
+	s.HandoffTime = "02:30"
+	s.now = time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	lint := strings.Join(s.Lint(), "\n")
+	if !strings.Contains(lint, "2017-03-26 skips HandoffTime 02:30") || !strings.Contains(lint, "consider handing off at 03:30 instead") {
+		t.Errorf("expected a warning suggesting 03:30, got %s", lint)
+	}
+
+	s.HandoffTime = "09:00"
+	if lint := s.Lint(); len(lint) != 0 {
+		t.Errorf("expected no warnings for 09:00, got %v", lint)
+	}
+}
+
+// Return the position of the rotation in s covering t, or -1.
+func covering(s *Schedule, t time.Time) int {
+	for i, r := range s.Rotations {
+		if !t.Before(r.Start) && t.Before(s.RotationEnd(i)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// An AtOption changes which rotations At looks in.
+type AtOption int
+
+const (
+	// Also look in History, so that times before the first rotation resolve
+	// to whoever was on call then.
+	SearchHistory AtOption = iota + 1
+)
+
+func (s Schedule) validateHistory() error {
+	if s.HistoryForDuration < 0 {
+		return fmt.Errorf("cannot have negative HistoryFor (got %s)", s.HistoryForDuration)
+	}
+	if s.HistoryMax < 0 {
+		return fmt.Errorf("cannot have negative HistoryMax (got %d)", s.HistoryMax)
+	}
+	return nil
+}
+
+// Carry History over from s to ns. If KeepHistory is set, the rotations of s
+// before kept, a suffix of them without overrides, are added to it as they
+// were actually worked: with overrides applied and their End filled in.
+// Rotations already in History are not added again. History is then trimmed
+// to HistoryFor and HistoryMax.
+func (ns *Schedule) archive(s *Schedule, kept []Rotation) {
+	ns.History = s.History
+	if !ns.KeepHistory {
+		return
+	}
+	history := append([]Rotation{}, s.History...)
+	for i, r := range s.Rotations {
+		if len(kept) > 0 && !r.Start.Before(kept[0].Start) {
+			break
+		}
+		if len(history) > 0 && !r.Start.After(history[len(history)-1].Start) {
+			continue
+		}
+		r.End = s.RotationEnd(i)
+		history = append(history, r)
+	}
+	if ns.HistoryForDuration > 0 {
+		cutoff := ns.now.Add(-ns.HistoryForDuration)
+		i := sort.Search(len(history), func(i int) bool {
+			return history[i].End.After(cutoff)
+		})
+		history = history[i:]
+	}
+	if ns.HistoryMax > 0 && len(history) > ns.HistoryMax {
+		history = history[len(history) - ns.HistoryMax:]
+	}
+	if len(history) == 0 {
+		history = nil
+	}
+	ns.History = history
+}
+
+// Return the rotation of History covering t.
+func (s Schedule) historyAt(t time.Time) (Rotation, bool) {
+	i := sort.Search(len(s.History), func(i int) bool {
+		return s.History[i].Start.After(t)
+	}) - 1
+	if i < 0 || !t.Before(s.History[i].End) {
+		return Rotation{}, false
+	}
+	return s.History[i], true
+}
@@ -0,0 +1,86 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistorySurvivesRegeneration(t *testing.T) {
+	s := EmptySchedule()
+	s.KeepHistory = true
+	s.now = Start
+	s.Overrides = []Override{{Start: day(1), End: day(2), Role: TierPrimary, User: "c"}}
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = regenerateWeekly(t, s, 6)
+	// Each generation keeps the rotation before the current one, so five of
+	// the first seven rotations have been archived, each once, and the first
+	// as the two segments the override split it into.
+	if len(s.History) != 6 {
+		t.Fatalf("expected 6 rotations in History, got %v", s.History)
+	}
+	for i, r := range s.History {
+		if i > 0 && !r.Start.Equal(s.History[i-1].End) {
+			t.Errorf("expected History to be contiguous, got %v", s.History)
+		}
+	}
+	if !s.History[len(s.History)-1].End.Equal(s.Rotations[0].Start) {
+		t.Errorf("expected History to end where Rotations start, got %v then %v", s.History, s.Rotations)
+	}
+
+	// The override is archived as worked.
+	r, err := s.At(day(1).Add(time.Hour), SearchHistory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Primary != "c" {
+		t.Errorf("expected c, who covered the first day, got %s", r)
+	}
+	if _, err := s.At(day(1)); !errors.Is(err, ErrBeforeRotations) {
+		t.Errorf("expected At without SearchHistory to ignore History, got %v", err)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	for _, c := range []struct {
+		historyFor time.Duration
+		max int
+		expected int
+	}{
+		{0, 0, 5},
+		{0, 2, 2},
+		// Rotations that ended within three weeks of now.
+		{3 * Week, 0, 2},
+	} {
+		s := EmptySchedule()
+		s.KeepHistory = true
+		s.HistoryForDuration, s.HistoryMax = c.historyFor, c.max
+		if c.historyFor > 0 {
+			s.HistoryFor = c.historyFor.String()
+		}
+		s.now = Start
+		s, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s = regenerateWeekly(t, s, 6)
+		if len(s.History) != c.expected {
+			t.Errorf("HistoryFor %s, HistoryMax %d: expected %d rotations in History, got %v", c.historyFor, c.max, c.expected, s.History)
+		}
+	}
+}
+
+func TestHistoryOff(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s = regenerateWeekly(t, s, 3); s.History != nil {
+		t.Errorf("expected no History without KeepHistory, got %v", s.History)
+	}
+}
@@ -0,0 +1,154 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const holidayDateFormat = "2006-01-02"
+
+// How Generate treats rotations over Holidays.
+const (
+	// Note the holidays a rotation covers in its Notes. This is the default.
+	HolidayAnnotate = "annotate"
+	// Also pass over, for the primary of a rotation covering a holiday, anyone
+	// who was primary over a holiday in the rotations kept or generated so far,
+	// in favour of the next user the round-robin reaches who was not. They
+	// swap places in the order, as for LandmarkShifts, so the round-robin is
+	// otherwise undisturbed.
+	HolidayAvoid = "avoid"
+)
+
+// A Holiday is one or more days, such as the days between Christmas and New
+// Year, that nobody wants to be on call over twice in a row.
+type Holiday struct {
+	Name string
+	// The first and last day of the holiday, as YYYY-MM-DD. End defaults to
+	// Start. Days run from midnight to midnight in the time zone of the
+	// rotation they are checked against.
+	Start string
+	End string `json:",omitempty"`
+}
+
+func (h Holiday) String() string {
+	if h.End == "" || h.End == h.Start {
+		return fmt.Sprintf("%s (%s)", h.Name, h.Start)
+	}
+	return fmt.Sprintf("%s (%s to %s)", h.Name, h.Start, h.End)
+}
+
+// Return the first and last days of h.
+func (h Holiday) days() (time.Time, time.Time, error) {
+	first, err := time.Parse(holidayDateFormat, h.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("error parsing Start of holiday %s: %s", h.Name, err)
+	}
+	if h.End == "" {
+		return first, first, nil
+	}
+	last, err := time.Parse(holidayDateFormat, h.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("error parsing End of holiday %s: %s", h.Name, err)
+	}
+	return first, last, nil
+}
+
+// Whether h overlaps [start, end).
+func (h Holiday) overlaps(start, end time.Time) bool {
+	first, last, err := h.days()
+	if err != nil {
+		return false
+	}
+	from := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, start.Location())
+	until := time.Date(last.Year(), last.Month(), last.Day() + 1, 0, 0, 0, 0, start.Location())
+	return from.Before(end) && until.After(start)
+}
+
+// ParseHolidays parses a JSON list of holidays, such as one kept in its own
+// file and shared between schedules.
+func ParseHolidays(text []byte) ([]Holiday, error) {
+	holidays := []Holiday{}
+	if err := json.Unmarshal(text, &holidays); err != nil {
+		return nil, fmt.Errorf("error parsing holidays: %s", err)
+	}
+	if err := validateHolidays(holidays); err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+func validateHolidays(holidays []Holiday) error {
+	for _, h := range holidays {
+		if h.Name == "" {
+			return fmt.Errorf("Holidays cannot include a holiday with no Name")
+		}
+		first, last, err := h.days()
+		if err != nil {
+			return err
+		}
+		if last.Before(first) {
+			return fmt.Errorf("holiday %s ends before it starts", h)
+		}
+	}
+	return nil
+}
+
+func validateHolidayPolicy(policy string) error {
+	switch policy {
+	case "", HolidayAnnotate, HolidayAvoid:
+		return nil
+	}
+	return fmt.Errorf("unknown HolidayPolicy %q: must be %q or %q", policy, HolidayAnnotate, HolidayAvoid)
+}
+
+// Return the holidays overlapping [start, end).
+func (s *Schedule) holidaysOverlapping(start, end time.Time) []Holiday {
+	holidays := []Holiday{}
+	for _, h := range s.Holidays {
+		if h.overlaps(start, end) {
+			holidays = append(holidays, h)
+		}
+	}
+	return holidays
+}
+
+// Return the note for a rotation covering holidays.
+func holidayNote(holidays []Holiday) string {
+	names := []string{}
+	for _, h := range holidays {
+		names = append(names, h.String())
+	}
+	return "covers " + strings.Join(names, ", ")
+}
+
+// Return the position of the user in users to make primary of the rotation
+// starting at s.Start under HolidayAvoid: the first available user from
+// position p, which the round-robin reached, who has not been primary over a
+// holiday in Rotations, or p itself if everyone has.
+func (s *Schedule) fairestForHolidays(users []string, p int) int {
+	had := map[string]bool{}
+	for i, r := range s.Rotations {
+		if len(s.holidaysOverlapping(r.Start, s.RotationEnd(i))) > 0 {
+			had[r.Primary] = true
+		}
+	}
+	return s.best(users, p, func(u string) float64 {
+		if had[u] {
+			return 1
+		}
+		return 0
+	})
+}
+
+// Join the non-empty notes with semicolons.
+func joinNotes(notes ...string) string {
+	kept := []string{}
+	for _, n := range notes {
+		if n != "" {
+			kept = append(kept, n)
+		}
+	}
+	return strings.Join(kept, "; ")
+}
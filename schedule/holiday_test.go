@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHolidayNotes(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	// Spans the handoff between the first and second rotations.
+	s.Holidays = []Holiday{{Name: "Offsite", Start: "2017-02-07", End: "2017-02-08"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"covers Offsite (2017-02-07 to 2017-02-08)", "covers Offsite (2017-02-07 to 2017-02-08)", ""} {
+		if notes := ns.Rotations[i].Notes; notes != expected {
+			t.Errorf("rotation %d: expected notes %q, got %q", i, expected, notes)
+		}
+	}
+	if primaries := primariesOf(ns.Rotations); primaries != "a b c a" {
+		t.Errorf("expected annotating to leave the round-robin alone, got %s", primaries)
+	}
+	if d := NewDiff(s, ns).String(); !strings.Contains(d, "a b (covers Offsite") {
+		t.Errorf("expected the diff to call out the holiday, got:\n%s", d)
+	}
+}
+
+func TestHolidayAvoid(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.ScheduleForDuration = 5 * Week
+	s.HolidayPolicy = HolidayAvoid
+	s.Holidays = []Holiday{
+		{Name: "Founders' Day", Start: "2017-02-02"},
+		// Spans the handoff between the fourth and fifth rotations.
+		{Name: "Winter break", Start: "2017-02-28", End: "2017-03-01"},
+	}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a had Founders' Day, so b takes the first rotation over the break, and c,
+	// rather than a, the second. a is next in line once the break is over.
+	if primaries := primariesOf(ns.Rotations); primaries != "a b c b c a" {
+		t.Errorf("expected a to be passed over for the break, got %s", primaries)
+	}
+	again, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ns.Rotations, again.Rotations) {
+		t.Errorf("expected generating again to give the same rotations")
+	}
+}
+
+func TestValidateHolidays(t *testing.T) {
+	for _, c := range []struct {
+		holidays []Holiday
+		policy string
+		err string
+	}{
+		{[]Holiday{{Start: "2017-12-25"}}, "", "no Name"},
+		{[]Holiday{{Name: "Christmas", Start: "12-25"}}, "", "error parsing Start of holiday Christmas"},
+		{[]Holiday{{Name: "Break", Start: "2017-12-31", End: "2017-12-24"}}, "", "ends before it starts"},
+		{nil, "skip", "unknown HolidayPolicy"},
+	} {
+		s := EmptySchedule()
+		s.Holidays, s.HolidayPolicy = c.holidays, c.policy
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
+
+func TestParseHolidays(t *testing.T) {
+	holidays, err := ParseHolidays([]byte(`[{"Name": "Christmas", "Start": "2017-12-25", "End": "2017-12-26"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holidays) != 1 || holidays[0].String() != "Christmas (2017-12-25 to 2017-12-26)" {
+		t.Errorf("unexpected holidays %v", holidays)
+	}
+	if _, err := ParseHolidays([]byte(`[{"Name": "Christmas"}]`)); err == nil {
+		t.Errorf("expected an error for a holiday with no Start")
+	}
+}
+
+func primariesOf(rs []Rotation) string {
+	primaries := []string{}
+	for _, r := range rs {
+		primaries = append(primaries, r.Primary)
+	}
+	return strings.Join(primaries, " ")
+}
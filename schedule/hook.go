@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout is how long a Hook may run when it does not set Timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// A Hook is a follow-up to a Generate run that changed the rotations, e.g.
+// committing the schedule file or posting to chat: either a URL to POST a
+// summary of the changes to, or a command to run with it on stdin. The
+// schedule only lists its hooks; the hooks package runs them.
+type Hook struct {
+	// Names the hook in errors. Defaults to its URL or command.
+	Name string `json:",omitempty"`
+	URL string `json:",omitempty"`
+	// The program to run and its arguments, e.g. ["git", "commit", "-a", "-F", "-"].
+	Command []string `json:",omitempty"`
+	// How long the hook may run before it is stopped, e.g. "10s". Defaults to
+	// DefaultHookTimeout.
+	Timeout string `json:",omitempty"`
+}
+
+func (h Hook) String() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.URL != "":
+		return h.URL
+	}
+	return strings.Join(h.Command, " ")
+}
+
+// TimeoutDuration returns how long h may run.
+func (h Hook) TimeoutDuration() time.Duration {
+	if h.Timeout == "" {
+		return DefaultHookTimeout
+	}
+	d, err := ParseDuration(h.Timeout)
+	if err != nil {
+		// Validate reports it.
+		return DefaultHookTimeout
+	}
+	return d
+}
+
+func validateHooks(hooks []Hook) error {
+	for _, h := range hooks {
+		if (h.URL == "") == (len(h.Command) == 0) {
+			return fmt.Errorf("hook %s must set exactly one of URL and Command", h)
+		}
+		if h.Timeout == "" {
+			continue
+		}
+		d, err := ParseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("error parsing Timeout of hook %s: %s", h, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("hook %s must have a positive Timeout (got %s)", h, h.Timeout)
+		}
+	}
+	return nil
+}
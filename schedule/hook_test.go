@@ -0,0 +1,31 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateHooks(t *testing.T) {
+	for _, c := range []struct {
+		hook Hook
+		expected string
+	}{
+		{Hook{Name: "chat"}, "hook chat must set exactly one of URL and Command"},
+		{Hook{URL: "https://example.com/hook", Command: []string{"true"}}, "hook https://example.com/hook must set exactly one of URL and Command"},
+		{Hook{Command: []string{"git", "commit"}, Timeout: "soon"}, `error parsing Timeout of hook git commit: `},
+		{Hook{Command: []string{"true"}, Timeout: "-1s"}, "hook true must have a positive Timeout (got -1s)"},
+	} {
+		s := EmptySchedule()
+		s.Hooks = []Hook{c.hook}
+		if err := s.Validate(); err == nil || !strings.HasPrefix(err.Error(), c.expected) {
+			t.Errorf("expected an error starting %q, got %v", c.expected, err)
+		}
+	}
+	if d := (Hook{Timeout: "2m"}).TimeoutDuration(); d != 2 * time.Minute {
+		t.Errorf("expected a 2m timeout, got %s", d)
+	}
+	if d := (Hook{}).TimeoutDuration(); d != DefaultHookTimeout {
+		t.Errorf("expected the default timeout, got %s", d)
+	}
+}
@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Return text, a schedule document that may have // and /* */ comments and
+// trailing commas, as standard JSON. Each comment and trailing comma is
+// replaced by spaces, keeping any newlines, so that every byte left stays at
+// the same offset and errors found in the result can be located in text.
+// Strings, including any "//" in them, are left as they are.
+func standardize(text []byte) []byte {
+	out := append([]byte{}, text...)
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	// Blank the comments first, so that a comment between a comma and the
+	// bracket after it does not hide that the comma trails.
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == '"':
+			i = stringEnd(out, i)
+		case out[i] == '/' && i + 1 < len(out) && out[i+1] == '/':
+			end := bytes.IndexByte(out[i:], '\n')
+			if end < 0 {
+				end = len(out) - i
+			}
+			blank(i, i + end)
+			i += end
+		case out[i] == '/' && i + 1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				// Left unclosed, it runs to the end, where JSON then finds
+				// the document unfinished.
+				end = len(out) - i - 2
+			} else {
+				end += 2
+			}
+			blank(i, i + 2 + end)
+			i += 1 + end
+		}
+	}
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case '"':
+			i = stringEnd(out, i)
+		case ',':
+			next := i + 1
+			for next < len(out) && isSpace(out[next]) {
+				next++
+			}
+			if next < len(out) && (out[next] == '}' || out[next] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
+
+// Return the offset of the quote closing the string starting at text[i], or
+// the last offset if it is not closed.
+func stringEnd(text []byte, i int) int {
+	for i++; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(text) - 1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Return err, from decoding the standardized form of text, with the line and
+// column of text it was found at, if it says.
+func locateJSON(text []byte, err error) error {
+	offset := -1
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		offset = int(e.Offset)
+	case *json.SyntaxError:
+		// A document that ends early has nothing to point at but its end.
+		if e.Error() != "unexpected end of JSON input" {
+			offset = int(e.Offset)
+		}
+	}
+	if offset <= 0 || offset > len(text) {
+		return err
+	}
+	// The offset is just past the byte that was wrong, or the value that was.
+	line, column := lineColumn(text, offset - 1)
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
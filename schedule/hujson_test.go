@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStandardize(t *testing.T) {
+	text := `{
+	// bob is out until March, see thread
+	"Users": ["a", "b", /* "bob", */ "c",],
+	"Notes": "https://example.com/a//b /* not a comment */ \"// still a string",
+	"Rotations": [
+		{"Primary": "a",}, // trailing
+	],
+}`
+	got := standardize([]byte(text))
+	// Everything left is where it was, so errors can be located in text.
+	if len(got) != len(text) || strings.Count(string(got), "\n") != strings.Count(text, "\n") {
+		t.Errorf("expected the same length and lines, got\n%s", got)
+	}
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, got); err != nil {
+		t.Fatalf("expected valid JSON, got %s in\n%s", err, got)
+	}
+	expected := `{"Users":["a","b","c"],"Notes":"https://example.com/a//b /* not a comment */ \"// still a string","Rotations":[{"Primary":"a"}]}`
+	if compact.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, compact)
+	}
+}
+func TestNewScheduleWithComments(t *testing.T) {
+	text := strings.Replace(EmptyScheduleText, `"Users": ["a", "b", "c"]`, `// c is out until March
+	"Users": ["a", "b", "c",] /* see the thread */`, 1)
+	s, err := NewSchedule([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(s.Users, ",") != "a,b,c" {
+		t.Errorf("expected users a,b,c, got %v", s.Users)
+	}
+	out, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(out) {
+		t.Errorf("expected standard JSON to be written, got\n%s", out)
+	}
+}
+
+func TestNewScheduleErrorLocation(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		expected string
+	}{
+		{"{\n\t// weekly\n\t\"Users\": 7,\n}", "error parsing Users: line 3, column 11: json: cannot unmarshal number"},
+		{"{\n\t/* a\n\tcomment */ \"Users\": [\"a\" \"b\"]\n}", "error parsing schedule: line 3, column 27: invalid character '\"' after array element"},
+	} {
+		_, err := NewSchedule([]byte(c.text))
+		if err == nil || !strings.HasPrefix(err.Error(), c.expected) {
+			t.Errorf("expected an error starting %q, got %v", c.expected, err)
+		}
+	}
+}
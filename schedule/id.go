@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Give each rotation of ns without an ID one. The ID is derived from the
+// rotation's start, the window or region it covers and the team of a
+// ScheduleSet it is scheduled for, so a rotation generated again, e.g. after
+// GenerateFresh discarded it, is given the same ID, while two teams' rotations
+// starting together are not. IDs already given, including any edited in by
+// hand, are kept.
+func (ns *Schedule) assignIDs() {
+	for i := range ns.Rotations {
+		if ns.Rotations[i].ID == "" {
+			ns.Rotations[i].ID = rotationID(ns.team, ns.Rotations[i])
+		}
+	}
+}
+
+// A schedule read on its own has no team, which is left out of the IDs of its
+// rotations.
+func rotationID(team string, r Rotation) string {
+	key := fmt.Sprintf("%s|%s|%s", r.Start.UTC().Format(time.RFC3339Nano), r.Window, r.Region)
+	if team != "" {
+		key = team + "|" + key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Return the ID of segment k of the rotation with ID id, split by overrides:
+// the rotation's own ID for its first segment, and with the segment's number
+// as a suffix for the rest. A rotation without an ID has segments without
+// one.
+func segmentID(id string, k int) string {
+	if id == "" || k == 0 {
+		return id
+	}
+	return fmt.Sprintf("%s-%d", id, k)
+}
+
+// Check that no two of rs have the same ID.
+func validateRotationIDs(rs []Rotation) error {
+	starts := map[string]time.Time{}
+	for _, r := range rs {
+		if r.ID == "" {
+			continue
+		}
+		if start, ok := starts[r.ID]; ok {
+			return fmt.Errorf("rotations starting at %s and %s have the same ID %s; IDs must be unique, so remove one to have Generate give it a new one", start.Format(time.RFC3339), r.Start.Format(time.RFC3339), r.ID)
+		}
+		starts[r.ID] = r.Start
+	}
+	return nil
+}
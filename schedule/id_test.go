@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIDsSurviveRegeneration(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ns.Rotations {
+		if r.ID == "" {
+			t.Fatalf("expected every rotation to be given an ID, got %v without one", r)
+		}
+	}
+
+	// A hand edit keeping the ID field, and one giving a rotation an ID of
+	// its own, both survive a round trip through JSON and regeneration.
+	ns.Rotations[2].Primary, ns.Rotations[2].Secondary = ns.Rotations[2].Secondary, ns.Rotations[2].Primary
+	ns.Rotations[3].ID = "handover"
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = day(9)
+	again, err := reloaded.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range again.Rotations {
+		for _, old := range ns.Rotations {
+			if old.Start.Equal(r.Start) && old.ID != r.ID {
+				t.Errorf("expected the rotation starting at %s to keep ID %s, got %s", r.Start, old.ID, r.ID)
+			}
+		}
+	}
+}
+
+func TestIDsOfSplitRotations(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(10), End: day(12), Role: TierSecondary, User: "a"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := ns.Rotations[1].ID
+	if got := []string{ns.Rotations[2].ID, ns.Rotations[3].ID}; got[0] != id + "-1" || got[1] != id + "-2" {
+		t.Errorf("expected the segments of %s to be %s-1 and %s-2, got %v", id, id, id, got)
+	}
+
+	// Without the override the rotation is whole again, under the same ID.
+	ns.Overrides = nil
+	again, err := ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Rotations[1].ID != id || again.Rotations[2].ID == id + "-1" {
+		t.Errorf("expected the rotation to be rejoined as %s, got %v", id, again.Rotations[1:3])
+	}
+}
+
+func TestValidateDuplicateIDs(t *testing.T) {
+	s := FilledSchedule()
+	s.Rotations[1].ID = "x"
+	s.Rotations[3].ID = "x"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "have the same ID x") {
+		t.Errorf("expected an error for duplicate IDs, got %v", err)
+	}
+}
+
+func TestIDsDifferBetweenTeams(t *testing.T) {
+	// Both teams start together, with the same users and rotation length.
+	ss, err := NewScheduleSet([]byte(`{
+		"RotationLength": "1w",
+		"ScheduleFor": "3w",
+		"Start": "2017-02-01T10:00:00Z",
+		"Users": ["a", "b"],
+		"Teams": {"payments": {}, "search": {}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range ss.Teams {
+		s.now = Start
+	}
+	ns, err := ss.GenerateAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams := map[string]string{}
+	for _, team := range ns.Names() {
+		for _, r := range ns.Teams[team].Rotations {
+			if other, ok := teams[r.ID]; ok {
+				t.Errorf("rotation %s of %s has the same ID as one of %s", r.ID, team, other)
+			}
+			teams[r.ID] = team
+		}
+	}
+	// A single schedule has no team in its IDs.
+	s := EmptySchedule()
+	s.now = Start
+	single, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := rotationID("", single.Rotations[0]); single.Rotations[0].ID != id {
+		t.Errorf("expected the ID of a schedule without a team to be %s, got %s", id, single.Rotations[0].ID)
+	}
+}
@@ -0,0 +1,149 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// A jsonValue is a value in a schedule document, found by walking it.
+type jsonValue struct {
+	// Where the value is in the document, e.g. "Rotations[12].Start".
+	path string
+	// The offset of the value's first byte in the document.
+	offset int
+	// The value as written.
+	raw []byte
+	// Why the value cannot be decoded, if it cannot.
+	err error
+}
+
+// Return the value as written, without the quotes if it is a string.
+func (v jsonValue) value() string {
+	var s string
+	if json.Unmarshal(v.raw, &s) == nil {
+		return s
+	}
+	return string(v.raw)
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Walk raw, a value in a standard JSON document starting at offset, which is
+// decoded into a t at path. Return the innermost value that cannot be decoded,
+// if any. If unknown is not nil, it is called with every object key that names
+// no field of the struct it is decoded into, which encoding/json ignores, at
+// the offset of the key.
+func walkJSON(raw []byte, offset int, t reflect.Type, path string, unknown func(jsonValue)) *jsonValue {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	offset += len(raw) - len(trimmed)
+	raw = trimmed
+	var bad *jsonValue
+	if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+		bad = &jsonValue{path: path, offset: offset, raw: raw, err: err}
+	}
+	if (bad == nil && unknown == nil) || reflect.PtrTo(t).Implements(unmarshalerType) || len(raw) == 0 {
+		return bad
+	}
+	object := raw[0] == '{' && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map)
+	array := raw[0] == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array)
+	if !object && !array {
+		return bad
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.Token()
+	inner := false
+	for i := 0; d.More(); i++ {
+		var key string
+		keyStart := 0
+		if object {
+			token, err := d.Token()
+			if err != nil {
+				break
+			}
+			key, _ = token.(string)
+			quoted, _ := json.Marshal(key)
+			keyStart = offset + int(d.InputOffset()) - len(quoted)
+		}
+		var child json.RawMessage
+		if err := d.Decode(&child); err != nil {
+			break
+		}
+		start := offset + int(d.InputOffset()) - len(child)
+		var elem reflect.Type
+		var elemPath string
+		switch {
+		case array:
+			elem, elemPath = t.Elem(), fmt.Sprintf("%s[%d]", path, i)
+		case t.Kind() == reflect.Map:
+			elem, elemPath = t.Elem(), joinPath(path, key)
+		default:
+			f, ok := jsonField(t, key)
+			if !ok {
+				if unknown != nil {
+					unknown(jsonValue{path: joinPath(path, key), offset: keyStart, raw: child})
+				}
+				continue
+			}
+			elem, elemPath = f.Type, joinPath(path, key)
+		}
+		// The innermost value that cannot be decoded says best what is wrong.
+		if b := walkJSON(child, start, elem, elemPath, unknown); b != nil && bad != nil && !inner {
+			bad, inner = b, true
+		}
+	}
+	return bad
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Return the field of t that encoding/json decodes key into, matching
+// names as it does, case-insensitively.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var match reflect.StructField
+	found := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if embedded, ok := jsonField(f.Type, key); ok && !found {
+				match, found = embedded, true
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f, true
+		}
+		if strings.EqualFold(name, key) && !found {
+			match, found = f, true
+		}
+	}
+	return match, found
+}
+
+// Return the line and column of the byte at offset in text, counting from 1.
+func lineColumn(text []byte, offset int) (int, int) {
+	at := text[:offset]
+	return bytes.Count(at, []byte("\n")) + 1, utf8.RuneCount(at[bytes.LastIndexByte(at, '\n')+1:]) + 1
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const landmarkDateFormat = "01-02"
+
+// A Landmark is a day that recurs every year, such as New Year's Eve.
+type Landmark struct {
+	Name string
+	// The day of the year, as MM-DD. The day runs from midnight to midnight in
+	// the time zone of the rotation it is checked against.
+	Date string
+}
+
+// Whether any occurrence of l overlaps [start, end).
+func (l Landmark) overlaps(start, end time.Time) bool {
+	d, err := time.Parse(landmarkDateFormat, l.Date)
+	if err != nil {
+		return false
+	}
+	for y := start.Year() - 1; y <= end.Year(); y++ {
+		day := time.Date(y, d.Month(), d.Day(), 0, 0, 0, 0, start.Location())
+		if day.Before(end) && day.AddDate(0, 0, 1).After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the names of the landmarks overlapping [start, end).
+func (s *Schedule) landmarksOverlapping(start, end time.Time) []string {
+	names := []string{}
+	for _, l := range s.LandmarkShifts {
+		if l.overlaps(start, end) {
+			names = append(names, l.Name)
+		}
+	}
+	return names
+}
+
+// Return the position of the eligible user in users with the fewest shifts
+// over the given landmarks. Ties go to whoever the round-robin reaches first
+// from position p.
+func (s *Schedule) fairestForLandmarks(landmarks []string, users []string, p int) int {
+	return s.best(users, p, func(u string) float64 {
+		count := 0
+		for _, l := range landmarks {
+			count += s.LandmarkCounts[l][u]
+		}
+		return float64(count)
+	})
+}
+
+func (s *Schedule) recordLandmarks(landmarks []string, user string) {
+	if s.LandmarkCounts == nil {
+		s.LandmarkCounts = map[string]map[string]int{}
+	}
+	for _, l := range landmarks {
+		if s.LandmarkCounts[l] == nil {
+			s.LandmarkCounts[l] = map[string]int{}
+		}
+		s.LandmarkCounts[l][user]++
+	}
+}
+
+func copyLandmarkCounts(counts map[string]map[string]int) map[string]map[string]int {
+	if counts == nil {
+		return nil
+	}
+	c := map[string]map[string]int{}
+	for l, users := range counts {
+		c[l] = map[string]int{}
+		for u, n := range users {
+			c[l][u] = n
+		}
+	}
+	return c
+}
+
+// LandmarkReport renders, for each landmark, how many times each user has been
+// primary over it. Users are listed in the order they would next be given the
+// landmark, so the first user listed is up next.
+func (s *Schedule) LandmarkReport() string {
+	out := &bytes.Buffer{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "LANDMARK\tUSER\tSHIFTS\n")
+	for _, l := range s.LandmarkShifts {
+		users := append([]string{}, s.Users...)
+		sort.SliceStable(users, func(i, j int) bool {
+			return s.LandmarkCounts[l.Name][users[i]] < s.LandmarkCounts[l.Name][users[j]]
+		})
+		for _, u := range users {
+			fmt.Fprintf(w, "%s (%s)\t%s\t%d\n", l.Name, l.Date, u, s.LandmarkCounts[l.Name][u])
+		}
+	}
+	w.Flush()
+	return out.String()
+}
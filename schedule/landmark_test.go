@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func landmarkSchedule() *Schedule {
+	s := EmptySchedule()
+	s.Start = time.Date(2016, time.December, 28, 10, 0, 0, 0, time.UTC)
+	s.now = s.Start
+	s.LandmarkShifts = []Landmark{{Name: "nye", Date: "12-31"}}
+	return s
+}
+
+func TestLandmarkGoesToLowestCount(t *testing.T) {
+	s := landmarkSchedule()
+	// a would be primary over New Year's Eve, but has already had it.
+	s.LandmarkCounts = map[string]map[string]int{"nye": {"a": 2, "b": 1}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ns.Rotations[0]; r.Primary != "c" {
+		t.Errorf("expected c to be given the landmark, got %s", r)
+	}
+	// a takes c's place in the order rather than losing their turn.
+	expected := []string{"c", "b", "a", "c"}
+	for i, r := range ns.Rotations {
+		if r.Primary != expected[i] {
+			t.Errorf("rotation %d: expected primary %s, got %s", i, expected[i], r)
+		}
+	}
+	if counts := ns.LandmarkCounts["nye"]; !reflect.DeepEqual(map[string]int{"a": 2, "b": 1, "c": 1}, counts) {
+		t.Errorf("expected c's landmark shift to be recorded, got %v", counts)
+	}
+	if s.LandmarkCounts["nye"]["c"] != 0 {
+		t.Errorf("Generate modified the input's landmark counts")
+	}
+}
+
+func TestLandmarkTiesFollowRoundRobin(t *testing.T) {
+	s := landmarkSchedule()
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ns.Rotations[0]; r.Primary != "a" || r.Secondary != "b" {
+		t.Errorf("expected the round-robin to be undisturbed, got %s", r)
+	}
+}
+
+func TestLandmarkReport(t *testing.T) {
+	s := landmarkSchedule()
+	s.LandmarkCounts = map[string]map[string]int{"nye": {"a": 2, "b": 1}}
+	report := s.LandmarkReport()
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[1], "c") || !strings.Contains(lines[3], "a") {
+		t.Errorf("expected c to be listed first and a last, got:\n%s", report)
+	}
+}
+
+func TestValidateLandmarkDate(t *testing.T) {
+	s := landmarkSchedule()
+	s.LandmarkShifts = []Landmark{{Name: "bad", Date: "31-12"}}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "landmark bad") {
+		t.Errorf("expected an error naming the landmark, got %v", err)
+	}
+}
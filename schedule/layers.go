@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"time"
+)
+
+// A Span is a stretch of time one user is on call for in a tier, over one or
+// more rotations.
+type Span struct {
+	Start time.Time
+	End time.Time
+	User string
+}
+
+// A Layer is who is on call in one tier, as a flat list of spans in order,
+// the shape PagerDuty overrides and most calendar APIs want.
+type Layer struct {
+	Tier string
+	Spans []Span
+}
+
+// Layers returns a Layer for each tier of s, lowest first, derived from the
+// rotations as they stand, hand edits and all. Consecutive rotations, or
+// segments an override split a rotation into, with the same user in a tier
+// are merged into one span, however long each of them is. A tier left
+// unfilled, or Unassigned, has no span for the time.
+func (s Schedule) Layers() []Layer {
+	layers := make([]Layer, len(s.tiers()))
+	for t, tier := range s.tiers() {
+		layers[t] = Layer{Tier: tier, Spans: []Span{}}
+	}
+	for i, r := range s.Rotations {
+		end := s.RotationEnd(i)
+		for t, tier := range s.tiers() {
+			user := s.tierUser(r, tier)
+			if user == "" || user == Unassigned {
+				continue
+			}
+			spans := &layers[t].Spans
+			if n := len(*spans); n > 0 && (*spans)[n-1].User == user && !r.Start.After((*spans)[n-1].End) {
+				if last := &(*spans)[n-1]; end.After(last.End) {
+					last.End = end
+				}
+				continue
+			}
+			*spans = append(*spans, Span{Start: r.Start, End: end, User: user})
+		}
+	}
+	return layers
+}
@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayersMergeHandEdits(t *testing.T) {
+	s := FilledSchedule()
+	// The second rotation was lengthened by hand to nine days, keeping a as
+	// primary, and the secondary of the last left unfilled.
+	s.Rotations = withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "a", Secondary: "c"},
+		{Start: day(17), Primary: "b", Secondary: "c"},
+		{Start: day(22), Primary: "b", Secondary: ""},
+	})
+	expected := []Layer{
+		{Tier: TierPrimary, Spans: []Span{
+			{Start: day(1), End: day(17), User: "a"},
+			{Start: day(17), End: day(29), User: "b"},
+		}},
+		{Tier: TierSecondary, Spans: []Span{
+			{Start: day(1), End: day(8), User: "b"},
+			{Start: day(8), End: day(22), User: "c"},
+		}},
+	}
+	if got := s.Layers(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("layers do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, got)
+	}
+}
+
+func TestLayersMergeOverrideSegments(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(10), End: day(12), Role: TierSecondary, User: "a"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := ns.Layers()
+	// b stays primary across the segments the override split their rotation
+	// into.
+	if got := layers[0].Spans[1]; got != (Span{Start: day(8), End: day(15), User: "b"}) {
+		t.Errorf("expected b to be primary from day 8 to 15 in one span, got %+v", got)
+	}
+	expected := []Span{
+		{Start: day(1), End: day(8), User: "b"},
+		{Start: day(8), End: day(10), User: "c"},
+		{Start: day(10), End: day(12), User: "a"},
+		{Start: day(12), End: day(15), User: "c"},
+		{Start: day(15), End: day(22), User: "a"},
+		{Start: day(22), End: day(29), User: "b"},
+	}
+	if !reflect.DeepEqual(expected, layers[1].Spans) {
+		t.Errorf("secondary spans do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, layers[1].Spans)
+	}
+}
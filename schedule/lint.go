@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Lint returns suggestions for tidying up s. Unlike problems reported by
+// Validate, none of them prevent the schedule from being generated.
+func (s *Schedule) Lint() []string {
+	now := s.currentTime()
+	suggestions := append([]string{}, s.warnings...)
+
+	ended := []string{}
+	for _, u := range s.allUsers() {
+		if end, ok := s.UserEndDates[u]; ok && !end.After(now) && !s.hasShiftEndingAfter(u, now) {
+			ended = append(ended, u)
+		}
+	}
+	sort.Strings(ended)
+	for _, u := range ended {
+		suggestions = append(suggestions, fmt.Sprintf("%s reached their end date %s and has no remaining rotations; remove them from Users and UserEndDates", u, s.UserEndDates[u].Format(time.RFC3339)))
+	}
+
+	for _, u := range s.graduates(now) {
+		suggestions = append(suggestions, fmt.Sprintf("%s has shadowed %d rotations and is ready to graduate; move them from Trainees to Users", u, s.shadowed(u)))
+	}
+
+	if warning := s.handoffTimeDSTWarning(now); warning != "" {
+		suggestions = append(suggestions, warning)
+	}
+
+	for _, r := range s.Rotations {
+		if r.Start.After(now) && !s.onStartWeekday(r.Start) {
+			suggestions = append(suggestions, fmt.Sprintf("rotation starting at %s falls on a %s rather than StartWeekday %s; move it, or the rotation before it will run long and the one after it will end early", r.Start.Format(time.RFC3339), r.Start.In(s.weekdayLocation(r.Start)).Weekday(), s.StartWeekday))
+		}
+	}
+
+	for i, r := range s.Rotations {
+		if !s.RotationEnd(i).After(now) {
+			continue
+		}
+		if r.Primary == Unassigned {
+			suggestions = append(suggestions, fmt.Sprintf("rotation from %s to %s is %s because nobody is available; add users, or shorten someone's unavailability", r.Start.Format(time.RFC3339), s.RotationEnd(i).Format(time.RFC3339), Unassigned))
+			continue
+		}
+		for _, u := range s.Assigned(r) {
+			if a := s.unavailability(u, r.Start, s.RotationEnd(i)); a != nil {
+				suggestions = append(suggestions, fmt.Sprintf("rotation starting at %s assigns %s, but %s; add an override to cover them", r.Start.Format(time.RFC3339), u, a))
+			}
+		}
+	}
+
+	for _, i := range s.doubledTiers(s.Rotations) {
+		if !s.RotationEnd(i).After(now) {
+			continue
+		}
+		suggestions = append(suggestions, s.doubledTierMessage(s.Rotations[i]))
+	}
+	return suggestions
+}
+
+// Describe the rotation r assigning one user to two tiers, naming what made it
+// do so.
+func (s *Schedule) doubledTierMessage(r Rotation) string {
+	lower, higher := s.doubledTier(r)
+	u, tiers := s.Assigned(r)[lower], s.tiers()
+	if r.Overridden != nil {
+		return fmt.Sprintf("rotation starting at %s has %s as both %s and %s because of an override with Force set, leaving one person on call", r.Start.Format(time.RFC3339), u, tiers[lower], tiers[higher])
+	}
+	// Generate never assigns one user to two tiers, so the rotation must have
+	// been edited by hand.
+	return fmt.Sprintf("rotation starting at %s has %s as both %s and %s, leaving one person on call; the assignment was edited into Rotations by hand, so edit that rotation to name a different %s", r.Start.Format(time.RFC3339), u, tiers[lower], tiers[higher], tiers[higher])
+}
+
+// Return the positions of the rotations in rs that assign the same user to
+// more than one tier.
+func (s *Schedule) doubledTiers(rs []Rotation) []int {
+	doubled := []int{}
+	for i, r := range rs {
+		if lower, _ := s.doubledTier(r); lower >= 0 {
+			doubled = append(doubled, i)
+		}
+	}
+	return doubled
+}
+
+// Whether u is assigned to any rotation that has not elapsed by t.
+func (s *Schedule) hasShiftEndingAfter(u string, t time.Time) bool {
+	for i, r := range s.Rotations {
+		for _, assigned := range s.Assigned(r) {
+			if assigned == u && s.RotationEnd(i).After(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The layout ToMarkdown writes times in by default.
+const DefaultMarkdownLayout = "Mon 2 Jan 2006 15:04 MST"
+
+// MarkdownOptions control which rotations ToMarkdown lists and how.
+type MarkdownOptions struct {
+	// Rotations ending at or before From are left out. Defaults to now.
+	From time.Time
+	// If set, rotations starting at or after Until are left out, e.g. to list
+	// the rest of the month.
+	Until time.Time
+	// If positive, at most this many rotations are listed, e.g. for the next
+	// six. The segments an override split a rotation into count as one.
+	Rotations int
+	// The location times are written in. Defaults to DisplayTimezone, then to
+	// the locations the times carry.
+	Location *time.Location
+	// The layout times are written in, as for time.Format. Defaults to
+	// DefaultMarkdownLayout.
+	Layout string
+	// If set, a text/template executed with each User for the link their name
+	// is rendered as, e.g.
+	// "{{with .SlackID}}https://example.slack.com/team/{{.}}{{end}}". A user
+	// for whom it renders nothing is left as a plain name.
+	UserLink string
+}
+
+// ToMarkdown renders the rotations of s as a Markdown table, e.g. to paste
+// into a wiki page or chat: a row for each rotation, or segment of one an
+// override split off, with its start, end, and who is on call in each tier.
+// The same schedule and options always produce the same table. Pipes in names
+// are escaped so that they do not break the table.
+func (s Schedule) ToMarkdown(opts MarkdownOptions) (string, error) {
+	var link *template.Template
+	if opts.UserLink != "" {
+		var err error
+		if link, err = template.New("UserLink").Parse(opts.UserLink); err != nil {
+			return "", fmt.Errorf("error parsing UserLink: %s", err)
+		}
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = s.displayLocation
+	}
+	layout := opts.Layout
+	if layout == "" {
+		layout = DefaultMarkdownLayout
+	}
+	from := opts.From
+	if from.IsZero() {
+		from = s.currentTime()
+	}
+
+	name := func(u string) (string, error) {
+		if u == "" {
+			return "-", nil
+		}
+		text := markdownEscape(u)
+		if link == nil {
+			return text, nil
+		}
+		details, _ := s.User(u)
+		b := &bytes.Buffer{}
+		if err := link.Execute(b, details); err != nil {
+			return "", fmt.Errorf("error executing UserLink for %s: %s", u, err)
+		}
+		if b.Len() == 0 {
+			return text, nil
+		}
+		return fmt.Sprintf("[%s](%s)", text, markdownEscape(b.String())), nil
+	}
+
+	out := &bytes.Buffer{}
+	tiers := s.tiers()
+	fmt.Fprintf(out, "| Start | End |")
+	for _, tier := range tiers {
+		fmt.Fprintf(out, " %s |", markdownEscape(strings.ToUpper(tier[:1]) + tier[1:]))
+	}
+	fmt.Fprintf(out, "\n|---|---|%s\n", strings.Repeat("---|", len(tiers)))
+	listed := 0
+	for i, r := range s.Rotations {
+		end := s.RotationEnd(i)
+		if !end.After(from) {
+			continue
+		}
+		if !opts.Until.IsZero() && !r.Start.Before(opts.Until) {
+			break
+		}
+		if !r.Continues || listed == 0 {
+			if opts.Rotations > 0 && listed == opts.Rotations {
+				break
+			}
+			listed++
+		}
+		fmt.Fprintf(out, "| %s | %s |", formatTime(r.Start, loc, layout), formatTime(end, loc, layout))
+		for _, tier := range tiers {
+			cell, err := name(s.tierUser(r, tier))
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(out, " %s |", cell)
+		}
+		fmt.Fprintf(out, "\n")
+	}
+	return out.String(), nil
+}
+
+// Return text with the characters that would break a Markdown table cell
+// escaped.
+func markdownEscape(text string) string {
+	text = strings.Replace(text, `\`, `\\`, -1)
+	text = strings.Replace(text, "|", `\|`, -1)
+	return strings.Replace(text, "\n", " ", -1)
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToMarkdown(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	s.Rotations[3].Primary = "a|z"
+	got, err := s.ToMarkdown(MarkdownOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "| Start | End | Primary | Secondary |\n" +
+		"|---|---|---|---|\n" +
+		"| Wed 15 Feb 2017 10:00 UTC | Wed 22 Feb 2017 10:00 UTC | c | a |\n" +
+		"| Wed 22 Feb 2017 10:00 UTC | Wed 1 Mar 2017 10:00 UTC | a\\|z | b |\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestToMarkdownWindow(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		opts MarkdownOptions
+		expected string
+	}{
+		{
+			"count",
+			MarkdownOptions{From: Start, Rotations: 2, Layout: "2 Jan"},
+			"| 1 Feb | 8 Feb | a | b |\n| 8 Feb | 15 Feb | b | c |\n",
+		},
+		{
+			"until",
+			MarkdownOptions{Until: time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC), Location: newYork, Layout: "Jan 2 15:04"},
+			"| Feb 15 05:00 | Feb 22 05:00 | c | a |\n",
+		},
+	} {
+		got, err := s.ToMarkdown(c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "| Start | End | Primary | Secondary |\n|---|---|---|---|\n" + c.expected; got != expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", c.name, expected, got)
+		}
+	}
+}
+
+func TestToMarkdownUserLink(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	s.userDetails = map[string]User{"a": {Name: "a", SlackID: "U024BE7LH"}}
+	got, err := s.ToMarkdown(MarkdownOptions{Rotations: 1, Layout: "2 Jan", UserLink: "{{with .SlackID}}https://example.slack.com/team/{{.}}{{end}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "| Start | End | Primary | Secondary |\n|---|---|---|---|\n| 15 Feb | 22 Feb | c | [a](https://example.slack.com/team/U024BE7LH) |\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if _, err := s.ToMarkdown(MarkdownOptions{UserLink: "{{.Nickname}}"}); err == nil {
+		t.Errorf("expected an error for a UserLink naming a missing field")
+	}
+}
@@ -0,0 +1,88 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Marshal returns s as a schedule file in canonical form, so that writing the
+// same schedule always gives the same bytes and a file checked in to version
+// control only changes when the schedule does: JSON at CurrentFormatVersion,
+// indented by two spaces, with the fields of Input in order, times in the
+// schedule's Timezone, durations as written, and a trailing newline.
+func (s *Schedule) Marshal() ([]byte, error) {
+	in := s.Input()
+	in.FormatVersion = CurrentFormatVersion
+	if s.location != nil {
+		in = in.inLocation(s.location)
+	}
+	b := &bytes.Buffer{}
+	e := json.NewEncoder(b)
+	e.SetIndent("", "  ")
+	if err := e.Encode(in); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// WriteTo writes s to w as Marshal does.
+func (s *Schedule) WriteTo(w io.Writer) (int64, error) {
+	text, err := s.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(text)
+	return int64(n), err
+}
+
+// Return in with its times in loc, copying rather than modifying the slices
+// it shares. A slice copied is only nil if it was, so it is written the same.
+func (in Input) inLocation(loc *time.Location) Input {
+	at := func(t time.Time) time.Time {
+		// A zero time would be written with loc's offset in the year 1.
+		if t.IsZero() {
+			return t
+		}
+		return t.In(loc)
+	}
+	in.Start = at(in.Start)
+	if in.SecondaryStart != nil {
+		t := at(*in.SecondaryStart)
+		in.SecondaryStart = &t
+	}
+	in.Rotations = append(in.Rotations[:0:0], in.Rotations...)
+	for i := range in.Rotations {
+		in.Rotations[i].Start, in.Rotations[i].End = at(in.Rotations[i].Start), at(in.Rotations[i].End)
+	}
+	in.History = append(in.History[:0:0], in.History...)
+	for i := range in.History {
+		in.History[i].Start, in.History[i].End = at(in.History[i].Start), at(in.History[i].End)
+	}
+	in.Unavailable = append(in.Unavailable[:0:0], in.Unavailable...)
+	for i := range in.Unavailable {
+		in.Unavailable[i].Start, in.Unavailable[i].End = at(in.Unavailable[i].Start), at(in.Unavailable[i].End)
+	}
+	in.Pauses = append(in.Pauses[:0:0], in.Pauses...)
+	for i := range in.Pauses {
+		in.Pauses[i].Start, in.Pauses[i].End = at(in.Pauses[i].Start), at(in.Pauses[i].End)
+	}
+	in.Freezes = append(in.Freezes[:0:0], in.Freezes...)
+	for i := range in.Freezes {
+		in.Freezes[i].Start, in.Freezes[i].End = at(in.Freezes[i].Start), at(in.Freezes[i].End)
+	}
+	in.Gaps = append(in.Gaps[:0:0], in.Gaps...)
+	for i := range in.Gaps {
+		in.Gaps[i].Start, in.Gaps[i].End = at(in.Gaps[i].Start), at(in.Gaps[i].End)
+	}
+	in.Overrides = append(in.Overrides[:0:0], in.Overrides...)
+	for i := range in.Overrides {
+		in.Overrides[i].Start, in.Overrides[i].End = at(in.Overrides[i].Start), at(in.Overrides[i].End)
+	}
+	in.Swaps = append(in.Swaps[:0:0], in.Swaps...)
+	for i := range in.Swaps {
+		in.Swaps[i].Start, in.Swaps[i].End, in.Swaps[i].Made = at(in.Swaps[i].Start), at(in.Swaps[i].End), at(in.Swaps[i].Made)
+	}
+	return in
+}
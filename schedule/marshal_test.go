@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	s := FilledSchedule()
+	s.now = s.Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := ns.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(text), "{\n  \"FormatVersion\": ") || !strings.HasSuffix(string(text), "}\n") {
+		t.Errorf("expected indented JSON ending in a newline, got\n%s", text)
+	}
+	again, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Input(), ns.Input()) {
+		t.Errorf("expected the schedule to round-trip\nExpected:\n%+v\nGot:\n%+v", ns.Input(), again.Input())
+	}
+	// Writing it again, or from what was read back, gives the same bytes.
+	for _, s := range []*Schedule{ns, again} {
+		b := &bytes.Buffer{}
+		if _, err := s.WriteTo(b); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != string(text) {
+			t.Errorf("expected the same bytes\nExpected:\n%s\nGot:\n%s", text, b)
+		}
+	}
+}
+
+func TestMarshalInTimezone(t *testing.T) {
+	s, err := NewSchedule([]byte(`{
+		"Users": ["a", "b"],
+		"Start": "2017-02-01T15:00:00Z",
+		"Timezone": "America/New_York",
+		"RotationLength": "1w",
+		"ScheduleFor": "2w",
+		"Overrides": [{"Start": "2017-02-02T15:00:00Z", "End": "2017-02-03T15:00:00Z", "Role": "primary", "User": "b"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As if built by a caller in UTC.
+	s.Overrides[0].Start = s.Overrides[0].Start.UTC()
+	text, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"Start": "2017-02-01T10:00:00-05:00"`, `"Start": "2017-02-02T10:00:00-05:00"`, `"RotationLength": "1w"`, `"Rotations": null`} {
+		if !strings.Contains(string(text), expected) {
+			t.Errorf("expected %s in\n%s", expected, text)
+		}
+	}
+	if strings.Contains(string(text), "0001-01-01") {
+		t.Errorf("expected no zero times to be written, got\n%s", text)
+	}
+}
@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentFormatVersion is the version of the schedule document format written
+// by this version of oncallator. Documents without a FormatVersion are version
+// 0.
+const CurrentFormatVersion = 2
+
+// A migration upgrades a raw schedule document by one format version in place,
+// returning a description of each change it made.
+type migration func(doc map[string]interface{}) ([]string, error)
+
+// migrations[n] upgrades a document from version n to version n+1.
+var migrations = []migration{
+	migrateV0ToV1,
+	migrateV1ToV2,
+}
+
+// Migrate upgrades a schedule document to CurrentFormatVersion, returning the
+// upgraded document and a description of every change made. Documents from a
+// newer version of oncallator are rejected rather than misparsed. Comments and
+// trailing commas are allowed, and the document returned is standard JSON.
+func Migrate(text []byte) ([]byte, []string, error) {
+	original := text
+	text = standardize(text)
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(text, &doc); err != nil {
+		return nil, nil, &ParseError{Op: "parsing", Field: "schedule", Err: locateJSON(original, err)}
+	}
+	version := 0
+	if v, ok := doc["FormatVersion"]; ok {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			return nil, nil, &ParseError{Op: "parsing", Field: "FormatVersion", Value: fmt.Sprint(v), Err: fmt.Errorf("not a version number: %v", v)}
+		}
+		version = int(f)
+	}
+	if version > CurrentFormatVersion {
+		return nil, nil, fmt.Errorf("schedule format version %d is newer than the newest supported version %d; upgrade oncallator to read it", version, CurrentFormatVersion)
+	}
+	if version == CurrentFormatVersion {
+		return text, nil, nil
+	}
+
+	changes := []string{}
+	for ; version < CurrentFormatVersion; version++ {
+		c, err := migrations[version](doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error migrating schedule from format version %d to %d: %s", version, version + 1, err)
+		}
+		changes = append(changes, c...)
+		doc["FormatVersion"] = version + 1
+	}
+	changes = append(changes, fmt.Sprintf("set FormatVersion to %d", CurrentFormatVersion))
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, changes, nil
+}
+
+// Version 0 documents were written before FormatVersion existed, and have the
+// same fields as version 1, which only adds FormatVersion itself.
+func migrateV0ToV1(doc map[string]interface{}) ([]string, error) {
+	return nil, nil
+}
+
+// Version 1 documents could leave out the End of a rotation, which was then
+// taken to be the Start of the next. Version 2 writes every End that can be
+// inferred that way, in Rotations and History, so that a rotation moved by
+// hand keeps its length rather than silently changing the one before it. The
+// End of the last rotation depends on the rest of the schedule, so it is left
+// for Generate to fill in.
+func migrateV1ToV2(doc map[string]interface{}) ([]string, error) {
+	changes := []string{}
+	for _, field := range []string{"Rotations", "History"} {
+		v, ok := doc[field]
+		if !ok || v == nil {
+			continue
+		}
+		rotations, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of rotations", field)
+		}
+		inferred := 0
+		for i := 0; i + 1 < len(rotations); i++ {
+			r, ok := rotations[i].(map[string]interface{})
+			next, nextOK := rotations[i+1].(map[string]interface{})
+			if !ok || !nextOK {
+				return nil, fmt.Errorf("%s must be a list of rotations", field)
+			}
+			if end, _ := r["End"].(string); end != "" && end != zeroTime {
+				continue
+			}
+			if start, ok := next["Start"].(string); ok {
+				r["End"] = start
+				inferred++
+			}
+		}
+		if inferred > 0 {
+			changes = append(changes, fmt.Sprintf("inferred the End of %s in %s from the Start of the rotation after each", plural(inferred, "rotation"), field))
+		}
+	}
+	return changes, nil
+}
+
+// How encoding/json writes a zero time.Time.
+const zeroTime = "0001-01-01T00:00:00Z"
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) map[string]interface{} {
+	text, err := ioutil.ReadFile(filepath.Join("testdata", "migrate", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(text, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestMigrateV0ToV1(t *testing.T) {
+	doc := readFixture(t, "v0.json")
+	changes, err := migrateV0ToV1(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc["FormatVersion"] = float64(1)
+	if expected := readFixture(t, "v1.json"); !reflect.DeepEqual(expected, doc) {
+		t.Errorf("migrated document does not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, doc)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestMigrateV1ToV2(t *testing.T) {
+	doc := readFixture(t, "v1-rotations.json")
+	changes, err := migrateV1ToV2(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc["FormatVersion"] = float64(2)
+	if expected := readFixture(t, "v2-rotations.json"); !reflect.DeepEqual(expected, doc) {
+		t.Errorf("migrated document does not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, doc)
+	}
+	expected := []string{
+		"inferred the End of 2 rotations in Rotations from the Start of the rotation after each",
+		"inferred the End of 1 rotation in History from the Start of the rotation after each",
+	}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Errorf("expected changes %q, got %q", expected, changes)
+	}
+	if _, err := migrateV1ToV2(map[string]interface{}{"Rotations": "weekly"}); err == nil {
+		t.Errorf("expected an error for Rotations that are not a list")
+	}
+}
+
+func TestMigrateV0ToCurrent(t *testing.T) {
+	text, err := ioutil.ReadFile(filepath.Join("testdata", "migrate", "v0-rotations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, changes, err := Migrate(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if expected := readFixture(t, "v2-rotations.json"); !reflect.DeepEqual(expected, doc) {
+		t.Errorf("migrated document does not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, doc)
+	}
+	if len(changes) != 3 || changes[2] != "set FormatVersion to 2" {
+		t.Errorf("expected each step's changes, got %q", changes)
+	}
+}
+
+func TestMigrateCurrentIsUnchanged(t *testing.T) {
+	text, err := ioutil.ReadFile(filepath.Join("testdata", "migrate", "v2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, changes, err := Migrate(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 || string(out) != string(text) {
+		t.Errorf("expected current document to be unchanged, got changes %v", changes)
+	}
+}
+
+func TestNewScheduleMigratesV0(t *testing.T) {
+	text, err := ioutil.ReadFile(filepath.Join("testdata", "migrate", "v0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FormatVersion != CurrentFormatVersion {
+		t.Errorf("expected FormatVersion %d, got %d", CurrentFormatVersion, s.FormatVersion)
+	}
+	if s.RotationDuration != EmptySchedule().RotationDuration {
+		t.Errorf("expected RotationDuration %s, got %s", EmptySchedule().RotationDuration, s.RotationDuration)
+	}
+	// It is written back at the current version, even if the field is edited.
+	s.FormatVersion = 1
+	out, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"FormatVersion": 2,`) {
+		t.Errorf("expected the current FormatVersion to be written, got\n%s", out)
+	}
+}
+
+// Durations were never written as numbers, so a version 0 document with one is
+// not migrated but rejected, naming the field.
+func TestNewScheduleRejectsV0NumericDuration(t *testing.T) {
+	_, err := NewSchedule([]byte(`{"Users": ["a", "b"], "Start": "2017-02-01T10:00:00Z", "RotationLength": 604800000000000, "ScheduleFor": "504h"}`))
+	pe := &ParseError{}
+	if !errors.As(err, &pe) || pe.Field != "RotationLength" {
+		t.Errorf("expected a ParseError for RotationLength, got %v", err)
+	}
+}
+
+func TestNewScheduleRejectsNewerVersion(t *testing.T) {
+	_, err := NewSchedule([]byte(`{"FormatVersion": 99, "Users": ["a"], "RotationLength": "1h", "ScheduleFor": "1h"}`))
+	if err == nil || !strings.Contains(err.Error(), "upgrade oncallator") {
+		t.Errorf("expected an upgrade error, got %v", err)
+	}
+}
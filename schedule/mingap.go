@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+func (s Schedule) validateMinGap() error {
+	if s.MinGap < 0 {
+		return fmt.Errorf("cannot have negative MinGap (got %d)", s.MinGap)
+	}
+	// With Windows or Regions, each has users of its own.
+	if len(s.Windows) > 0 || len(s.Regions) > 0 {
+		return nil
+	}
+	if n := len(s.primaryPool()); n < s.MinGap + 1 {
+		return fmt.Errorf("MinGap %d needs at least %d users to take turns as primary, one and then %d others before them again, but there are %d", s.MinGap, s.MinGap + 1, s.MinGap, n)
+	}
+	return nil
+}
+
+// Whether u was primary of any of the last MinGap rotations before the one
+// starting at s.Start, in Rotations or else in History.
+func (s *Schedule) tooSoon(u string) bool {
+	n := s.MinGap
+	for _, rs := range [][]Rotation{s.Rotations, s.History} {
+		for i := len(rs) - 1; i >= 0 && n > 0; i-- {
+			if rs[i].Continues || rs[i].SecondaryHandoff {
+				continue
+			}
+			if rs[i].Primary == u {
+				return true
+			}
+			n--
+		}
+	}
+	return false
+}
+
+// Return the position of the first available user after position q in users,
+// whose turn as primary came too soon after their last, who may be primary of
+// the rotation starting at s.Start. They are moved back to position q, so that
+// the users passed over keep their order and are primary as soon as the gap
+// allows.
+func (s *Schedule) nextAfterGap(users []string, q int) (int, error) {
+	end := s.NominalEnd(s.Start)
+	available := 0
+	for i := 0; i < len(users); i++ {
+		j := (q + i) % len(users)
+		if !s.available(users[j], s.Start, end) {
+			continue
+		}
+		available++
+		if s.tooSoon(users[j]) {
+			continue
+		}
+		for ; j != q; j = mod(j - 1, len(users)) {
+			prev := mod(j - 1, len(users))
+			users[j], users[prev] = users[prev], users[j]
+		}
+		return q, nil
+	}
+	return -1, fmt.Errorf("nobody can be primary of the rotation starting at %s: MinGap %d needs %d users available to take turns as primary, one and then %d others before them again, but only %d are available", s.Start.Format(time.RFC3339), s.MinGap, s.MinGap + 1, s.MinGap, available)
+}
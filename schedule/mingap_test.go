@@ -0,0 +1,62 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMinGapAfterUserRemoved(t *testing.T) {
+	for _, c := range []struct {
+		minGap int
+		expected string
+	}{
+		// x left, so the order restarts from a, who was primary just before x.
+		{0, "a x a b c a b"},
+		// a is passed over once, then has the next turn.
+		{2, "a x b a c b a"},
+	} {
+		s := EmptySchedule()
+		s.MinGap = c.minGap
+		s.Rotations = withEnds(day(15), []Rotation{
+			{Start: day(1), Primary: "a", Secondary: "b"},
+			{Start: day(8), Primary: "x", Secondary: "a"},
+		})
+		s.now = day(9)
+		s.ScheduleForDuration = 4 * Week
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if primaries := primariesOf(ns.Rotations); primaries != c.expected {
+			t.Errorf("MinGap %d: expected primaries %s, got %s", c.minGap, c.expected, primaries)
+		}
+	}
+}
+
+func TestMinGapBoundary(t *testing.T) {
+	// Three users can just keep two rotations between each one's turns.
+	s := EmptySchedule()
+	s.MinGap = 2
+	s.now = Start
+	s.ScheduleForDuration = 6 * Week
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primaries := primariesOf(ns.Rotations); primaries != "a b c a b c a" {
+		t.Errorf("expected the usual round-robin, got %s", primaries)
+	}
+
+	// But not three.
+	s.MinGap = 3
+	if _, err := s.Generate(); err == nil || !strings.Contains(err.Error(), "MinGap 3 needs at least 4 users to take turns as primary, one and then 3 others before them again, but there are 3") {
+		t.Errorf("expected an error explaining MinGap, got %v", err)
+	}
+
+	// Nor two, while one of the three is away.
+	s.MinGap = 2
+	s.Unavailable = []Unavailability{{User: "c", Start: day(15), End: day(22)}}
+	if _, err := s.Generate(); err == nil || !strings.Contains(err.Error(), "nobody can be primary of the rotation starting at 2017-02-15T10:00:00Z: MinGap 2 needs 3 users available") {
+		t.Errorf("expected an error explaining MinGap, got %v", err)
+	}
+}
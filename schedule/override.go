@@ -0,0 +1,149 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An Override puts User on call in one tier for a span of time, in place of
+// whoever the rotation assigns, e.g. to cover a two-day absence. Overrides are
+// applied after rotations are generated, splitting rotations where they start
+// and end, so they survive regeneration.
+type Override struct {
+	Start time.Time
+	End time.Time
+	// The tier overridden: one of the schedule's Roles, or TierPrimary or
+	// TierSecondary if it has none.
+	Role string
+	User string
+	// Allow the override to make User fill two tiers at once, which is
+	// otherwise an error.
+	Force bool `json:",omitempty"`
+}
+
+func (o Override) String() string {
+	return fmt.Sprintf("%s override by %s from %s to %s", o.Role, o.User, o.Start.Format(time.RFC3339), o.End.Format(time.RFC3339))
+}
+
+func (s Schedule) validateOverrides() error {
+	byRole := map[string][]Override{}
+	for _, o := range s.Overrides {
+		if s.tierIndex(o.Role) < 0 {
+			return fmt.Errorf("%s: Role must be one of %s", o, strings.Join(s.tiers(), ", "))
+		}
+		if o.User == "" {
+			return fmt.Errorf("%s: must name a User", o)
+		}
+		if !o.Start.Before(o.End) {
+			return fmt.Errorf("%s: must end after it starts", o)
+		}
+		byRole[o.Role] = append(byRole[o.Role], o)
+	}
+	for _, role := range s.tiers() {
+		os := byRole[role]
+		sort.Slice(os, func(i, j int) bool {
+			return os[i].Start.Before(os[j].Start)
+		})
+		for i := 1; i < len(os); i++ {
+			if os[i].Start.Before(os[i-1].End) {
+				return fmt.Errorf("overlapping %s overrides: %s to %s and %s to %s", role, os[i-1].Start.Format(time.RFC3339), os[i-1].End.Format(time.RFC3339), os[i].Start.Format(time.RFC3339), os[i].End.Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// Return the rotations the overrides in rs were applied to: segments that
+// continue a split rotation are dropped, and overridden users are restored.
+func (s Schedule) unapplyOverrides(rs []Rotation) []Rotation {
+	base := make([]Rotation, 0, len(rs))
+	for _, r := range rs {
+		if r.Continues && len(base) > 0 {
+			base[len(base)-1].End = r.End
+			continue
+		}
+		for tier, user := range r.Overridden {
+			r = s.withTierUser(r, tier, user)
+		}
+		r.Overridden = nil
+		r.Continues = false
+		base = append(base, r)
+	}
+	return base
+}
+
+// Apply overrides to the rotations of s, which must not already have
+// overrides applied. Each rotation is split into segments wherever an override
+// starts or ends during it, and each segment with an override in effect
+// records who it replaced.
+func (s *Schedule) applyOverrides() error {
+	overrides := s.effectiveOverrides()
+	if len(overrides) == 0 {
+		return nil
+	}
+	applied := []Rotation{}
+	for i, r := range s.Rotations {
+		start, end := r.Start, s.RotationEnd(i)
+		bounds := []time.Time{start}
+		for _, o := range overrides {
+			for _, b := range []time.Time{o.Start, o.End} {
+				if b.After(start) && b.Before(end) {
+					bounds = append(bounds, b)
+				}
+			}
+		}
+		sort.Slice(bounds, func(i, j int) bool {
+			return bounds[i].Before(bounds[j])
+		})
+		unique := bounds[:1]
+		for _, b := range bounds[1:] {
+			if !b.Equal(unique[len(unique)-1]) {
+				unique = append(unique, b)
+			}
+		}
+		bounds = append(unique, end)
+		for k, b := range bounds[:len(bounds)-1] {
+			seg := r
+			seg.Start = b
+			seg.End = bounds[k+1]
+			seg.Continues = k > 0
+			seg.ID = segmentID(r.ID, k)
+			if k > 0 {
+				// The rotation's notes are kept on its first segment.
+				seg.Notes = ""
+			}
+			for _, o := range overrides {
+				if b.Before(o.Start) || !b.Before(o.End) {
+					continue
+				}
+				if seg.Overridden == nil {
+					seg.Overridden = map[string]string{}
+				}
+				seg.Overridden[o.Role] = s.tierUser(r, o.Role)
+				seg = s.withTierUser(seg, o.Role, o.User)
+				if i, j := s.doubledTier(seg); i >= 0 && !o.Force {
+					return fmt.Errorf("%s makes %s both %s and %s from %s; set Force on the override to allow it", o, o.User, s.tiers()[i], s.tiers()[j], b.Format(time.RFC3339))
+				}
+			}
+			applied = append(applied, seg)
+		}
+	}
+	s.Rotations = applied
+	return nil
+}
+
+// Drop the overrides that ended before cutoff.
+func dropOverridesBefore(overrides []Override, cutoff time.Time) []Override {
+	kept := []Override{}
+	for _, o := range overrides {
+		if o.End.After(cutoff) {
+			kept = append(kept, o)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
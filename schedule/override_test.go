@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOverrideSplitsRotation(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(10), End: day(12), Role: TierSecondary, User: "a"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "b", Secondary: "c"},
+		{Start: day(10), Primary: "b", Secondary: "a", Overridden: map[string]string{TierSecondary: "c"}, Continues: true},
+		{Start: day(12), Primary: "b", Secondary: "c", Continues: true},
+		{Start: day(15), Primary: "c", Secondary: "a"},
+		{Start: day(22), Primary: "a", Secondary: "b"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+
+	// The override survives regeneration, after a round trip through JSON.
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), `"Overridden":{"secondary":"c"}`) {
+		t.Errorf("expected the overridden segment to be marked in JSON, got %s", text)
+	}
+	reloaded, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = day(9)
+	again, err := reloaded.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected[:5], again.Rotations[:5]) {
+		t.Errorf("regenerated rotations do not match\nExpected:\n%+v\n---\nGot:\n%+v\n", expected[:5], again.Rotations[:5])
+	}
+}
+
+func TestOverrideAcrossRotations(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(8), End: day(17), Role: TierPrimary, User: "c", Force: true}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "c", Secondary: "c", Overridden: map[string]string{TierPrimary: "b"}},
+		{Start: day(15), Primary: "c", Secondary: "a", Overridden: map[string]string{TierPrimary: "c"}},
+		{Start: day(17), Primary: "c", Secondary: "a", Continues: true},
+		{Start: day(22), Primary: "a", Secondary: "b"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	// Forcing c into both tiers is allowed, but still worth a warning.
+	if lint := strings.Join(ns.Lint(), "\n"); !strings.Contains(lint, "because of an override with Force set") {
+		t.Errorf("expected a warning about the forced override, got %s", lint)
+	}
+}
+
+func TestOverrideBothTiersNeedsForce(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(8), End: day(9), Role: TierPrimary, User: "c"}}
+	if _, err := s.Generate(); err == nil || !strings.Contains(err.Error(), "set Force") {
+		t.Errorf("expected an error asking for Force, got %v", err)
+	}
+}
+
+func TestOverridesDroppedWithRotations(t *testing.T) {
+	s := FilledSchedule()
+	s.now = day(20)
+	s.Overrides = []Override{
+		{Start: day(2), End: day(3), Role: TierPrimary, User: "c"},
+		{Start: day(16), End: day(17), Role: TierPrimary, User: "b"},
+	}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only rotations from day 8 are kept, so only the second override is.
+	if len(ns.Overrides) != 1 || !ns.Overrides[0].Start.Equal(day(16)) {
+		t.Errorf("expected only the override on day 16 to be kept, got %v", ns.Overrides)
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	for name, c := range map[string]struct {
+		overrides []Override
+		err string
+	}{
+		"overlapping": {
+			[]Override{
+				{Start: day(3), End: day(6), Role: TierPrimary, User: "a"},
+				{Start: day(5), End: day(9), Role: TierPrimary, User: "b"},
+			},
+			"overlapping primary overrides: 2017-02-03T10:00:00Z to 2017-02-06T10:00:00Z and 2017-02-05T10:00:00Z to 2017-02-09T10:00:00Z",
+		},
+		"role": {[]Override{{Start: day(3), End: day(6), Role: "tertiary", User: "a"}}, "Role must be"},
+		"backwards": {[]Override{{Start: day(6), End: day(3), Role: TierPrimary, User: "a"}}, "must end after it starts"},
+		"user": {[]Override{{Start: day(3), End: day(6), Role: TierPrimary}}, "must name a User"},
+	} {
+		s := EmptySchedule()
+		s.Overrides = c.overrides
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", name, c.err, err)
+		}
+	}
+
+	// Overrides of different tiers may overlap.
+	s := EmptySchedule()
+	s.Overrides = []Override{
+		{Start: day(3), End: day(6), Role: TierPrimary, User: "a"},
+		{Start: day(3), End: day(6), Role: TierSecondary, User: "b"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+}
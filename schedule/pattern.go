@@ -0,0 +1,28 @@
+package schedule
+
+import (
+	"fmt"
+)
+
+func (s Schedule) validatePattern() error {
+	if len(s.Pattern) == 0 {
+		return nil
+	}
+	if s.PatternNext < 0 {
+		return fmt.Errorf("PatternNext must not be negative (got %d)", s.PatternNext)
+	}
+	for _, u := range s.Pattern {
+		if !containsUser(s.Users, u) {
+			return fmt.Errorf("Pattern lists %s, who is not among the Users", u)
+		}
+	}
+	switch {
+	case len(s.PrimaryUsers) > 0:
+		return fmt.Errorf("Pattern cannot be used with PrimaryUsers; the pattern is the primaries' order")
+	case len(s.Windows) > 0 || len(s.Regions) > 0:
+		return fmt.Errorf("Pattern cannot be used with Windows or Regions, which keep their own orders")
+	case s.Fairness == FairnessBalanced:
+		return fmt.Errorf("Pattern cannot be used with balanced Fairness, which picks primaries by load rather than in order")
+	}
+	return nil
+}
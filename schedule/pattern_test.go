@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPattern(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Pattern = []string{"a", "b", "a", "c"}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "b", Secondary: "c"},
+		{Start: day(15), Primary: "a", Secondary: "b"},
+		{Start: day(22), Primary: "c", Secondary: "a"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	if !reflect.DeepEqual(ns.Pattern, s.Pattern) || ns.PatternNext != 0 || !reflect.DeepEqual(ns.Users, s.Users) {
+		t.Errorf("expected the pattern to come round again and Users to be left alone, got %v at %d and %v", ns.Pattern, ns.PatternNext, ns.Users)
+	}
+}
+
+func TestPatternContinuesMidPattern(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.ScheduleFor, s.ScheduleForDuration = "2w", 2 * Week
+	s.Pattern = []string{"a", "b", "a", "c"}
+	primaries := map[time.Time]string{}
+	positions := []int{}
+	for _, now := range []int{1, 9, 16} {
+		s.now = day(now)
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range ns.Rotations {
+			if p, ok := primaries[r.Start]; ok && p != r.Primary {
+				t.Errorf("expected the rotation starting at %s to keep %s, got %s", r.Start, p, r.Primary)
+			}
+			primaries[r.Start] = r.Primary
+		}
+		if !reflect.DeepEqual(ns.Pattern, []string{"a", "b", "a", "c"}) {
+			t.Errorf("expected the pattern to be left as written, got %v", ns.Pattern)
+		}
+		positions = append(positions, ns.PatternNext)
+
+		// Round trip through JSON, which only keeps PatternNext to say where
+		// in the pattern it is.
+		text, err := json.Marshal(ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, err = NewSchedule(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := []string{}
+	for d := 1; d <= 36; d += 7 {
+		got = append(got, primaries[day(d)])
+	}
+	if expected := []string{"a", "b", "a", "c", "a", "b"}; !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected primaries %v, got %v", expected, got)
+	}
+	if expected := []int{3, 1, 2}; !reflect.DeepEqual(expected, positions) {
+		t.Errorf("expected positions %v in the pattern, got %v", expected, positions)
+	}
+}
+
+func TestPatternNeverDoublesPrimary(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	// Taking the secondary from the pattern would put a in both tiers.
+	s.Pattern = []string{"a", "a", "b"}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ns.Rotations {
+		if r.Primary == r.Secondary {
+			t.Errorf("expected a different secondary from the primary, got %v", r)
+		}
+	}
+	if r := ns.Rotations[1]; r.Primary != "a" || r.Secondary != "b" {
+		t.Errorf("expected a second turn for a, with b after them in Users, got %v", r)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for _, c := range []struct {
+		edit func(s *Schedule)
+		err string
+	}{
+		{func(s *Schedule) { s.Pattern = []string{"a", "z"} }, "Pattern lists z, who is not among the Users"},
+		{func(s *Schedule) { s.Pattern, s.PrimaryUsers = []string{"a"}, []string{"a", "b"} }, "Pattern cannot be used with PrimaryUsers"},
+		{func(s *Schedule) { s.Pattern, s.Fairness = []string{"a"}, FairnessBalanced }, "Pattern cannot be used with balanced Fairness"},
+		{func(s *Schedule) { s.Pattern, s.PatternNext = []string{"a"}, -1 }, "PatternNext must not be negative"},
+	} {
+		s := EmptySchedule()
+		c.edit(s)
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
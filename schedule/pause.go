@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A TimeRange is the span of time from Start up to, but not including, End.
+type TimeRange struct {
+	Start time.Time
+	End time.Time
+}
+
+// Extend end, the end of a rotation starting at start, by the length of the
+// pauses the rotation covers, and return it with those pauses. A rotation
+// covers a pause that begins during it or right as it ends, so whoever is on
+// call going into a pause stays on call throughout it, and the pause does not
+// count towards their turn. Freezes are covered the same way.
+func (s Schedule) extendForPauses(start, end time.Time) (time.Time, []TimeRange) {
+	covered := []TimeRange{}
+	if len(s.Pauses) == 0 && len(s.Freezes) == 0 {
+		return end, covered
+	}
+	for _, p := range sortedPauses(append(append([]TimeRange{}, s.Pauses...), s.Freezes...)) {
+		if !p.End.After(start) {
+			continue
+		}
+		if p.Start.After(end) {
+			break
+		}
+		from := p.Start
+		if from.Before(start) {
+			from = start
+		}
+		end = end.Add(p.End.Sub(from))
+		covered = append(covered, p)
+	}
+	return end, covered
+}
+
+// The note recorded on a rotation starting at start that explains why it is
+// longer than RotationLength, or "" if it is not.
+func (s Schedule) pauseNote(start time.Time) string {
+	_, covered := s.extendForPauses(start, s.naturalEnd(start))
+	notes := []string{}
+	for _, p := range covered {
+		kind := "pause"
+		if containsRange(s.Freezes, p) {
+			kind = "freeze"
+		}
+		notes = append(notes, fmt.Sprintf("extended to cover the %s from %s to %s", kind, p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339)))
+	}
+	return strings.Join(notes, "; ")
+}
+
+func sortedPauses(pauses []TimeRange) []TimeRange {
+	sorted := append([]TimeRange{}, pauses...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	return sorted
+}
+
+func validatePauses(pauses []TimeRange) error {
+	sorted := sortedPauses(pauses)
+	for i, p := range sorted {
+		if !p.Start.Before(p.End) {
+			return fmt.Errorf("pause from %s must end after it starts (ends %s)", p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339))
+		}
+		if i > 0 && p.Start.Before(sorted[i-1].End) {
+			return fmt.Errorf("pause from %s overlaps the pause from %s", p.Start.Format(time.RFC3339), sorted[i-1].Start.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Drop the pauses or freezes that ended before cutoff, which can no longer
+// lengthen any rotation kept.
+func dropRangesBefore(ranges []TimeRange, cutoff time.Time) []TimeRange {
+	kept := []TimeRange{}
+	for _, r := range ranges {
+		if r.End.After(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
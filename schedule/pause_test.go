@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return Start.AddDate(0, 0, d - 1)
+}
+
+func TestGeneratePauseMidRotation(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Pauses = []TimeRange{{Start: day(10), End: day(17)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b's rotation is extended by the week-long pause, and c still follows b.
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "b", Secondary: "c", Notes: "extended to cover the pause from 2017-02-10T10:00:00Z to 2017-02-17T10:00:00Z"},
+		{Start: day(22), Primary: "c", Secondary: "a"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	if !reflect.DeepEqual([]string{"a", "b", "c"}, ns.Users) {
+		t.Errorf("expected a to be primary next, got %v", ns.Users)
+	}
+}
+
+func TestGeneratePauseAtHandoff(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Pauses = []TimeRange{{Start: day(8), End: day(15)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Whoever is on call going into the pause keeps it; there is no handoff at
+	// its start.
+	if ns.Rotations[0].Primary != "a" || !ns.Rotations[1].Start.Equal(day(15)) || ns.Rotations[1].Primary != "b" {
+		t.Errorf("expected a's rotation to cover the pause and b to follow it, got %+v", ns.Rotations)
+	}
+}
+
+func TestGeneratePauseExtendsKeptRotation(t *testing.T) {
+	s := FilledSchedule()
+	s.now = day(23)
+	s.Pauses = []TimeRange{{Start: day(25), End: day(29)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := ns.Rotations[1]
+	if !active.Start.Equal(day(22)) || active.Notes == "" {
+		t.Errorf("expected the active rotation to note the pause, got %+v", active)
+	}
+	if !ns.Rotations[2].Start.Equal(day(33)) {
+		t.Errorf("expected the next rotation to start at %s, got %+v", day(33), ns.Rotations[2])
+	}
+}
+
+func TestNominalEnd(t *testing.T) {
+	s := EmptySchedule()
+	s.Pauses = []TimeRange{
+		{Start: day(3), End: day(5)},
+		// Reached only because the first pause extends the rotation.
+		{Start: day(9), End: day(10)},
+		{Start: day(20), End: day(21)},
+	}
+	if end := s.NominalEnd(day(1)); !end.Equal(day(11)) {
+		t.Errorf("expected rotation to end at %s, got %s", day(11), end)
+	}
+}
+
+func TestValidatePauses(t *testing.T) {
+	for name, c := range map[string]struct {
+		pauses []TimeRange
+		err string
+	}{
+		"backwards": {[]TimeRange{{Start: day(5), End: day(3)}}, "must end after it starts"},
+		"overlapping": {[]TimeRange{{Start: day(5), End: day(9)}, {Start: day(3), End: day(6)}}, "overlaps"},
+	} {
+		s := EmptySchedule()
+		s.now = Start
+		s.Pauses = c.pauses
+		err := s.Validate()
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", name, c.err, err)
+		}
+	}
+}
+
+func TestGenerateDropsEndedPauses(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Pauses = []TimeRange{{Start: day(-10), End: day(-3)}, {Start: day(10), End: day(12)}}
+	// A pause entirely in the past is not an error, but is dropped once no
+	// rotation kept covers it.
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns.Pauses) != 1 || !ns.Pauses[0].Start.Equal(day(10)) {
+		t.Errorf("expected only the pause from %s to be kept, got %v", day(10), ns.Pauses)
+	}
+}
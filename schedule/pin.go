@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// How Generate continues the round-robin after a pinned rotation.
+const (
+	// Continue after the pinned rotation's primary, as after any other
+	// rotation, as if the pinned assignee had taken their normal turn. This is
+	// the default.
+	PinTurn = "turn"
+	// Continue from where the round-robin had reached before the pinned
+	// rotation, as if it were extra to the cycle.
+	PinExtra = "extra"
+)
+
+func validatePinPolicy(policy string) error {
+	switch policy {
+	case "", PinTurn, PinExtra:
+		return nil
+	}
+	return fmt.Errorf("unknown PinPolicy %q: must be %q or %q", policy, PinTurn, PinExtra)
+}
+
+func (s Schedule) validatePins() error {
+	for _, r := range s.Rotations {
+		if !r.Pinned {
+			continue
+		}
+		if r.End.IsZero() {
+			return fmt.Errorf("pinned rotation starting at %s must have an End", r.Start.Format(time.RFC3339))
+		}
+		if len(s.Windows) > 0 {
+			return fmt.Errorf("pinned rotation starting at %s: rotations cannot be pinned with Windows", r.Start.Format(time.RFC3339))
+		}
+		if len(s.Regions) > 0 {
+			return fmt.Errorf("pinned rotation starting at %s: rotations cannot be pinned with Regions", r.Start.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Split the rotations of s at the first gap, which only a pinned rotation may
+// follow, returning how many come before it and the rotations after it as
+// generated, in blocks each starting with a pinned rotation after a gap.
+func (s Schedule) splitPinned() (int, [][]Rotation) {
+	n := len(s.Rotations)
+	for i := 1; i < len(s.Rotations); i++ {
+		if prev := s.Rotations[i-1]; !prev.End.IsZero() && prev.End.Before(s.Rotations[i].Start) {
+			n = i
+			break
+		}
+	}
+	blocks := [][]Rotation{}
+	for i, r := range s.unapplyOverrides(s.Rotations[n:]) {
+		if i == 0 || !r.Start.Equal(blocks[len(blocks)-1][len(blocks[len(blocks)-1])-1].End) {
+			blocks = append(blocks, nil)
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], r)
+	}
+	return n, blocks
+}
+
+// Return the rotations of rs that count as turns of the round-robin: all of
+// them, or those that are not pinned under PinExtra.
+func (s Schedule) turns(rs []Rotation) []Rotation {
+	if s.PinPolicy != PinExtra {
+		return rs
+	}
+	turns := []Rotation{}
+	for _, r := range rs {
+		if !r.Pinned {
+			turns = append(turns, r)
+		}
+	}
+	return turns
+}
+
+// Add the block of rotations starting with a pinned rotation to the rotations
+// of ns, shortening the last rotation generated to end where the block starts,
+// and return the order of users and position to continue the round-robin from.
+func (ns *Schedule) addPinned(block []Rotation, users []string, p int) ([]string, int, error) {
+	pin := block[0]
+	if ns.Start.After(pin.Start) {
+		return nil, 0, fmt.Errorf("pinned rotation starting at %s starts before the rotation it follows ends at %s; edit the rotations around it", pin.Start.Format(time.RFC3339), ns.Start.Format(time.RFC3339))
+	}
+	ns.Rotations = append(ns.Rotations, block...)
+	last := block[len(block)-1]
+	ns.Start = last.End
+	if last.End.IsZero() {
+		ns.Start = ns.NominalEnd(last.Start)
+	}
+	// A Pattern, or a shuffled cycle, carries on where it was, as the
+	// pinned primaries cannot say where in it to continue from.
+	turns := ns.turns(block)
+	if len(turns) == 0 || len(ns.Pattern) > 0 || ns.Order == OrderShuffled {
+		return users, p, nil
+	}
+	return ns.continueOrder(users, turns, "Users"), 0, nil
+}
+
+// Shorten the last rotation of ns to end where the pinned rotation pin starts.
+func (ns *Schedule) shortenBefore(pin Rotation) {
+	last := &ns.Rotations[len(ns.Rotations)-1]
+	last.End = pin.Start
+	last.Notes = joinNotes(last.Notes, fmt.Sprintf("shortened to end as the pinned rotation starting at %s begins", pin.Start.Format(time.RFC3339)))
+	ns.Start = pin.Start
+}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The schedule generated from EmptySchedule, with a rotation pinned eleven
+// days after the last, regenerated with the rotation from day 22 under way.
+func pinnedSchedule(t *testing.T, policy string) (*Schedule, *Schedule) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.Rotations = append(ns.Rotations, Rotation{Start: day(40), End: day(47), Primary: "b", Secondary: "c", Pinned: true})
+	ns.PinPolicy = policy
+	ns.ScheduleForDuration = 5 * Week
+	ns.now = day(23)
+	if err := ns.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ns, again
+}
+
+func TestPinned(t *testing.T) {
+	for _, c := range []struct {
+		policy string
+		after []Rotation
+	}{
+		// The round-robin continues from b, the pinned primary.
+		{PinTurn, []Rotation{{Start: day(47), Primary: "c", Secondary: "a"}, {Start: day(54), Primary: "a", Secondary: "b"}, {Start: day(61), Primary: "b", Secondary: "c"}}},
+		// The round-robin continues from c, who had the rotation before.
+		{PinExtra, []Rotation{{Start: day(47), Primary: "a", Secondary: "b"}, {Start: day(54), Primary: "b", Secondary: "c"}, {Start: day(61), Primary: "c", Secondary: "a"}}},
+	} {
+		s, ns := pinnedSchedule(t, c.policy)
+		expected := withEnds(day(68), append([]Rotation{
+			{Start: day(15), Primary: "c", Secondary: "a"},
+			{Start: day(22), Primary: "a", Secondary: "b"},
+			{Start: day(29), Primary: "b", Secondary: "c"},
+			{Start: day(36), Primary: "c", Secondary: "a", Notes: "shortened to end as the pinned rotation starting at 2017-03-12T10:00:00Z begins"},
+			{Start: day(40), Primary: "b", Secondary: "c", Pinned: true},
+		}, c.after...))
+		if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+			t.Errorf("%s: rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", c.policy, expected, ns.Rotations)
+		}
+		d := NewDiff(s, ns)
+		if len(d.Pinned) != 1 || !strings.Contains(d.String(), "Added rotations around 1 pinned rotation, kept as written:\n  2017-03-12T10:00:00Z/2017-03-19T10:00:00Z b c pinned\n") {
+			t.Errorf("%s: expected the diff to call out the pinned rotation, got:\n%s", c.policy, d)
+		}
+
+		// Regenerating leaves the pinned rotation, and the round-robin, alone.
+		ns.now = day(41)
+		again, err := ns.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected[3:], again.Rotations[:len(expected)-3]) {
+			t.Errorf("%s: regenerated rotations do not match\nExpected:\n%+v\n---\nGot:\n%+v\n", c.policy, expected[3:], again.Rotations)
+		}
+	}
+}
+
+func TestPinnedValidation(t *testing.T) {
+	s := FilledSchedule()
+	s.Rotations = append([]Rotation{}, s.Rotations...)
+	s.Rotations[2].Pinned = true
+	s.Rotations[2].End = s.Rotations[2].End.Add(Day)
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "one of them is pinned") {
+		t.Errorf("expected an error for the pinned rotation overlapping the next, got %v", err)
+	}
+	s.Rotations[2].End = s.Rotations[2].End.Add(-2 * Day)
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "gap between") {
+		t.Errorf("expected an error for the gap after the pinned rotation, got %v", err)
+	}
+	s.Rotations[2].End = time.Time{}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "must have an End") {
+		t.Errorf("expected an error for the pinned rotation without an End, got %v", err)
+	}
+}
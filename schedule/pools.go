@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"fmt"
+)
+
+// A pool is the order the users of one tier are taken in while generating,
+// and the position to continue from.
+type pool struct {
+	users []string
+	p int
+}
+
+// Whether s takes primaries and secondaries from separate pools of users.
+func (s Schedule) separatePools() bool {
+	return len(s.PrimaryUsers) > 0 || len(s.SecondaryUsers) > 0
+}
+
+func (s Schedule) primaryPool() []string {
+	if len(s.PrimaryUsers) > 0 {
+		return s.PrimaryUsers
+	}
+	return s.Users
+}
+
+func (s Schedule) secondaryPool() []string {
+	if len(s.SecondaryUsers) > 0 {
+		return s.SecondaryUsers
+	}
+	return s.Users
+}
+
+// Return every user s may assign, in the order they first appear in Users,
+// PrimaryUsers, SecondaryUsers and the Users of each of Windows and Regions.
+func (s Schedule) allUsers() []string {
+	if !s.separatePools() && len(s.Windows) == 0 && len(s.Regions) == 0 {
+		return s.Users
+	}
+	lists := [][]string{s.Users, s.PrimaryUsers, s.SecondaryUsers}
+	for _, w := range s.Windows {
+		lists = append(lists, w.Users)
+	}
+	for _, g := range s.Regions {
+		lists = append(lists, g.Users)
+	}
+	seen := map[string]bool{}
+	users := []string{}
+	for _, us := range lists {
+		for _, u := range us {
+			if !seen[u] {
+				seen[u] = true
+				users = append(users, u)
+			}
+		}
+	}
+	return users
+}
+
+// Check that no user is listed twice in Users or either pool, which would
+// silently give them twice the shifts of everyone else.
+func (s Schedule) validateUserLists() error {
+	for _, list := range []struct {
+		name string
+		users []string
+	}{{"Users", s.Users}, {"PrimaryUsers", s.PrimaryUsers}, {"SecondaryUsers", s.SecondaryUsers}} {
+		seen := map[string]bool{}
+		for _, u := range list.users {
+			if seen[u] {
+				return fmt.Errorf("%s lists %s more than once", list.name, u)
+			}
+			seen[u] = true
+		}
+	}
+	return nil
+}
+
+func (s Schedule) validatePools() error {
+	if !s.separatePools() {
+		return nil
+	}
+	if len(s.primaryPool()) == 0 {
+		return fmt.Errorf("must provide at least 1 user in PrimaryUsers or Users")
+	}
+	if len(s.secondaryPool()) == 0 {
+		return fmt.Errorf("must provide at least 1 user in SecondaryUsers or Users")
+	}
+	if len(s.tiers()) != 2 {
+		return fmt.Errorf("PrimaryUsers and SecondaryUsers can only be used with 2 Roles (got %d)", len(s.tiers()))
+	}
+	if s.SecondaryMode != "" {
+		return fmt.Errorf("SecondaryMode cannot be used with PrimaryUsers or SecondaryUsers, which give secondaries their own order")
+	}
+	return nil
+}
+
+// Return the next available user in the secondary pool other than primary,
+// and advance the pool past them. Users skipped, because they are the primary
+// or unavailable, move up the order behind them so they are next in line. If
+// nobody but primary is available, primary is returned and the pool does not
+// advance.
+func (s *Schedule) nextSecondary(primary string) string {
+	q := s.secondaries
+	i := s.best(q.users, q.p, func(u string) float64 {
+		if u == primary {
+			return 1
+		}
+		return 0
+	})
+	if i < 0 || q.users[i] == primary {
+		return primary
+	}
+	for p := q.p % len(q.users); i != p; {
+		prev := mod(i - 1, len(q.users))
+		q.users[i], q.users[prev] = q.users[prev], q.users[i]
+		i = prev
+	}
+	q.p = i + 1
+	return q.users[i]
+}
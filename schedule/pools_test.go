@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPoolsSeparateRoundRobins(t *testing.T) {
+	s := EmptySchedule()
+	s.Users = nil
+	s.PrimaryUsers = []string{"a", "b", "c", "d"}
+	s.SecondaryUsers = []string{"x", "y"}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "x"},
+		{Start: day(8), Primary: "b", Secondary: "y"},
+		{Start: day(15), Primary: "c", Secondary: "x"},
+		{Start: day(22), Primary: "d", Secondary: "y"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	if !reflect.DeepEqual(ns.PrimaryUsers, []string{"a", "b", "c", "d"}) || !reflect.DeepEqual(ns.SecondaryUsers, []string{"x", "y"}) {
+		t.Errorf("expected both pools to be ordered with whoever is next first, got %v and %v", ns.PrimaryUsers, ns.SecondaryUsers)
+	}
+
+	// Regenerating, after a round trip through JSON, carries on from both
+	// pools rather than starting them over.
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = day(16)
+	again, err := reloaded.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = withEnds(day(50), append(expected[1:],
+		Rotation{Start: day(29), Primary: "a", Secondary: "x"},
+		Rotation{Start: day(36), Primary: "b", Secondary: "y"},
+		Rotation{Start: day(43), Primary: "c", Secondary: "x"},
+	))
+	if !reflect.DeepEqual(withIDs(expected), again.Rotations) {
+		t.Errorf("regenerated rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, again.Rotations)
+	}
+	if again.Users != nil {
+		t.Errorf("expected Users to stay unset, got %v", again.Users)
+	}
+}
+
+func TestPoolsSkipPrimary(t *testing.T) {
+	s := EmptySchedule()
+	s.PrimaryUsers = []string{"a", "b", "c"}
+	s.SecondaryUsers = []string{"c", "b"}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b is primary when their turn as secondary comes round, so c steps in and
+	// b is next in line instead.
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "c"},
+		{Start: day(8), Primary: "b", Secondary: "c"},
+		{Start: day(15), Primary: "c", Secondary: "b"},
+		{Start: day(22), Primary: "a", Secondary: "c"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+}
+
+func TestPoolsFallBackToUsers(t *testing.T) {
+	s := EmptySchedule()
+	s.SecondaryUsers = []string{"z"}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ns.Rotations {
+		if r.Secondary != "z" {
+			t.Errorf("expected z to be secondary for every rotation, got %s", r)
+		}
+	}
+	if !reflect.DeepEqual(ns.Users, []string{"b", "c", "a"}) {
+		t.Errorf("expected Users to remain the primary pool, got %v", ns.Users)
+	}
+
+	backfilled, err := ns.Backfill(Start.AddDate(0, 0, -14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(Start, []Rotation{
+		{Start: Start.AddDate(0, 0, -14), Primary: "b", Secondary: "z"},
+		{Start: Start.AddDate(0, 0, -7), Primary: "c", Secondary: "z"},
+	})
+	if !reflect.DeepEqual(expected, backfilled) {
+		t.Errorf("backfilled rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, backfilled)
+	}
+}
+
+func TestValidatePools(t *testing.T) {
+	for _, c := range []struct {
+		users, primaries, secondaries []string
+		err string
+	}{
+		{err: "must provide at least 1 user"},
+		{primaries: []string{"a", "b"}, err: "at least 1 user in SecondaryUsers or Users"},
+		{secondaries: []string{"a", "b"}, err: "at least 1 user in PrimaryUsers or Users"},
+		{primaries: []string{"a"}, secondaries: []string{"a"}, err: "1 users cannot fill 2 tiers"},
+		{users: []string{"a", "b", "a"}, err: "Users lists a more than once"},
+		{primaries: []string{"a", "b"}, secondaries: []string{"c", "c"}, err: "SecondaryUsers lists c more than once"},
+	} {
+		s := EmptySchedule()
+		s.Users, s.PrimaryUsers, s.SecondaryUsers = c.users, c.primaries, c.secondaries
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%v, %v, %v: expected error %q, got %v", c.users, c.primaries, c.secondaries, c.err, err)
+		}
+	}
+}
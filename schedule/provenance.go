@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/user"
+	"runtime/debug"
+	"time"
+)
+
+// Version is the version of oncallator recorded in the Provenance of generated
+// schedules. Release builds set it with
+//
+//	-ldflags "-X github.com/websdev/oncallator/schedule.Version=v1.2.3"
+var Version = "dev"
+
+// The module path of oncallator, to find its version in build info.
+const modulePath = "github.com/websdev/oncallator"
+
+// Provenance records the Generate run that last changed a schedule's
+// rotations, to answer the first questions asked of a schedule that looks
+// wrong: when and by what it was generated, and from what. It is replaced on
+// every run that changes the rotations, and kept by those that do not, so it
+// never counts as a change itself; Diff compares only rotations, and the input
+// hash leaves Provenance out.
+type Provenance struct {
+	// The oncallator version that ran Generate, as from toolVersion.
+	Version string
+	// The SHA-256 of the input schedule, excluding its own Provenance.
+	InputHash string
+	// The schedule's clock when Generate ran.
+	Clock time.Time
+	// How many Generate runs have changed the rotations, counting this one.
+	Generation int `json:",omitempty"`
+	// The host and user Generate ran as, if RecordHost is set.
+	Host string `json:",omitempty"`
+	User string `json:",omitempty"`
+}
+
+// Return the Provenance of a run generating from s at now, which follows any
+// Provenance s has.
+func newProvenance(s *Schedule, now time.Time) (*Provenance, error) {
+	input := *s
+	input.Provenance = nil
+	text, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(text)
+	p := &Provenance{
+		Version: toolVersion(),
+		InputHash: hex.EncodeToString(sum[:]),
+		Clock: now,
+		Generation: 1,
+	}
+	if s.Provenance != nil {
+		p.Generation = s.Provenance.Generation + 1
+	}
+	if s.RecordHost {
+		p.Host, _ = os.Hostname()
+		if u, err := user.Current(); err == nil {
+			p.User = u.Username
+		}
+	}
+	return p, nil
+}
+
+// Return the version of oncallator running: Version if a release build set it,
+// or else the module version in the binary's build info, e.g. after go
+// install, or else Version as it is.
+func toolVersion() string {
+	if Version != "dev" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return Version
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "(devel)" && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return Version
+}
@@ -0,0 +1,149 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func expectedProvenance(t *testing.T, s *Schedule) *Provenance {
+	p, err := newProvenance(s, s.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProvenance(t *testing.T) {
+	empty := EmptySchedule()
+	empty.now = Start
+	s, err := empty.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Provenance == nil {
+		t.Fatal("expected Generate to record its provenance")
+	}
+	if s.Provenance.Version != toolVersion() || s.Provenance.Version == "" || !s.Provenance.Clock.Equal(Start) || len(s.Provenance.InputHash) != 64 {
+		t.Errorf("unexpected provenance %+v", s.Provenance)
+	}
+
+	// Regenerating once a rotation is added replaces the provenance, and the
+	// previous provenance does not affect the input hash.
+	s.now = day(2)
+	again, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Provenance = nil
+	if hash := expectedProvenance(t, s).InputHash; again.Provenance.InputHash != hash {
+		t.Errorf("expected input hash %s, got %s", hash, again.Provenance.InputHash)
+	}
+	if again.Provenance.InputHash == expectedProvenance(t, empty).InputHash {
+		t.Errorf("expected different inputs to hash differently")
+	}
+}
+
+func TestProvenanceCountsChangedGenerations(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	generations := []int{}
+	for i, now := range []int{1, 1, 2, 2, 8} {
+		s.now = day(now)
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Runs that leave the rotations as they were leave Provenance as it
+		// was.
+		if changed := []int{1, 1, 2, 2, 2}[i]; !ns.Provenance.Clock.Equal(day(changed)) {
+			t.Errorf("expected Provenance to record the clock %s, got %s", day(changed), ns.Provenance.Clock)
+		}
+		if ns.Provenance.Host != "" || ns.Provenance.User != "" {
+			t.Errorf("expected no host or user without RecordHost, got %+v", ns.Provenance)
+		}
+		generations = append(generations, ns.Provenance.Generation)
+
+		// Round trip through JSON, as the schedule file does between runs.
+		text, err := json.Marshal(ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, err = NewSchedule(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first run fills the schedule, and the rotations only change again
+	// once time has passed and a rotation is added, not by the time the next
+	// rotation starts.
+	expected := []int{1, 1, 2, 2, 2}
+	for i := range expected {
+		if generations[i] != expected[i] {
+			t.Fatalf("expected generations %v, got %v", expected, generations)
+		}
+	}
+}
+
+func TestProvenanceDoesNotCountAsAChange(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, d, err := ns.GenerateWithDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("expected regenerating an unchanged schedule to be empty, got %s", d)
+	}
+	if again.Provenance != ns.Provenance {
+		t.Errorf("expected regenerating an unchanged schedule to keep its Provenance")
+	}
+}
+
+func TestRegenerateUnchangedIsByteIdentical(t *testing.T) {
+	s := EmptySchedule()
+	s.now = day(2)
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ns.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two later runs, through the file as the oncallator command does, that
+	// leave the rotations as they were.
+	for _, now := range []int{3, 8} {
+		if s, err = NewSchedule(expected); err != nil {
+			t.Fatal(err)
+		}
+		s.now = day(now)
+		if ns, err = s.Generate(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ns.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, got) {
+			t.Errorf("expected regenerating on day %d to leave the schedule as it was\nExpected:\n%s\n---\nGot:\n%s\n", now, expected, got)
+		}
+	}
+}
+
+func TestProvenanceRecordHost(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.RecordHost = true
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, err := os.Hostname(); err == nil && ns.Provenance.Host != host {
+		t.Errorf("expected host %s, got %s", host, ns.Provenance.Host)
+	}
+}
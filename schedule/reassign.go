@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReassignUser returns a copy of s with old, e.g. someone leaving the team,
+// replaced in every tier of the rotations yet to start, and how many rotations
+// were changed. If replacement is "", old's shifts are shared out instead:
+// each goes to the next available user in the round-robin of the tier's pool
+// who is not already on the rotation, as Generate would choose them. Each
+// rotation changed is noted with who it was reassigned from.
+//
+// Rotations that have started are left as they were worked, so that they stay
+// a record of who was on call; to take old off the rotation under way, add an
+// Override. It is an error if an Override yet to end puts old on call, since
+// it would put them back, or if replacement is already on a rotation old is
+// reassigned from.
+func (s *Schedule) ReassignUser(old, replacement string) (*Schedule, int, error) {
+	if old == "" {
+		return nil, 0, fmt.Errorf("must name the user to reassign")
+	}
+	if old == replacement {
+		return nil, 0, fmt.Errorf("cannot reassign %s to themselves", old)
+	}
+	if replacement != "" && !containsUser(s.allUsers(), replacement) {
+		return nil, 0, fmt.Errorf("cannot reassign %s to %s, who is not among the users to schedule", old, replacement)
+	}
+	now := s.currentTime()
+	for _, o := range s.Overrides {
+		if o.User == old && o.End.After(now) {
+			return nil, 0, fmt.Errorf("%s would put %s back on call; remove it first", o, old)
+		}
+	}
+
+	ns := *s
+	ns.Rotations = make([]Rotation, len(s.Rotations))
+	tiers := s.tiers()
+	// The position to continue each tier's round-robin from, and who took over
+	// each tier of the rotation being reassigned, for the segments an override
+	// split it into.
+	next := map[string]int{}
+	var start time.Time
+	var chosen map[string]string
+	changed := 0
+	for i, r := range s.Rotations {
+		r = r.clone()
+		if !r.Continues {
+			start, chosen = r.Start, map[string]string{}
+		}
+		if !start.After(now) {
+			ns.Rotations[i] = r
+			continue
+		}
+		pick := func(tier string) (string, error) {
+			if u, ok := chosen[tier]; ok {
+				return u, nil
+			}
+			if replacement != "" {
+				if containsUser(s.Assigned(r), replacement) {
+					return "", fmt.Errorf("cannot reassign %s to %s, who is already on the rotation starting at %s", old, replacement, r.Start.Format(time.RFC3339))
+				}
+				chosen[tier] = replacement
+				return replacement, nil
+			}
+			pool := s.secondaryPool()
+			if s.tierIndex(tier) == 0 {
+				pool = s.primaryPool()
+			}
+			at := ns
+			at.Start = start
+			j := at.best(pool, next[tier], func(u string) float64 {
+				if u == old || containsUser(s.Assigned(r), u) {
+					return 1
+				}
+				return 0
+			})
+			if j < 0 || pool[j] == old || containsUser(s.Assigned(r), pool[j]) {
+				return "", fmt.Errorf("nobody is available to take over %s's %s shift in the rotation starting at %s", old, tier, start.Format(time.RFC3339))
+			}
+			next[tier] = j + 1
+			chosen[tier] = pool[j]
+			return pool[j], nil
+		}
+		notes := []string{}
+		for _, tier := range tiers {
+			if r.Overridden[tier] == old {
+				u, err := pick(tier)
+				if err != nil {
+					return nil, 0, err
+				}
+				r.Overridden[tier] = u
+				notes = append(notes, fmt.Sprintf("%s reassigned from %s to %s", tier, old, u))
+			} else if s.tierUser(r, tier) == old {
+				u, err := pick(tier)
+				if err != nil {
+					return nil, 0, err
+				}
+				r = s.withTierUser(r, tier, u)
+				notes = append(notes, fmt.Sprintf("%s reassigned from %s to %s", tier, old, u))
+			}
+		}
+		if len(notes) > 0 {
+			r.Notes = joinNotes(append([]string{r.Notes}, notes...)...)
+			changed++
+		}
+		ns.Rotations[i] = r
+	}
+	return &ns, changed, nil
+}
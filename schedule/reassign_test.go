@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReassignUser(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	ns, n, err := s.ReassignUser("a", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 rotation reassigned, got %d", n)
+	}
+	// The rotation under way, with a as secondary, has started, so is left
+	// as it was worked.
+	if r := ns.Rotations[2]; r.Primary != "c" || r.Secondary != "a" || r.Notes != "" {
+		t.Errorf("expected the rotation under way to be left alone, got %v", r)
+	}
+	if r := ns.Rotations[3]; r.Primary != "c" || r.Secondary != "b" || r.Notes != "primary reassigned from a to c" {
+		t.Errorf("expected c to take a's place as primary, got %v (%s)", r, r.Notes)
+	}
+	if s.Rotations[3].Primary != "a" {
+		t.Errorf("expected the receiver to be left as it was, got %v", s.Rotations[3])
+	}
+}
+
+func TestReassignUserSecondaryOnly(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	// b is only secondary in the rotations yet to start, and their shifts
+	// are shared out.
+	ns, n, err := s.ReassignUser("b", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 rotation reassigned, got %d", n)
+	}
+	if r := ns.Rotations[3]; r.Primary != "a" || r.Secondary != "c" || r.Notes != "secondary reassigned from b to c" {
+		t.Errorf("expected c to take b's place as secondary, got %v (%s)", r, r.Notes)
+	}
+	if r := ns.Rotations[1]; r.Primary != "b" {
+		t.Errorf("expected the elapsed rotation to keep b, got %v", r)
+	}
+}
+
+func TestReassignUserAbsent(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	ns, n, err := s.ReassignUser("z", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || !reflect.DeepEqual(ns.Rotations, s.Rotations) {
+		t.Errorf("expected nothing to change, got %d rotations changed:\n%v", n, ns.Rotations)
+	}
+}
+
+func TestReassignUserRedistributes(t *testing.T) {
+	s := EmptySchedule()
+	s.Users = []string{"a", "b", "c", "d", "e"}
+	s.ScheduleFor, s.ScheduleForDuration = "10w", 10 * Week
+	s.now = Start
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = day(2)
+	ns, n, err := s.ReassignUser("b", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("expected b's 4 rotations yet to start to be reassigned, got %d", n)
+	}
+	took := map[string]int{}
+	for _, r := range ns.Rotations[1:] {
+		if r.Primary == "b" || r.Secondary == "b" {
+			t.Errorf("expected b to be reassigned, got %v", r)
+		}
+		if r.Primary == r.Secondary {
+			t.Errorf("expected a different user in each tier, got %v", r)
+		}
+		if fields := strings.Fields(r.Notes); len(fields) > 0 {
+			took[fields[len(fields)-1]]++
+		}
+	}
+	if len(took) < 2 {
+		t.Errorf("expected b's shifts to be shared out, got %v", took)
+	}
+	if ns.Rotations[0].Secondary != "b" {
+		t.Errorf("expected the rotation under way to keep b, got %v", ns.Rotations[0])
+	}
+}
+
+func TestReassignUserErrors(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	if _, _, err := s.ReassignUser("a", "b"); err == nil || !strings.Contains(err.Error(), "already on the rotation") {
+		t.Errorf("expected an error reassigning a to b, who is their secondary, got %v", err)
+	}
+	if _, _, err := s.ReassignUser("a", "z"); err == nil || !strings.Contains(err.Error(), "not among the users") {
+		t.Errorf("expected an error reassigning to an unknown user, got %v", err)
+	}
+	s.Overrides = []Override{{Start: time.Date(2017, time.February, 23, 10, 0, 0, 0, time.UTC), End: time.Date(2017, time.February, 24, 10, 0, 0, 0, time.UTC), Role: TierPrimary, User: "c"}}
+	if _, _, err := s.ReassignUser("c", ""); err == nil || !strings.Contains(err.Error(), "remove it first") {
+		t.Errorf("expected an error while an override puts c on call, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A Region is a part of the world that takes a shift each day from its own
+// users, so that on-call follows the sun.
+type Region struct {
+	// Identifies the region in the Region of each of its rotations.
+	Name string
+	// When the region's shift starts and ends each day, as HH:MM on a 24-hour
+	// clock in Timezone, e.g. "09:00". A shift may run past midnight.
+	Start string
+	End string
+	// The time zone of Start and End, as a name from the IANA Time Zone
+	// database, e.g. "Europe/London". Defaults to the schedule's Timezone.
+	Timezone string `json:",omitempty"`
+	// The users to take the region's shifts. Like Users, Generate leaves them
+	// ordered so that whoever is next comes first.
+	Users []string
+
+	// The loaded Timezone, or nil if it is not set.
+	location *time.Location
+}
+
+// Load the Timezone of each of Regions.
+func (s *Schedule) loadRegionLocations() error {
+	s.Regions = append([]Region(nil), s.Regions...)
+	for i, g := range s.Regions {
+		if g.Timezone == "" {
+			continue
+		}
+		loc, err := time.LoadLocation(g.Timezone)
+		if err != nil {
+			return &ParseError{Op: "loading", Field: "Timezone of region " + g.Name, Value: g.Timezone, Err: err}
+		}
+		s.Regions[i].location = loc
+	}
+	return nil
+}
+
+// The location g's hours are in at t.
+func (s Schedule) regionLocation(g Region, t time.Time) *time.Location {
+	if g.location != nil {
+		return g.location
+	}
+	return s.weekdayLocation(t)
+}
+
+// Parse a region's Start or End into hours and minutes.
+func parseRegionTime(text string) (int, int, error) {
+	m := handoffTimeFormat.FindStringSubmatch(text)
+	if m == nil {
+		return 0, 0, fmt.Errorf("must be HH:MM on a 24-hour clock (got %q)", text)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	return hour, min, nil
+}
+
+func (s Schedule) validateRegions() error {
+	if len(s.Regions) == 0 {
+		return nil
+	}
+	for _, f := range []struct {
+		name string
+		set bool
+	}{
+		{"Windows", len(s.Windows) > 0},
+		{"HandoffTime", s.HandoffTime != ""},
+		{"StartWeekday", s.StartWeekday != ""},
+		{"PrimaryUsers or SecondaryUsers", s.separatePools()},
+		{"Pauses", len(s.Pauses) > 0},
+		{"Freezes", len(s.Freezes) > 0},
+		{"Gaps", len(s.Gaps) > 0},
+		{"MinGap", s.MinGap > 0},
+	} {
+		if f.set {
+			return fmt.Errorf("%s cannot be used with Regions, which set when each rotation starts and ends and who it is taken from", f.name)
+		}
+	}
+	if s.RotationDuration <= 0 || s.RotationDuration % Day != 0 {
+		return fmt.Errorf("RotationLength must be a whole number of days with Regions, as each user keeps their region's daily shifts for that long (got %s)", s.RotationLength)
+	}
+	// The regions' hours are compared in UTC as of Start, since their zones
+	// may be apart by more or less at other times of year.
+	ref := s.Start
+	if ref.IsZero() {
+		ref = s.currentTime()
+	}
+	names := map[string]bool{}
+	starts := make([]time.Duration, len(s.Regions))
+	lengths := make([]time.Duration, len(s.Regions))
+	for i, g := range s.Regions {
+		if g.Name == "" {
+			return fmt.Errorf("Regions cannot include a region with no Name")
+		}
+		if names[g.Name] {
+			return fmt.Errorf("Regions lists %s more than once", g.Name)
+		}
+		names[g.Name] = true
+		startHour, startMin, err := parseRegionTime(g.Start)
+		if err != nil {
+			return fmt.Errorf("error parsing Start of region %s: %s", g.Name, err)
+		}
+		endHour, endMin, err := parseRegionTime(g.End)
+		if err != nil {
+			return fmt.Errorf("error parsing End of region %s: %s", g.Name, err)
+		}
+		local := ref.In(s.regionLocation(g, ref))
+		start := wallClock(local.Year(), local.Month(), local.Day(), startHour, startMin, local.Location()).UTC()
+		starts[i] = modDay(time.Duration(start.Hour()) * time.Hour + time.Duration(start.Minute()) * time.Minute)
+		lengths[i] = modDay(time.Duration(endHour - startHour) * time.Hour + time.Duration(endMin - startMin) * time.Minute)
+		if lengths[i] == 0 {
+			lengths[i] = Day
+		}
+		if len(g.Users) == 0 {
+			return fmt.Errorf("must provide at least 1 user in region %s", g.Name)
+		}
+		seen := map[string]bool{}
+		for _, u := range g.Users {
+			if seen[u] {
+				return fmt.Errorf("region %s lists %s more than once", g.Name, u)
+			}
+			seen[u] = true
+		}
+		if len(g.Users) < len(s.tiers()) && !s.AllowTierCollapse {
+			return fmt.Errorf("%d users in region %s cannot fill %d tiers (set AllowTierCollapse to leave the highest tiers unfilled)", len(g.Users), g.Name, len(s.tiers()))
+		}
+	}
+	// Each region must end exactly where the next to start begins.
+	order := make([]int, len(s.Regions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return starts[order[i]] < starts[order[j]]
+	})
+	utc := func(d time.Duration) string {
+		return time.Time{}.Add(modDay(d)).Format("15:04")
+	}
+	for k, i := range order {
+		j := order[(k + 1) % len(order)]
+		g, next := s.Regions[i], s.Regions[j]
+		until := modDay(starts[j] - starts[i])
+		if until == 0 {
+			until = Day
+		}
+		switch {
+		case lengths[i] < until:
+			return fmt.Errorf("region %s ends at %s UTC but the next region, %s, starts at %s UTC, leaving a gap; Regions must cover the day", g.Name, utc(starts[i] + lengths[i]), next.Name, utc(starts[j]))
+		case lengths[i] > until:
+			return fmt.Errorf("region %s ends at %s UTC, after the next region, %s, starts at %s UTC; Regions cannot overlap", g.Name, utc(starts[i] + lengths[i]), next.Name, utc(starts[j]))
+		}
+	}
+	return nil
+}
+
+func modDay(d time.Duration) time.Duration {
+	return (d % Day + Day) % Day
+}
+
+// Return the starts of each region's shifts from two days before t to two
+// days after, by region.
+func (s Schedule) regionStartsAround(t time.Time) map[string][]time.Time {
+	starts := map[string][]time.Time{}
+	for _, g := range s.Regions {
+		hour, min, err := parseRegionTime(g.Start)
+		if err != nil {
+			// Validate rejects this before any rotation is computed.
+			continue
+		}
+		local := t.In(s.regionLocation(g, t))
+		for d := -2; d <= 2; d++ {
+			starts[g.Name] = append(starts[g.Name], wallClock(local.Year(), local.Month(), local.Day() + d, hour, min, local.Location()))
+		}
+	}
+	return starts
+}
+
+// Return the name of the region whose shift covers t, or "" if Regions is not
+// set.
+func (s Schedule) regionAt(t time.Time) string {
+	latest, name := time.Time{}, ""
+	for region, starts := range s.regionStartsAround(t) {
+		for _, b := range starts {
+			if !b.After(t) && b.After(latest) {
+				latest, name = b, region
+			}
+		}
+	}
+	return name
+}
+
+// Return the first time after t at which a region's shift starts. Each shift
+// ends there, so the shifts cover the day even on days when a daylight saving
+// change moves one region's hours against another's.
+func (s Schedule) nextRegionStart(t time.Time) time.Time {
+	earliest := time.Time{}
+	for _, starts := range s.regionStartsAround(t) {
+		for _, b := range starts {
+			if b.After(t) && (earliest.IsZero() || b.Before(earliest)) {
+				earliest = b
+			}
+		}
+	}
+	if earliest.IsZero() {
+		return t.Add(Day)
+	}
+	return earliest
+}
+
+// The length of the shortest region's shift.
+func (s Schedule) shortestRegion() time.Duration {
+	shortest := Day
+	for _, g := range s.Regions {
+		startHour, startMin, err1 := parseRegionTime(g.Start)
+		endHour, endMin, err2 := parseRegionTime(g.End)
+		length := modDay(time.Duration(endHour - startHour) * time.Hour + time.Duration(endMin - startMin) * time.Minute)
+		if err1 == nil && err2 == nil && length > 0 && length < shortest {
+			shortest = length
+		}
+	}
+	return shortest
+}
+
+// The rotations of rs in the named region.
+func inRegion(rs []Rotation, name string) []Rotation {
+	in := []Rotation{}
+	for _, r := range rs {
+		if r.Region == name {
+			in = append(in, r)
+		}
+	}
+	return in
+}
+
+// A region's round-robin: its users in order, the position of the user whose
+// turn it is, how many of their shifts are left, and the position to continue
+// from after them.
+type regionTurn struct {
+	order []string
+	turn, left, next int
+}
+
+// Add rotations to ns, each one region's shift on one day, for as long as
+// more says to. Each region keeps the same user on its shifts for
+// RotationLength before moving on to the next of its users, and continues its
+// own round-robin from its last rotation in rs, moved on by the number of its
+// turns skipped.
+func (ns *Schedule) addRegionRotations(rs []Rotation, more func() bool, skipped map[string]int) error {
+	shifts := int(ns.RotationDuration / Day)
+	turns := map[string]*regionTurn{}
+	for _, g := range ns.Regions {
+		in := inRegion(rs, g.Name)
+		order := ns.continueOrder(g.Users, in, fmt.Sprintf("Users of region %s", g.Name))
+		t := &regionTurn{order: rotateUsers(order, skipped[g.Name] / shifts)}
+		// A turn under way carries on with whoever has it.
+		last := lastPrimary(in)
+		if run := trailingShifts(in, last); skipped[g.Name] == 0 && run > 0 && run < shifts && order[len(order)-1] == last {
+			t.turn, t.left = len(order) - 1, shifts - run
+		}
+		turns[g.Name] = t
+	}
+	for more() {
+		name := ns.regionAt(ns.Start)
+		t := turns[name]
+		users := t.order
+		start := t.left == 0
+		if start {
+			t.turn, t.left = t.next, shifts
+		} else {
+			// Only the first shift of a turn moves users in the round-robin;
+			// anyone covering a later one while the user whose turn it is
+			// is away does so without taking their place.
+			users = append([]string{}, t.order...)
+		}
+		p, err := ns.addRotation(users, t.turn)
+		if err != nil {
+			return err
+		}
+		if start {
+			t.next = p
+			if ns.Rotations[len(ns.Rotations)-1].Primary != Unassigned {
+				t.turn = mod(p - 1, len(users))
+			}
+		}
+		t.left--
+		ns.Rotations[len(ns.Rotations)-1].Region = name
+	}
+	// Leave each region's users in the order they are next taken in.
+	regions := make([]Region, len(ns.Regions))
+	for i, g := range ns.Regions {
+		t := turns[g.Name]
+		g.Users = rotateUsers(t.order, t.next)
+		regions[i] = g
+	}
+	ns.Regions = regions
+	return nil
+}
+
+// How many of the last of rs in a row user was primary of.
+func trailingShifts(rs []Rotation, user string) int {
+	n := 0
+	for i := len(rs) - 1; i >= 0 && rs[i].Primary == user; i-- {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tokyo, London and New York, which in February cover 00:00-08:00,
+// 08:00-16:00 and 16:00-00:00 UTC.
+const RegionsScheduleText = `{
+	"Start": "2017-02-06T00:00:00Z",
+	"RotationLength": "2d",
+	"ScheduleFor": "96h",
+	"Regions": [
+		{"Name": "apac", "Start": "09:00", "End": "17:00", "Timezone": "Asia/Tokyo", "Users": ["a", "b"]},
+		{"Name": "emea", "Start": "08:00", "End": "16:00", "Timezone": "Europe/London", "Users": ["c", "d"]},
+		{"Name": "amer", "Start": "11:00", "End": "19:00", "Timezone": "America/New_York", "Users": ["e", "f"]}
+	]
+}`
+
+func RegionsSchedule(t *testing.T) *Schedule {
+	s, err := NewSchedule([]byte(RegionsScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// r as String writes it, but in UTC.
+func utcString(r Rotation) string {
+	r.Start, r.End = r.Start.UTC(), r.End.UTC()
+	return r.String()
+}
+
+func TestRegionsGenerate(t *testing.T) {
+	s := RegionsSchedule(t)
+	s.now = s.Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each region keeps its user for two days' shifts before moving on.
+	expected := []string{}
+	for day, users := range []string{"a b|c d|e f", "a b|c d|e f", "b a|d c|f e", "b a|d c|f e", "a b"} {
+		date := time.Date(2017, time.February, 6 + day, 0, 0, 0, 0, time.UTC)
+		for i, u := range strings.Split(users, "|") {
+			start, end := date.Add(time.Duration(i) * 8 * time.Hour), date.Add(time.Duration(i + 1) * 8 * time.Hour)
+			expected = append(expected, start.Format(time.RFC3339) + "/" + end.Format(time.RFC3339) + " " + u + " region=" + []string{"apac", "emea", "amer"}[i])
+		}
+	}
+	if len(ns.Rotations) != len(expected) {
+		t.Fatalf("expected %d rotations, got %v", len(expected), ns.Rotations)
+	}
+	for i, r := range ns.Rotations {
+		if got := utcString(r); got != expected[i] {
+			t.Errorf("rotation %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+	// apac has started a's second turn, so b is next, and the others are
+	// about to start theirs.
+	for i, next := range []string{"b,a", "c,d", "e,f"} {
+		if users := strings.Join(ns.Regions[i].Users, ","); users != next {
+			t.Errorf("expected the users of %s to be left as %s, got %s", ns.Regions[i].Name, next, users)
+		}
+	}
+}
+
+func TestRegionsContinueTurnUnderWay(t *testing.T) {
+	s := RegionsSchedule(t)
+	s.now = s.Start
+	first, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep only the first day, and regenerate partway through it.
+	first.Rotations = first.Rotations[:3]
+	first.now = s.Start.Add(12 * time.Hour)
+	second, err := first.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	primaries := []string{}
+	for _, r := range second.Rotations {
+		primaries = append(primaries, r.Primary)
+	}
+	// The first day is kept, and each region's user has one more shift.
+	if got := strings.Join(primaries, " "); !strings.HasPrefix(got, "a c e a c e b d f") {
+		t.Errorf("expected each region's first turn to finish before the next starts, got %s", got)
+	}
+}
+
+func TestRegionsInUTC(t *testing.T) {
+	s := RegionsSchedule(t)
+	s.now = s.Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// In July, New York and London are an hour ahead, so their shifts start
+	// an hour earlier in UTC and apac's runs an hour longer.
+	ns.now = time.Date(2017, time.July, 3, 0, 0, 0, 0, time.UTC)
+	ns, err = ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ns.Rotations {
+		if r.Region == "emea" && r.Start.UTC().Hour() != 7 {
+			t.Errorf("expected emea's shifts to start at 07:00 UTC in July, got %s", utcString(r))
+		}
+	}
+}
+
+func TestValidateRegions(t *testing.T) {
+	for _, c := range []struct {
+		edit func(s *Schedule)
+		err string
+	}{
+		{func(s *Schedule) { s.Regions[1].End = "15:00" }, "region emea ends at 15:00 UTC but the next region, amer, starts at 16:00 UTC, leaving a gap"},
+		{func(s *Schedule) { s.Regions[0].End = "18:00" }, "region apac ends at 09:00 UTC, after the next region, emea, starts at 08:00 UTC; Regions cannot overlap"},
+		{func(s *Schedule) { s.RotationLength, s.RotationDuration = "36h", 36 * time.Hour }, "RotationLength must be a whole number of days"},
+		{func(s *Schedule) { s.Regions[2].Users = []string{"e"} }, "1 users in region amer cannot fill 2 tiers"},
+		{func(s *Schedule) { s.Regions[2].Name = "emea" }, "Regions lists emea more than once"},
+		{func(s *Schedule) { s.HandoffTime = "09:00" }, "HandoffTime cannot be used with Regions"},
+		{func(s *Schedule) { s.Regions[0].Start = "9am" }, "error parsing Start of region apac"},
+	} {
+		s := RegionsSchedule(t)
+		s.Regions = append([]Region(nil), s.Regions...)
+		c.edit(s)
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error containing %q, got %v", c.err, err)
+		}
+	}
+	text := strings.Replace(RegionsScheduleText, "Asia/Tokyo", "Asia/Nowhere", 1)
+	if _, err := NewSchedule([]byte(text)); err == nil || !strings.Contains(err.Error(), "error loading Timezone of region apac") {
+		t.Errorf("expected an unknown Timezone to be rejected, got %v", err)
+	}
+}
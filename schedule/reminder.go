@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+)
+
+// A Reminder is a nudge to send a user ahead of a shift, e.g. from cron.
+type Reminder struct {
+	// When to send the reminder: LeadTime before the rotation starts.
+	At time.Time
+	LeadTime time.Duration
+	Rotation Rotation
+	Tier string
+	User string
+}
+
+// Reminders returns the reminders to send ahead of the shifts in the rotations
+// starting in window, one for each of leadTimes before each shift, e.g. 48
+// hours and 1 hour before handoff. A zero End of window leaves it open. A
+// segment an override split off a rotation only starts shifts in the tiers
+// whose user it changes. Reminders already due to have been sent by the
+// schedule's clock are left out, and the rest are ordered by when to send
+// them, then as the shifts are, so that a consumer can walk them in order.
+// Nothing is sent.
+func (s Schedule) Reminders(leadTimes []time.Duration, window TimeRange) []Reminder {
+	now := s.currentTime()
+	reminders := []Reminder{}
+	for i, r := range s.Rotations {
+		if r.Start.Before(window.Start) || (!window.End.IsZero() && !r.Start.Before(window.End)) {
+			continue
+		}
+		for _, tier := range s.tiers() {
+			user := s.tierUser(r, tier)
+			if user == "" || (r.Continues && i > 0 && s.tierUser(s.Rotations[i-1], tier) == user) {
+				continue
+			}
+			for _, lead := range leadTimes {
+				at := r.Start.Add(-lead)
+				if at.Before(now) {
+					continue
+				}
+				reminders = append(reminders, Reminder{
+					At: at,
+					LeadTime: lead,
+					Rotation: r,
+					Tier: tier,
+					User: user,
+				})
+			}
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return reminders[i].At.Before(reminders[j].At)
+	})
+	return reminders
+}
@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Return a line for each of rs, to compare with expected.
+func reminderLines(rs []Reminder) []string {
+	lines := []string{}
+	for _, r := range rs {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s", r.At.Format(time.RFC3339), r.LeadTime, r.Tier, r.User))
+	}
+	return lines
+}
+
+func TestReminders(t *testing.T) {
+	s := FilledSchedule()
+	s.now = Start
+	reminders := s.Reminders([]time.Duration{48 * time.Hour, time.Hour}, TimeRange{Start: Start, End: day(22)})
+	// The reminders for the first rotation are already due, and the last
+	// rotation starts at the end of the window.
+	expected := []string{
+		"2017-02-06T10:00:00Z 48h0m0s primary b",
+		"2017-02-06T10:00:00Z 48h0m0s secondary c",
+		"2017-02-08T09:00:00Z 1h0m0s primary b",
+		"2017-02-08T09:00:00Z 1h0m0s secondary c",
+		"2017-02-13T10:00:00Z 48h0m0s primary c",
+		"2017-02-13T10:00:00Z 48h0m0s secondary a",
+		"2017-02-15T09:00:00Z 1h0m0s primary c",
+		"2017-02-15T09:00:00Z 1h0m0s secondary a",
+	}
+	if got := reminderLines(reminders); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected reminders\n%v\ngot\n%v", expected, got)
+	}
+	if !reminders[0].Rotation.Start.Equal(day(8)) {
+		t.Errorf("expected the first reminder to carry its rotation, got %v", reminders[0].Rotation)
+	}
+
+	// A reminder due right now is still to be sent.
+	s.now = day(6)
+	if got := s.Reminders([]time.Duration{48 * time.Hour}, TimeRange{Start: Start}); len(got) != 6 || !got[0].At.Equal(day(6)) {
+		t.Errorf("expected the reminders from now on, got %v", reminderLines(got))
+	}
+}
+
+func TestRemindersLongerThanRotation(t *testing.T) {
+	s := FilledSchedule()
+	s.now = Start
+	// Ten days ahead of a weekly rotation, the first reminder for a shift
+	// comes before the last for the shift before it.
+	reminders := s.Reminders([]time.Duration{time.Hour, 10 * 24 * time.Hour}, TimeRange{Start: day(8)})
+	expected := []string{
+		"2017-02-05T10:00:00Z 240h0m0s primary c",
+		"2017-02-05T10:00:00Z 240h0m0s secondary a",
+		"2017-02-08T09:00:00Z 1h0m0s primary b",
+		"2017-02-08T09:00:00Z 1h0m0s secondary c",
+		"2017-02-12T10:00:00Z 240h0m0s primary a",
+		"2017-02-12T10:00:00Z 240h0m0s secondary b",
+		"2017-02-15T09:00:00Z 1h0m0s primary c",
+		"2017-02-15T09:00:00Z 1h0m0s secondary a",
+		"2017-02-22T09:00:00Z 1h0m0s primary a",
+		"2017-02-22T09:00:00Z 1h0m0s secondary b",
+	}
+	if got := reminderLines(reminders); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected reminders\n%v\ngot\n%v", expected, got)
+	}
+}
+
+func TestRemindersForOverrides(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(10), End: day(12), Role: TierSecondary, User: "a"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the user an override puts on call, and the user it hands back to,
+	// start a shift when it splits a rotation.
+	reminders := ns.Reminders([]time.Duration{time.Hour}, TimeRange{Start: day(9), End: day(15)})
+	expected := []string{
+		"2017-02-10T09:00:00Z 1h0m0s secondary a",
+		"2017-02-12T09:00:00Z 1h0m0s secondary c",
+	}
+	if got := reminderLines(reminders); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected reminders\n%v\ngot\n%v", expected, got)
+	}
+	if got := ns.Reminders(nil, TimeRange{Start: Start}); len(got) != 0 {
+		t.Errorf("expected no reminders without lead times, got %v", reminderLines(got))
+	}
+}
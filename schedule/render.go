@@ -0,0 +1,267 @@
+package schedule
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RenderView is how much of the calendar each row Render writes covers.
+type RenderView string
+
+const (
+	// A row per day, with a heading for each week.
+	RenderWeek RenderView = "week"
+	// A row per week, starting on Monday, with a heading for each month.
+	RenderMonth RenderView = "month"
+)
+
+// How many characters of a user's name Render shows by default.
+const DefaultRenderNameWidth = 12
+
+// RenderOptions control how Render lays out a schedule.
+type RenderOptions struct {
+	// The span of time to render. A zero Start or End defaults to the start of
+	// the first rotation or the end of the last.
+	Window TimeRange
+	// Defaults to RenderWeek.
+	View RenderView
+	// Whether to leave out the secondary, e.g. for a standup that only needs
+	// to know who is primary.
+	HideSecondary bool
+	// The location dates are judged in. Defaults to DisplayTimezone, then to
+	// Timezone, then to the location of Start.
+	Location *time.Location
+	// Whether to highlight rows with ANSI escape codes, for a terminal.
+	Color bool
+	// Longer names are cut short with an ellipsis so that the columns stay
+	// aligned. Defaults to DefaultRenderNameWidth.
+	NameWidth int
+}
+
+const (
+	renderUnderWay = "*"
+	renderOverride = "~"
+	renderPinned = "+"
+)
+
+// Render writes a plain-text calendar of s to w, e.g. to show at a standup: a
+// row for each day (or week) of the window, with who is primary and secondary
+// during it. Where a handoff happens during a row, everyone on call in that
+// row is listed in turn. Rows during the rotation under way are marked with
+// "*", rows where an override changed who is on call with "~", and rows
+// covered by a pinned rotation, arranged by hand, with "+". Only presentation
+// is affected; s is not changed.
+func (s Schedule) Render(w io.Writer, opts RenderOptions) error {
+	if len(s.Rotations) == 0 {
+		return fmt.Errorf("no rotations to render")
+	}
+	loc := opts.Location
+	for _, l := range []*time.Location{s.displayLocation, s.location, s.Start.Location()} {
+		if loc == nil {
+			loc = l
+		}
+	}
+	window := opts.Window
+	if window.Start.IsZero() {
+		window.Start = s.Rotations[0].Start
+	}
+	if window.End.IsZero() {
+		window.End = s.RotationEnd(len(s.Rotations) - 1)
+	}
+	if !window.Start.Before(window.End) {
+		return fmt.Errorf("cannot render the empty window from %s to %s", window.Start.Format(time.RFC3339), window.End.Format(time.RFC3339))
+	}
+	view := opts.View
+	if view == "" {
+		view = RenderWeek
+	}
+	if view != RenderWeek && view != RenderMonth {
+		return fmt.Errorf("unknown view %q (must be %s or %s)", view, RenderWeek, RenderMonth)
+	}
+	nameWidth := opts.NameWidth
+	if nameWidth <= 0 {
+		nameWidth = DefaultRenderNameWidth
+	}
+	tiers := s.tiers()[:1]
+	if !opts.HideSecondary && len(s.tiers()) > 1 {
+		tiers = s.tiers()[:2]
+	}
+
+	// The rotation under way, including any segments an override split off.
+	now := s.currentTime()
+	var underWay TimeRange
+	for i, r := range s.Rotations {
+		if r.Start.After(now) {
+			break
+		}
+		if !r.Continues {
+			underWay.Start = r.Start
+		}
+		if s.RotationEnd(i).After(now) {
+			underWay.End = s.RotationEnd(i)
+			for j := i + 1; j < len(s.Rotations) && s.Rotations[j].Continues; j++ {
+				underWay.End = s.RotationEnd(j)
+			}
+			break
+		}
+	}
+
+	type row struct {
+		heading string
+		marks string
+		cells []string
+	}
+	rows := []row{}
+	heading := ""
+	start := midnight(window.Start.In(loc))
+	if view == RenderMonth {
+		start = monday(start)
+	}
+	for start.Before(window.End) {
+		next := time.Date(start.Year(), start.Month(), start.Day() + 1, 0, 0, 0, 0, loc)
+		label, h := start.Format("Mon 2 Jan"), "Week of " + monday(start).Format("Mon 2 Jan 2006")
+		if view == RenderMonth {
+			next = time.Date(start.Year(), start.Month(), start.Day() + 7, 0, 0, 0, 0, loc)
+			h = start.Format("January 2006")
+		}
+		from, to := start, next
+		if from.Before(window.Start) {
+			from = window.Start
+		}
+		if to.After(window.End) {
+			to = window.End
+		}
+		start = next
+
+		rw := row{cells: []string{label}}
+		if h != heading {
+			rw.heading, heading = h, h
+		}
+		users := make([][]string, len(tiers))
+		overridden, pinned := false, false
+		for i, r := range s.Rotations {
+			if !r.Start.Before(to) || !s.RotationEnd(i).After(from) {
+				continue
+			}
+			overridden = overridden || r.Overridden != nil
+			pinned = pinned || r.Pinned
+			for t, tier := range tiers {
+				u := s.tierUser(r, tier)
+				if u == "" {
+					u = "-"
+				}
+				if n := len(users[t]); n == 0 || users[t][n-1] != u {
+					users[t] = append(users[t], u)
+				}
+			}
+		}
+		for t := range tiers {
+			names := []string{}
+			for _, u := range users[t] {
+				names = append(names, truncateName(u, nameWidth))
+			}
+			if len(names) == 0 {
+				names = []string{"-"}
+			}
+			rw.cells = append(rw.cells, strings.Join(names, " → "))
+		}
+		if underWay.Start.Before(to) && underWay.End.After(from) {
+			rw.marks += renderUnderWay
+		}
+		if overridden {
+			rw.marks += renderOverride
+		}
+		if pinned {
+			rw.marks += renderPinned
+		}
+		rows = append(rows, rw)
+	}
+
+	header := []string{""}
+	for _, tier := range tiers {
+		header = append(header, strings.ToUpper(tier))
+	}
+	widths := make([]int, len(header))
+	for _, rw := range append([]row{{cells: header}}, rows...) {
+		for i, c := range rw.cells {
+			if n := utf8.RuneCountInString(c); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	line := func(marks string, cells []string) string {
+		text := fmt.Sprintf("%-3s", marks)
+		for i, c := range cells {
+			text += " " + c
+			if i < len(cells) - 1 {
+				text += strings.Repeat(" ", widths[i] - utf8.RuneCountInString(c) + 1)
+			}
+		}
+		return strings.TrimRight(text, " ")
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s\n", line("", header))
+	marked := false
+	for _, rw := range rows {
+		if rw.heading != "" {
+			fmt.Fprintf(b, "\n%s\n", rw.heading)
+		}
+		text := line(rw.marks, rw.cells)
+		if opts.Color {
+			text = colorRow(text, rw.marks)
+		}
+		fmt.Fprintf(b, "%s\n", text)
+		marked = marked || rw.marks != ""
+	}
+	if marked {
+		fmt.Fprintf(b, "\n%s under way  %s override  %s pinned\n", renderUnderWay, renderOverride, renderPinned)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Return the midnight starting the day t falls on, in t's location.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Return the midnight starting the Monday on or before t, in t's location.
+func monday(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day() - (int(t.Weekday()) + 6) % 7, 0, 0, 0, 0, t.Location())
+}
+
+// Return name cut short with an ellipsis if it is longer than width
+// characters.
+func truncateName(name string, width int) string {
+	runes := []rune(name)
+	if len(runes) <= width {
+		return name
+	}
+	if width < 2 {
+		return "…"
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// Return text wrapped in the ANSI escape codes for marks: bold for the
+// rotation under way, and yellow for an override or cyan for a pinned
+// rotation.
+func colorRow(text, marks string) string {
+	codes := []string{}
+	if strings.Contains(marks, renderUnderWay) {
+		codes = append(codes, "1")
+	}
+	if strings.Contains(marks, renderOverride) {
+		codes = append(codes, "33")
+	} else if strings.Contains(marks, renderPinned) {
+		codes = append(codes, "36")
+	}
+	if len(codes) == 0 {
+		return text
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
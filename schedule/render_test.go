@@ -0,0 +1,88 @@
+package schedule_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+const renderScheduleText = `
+{
+	"Users": ["alice", "bob", "bartholomew-longname", "dee"],
+	"Start": "2017-02-01T10:00:00Z",
+	"RotationLength": "1w",
+	"ScheduleFor": "4w",
+	"Overrides": [
+		{"Start": "2017-02-09T10:00:00Z", "End": "2017-02-10T10:00:00Z", "Role": "primary", "User": "dee"}
+	]
+}`
+
+func TestRenderGolden(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(renderScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetNow(time.Date(2017, time.February, 3, 0, 0, 0, 0, time.UTC))
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		opts schedule.RenderOptions
+	}{
+		{"week", schedule.RenderOptions{
+			Window: schedule.TimeRange{
+				Start: time.Date(2017, time.February, 1, 10, 0, 0, 0, time.UTC),
+				End: time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC),
+			},
+		}},
+		{"month-primary-color", schedule.RenderOptions{View: schedule.RenderMonth, HideSecondary: true, Color: true, NameWidth: 6}},
+	} {
+		b := &bytes.Buffer{}
+		if err := ns.Render(b, c.opts); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		path := filepath.Join("testdata", "render", c.name + ".txt")
+		if *update {
+			if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != string(expected) {
+			t.Errorf("%s: rendering does not match %s; got:\n%s", c.name, path, b)
+		}
+	}
+}
+
+func TestRenderTruncatesNames(t *testing.T) {
+	s, err := schedule.NewSchedule([]byte(renderScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetNow(time.Date(2017, time.February, 3, 0, 0, 0, 0, time.UTC))
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	if err := ns.Render(b, schedule.RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if text := b.String(); strings.Contains(text, "bartholomew-longname") || !strings.Contains(text, "bartholomew…") {
+		t.Errorf("expected long names cut short, got:\n%s", text)
+	}
+	if err := ns.Render(b, schedule.RenderOptions{View: "year"}); err == nil {
+		t.Errorf("expected an error for an unknown view")
+	}
+}
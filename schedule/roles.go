@@ -0,0 +1,123 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The tiers filled by every rotation, lowest first, unless Roles is set.
+var defaultTiers = []string{TierPrimary, TierSecondary}
+
+// Return the tiers filled by every rotation of s, lowest first.
+func (s Schedule) tiers() []string {
+	if len(s.Roles) > 0 {
+		return s.Roles
+	}
+	return defaultTiers
+}
+
+// Tiers returns the tiers filled by every rotation of s, lowest first:
+// Roles, or TierPrimary and TierSecondary if it is not set.
+func (s Schedule) Tiers() []string {
+	return append([]string{}, s.tiers()...)
+}
+
+func validateRoles(roles []string) error {
+	seen := map[string]bool{}
+	for _, role := range roles {
+		if role == "" {
+			return fmt.Errorf("Roles cannot include an empty role")
+		}
+		if seen[role] {
+			return fmt.Errorf("Roles lists %s more than once", role)
+		}
+		seen[role] = true
+	}
+	return nil
+}
+
+// Assigned returns the users assigned to each tier of r, lowest first. A tier
+// left unfilled has an empty user.
+func (s Schedule) Assigned(r Rotation) []string {
+	users := make([]string, len(s.tiers()))
+	for i, tier := range s.tiers() {
+		users[i] = s.tierUser(r, tier)
+	}
+	return users
+}
+
+// Return the user assigned to tier in r. The first two tiers are kept in
+// Primary and Secondary, whatever they are called, and the rest in Assignments.
+func (s Schedule) tierUser(r Rotation, tier string) string {
+	switch s.tierIndex(tier) {
+	case 0:
+		return r.Primary
+	case 1:
+		return r.Secondary
+	}
+	return r.Assignments[tier]
+}
+
+// Return r with user assigned to tier.
+func (s Schedule) withTierUser(r Rotation, tier, user string) Rotation {
+	switch s.tierIndex(tier) {
+	case 0:
+		r.Primary = user
+	case 1:
+		r.Secondary = user
+	default:
+		assignments := map[string]string{}
+		for t, u := range r.Assignments {
+			assignments[t] = u
+		}
+		if user == "" {
+			delete(assignments, tier)
+		} else {
+			assignments[tier] = user
+		}
+		if len(assignments) == 0 {
+			assignments = nil
+		}
+		r.Assignments = assignments
+	}
+	return r
+}
+
+func (s Schedule) tierIndex(tier string) int {
+	for i, t := range s.tiers() {
+		if t == tier {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return the first pair of tiers of r assigned the same user, ignoring
+// unfilled tiers and the Unassigned placeholder, or -1s if there is none.
+func (s Schedule) doubledTier(r Rotation) (int, int) {
+	users := s.Assigned(r)
+	for i := range users {
+		for j := i + 1; j < len(users); j++ {
+			if users[i] != "" && users[i] != Unassigned && users[i] == users[j] {
+				return i, j
+			}
+		}
+	}
+	return -1, -1
+}
+
+// Render the tiers of r beyond the first two, ordered by tier name so the
+// result does not depend on the schedule they belong to.
+func assignmentsString(r Rotation) string {
+	roles := []string{}
+	for role := range r.Assignments {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	parts := []string{}
+	for _, role := range roles {
+		parts = append(parts, fmt.Sprintf("%s=%s", role, r.Assignments[role]))
+	}
+	return strings.Join(parts, " ")
+}
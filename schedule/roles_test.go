@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRolesThreeTiers(t *testing.T) {
+	s := EmptySchedule()
+	s.Users = []string{"a", "b", "c", "d"}
+	s.Roles = []string{TierPrimary, TierSecondary, "ic"}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2017-02-01T10:00:00Z/2017-02-08T10:00:00Z a b ic=c",
+		"2017-02-08T10:00:00Z/2017-02-15T10:00:00Z b c ic=d",
+		"2017-02-15T10:00:00Z/2017-02-22T10:00:00Z c d ic=a",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z d a ic=b",
+	}
+	if len(ns.Rotations) != len(expected) {
+		t.Fatalf("expected %d rotations, got %v", len(expected), ns.Rotations)
+	}
+	for i, r := range ns.Rotations {
+		if r.String() != expected[i] {
+			t.Errorf("rotation %d: expected %q, got %q", i, expected[i], r)
+		}
+	}
+	if users := ns.Assigned(ns.Rotations[0]); !reflect.DeepEqual(users, []string{"a", "b", "c"}) {
+		t.Errorf("expected a, b and c to be assigned, got %v", users)
+	}
+
+	// The roles and assignments survive a round trip through JSON.
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ns.Rotations, reloaded.Rotations) || !reflect.DeepEqual(ns.Roles, reloaded.Roles) {
+		t.Errorf("round trip changed the schedule\nExpected:\n%+v\n---\nGot:\n%+v\n", ns.Rotations, reloaded.Rotations)
+	}
+
+	events := ns.HandoffNotifications(day(8))
+	if len(events) != 3 || events[2].Tier != "ic" || events[2].Outgoing != "c" || events[2].Incoming != "d" {
+		t.Errorf("expected a handoff for each tier, got %+v", events)
+	}
+}
+
+func TestRolesOverride(t *testing.T) {
+	s := EmptySchedule()
+	s.Users = []string{"a", "b", "c", "d"}
+	s.Roles = []string{"oncall", "backup", "ic"}
+	s.now = Start
+	s.Overrides = []Override{{Start: day(3), End: day(5), Role: "ic", User: "d"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2017-02-01T10:00:00Z/2017-02-03T10:00:00Z a b ic=c",
+		"2017-02-03T10:00:00Z/2017-02-05T10:00:00Z a b ic=d",
+		"2017-02-05T10:00:00Z/2017-02-08T10:00:00Z a b ic=c",
+	}
+	for i, e := range expected {
+		if r := ns.Rotations[i]; r.String() != e {
+			t.Errorf("rotation %d: expected %q, got %q", i, e, r)
+		}
+	}
+
+	s.Overrides = []Override{{Start: day(3), End: day(5), Role: "ic", User: "a"}}
+	if _, err := s.Generate(); err == nil || !strings.Contains(err.Error(), "both oncall and ic") {
+		t.Errorf("expected an error about a doubling up, got %v", err)
+	}
+
+	s.Overrides = []Override{{Start: day(3), End: day(5), Role: TierPrimary, User: "d"}}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "Role must be one of oncall, backup, ic") {
+		t.Errorf("expected an error about the unknown role, got %v", err)
+	}
+}
+
+func TestValidateRoles(t *testing.T) {
+	s := EmptySchedule()
+	s.Roles = []string{TierPrimary, TierPrimary}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("expected an error about the repeated role, got %v", err)
+	}
+}
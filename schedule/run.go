@@ -0,0 +1,169 @@
+package schedule
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Input is a schedule document as plain data: exactly the fields written to a
+// schedule file, with durations in their written form and no clock. It is the
+// input to Run, for callers that store schedules themselves rather than in
+// files.
+//
+// The fields tagged schedule:"generated" are Generate's output rather than
+// its configuration, and are what SaveComposite writes back.
+type Input struct {
+	FormatVersion int
+	Users []User
+	PrimaryUsers []string `json:",omitempty"`
+	SecondaryUsers []string `json:",omitempty"`
+	Pattern []string `json:",omitempty"`
+	PatternNext int `json:",omitempty" schedule:"generated"`
+	Start time.Time `schedule:"generated"`
+	Timezone string `json:",omitempty"`
+	DisplayTimezone string `json:",omitempty"`
+	HandoffTime string `json:",omitempty"`
+	StartWeekday string `json:",omitempty"`
+	Windows []WeekWindow `json:",omitempty"`
+	Regions []Region `json:",omitempty"`
+	RotationLength string
+	SecondaryRotationLength string `json:",omitempty"`
+	// A pointer, so that it is left out unless SecondaryRotationLength is
+	// used.
+	SecondaryStart *time.Time `json:",omitempty" schedule:"generated"`
+	ScheduleFor string
+	MaxRotations int `json:",omitempty"`
+	HandoffGrace string `json:",omitempty"`
+	UserEndDates map[string]time.Time `json:",omitempty"`
+	Roles []string `json:",omitempty"`
+	AllowTierCollapse bool `json:",omitempty"`
+	Unavailable []Unavailability `json:",omitempty"`
+	AllowUnassigned bool `json:",omitempty"`
+	LandmarkShifts []Landmark `json:",omitempty"`
+	LandmarkCounts map[string]map[string]int `json:",omitempty" schedule:"generated"`
+	Holidays []Holiday `json:",omitempty"`
+	HolidayPolicy string `json:",omitempty"`
+	BurdenWindows []WeightedRange `json:",omitempty"`
+	SecondaryMode string `json:",omitempty"`
+	Fairness string `json:",omitempty"`
+	Weights map[string]float64 `json:",omitempty"`
+	Order string `json:",omitempty"`
+	Seed int64 `json:",omitempty" schedule:"generated"`
+	Cycle int `json:",omitempty" schedule:"generated"`
+	MinGap int `json:",omitempty"`
+	Trainees []string `json:",omitempty"`
+	TraineeShifts int `json:",omitempty"`
+	ShiftCounts map[string]ShiftCount `json:",omitempty" schedule:"generated"`
+	Pauses []TimeRange `json:",omitempty"`
+	Freezes []TimeRange `json:",omitempty"`
+	FreezeUser string `json:",omitempty"`
+	Gaps []TimeRange `json:",omitempty"`
+	Overrides []Override `json:",omitempty"`
+	Swaps []Swap `json:",omitempty"`
+	PinPolicy string `json:",omitempty"`
+	Hooks []Hook `json:",omitempty"`
+	Rotations []Rotation `schedule:"generated"`
+	KeepHistory bool `json:",omitempty"`
+	HistoryFor string `json:",omitempty"`
+	HistoryMax int `json:",omitempty"`
+	History []Rotation `json:",omitempty" schedule:"generated"`
+	Provenance *Provenance `json:",omitempty" schedule:"generated"`
+	RecordHost bool `json:",omitempty"`
+}
+
+// Output is the result of Run.
+type Output struct {
+	// The generated schedule, ready to be stored and passed to a later Run.
+	Schedule Input
+	// Suggestions from Lint for the generated schedule.
+	Warnings []string `json:",omitempty"`
+}
+
+// Run parses and validates in, generates it as of now and reports on the
+// result. It is the whole of what the oncallator command does to a schedule
+// file, without the file I/O, and shares its parsing with NewSchedule and its
+// generation with Generate.
+//
+// Run does not modify in and keeps no state between calls, so it is safe to
+// call concurrently with distinct inputs.
+func Run(in Input, now time.Time) (Output, error) {
+	s, err := in.parse()
+	if err != nil {
+		return Output{}, err
+	}
+	s.now = now
+	ns, err := s.Generate()
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{
+		Schedule: ns.Input(),
+		Warnings: ns.Lint(),
+	}, nil
+}
+
+// MarshalJSON writes s as its Input, so that Users are written with their
+// details.
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Input())
+}
+
+// Input returns the serialized fields of s.
+func (s *Schedule) Input() Input {
+	return Input{
+		FormatVersion: s.FormatVersion,
+		Users: s.usersWithDetails(),
+		PrimaryUsers: s.PrimaryUsers,
+		SecondaryUsers: s.SecondaryUsers,
+		Pattern: s.Pattern,
+		PatternNext: s.PatternNext,
+		Start: s.Start,
+		Timezone: s.Timezone,
+		DisplayTimezone: s.DisplayTimezone,
+		HandoffTime: s.HandoffTime,
+		StartWeekday: s.StartWeekday,
+		Windows: s.Windows,
+		Regions: s.Regions,
+		RotationLength: s.RotationLength,
+		SecondaryRotationLength: s.SecondaryRotationLength,
+		SecondaryStart: s.secondaryStart(),
+		ScheduleFor: s.ScheduleFor,
+		MaxRotations: s.MaxRotations,
+		HandoffGrace: s.HandoffGrace,
+		UserEndDates: s.UserEndDates,
+		Roles: s.Roles,
+		AllowTierCollapse: s.AllowTierCollapse,
+		Unavailable: s.Unavailable,
+		AllowUnassigned: s.AllowUnassigned,
+		LandmarkShifts: s.LandmarkShifts,
+		LandmarkCounts: s.LandmarkCounts,
+		Holidays: s.Holidays,
+		HolidayPolicy: s.HolidayPolicy,
+		BurdenWindows: s.BurdenWindows,
+		SecondaryMode: s.SecondaryMode,
+		Fairness: s.Fairness,
+		Weights: s.Weights,
+		Order: s.Order,
+		Seed: s.Seed,
+		Cycle: s.Cycle,
+		MinGap: s.MinGap,
+		Trainees: s.Trainees,
+		TraineeShifts: s.TraineeShifts,
+		ShiftCounts: s.ShiftCounts,
+		Pauses: s.Pauses,
+		Freezes: s.Freezes,
+		FreezeUser: s.FreezeUser,
+		Gaps: s.Gaps,
+		Overrides: s.Overrides,
+		Swaps: s.Swaps,
+		PinPolicy: s.PinPolicy,
+		Hooks: s.Hooks,
+		Rotations: s.Rotations,
+		KeepHistory: s.KeepHistory,
+		HistoryFor: s.HistoryFor,
+		HistoryMax: s.HistoryMax,
+		History: s.History,
+		Provenance: s.Provenance,
+		RecordHost: s.RecordHost,
+	}
+}
@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	in := Input{}
+	if err := json.Unmarshal([]byte(FilledScheduleText), &in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Run(in, Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := FilledSchedule()
+	s.now = Start
+	expected, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected.Input(), out.Schedule) {
+		t.Errorf("Run does not match Generate\nExpected:\n%+v\n---\nGot:\n%+v\n", expected.Input(), out.Schedule)
+	}
+}
+
+func TestRunConcurrent(t *testing.T) {
+	in := Input{}
+	if err := json.Unmarshal([]byte(FilledScheduleText), &in); err != nil {
+		t.Fatal(err)
+	}
+	first, err := Run(in, Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	outs := make([]Output, 8)
+	errs := make([]error, len(outs))
+	for i := range outs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			in := Input{}
+			if err := json.Unmarshal([]byte(FilledScheduleText), &in); err != nil {
+				errs[i] = err
+				return
+			}
+			outs[i], errs[i] = Run(in, Start)
+		}(i)
+	}
+	wg.Wait()
+	for i := range outs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if !reflect.DeepEqual(first, outs[i]) {
+			t.Errorf("concurrent Run %d differs\nExpected:\n%+v\n---\nGot:\n%+v\n", i, first, outs[i])
+		}
+	}
+}
+
+// Input must carry every serialized field of Schedule, or Run and NewSchedule
+// would silently drop it.
+func TestInputMatchesSchedule(t *testing.T) {
+	s := FilledSchedule()
+	s.AllowTierCollapse = true
+	s.HandoffGrace = "2h"
+	expected, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(s.Input())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(got) {
+		t.Errorf("Input does not serialize like Schedule\nExpected:\n%s\n---\nGot:\n%s\n", expected, got)
+	}
+
+	fields := map[string]bool{}
+	it := reflect.TypeOf(Input{})
+	for i := 0; i < it.NumField(); i++ {
+		fields[it.Field(i).Name] = true
+	}
+	st := reflect.TypeOf(Schedule{})
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+			continue
+		}
+		if !fields[f.Name] {
+			t.Errorf("Input has no %s field", f.Name)
+		}
+	}
+}
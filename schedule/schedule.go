@@ -2,140 +2,1561 @@ package schedule
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"time"
 )
 
 // A Schedule holds 1) a pagerduty oncall schedule and 2) the data needed to
 // generate/extend the oncall schedule.
 type Schedule struct {
+	// The version of the schedule document format. Older documents are
+	// migrated when parsed, and generated schedules are always written at
+	// CurrentFormatVersion.
+	FormatVersion int
 	// A list of users to schedule. The first user listed will be primary on the
 	// first generated shift and the second user will be secondary. Upon schedule
 	// generation, the users field will be updated to indicate who is primary
-	// next.
+	// next. Once there are Rotations, though, generation continues the cycle
+	// from the primary of the last one, so Users need not be kept in step
+	// with edits to Rotations.
+	//
+	// In a schedule file, each user may be written as an object with details
+	// such as their Email (see User), which User looks up. Rotations refer
+	// to users by name only.
 	Users []string
+	// Separate pools to take primaries and secondaries from, each in its own
+	// round-robin. Either falls back to Users when it is not set. Like Users,
+	// Generate leaves each pool ordered so that whoever is next comes first.
+	PrimaryUsers []string `json:",omitempty"`
+	SecondaryUsers []string `json:",omitempty"`
+	// If set, the order primaries are taken in, cycled in place of Users, e.g.
+	// ["alice", "bob", "alice", "carol"] for Alice every other week. Names may
+	// repeat, and must be among the Users; secondaries are still taken from
+	// Users, or SecondaryUsers, as usual.
+	Pattern []string `json:",omitempty"`
+	// The index in Pattern of the next primary. A name the pattern repeats
+	// cannot say where in it the rotations left off, so Generate keeps this
+	// to carry on mid-pattern.
+	PatternNext int `json:",omitempty"`
 	// The start date of the first rotation.
 	Start time.Time
+	// The IANA name of the time zone rotations hand off in, e.g.
+	// "America/New_York". If set, rotations of whole days keep the same
+	// wall-clock handoff time across daylight saving changes, and times are
+	// written in that zone. Otherwise, rotations are a fixed length of absolute
+	// time and times keep the offsets they were written with.
+	Timezone string `json:",omitempty"`
+	// The IANA name of the time zone rotations are rendered in by String
+	// methods and reports such as Diff and Stats, e.g. "Europe/London" for a
+	// team reading a schedule that hands off in another zone. Only how times
+	// are shown changes; they are stored and written as before.
+	DisplayTimezone string `json:",omitempty"`
+	// The time of day, as HH:MM on a 24-hour clock in Timezone, at which
+	// rotations hand off. If set, each generated rotation starts at the first
+	// HandoffTime at or after the end of the one before, so rotations that are
+	// not a whole number of days long are rounded up: with a HandoffTime, a
+	// 36h rotation starting at 09:30 ends at 09:30 two days later.
+	HandoffTime string `json:",omitempty"`
+	// The day of the week, e.g. "Tuesday", on which rotations start. If set,
+	// each generated rotation starts on the first StartWeekday at or after the
+	// end of the one before, which keeps the cadence of rotations a whole
+	// number of weeks long. A rotation that does not start on StartWeekday,
+	// such as the first one when Start is mid-week, ends early on the next
+	// StartWeekday.
+	StartWeekday string `json:",omitempty"`
+	// Spans of each week, such as weekdays and weekends, each staffed from its
+	// own users in its own round-robin. If set, the windows must cover the week
+	// without overlapping, each rotation covers one occurrence of a window, and
+	// RotationLength is not used.
+	Windows []WeekWindow `json:",omitempty"`
+	// Regions that follow the sun, each taking a shift every day from its own
+	// users. If set, the regions' hours must cover the day without overlapping
+	// once converted to UTC, each rotation is one region's shift on one day,
+	// and RotationLength, a whole number of days, is how long each user keeps
+	// their region's shifts before the next takes over.
+	Regions []Region `json:",omitempty"`
 	// How long a single rotation lasts.
-	// Formatted as a Go Duration (https://golang.org/pkg/time/#ParseDuration).
+	// Formatted as a Go Duration (https://golang.org/pkg/time/#ParseDuration),
+	// which may also use days and weeks, e.g. "1w" or "10d"; see ParseDuration.
 	RotationLength string
 	// Parsed RotationLength
 	RotationDuration time.Duration `json:"-"`
+	// How long each secondary keeps the role, if not for RotationLength like
+	// the primary, e.g. "4w" for a secondary who backs up four weekly
+	// primaries in turn. The secondary then hands off on its own cadence,
+	// taking SecondaryUsers in their own round-robin, and rotations are split
+	// wherever either role hands off, so that each still has one Primary and
+	// one Secondary.
+	SecondaryRotationLength string `json:",omitempty"`
+	SecondaryRotationDuration time.Duration `json:"-"`
+	// When the next secondary turn starts, with SecondaryRotationLength.
+	// Generate keeps it up to date as it does Start, and leaves whoever holds
+	// the secondary turn under way last in SecondaryUsers.
+	SecondaryStart time.Time `json:"-"`
 	// A duration -- how far out to schedule rotations.
 	ScheduleFor string
 	ScheduleForDuration time.Duration `json:"-"`
+	// The most rotations Generate may leave in Rotations, as a guard against
+	// a typo such as a RotationLength of "1m" (a minute, where a month was
+	// meant) filling the schedule with thousands of rotations. Defaults to
+	// DefaultMaxRotations.
+	MaxRotations int `json:",omitempty"`
+	// A duration -- how close to a handoff HandoffNotifications must be invoked
+	// to report it. Defaults to DefaultHandoffGrace.
+	HandoffGrace string `json:",omitempty"`
+	HandoffGraceDuration time.Duration `json:"-"`
+	// The last day of each departing user. Generate will not assign a user to
+	// any rotation starting at or after their end date, but leaves their
+	// existing earlier assignments in place.
+	UserEndDates map[string]time.Time `json:",omitempty"`
+	// The tiers filled by every rotation, lowest first, if not just TierPrimary
+	// and TierSecondary. The first two are kept in each rotation's Primary and
+	// Secondary, whatever they are called, and the rest in its Assignments.
+	Roles []string `json:",omitempty"`
+	// Whether rotations may leave the highest tiers unfilled (an empty user)
+	// when there are too few users, or too few still eligible, to fill every
+	// tier with a different user. Otherwise that is an error. A team of one
+	// sets this to be on call alone, with no secondary, rather than as both.
+	AllowTierCollapse bool `json:",omitempty"`
+	// Spans of time users cannot be on call. Generate does not assign a user to
+	// any rotation overlapping one of theirs. Unavailabilities that ended
+	// before the rotations kept by Generate are dropped.
+	Unavailable []Unavailability `json:",omitempty"`
+	// Whether Generate may fill a rotation nobody is available for with the
+	// Unassigned placeholder, so that the gap is visible. Otherwise that is an
+	// error.
+	AllowUnassigned bool `json:",omitempty"`
+	// Recurring days whose shifts are undesirable enough that they are shared
+	// out fairly across years, rather than falling to whoever the round-robin
+	// happens to reach.
+	LandmarkShifts []Landmark `json:",omitempty"`
+	// For each landmark, how many times each user has been primary over it.
+	// Generate updates the counts as it assigns rotations.
+	LandmarkCounts map[string]map[string]int `json:",omitempty"`
+	// Days, such as company holidays, that rotations covering them are noted
+	// against, so that whoever draws them is not left to chance unseen.
+	Holidays []Holiday `json:",omitempty"`
+	// How Generate treats rotations over Holidays: HolidayAnnotate, the
+	// default, or HolidayAvoid.
+	HolidayPolicy string `json:",omitempty"`
+	// Windows of time whose on-call hours count for more or less than usual
+	// when measuring each user's load.
+	BurdenWindows []WeightedRange `json:",omitempty"`
+	// Who Generate makes each rotation's secondary: SecondaryNextPrimary, the
+	// default, or SecondaryPreviousPrimary.
+	SecondaryMode string `json:",omitempty"`
+	// How Generate chooses each rotation's primary: FairnessRoundRobin, the
+	// default, or FairnessBalanced.
+	Fairness string `json:",omitempty"`
+	// Each user's share of the primary shifts relative to everyone else's,
+	// e.g. 0.5 for someone on call half as often by agreement. Users not
+	// listed have a weight of 1, and a user with a weight of 0 is only made
+	// primary when nobody else is available. Requires FairnessBalanced.
+	Weights map[string]float64 `json:",omitempty"`
+	// How Generate orders Users for the round-robin: OrderRoundRobin, the
+	// default, or OrderShuffled.
+	Order string `json:",omitempty"`
+	// With OrderShuffled, seeds each cycle's permutation along with its
+	// index, so that regenerating permutes alike. Generate sets it on first
+	// use.
+	Seed int64 `json:",omitempty"`
+	// With OrderShuffled, how many cycles through Users have been permuted.
+	// Users then holds the order of the cycle under way from its start,
+	// rather than from whoever is next.
+	Cycle int `json:",omitempty"`
+	// How many rotations must come between two of a user's primary shifts,
+	// e.g. 1 so that nobody is primary two rotations running. Generate passes
+	// over a user until then, and makes them primary as soon as it can after.
+	MinGap int `json:",omitempty"`
+	// New users who shadow the primary before joining the users to schedule.
+	// Generate puts each in turn in the Shadow of TraineeShifts consecutive
+	// rotations, without changing who is primary or secondary, and Lint
+	// suggests graduating them once they are done.
+	Trainees []string `json:",omitempty"`
+	TraineeShifts int `json:",omitempty"`
+	// How much of the on-call each user has done in rotations that have since
+	// been dropped. Generate adds to the counts as it drops elapsed rotations.
+	ShiftCounts map[string]ShiftCount `json:",omitempty"`
+	// Periods during which the rotation does not advance, such as a company
+	// shutdown. The rotation in progress when a pause starts is extended to
+	// cover it, and the round-robin resumes afterwards as if the pause had not
+	// happened. Pauses that ended before the rotations kept by Generate are
+	// dropped.
+	Pauses []TimeRange `json:",omitempty"`
+	// Periods, such as a shutdown week, during which the rotation is frozen.
+	// They are covered like Pauses, by whoever is on call as a freeze starts,
+	// unless FreezeUser is set, in which case FreezeUser is primary throughout
+	// them instead, even if also on another tier, splitting the rotation they
+	// fall in as an override would.
+	// Either way, the rotation's user keeps the rest of their turn afterwards
+	// and everyone after them moves back. Freezes that ended before the
+	// rotations kept by Generate are dropped.
+	Freezes []TimeRange `json:",omitempty"`
+	FreezeUser string `json:",omitempty"`
+	// Periods with nobody on call at all, such as a volunteer team's winter
+	// break. Generate covers each with an Unstaffed rotation, cutting short the
+	// rotation before it, and the round-robin resumes afterwards where it left
+	// off.
+	Gaps []TimeRange `json:",omitempty"`
+	// Changes to who is on call for spans of time, applied on top of the
+	// generated rotations. Overrides that ended before the rotations kept by
+	// Generate are dropped.
+	Overrides []Override `json:",omitempty"`
+	// Exchanges of two users' places in the rotations in spans of time,
+	// applied after Overrides. Swaps that ended before the rotations kept by
+	// Generate are dropped.
+	Swaps []Swap `json:",omitempty"`
+	// How Generate continues the round-robin after a pinned rotation: PinTurn,
+	// the default, or PinExtra.
+	PinPolicy string `json:",omitempty"`
+	// Follow-ups to run when Generate changes the rotations.
+	Hooks []Hook `json:",omitempty"`
 
 	// The oncall rotations. This is generated by the scheduler, but may be
-	// modified by hand. Modifications will be reflected in the machine-friendly
-	// Primary and Secondary rotations after the next schedule generation
-	// invocation.
+	// modified by hand. Modifications are reflected in the machine-friendly
+	// flat spans of each tier that Layers derives from them.
 	Rotations []Rotation
 
+	// Whether Generate archives the rotations it truncates in History rather
+	// than dropping them, keeping those that ended within HistoryFor of now,
+	// and at most the last HistoryMax, if either is set.
+	KeepHistory bool `json:",omitempty"`
+	HistoryFor string `json:",omitempty"`
+	HistoryForDuration time.Duration `json:"-"`
+	HistoryMax int `json:",omitempty"`
+	// Elapsed rotations, oldest first, as they were worked. Generate only
+	// ever adds to and trims History; it works from Rotations alone.
+	History []Rotation `json:",omitempty"`
+
+	// Records the Generate run that last changed the rotations, and how
+	// many runs have.
+	Provenance *Provenance `json:",omitempty"`
+	// Whether Generate records the host and user it ran as in Provenance.
+	// Off by default, as not everyone wants them in a shared file.
+	RecordHost bool `json:",omitempty"`
+
 	// Used to truncate Rotations in a test-friendly way.
 	now time.Time
+	// The loaded Timezone, or nil if it is not set.
+	location *time.Location
+	// The loaded DisplayTimezone, or nil if it is not set.
+	displayLocation *time.Location
+	// The details of each user in Users that has any, by name.
+	userDetails map[string]User
+	// The team of the ScheduleSet the schedule was read from, if any.
+	team string
+	// The secondary pool while Generate runs, if separate from the primaries.
+	secondaries *pool
+	// Who holds the secondary turn under way while Generate runs, with
+	// SecondaryRotationLength.
+	secondaryHolder string
+	// Problems Generate worked around, which Lint reports.
+	warnings []string
 }
 
+// A Rotation covers the span of absolute time from its Start up to, but not
+// including, its End, which is the Start of the next rotation. Handoff times
+// are resolved to instants in the schedule's Timezone before they are
+// compared, so a page during an hour that a daylight saving change repeats
+// belongs to whichever rotation covers the instant it was sent.
 type Rotation struct {
+	// Identifies the rotation to other systems, e.g. for exporters to update
+	// what they exported before rather than add to it. Generate gives each
+	// rotation one when it is first generated, derived from its start, and
+	// never changes it after, even if the rotation is edited by hand. The
+	// later segments an override splits a rotation into have the rotation's
+	// ID with a suffix, e.g. "-1".
+	ID string `json:",omitempty"`
 	Start time.Time
+	// Filled in by Generate, which recomputes it from the rotation after it.
+	// Schedules written before End was added leave it zero, in which case
+	// RotationEnd works it out.
+	End time.Time
 	Primary string
 	Secondary string
+	// The users assigned to the tiers beyond the first two, by tier, when the
+	// schedule sets Roles.
+	Assignments map[string]string `json:",omitempty"`
+	// Why the rotation differs from the usual, e.g. because it covers a pause.
+	Notes string `json:",omitempty"`
+	// While an override is in effect, the users each overridden tier was
+	// assigned before the override, by tier.
+	Overridden map[string]string `json:",omitempty"`
+	// Whether this is a later segment of the rotation before it, split off
+	// where an override starts or ends, rather than a new rotation.
+	Continues bool `json:",omitempty"`
+	// The name of the window the rotation covers, when the schedule sets
+	// Windows.
+	Window string `json:",omitempty"`
+	// The name of the region whose shift the rotation is, when the schedule
+	// sets Regions.
+	Region string `json:",omitempty"`
+	// The trainee shadowing the primary, if any.
+	Shadow string `json:",omitempty"`
+	// Whether Generate must keep the rotation exactly as written, e.g. for a
+	// conference week with coverage arranged by hand. A pinned rotation must
+	// have an End, and may follow a gap after the rotations before it, which
+	// Generate fills, shortening the last rotation it adds there if need be.
+	Pinned bool `json:",omitempty"`
+	// Whether the rotation covers one of Gaps, with nobody on any tier.
+	// Exporters leave it out like any unfilled tier.
+	Unstaffed bool `json:",omitempty"`
+	// Whether this is a later part of the primary's turn in the rotation
+	// before it, split off where the secondary hands off, with
+	// SecondaryRotationLength.
+	SecondaryHandoff bool `json:",omitempty"`
+	// The users on call in the rotation who have acknowledged it, e.g. from a
+	// link in a handoff notification, in the order they did.
+	Acknowledged []string `json:",omitempty"`
 }
 
 func (r Rotation) String() string {
-	return fmt.Sprintf("%s %s %s", r.Start.Format(time.RFC3339), r.Primary, r.Secondary)
+	return r.Format(nil, time.RFC3339)
 }
 
-func NewSchedule(text []byte) (*Schedule, error) {
-	s := &Schedule{}
-	if err := json.Unmarshal(text, s); err != nil {
-		return nil, fmt.Errorf("error parsing schedule: %s", err)
+// Format renders r as String does, with its times in loc, or the locations
+// they carry if loc is nil, and laid out by layout, as for time.Format.
+func (r Rotation) Format(loc *time.Location, layout string) string {
+	span := formatTime(r.Start, loc, layout)
+	if !r.End.IsZero() {
+		span += "/" + formatTime(r.End, loc, layout)
 	}
-	if d, err := time.ParseDuration(s.RotationLength); err != nil {
-		return nil, fmt.Errorf("error parsing RotationLength: %s", err)
-	} else {
-		s.RotationDuration = d
+	secondary := r.Secondary
+	if secondary == "" {
+		// Left unfilled, e.g. for a team of one with AllowTierCollapse.
+		secondary = "-"
+	}
+	text := fmt.Sprintf("%s %s %s", span, r.Primary, secondary)
+	if len(r.Assignments) > 0 {
+		text += " " + assignmentsString(r)
+	}
+	if r.Shadow != "" {
+		text += " shadow=" + r.Shadow
+	}
+	if r.Region != "" {
+		text += " region=" + r.Region
+	}
+	if r.Pinned {
+		text += " pinned"
+	}
+	if r.Unstaffed {
+		text += " unstaffed"
+	}
+	return text
+}
+
+// Return a copy of r that shares no maps with it.
+func (r Rotation) clone() Rotation {
+	r.Assignments = copyTiers(r.Assignments)
+	r.Overridden = copyTiers(r.Overridden)
+	r.Acknowledged = append([]string(nil), r.Acknowledged...)
+	return r
+}
+
+func copyTiers(tiers map[string]string) map[string]string {
+	if tiers == nil {
+		return nil
+	}
+	c := make(map[string]string, len(tiers))
+	for k, v := range tiers {
+		c[k] = v
+	}
+	return c
+}
+
+// NewSchedule parses a schedule document, migrating it from an older format
+// if need be. The document may have // and /* */ comments and trailing commas,
+// which are dropped; the schedule is only ever written as standard JSON.
+func NewSchedule(text []byte, opts ...ParseOption) (*Schedule, error) {
+	migrated, _, err := Migrate(text)
+	if err != nil {
+		return nil, err
+	}
+	in := Input{}
+	if err := json.Unmarshal(migrated, &in); err != nil {
+		// A migrated document no longer lines up with text, so the error is
+		// found again in text as written, unless migrating it caused it.
+		if e := json.Unmarshal(standardize(text), &Input{}); e != nil {
+			return nil, locateParseError(text, e)
+		}
+		return nil, &ParseError{Op: "parsing", Field: "schedule", Err: err}
+	}
+	o := parseOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	unknown := []string{}
+	if o.strict || o.disallowUnknown {
+		// A migrated document no longer lines up with text, so unknown fields
+		// are found again in text as written, unless migrating added them.
+		offsets := map[string]int{}
+		walkJSON(standardize(text), 0, reflect.TypeOf(Input{}), "", func(v jsonValue) {
+			offsets[v.path] = v.offset
+		})
+		var err error
+		walkJSON(migrated, 0, reflect.TypeOf(Input{}), "", func(v jsonValue) {
+			where := ""
+			if offset, ok := offsets[v.path]; ok {
+				line, column := lineColumn(text, offset)
+				where = fmt.Sprintf(" at line %d, column %d", line, column)
+			}
+			if o.disallowUnknown && err == nil {
+				err = &ParseError{Op: "parsing", Field: v.path, Err: fmt.Errorf("unknown field%s", where)}
+			}
+			unknown = append(unknown, fmt.Sprintf("%s%s is not a schedule field, so is ignored; check its spelling, or whether it needs a newer oncallator", v.path, where))
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	s, err := in.parse(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if o.strict {
+		s.warnings = append(unknown, s.warnings...)
+	}
+	return s, nil
+}
+
+// Return a ParseError for err, from decoding text as written once comments
+// and trailing commas are dropped, saying where in text the problem is and,
+// if it is with a value, the path to it, e.g. Rotations[12].Start.
+func locateParseError(text []byte, err error) error {
+	if _, ok := err.(*json.SyntaxError); ok {
+		return &ParseError{Op: "parsing", Field: "schedule", Err: locateJSON(text, err)}
+	}
+	bad := walkJSON(standardize(text), 0, reflect.TypeOf(Input{}), "", nil)
+	if bad == nil || bad.path == "" {
+		return &ParseError{Op: "parsing", Field: "schedule", Err: locateJSON(text, err)}
+	}
+	line, column := lineColumn(text, bad.offset)
+	if _, ok := bad.err.(*json.UnmarshalTypeError); ok {
+		// It does not say what the value was.
+		return &ParseError{Op: "parsing", Field: bad.path, Value: bad.value(), Err: fmt.Errorf("line %d, column %d: %w (got %s)", line, column, bad.err, bad.raw)}
+	}
+	return &ParseError{Op: "parsing", Field: bad.path, Value: bad.value(), Err: fmt.Errorf("line %d, column %d: %w", line, column, bad.err)}
+}
+
+// Build a Schedule from in, parsing its durations and filling in defaults.
+func (in Input) parse(opts ...ParseOption) (*Schedule, error) {
+	if in.FormatVersion > CurrentFormatVersion {
+		return nil, fmt.Errorf("schedule format version %d is newer than the newest supported version %d; upgrade oncallator to read it", in.FormatVersion, CurrentFormatVersion)
+	}
+	// Older formats cannot be represented by an Input, so one without a version
+	// is already in the current format.
+	if in.FormatVersion == 0 {
+		in.FormatVersion = CurrentFormatVersion
 	}
-	if d, err := time.ParseDuration(s.ScheduleFor); err != nil {
-		return nil, fmt.Errorf("error parsing ScheduleFor: %s", err)
+	s := &Schedule{
+		FormatVersion: in.FormatVersion,
+		Users: namesOf(in.Users),
+		PrimaryUsers: in.PrimaryUsers,
+		SecondaryUsers: in.SecondaryUsers,
+		Pattern: in.Pattern,
+		PatternNext: in.PatternNext,
+		Start: in.Start,
+		Timezone: in.Timezone,
+		DisplayTimezone: in.DisplayTimezone,
+		HandoffTime: in.HandoffTime,
+		StartWeekday: in.StartWeekday,
+		Windows: in.Windows,
+		Regions: in.Regions,
+		RotationLength: in.RotationLength,
+		SecondaryRotationLength: in.SecondaryRotationLength,
+		ScheduleFor: in.ScheduleFor,
+		MaxRotations: in.MaxRotations,
+		HandoffGrace: in.HandoffGrace,
+		UserEndDates: in.UserEndDates,
+		Roles: in.Roles,
+		AllowTierCollapse: in.AllowTierCollapse,
+		Unavailable: in.Unavailable,
+		AllowUnassigned: in.AllowUnassigned,
+		LandmarkShifts: in.LandmarkShifts,
+		LandmarkCounts: in.LandmarkCounts,
+		Holidays: in.Holidays,
+		HolidayPolicy: in.HolidayPolicy,
+		BurdenWindows: in.BurdenWindows,
+		SecondaryMode: in.SecondaryMode,
+		Fairness: in.Fairness,
+		Weights: in.Weights,
+		Order: in.Order,
+		Seed: in.Seed,
+		Cycle: in.Cycle,
+		MinGap: in.MinGap,
+		Trainees: in.Trainees,
+		TraineeShifts: in.TraineeShifts,
+		ShiftCounts: in.ShiftCounts,
+		Pauses: in.Pauses,
+		Freezes: in.Freezes,
+		FreezeUser: in.FreezeUser,
+		Gaps: in.Gaps,
+		Overrides: in.Overrides,
+		Swaps: in.Swaps,
+		PinPolicy: in.PinPolicy,
+		Hooks: in.Hooks,
+		Rotations: in.Rotations,
+		KeepHistory: in.KeepHistory,
+		HistoryFor: in.HistoryFor,
+		HistoryMax: in.HistoryMax,
+		History: in.History,
+		Provenance: in.Provenance,
+		RecordHost: in.RecordHost,
+	}
+	if in.SecondaryStart != nil {
+		s.SecondaryStart = *in.SecondaryStart
+	}
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return nil, &ParseError{Op: "loading", Field: "Timezone", Value: s.Timezone, Err: err}
+		}
+		s.location = loc
+		s.Start = s.Start.In(loc)
+		if !s.SecondaryStart.IsZero() {
+			s.SecondaryStart = s.SecondaryStart.In(loc)
+		}
+		// Copy Rotations rather than modify the caller's.
+		s.Rotations = append([]Rotation(nil), s.Rotations...)
+		for i := range s.Rotations {
+			s.Rotations[i].Start = s.Rotations[i].Start.In(loc)
+			if !s.Rotations[i].End.IsZero() {
+				s.Rotations[i].End = s.Rotations[i].End.In(loc)
+			}
+		}
+		s.History = append([]Rotation(nil), s.History...)
+		for i := range s.History {
+			s.History[i].Start = s.History[i].Start.In(loc)
+			s.History[i].End = s.History[i].End.In(loc)
+		}
+	}
+	if s.DisplayTimezone != "" {
+		loc, err := time.LoadLocation(s.DisplayTimezone)
+		if err != nil {
+			return nil, &ParseError{Op: "loading", Field: "DisplayTimezone", Value: s.DisplayTimezone, Err: err}
+		}
+		s.displayLocation = loc
+	}
+	for _, u := range in.Users {
+		if u != (User{Name: u.Name}) {
+			if s.userDetails == nil {
+				s.userDetails = map[string]User{}
+			}
+			s.userDetails[u.Name] = u
+		}
+	}
+	if err := s.loadRegionLocations(); err != nil {
+		return nil, err
+	}
+	// Windows set the length of each rotation instead.
+	if len(s.Windows) == 0 || s.RotationLength != "" {
+		if d, err := ParseDuration(s.RotationLength); err != nil {
+			return nil, &ParseError{Op: "parsing", Field: "RotationLength", Value: s.RotationLength, Err: err}
+		} else {
+			s.RotationDuration = d
+		}
+	}
+	if s.SecondaryRotationLength != "" {
+		if d, err := ParseDuration(s.SecondaryRotationLength); err != nil {
+			return nil, &ParseError{Op: "parsing", Field: "SecondaryRotationLength", Value: s.SecondaryRotationLength, Err: err}
+		} else {
+			s.SecondaryRotationDuration = d
+		}
+	}
+	if d, err := ParseDuration(s.ScheduleFor); err != nil {
+		return nil, &ParseError{Op: "parsing", Field: "ScheduleFor", Value: s.ScheduleFor, Err: err}
 	} else {
 		s.ScheduleForDuration = d
 	}
-	if err := s.Validate(); err != nil {
+	if s.HistoryFor != "" {
+		if d, err := ParseDuration(s.HistoryFor); err != nil {
+			return nil, &ParseError{Op: "parsing", Field: "HistoryFor", Value: s.HistoryFor, Err: err}
+		} else {
+			s.HistoryForDuration = d
+		}
+	}
+	s.HandoffGraceDuration = DefaultHandoffGrace
+	if s.HandoffGrace != "" {
+		if d, err := ParseDuration(s.HandoffGrace); err != nil {
+			return nil, &ParseError{Op: "parsing", Field: "HandoffGrace", Value: s.HandoffGrace, Err: err}
+		} else {
+			s.HandoffGraceDuration = d
+		}
+	}
+	o := parseOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s.now = o.now
+	if !o.strict {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	warnings, err := s.ValidateStrict()
+	if err != nil {
 		return nil, err
 	}
+	for _, w := range warnings {
+		s.warnings = append(s.warnings, w.String())
+	}
 	return s, nil
 }
 
+// Validate checks that s can be generated, returning a *ValidationError
+// saying why not if it cannot.
 func (s Schedule) Validate() error {
-	if len(s.Users) == 0 {
-		return fmt.Errorf("must provide at least 1 user")
+	if err := s.validate(); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+func (s Schedule) validate() error {
+	if len(s.allUsers()) == 0 {
+		return ErrNoUsers
+	}
+	if err := s.validateUserLists(); err != nil {
+		return err
+	}
+	if err := s.validateUserDetails(); err != nil {
+		return err
 	}
-	if s.RotationDuration <= 0 {
-		return fmt.Errorf("cannot have nonpositive RotationLength (got %s)", s.RotationDuration)
+	if err := validateRoles(s.Roles); err != nil {
+		return err
+	}
+	if len(s.allUsers()) < len(s.tiers()) && !s.AllowTierCollapse {
+		return fmt.Errorf("%d users cannot fill %d tiers (set AllowTierCollapse to leave the highest tiers unfilled)", len(s.allUsers()), len(s.tiers()))
+	}
+	if s.HandoffTime != "" {
+		if _, _, err := parseHandoffTime(s.HandoffTime); err != nil {
+			return err
+		}
+	}
+	if s.StartWeekday != "" {
+		if _, err := parseWeekday(s.StartWeekday); err != nil {
+			return err
+		}
+	}
+	if err := s.validateWindows(); err != nil {
+		return err
+	}
+	if err := s.validateRegions(); err != nil {
+		return err
+	}
+	if s.RotationDuration <= 0 && len(s.Windows) == 0 {
+		return fmt.Errorf("%w (got %s)", ErrNonPositiveRotation, s.RotationDuration)
+	}
+	for _, l := range s.LandmarkShifts {
+		if _, err := time.Parse(landmarkDateFormat, l.Date); err != nil {
+			return fmt.Errorf("error parsing date of landmark %s: %s", l.Name, err)
+		}
+	}
+	if err := validateHolidays(s.Holidays); err != nil {
+		return err
+	}
+	if err := validateHolidayPolicy(s.HolidayPolicy); err != nil {
+		return err
+	}
+	if err := validateBurdenWindows(s.BurdenWindows); err != nil {
+		return err
+	}
+	if err := validateSecondaryMode(s.SecondaryMode); err != nil {
+		return err
+	}
+	if err := s.validatePools(); err != nil {
+		return err
+	}
+	if err := s.validatePattern(); err != nil {
+		return err
+	}
+	if err := s.validateOrder(); err != nil {
+		return err
+	}
+	if err := s.validateSecondaryRotation(); err != nil {
+		return err
+	}
+	if err := validateFairness(s.Fairness); err != nil {
+		return err
+	}
+	if err := s.validateWeights(); err != nil {
+		return err
+	}
+	if err := s.validateMinGap(); err != nil {
+		return err
+	}
+	if err := s.validateTrainees(); err != nil {
+		return err
+	}
+	if err := validatePauses(s.Pauses); err != nil {
+		return err
+	}
+	if err := s.validateFreezes(); err != nil {
+		return err
+	}
+	if err := s.validateGaps(); err != nil {
+		return err
+	}
+	if err := validateUnavailable(s.Unavailable); err != nil {
+		return err
+	}
+	if err := s.validateOverrides(); err != nil {
+		return err
+	}
+	for _, o := range s.Overrides {
+		if !s.eligible(o.User, o.Start) {
+			return fmt.Errorf("%s names %s after their end date %s", o, o.User, s.UserEndDates[o.User].Format(time.RFC3339))
+		}
+	}
+	if err := validateSwaps(s.Swaps); err != nil {
+		return err
+	}
+	if err := validatePinPolicy(s.PinPolicy); err != nil {
+		return err
+	}
+	if err := validateHooks(s.Hooks); err != nil {
+		return err
+	}
+	if err := s.validatePins(); err != nil {
+		return err
+	}
+	if s.HandoffGraceDuration < 0 {
+		return fmt.Errorf("cannot have negative HandoffGrace (got %s)", s.HandoffGraceDuration)
+	}
+	if s.MaxRotations < 0 {
+		return fmt.Errorf("cannot have negative MaxRotations (got %d)", s.MaxRotations)
+	}
+	if err := s.validateHistory(); err != nil {
+		return err
+	}
+	if err := validateRotations(s.Rotations); err != nil {
+		return err
+	}
+	for _, sw := range s.Swaps {
+		if err := s.checkSwapped(sw); err != nil {
+			return err
+		}
+	}
+	for i, r := range s.Rotations {
+		for t, u := range s.Assigned(r) {
+			if !s.eligible(u, s.turnStart(i, t)) {
+				return fmt.Errorf("rotation starting at %s names %s after their end date %s", r.Start.Format(time.RFC3339), u, s.UserEndDates[u].Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// Return when the turn of whoever holds tier t of rotation i started, which
+// is when Generate checked they were still eligible: the start of the
+// rotation an override or freeze split it off, or for the primary, that
+// SecondaryRotationLength split it off, and for a secondary with
+// SecondaryRotationLength, of the first rotation of their turn.
+func (s Schedule) turnStart(i, t int) time.Time {
+	rs := s.Rotations
+	u := s.tierUser(rs[i], s.tiers()[t])
+	for i > 0 && s.tierUser(rs[i-1], s.tiers()[t]) == u {
+		switch {
+		case rs[i].Continues:
+		case t == 0 && rs[i].SecondaryHandoff:
+		case t == 1 && s.SecondaryRotationDuration > 0 && !rs[i].SecondaryHandoff:
+		default:
+			return rs[i].Start
+		}
+		i--
+	}
+	return rs[i].Start
+}
+
+// Check that rs are in chronological order and, where they have an End, that
+// each ends as the next starts, with neither an overlap nor a gap, except
+// before a pinned rotation.
+func validateRotations(rs []Rotation) error {
+	if err := validateRotationIDs(rs); err != nil {
+		return err
+	}
+	for i, r := range rs {
+		if !r.End.IsZero() && !r.End.After(r.Start) {
+			return fmt.Errorf("rotation starting at %s must end after it starts (ends %s)", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+		}
+		if i + 1 == len(rs) {
+			break
+		}
+		next := rs[i+1]
+		if !next.Start.After(r.Start) {
+			return fmt.Errorf("rotation starting at %s is listed after the rotation starting at %s; rotations must be in chronological order", next.Start.Format(time.RFC3339), r.Start.Format(time.RFC3339))
+		}
+		if r.End.IsZero() || r.End.Equal(next.Start) {
+			continue
+		}
+		if r.End.Before(next.Start) && next.Pinned {
+			// Generate fills the gap.
+			continue
+		}
+		if r.End.Before(next.Start) {
+			return fmt.Errorf("gap between the rotation from %s to %s and the rotation starting at %s", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), next.Start.Format(time.RFC3339))
+		}
+		if r.Pinned || next.Pinned {
+			return fmt.Errorf("rotation from %s to %s overlaps the rotation starting at %s, and one of them is pinned; edit the other", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), next.Start.Format(time.RFC3339))
+		}
+		return fmt.Errorf("rotation from %s to %s overlaps the rotation starting at %s", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), next.Start.Format(time.RFC3339))
 	}
 	return nil
 }
 
+// Whether u may be assigned to a rotation starting at start.
+func (s *Schedule) eligible(u string, start time.Time) bool {
+	end, ok := s.UserEndDates[u]
+	return !ok || start.Before(end)
+}
+
+// AvailableUsers returns the users who may be assigned to a rotation starting
+// at start, in the order they are listed.
+func (s Schedule) AvailableUsers(start time.Time) []string {
+	end := s.NominalEnd(start)
+	available := []string{}
+	for _, u := range s.allUsers() {
+		if s.available(u, start, end) {
+			available = append(available, u)
+		}
+	}
+	return available
+}
+
+// RequiredUsers returns how many different users a rotation needs: one per
+// tier, or only a primary if AllowTierCollapse is set.
+func (s Schedule) RequiredUsers() int {
+	if s.AllowTierCollapse {
+		return 1
+	}
+	return len(s.tiers())
+}
+
 func (s *Schedule) Generate() (*Schedule, error) {
+	return s.generate(time.Time{}, nil)
+}
+
+// ErrAlreadyCovered is returned by Extend for a time the rotations already
+// cover.
+var ErrAlreadyCovered = errors.New("the schedule already covers the time")
+
+// Extend is Generate, but adds rotations until the last one covers until,
+// rather than until one starts ScheduleFor from now, e.g. to publish the
+// schedule through a given date. Elapsed rotations are truncated just as by
+// Generate, so the two can be mixed. If until is before the end of the last
+// rotation, Extend returns ErrAlreadyCovered.
+func (s *Schedule) Extend(until time.Time) (*Schedule, error) {
+	if len(s.Rotations) > 0 {
+		if end := s.RotationEnd(len(s.Rotations) - 1); until.Before(end) {
+			return nil, fmt.Errorf("%w: rotations run until %s, after %s", ErrAlreadyCovered, end.Format(time.RFC3339), until.Format(time.RFC3339))
+		}
+	}
+	return s.generate(until, nil)
+}
+
+// Generate s, adding rotations until the last covers until, or if it is zero,
+// until one starts ScheduleFor from now. If fresh is not nil, the rotations
+// after the one under way are discarded and the round-robin starts over from
+// the pools as written, as for GenerateFresh from *fresh.
+func (s *Schedule) generate(until time.Time, fresh *time.Time) (*Schedule, error) {
+	ns, err := s.generateRotations(until, fresh)
+	if err != nil {
+		return nil, err
+	}
+	// A run that leaves the rotations as they were keeps the Provenance of
+	// the run that last changed them, so that the schedule is rewritten byte
+	// for byte.
+	if s.Provenance != nil && NewDiff(s, ns).Empty() {
+		ns.Provenance = s.Provenance
+		return ns, nil
+	}
+	if ns.Provenance, err = newProvenance(s, ns.now); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+func (s *Schedule) generateRotations(until time.Time, fresh *time.Time) (*Schedule, error) {
 	if err := s.Validate(); err != nil {
 		return nil, err
 	}
 
 	ns := &Schedule{
-		Users: s.Users,
+		FormatVersion: CurrentFormatVersion,
+		Pattern: s.Pattern,
+		PatternNext: s.PatternNext,
+		Timezone: s.Timezone,
+		DisplayTimezone: s.DisplayTimezone,
+		HandoffTime: s.HandoffTime,
+		StartWeekday: s.StartWeekday,
+		Windows: s.Windows,
+		Regions: s.Regions,
 		RotationLength: s.RotationLength,
 		RotationDuration: s.RotationDuration,
+		SecondaryRotationLength: s.SecondaryRotationLength,
+		SecondaryRotationDuration: s.SecondaryRotationDuration,
+		SecondaryStart: s.SecondaryStart,
 		ScheduleFor: s.ScheduleFor,
 		ScheduleForDuration: s.ScheduleForDuration,
-		Rotations: s.Rotations[:],
+		MaxRotations: s.MaxRotations,
+		HandoffGrace: s.HandoffGrace,
+		HandoffGraceDuration: s.HandoffGraceDuration,
+		UserEndDates: s.UserEndDates,
+		Roles: s.Roles,
+		AllowTierCollapse: s.AllowTierCollapse,
+		Unavailable: s.Unavailable,
+		AllowUnassigned: s.AllowUnassigned,
+		LandmarkShifts: s.LandmarkShifts,
+		LandmarkCounts: copyLandmarkCounts(s.LandmarkCounts),
+		Holidays: s.Holidays,
+		HolidayPolicy: s.HolidayPolicy,
+		BurdenWindows: s.BurdenWindows,
+		SecondaryMode: s.SecondaryMode,
+		Fairness: s.Fairness,
+		Weights: s.Weights,
+		Order: s.Order,
+		Seed: s.Seed,
+		Cycle: s.Cycle,
+		MinGap: s.MinGap,
+		Trainees: s.Trainees,
+		TraineeShifts: s.TraineeShifts,
+		ShiftCounts: copyShiftCounts(s.ShiftCounts),
+		Pauses: s.Pauses,
+		Freezes: s.Freezes,
+		FreezeUser: s.FreezeUser,
+		Gaps: s.Gaps,
+		Overrides: s.Overrides,
+		Swaps: s.Swaps,
+		PinPolicy: s.PinPolicy,
+		Hooks: s.Hooks,
+		KeepHistory: s.KeepHistory,
+		HistoryFor: s.HistoryFor,
+		HistoryForDuration: s.HistoryForDuration,
+		HistoryMax: s.HistoryMax,
+		RecordHost: s.RecordHost,
 		now: s.now,
+		location: s.location,
+		displayLocation: s.displayLocation,
+		userDetails: s.userDetails,
+		team: s.team,
+	}
+	ns.now = s.currentTime()
+	end := ns.now.Add(s.ScheduleForDuration)
+	// Whether to add another rotation.
+	more := func() bool {
+		return len(ns.Rotations) == 0 || ns.Rotations[len(ns.Rotations)-1].Start.Before(end)
+	}
+	if !until.IsZero() {
+		if !until.After(ns.now) {
+			return nil, fmt.Errorf("cannot extend the schedule to %s, which is not after now (%s)", until.Format(time.RFC3339), ns.now.Format(time.RFC3339))
+		}
+		end = until
+		more = func() bool {
+			return len(ns.Rotations) == 0 || !ns.Start.After(until)
+		}
+	}
+	err := checkGapsLeaveStaffed(s.Gaps, ns.now, end)
+	if err != nil {
+		return nil, err
+	}
+	// Rotations pinned after a gap are added as the rotations generated reach
+	// them, and the rest are worked from as usual.
+	split, pinned := s.splitPinned()
+	if fresh != nil {
+		if s, err = ns.discardFuture(s, *fresh); err != nil {
+			return nil, err
+		}
+		pinned = nil
+	} else if len(pinned) > 0 {
+		head := *s
+		head.Rotations = s.Rotations[:split]
+		s = &head
 	}
 
-	if len(ns.Rotations) == 0 {
+	// Work out how many rotations to add up front so that Rotations is only
+	// allocated once, however much history is retained.
+	var kept []Rotation
+	var n int
+	// How many periods passed with no rotation generated for them, by window.
+	skipped := map[string]int{}
+	if len(s.Rotations) == 0 {
 		// If we're generating a schedule from scratch, seed Rotations with an
 		// initial rotation.
-		ns.Start = s.Start
-		ns.addRotation()
+		ns.Start = ns.snapToHandoff(s.Start)
+		n = 1 + numRotations(s.Start, end, s.typicalLength())
+	} else {
+		// Work from the rotations as generated, and reapply overrides at the
+		// end.
+		rs := s.unapplyOverrides(s.Rotations)
+		ns.Start = ns.NominalEnd(primaryTurnStart(rs, len(rs) - 1))
+		if last := rs[len(rs)-1]; last.Pinned {
+			ns.Start = last.End
+		}
+		if ns.Start.After(ns.now) {
+			kept = ns.truncateCounting(s)
+		} else {
+			// Every rotation has elapsed, e.g. because the schedule was left
+			// alone for months. Drop them all and start from the period under
+			// way, moving the cycle on as if the periods in between had been
+			// scheduled.
+			ns.dropCounting(s, nil)
+			for next := ns.NominalEnd(ns.Start); !next.After(ns.now); next = ns.NominalEnd(ns.Start) {
+				if len(ns.Regions) > 0 {
+					skipped[ns.regionAt(ns.Start)]++
+				} else if ns.gapAt(ns.Start) == nil {
+					skipped[ns.windowAt(ns.Start)]++
+				}
+				ns.Start = next
+			}
+		}
+		// Rotations are added until one starts at or after end.
+		n = 1 + numRotations(ns.Start, end, s.typicalLength())
+	}
+	if err := s.checkRotationCount(len(kept) + n, until); err != nil {
+		return nil, err
+	}
+	ns.archive(s, kept)
+
+	// Leave room for one more, in case a daylight saving change shortens a
+	// rotation by an hour.
+	ns.Rotations = make([]Rotation, len(kept), len(kept) + n + 1)
+	// Kept rotations are copied with their maps, so that nothing done to ns
+	// reaches the receiver.
+	for i, r := range kept {
+		ns.Rotations[i] = r.clone()
+	}
+	if len(kept) > 0 {
+		// A pause may have been added since the last kept rotation was
+		// generated.
+		last := &ns.Rotations[len(kept)-1]
+		if note := ns.pauseNote(last.Start); note != "" && !last.Pinned {
+			last.Notes = note
+		}
+		// Ends are recomputed, rather than trusted, in case a rotation was
+		// moved by hand.
+		for i := 0; i + 1 < len(kept); i++ {
+			ns.Rotations[i].End = ns.Rotations[i+1].Start
+		}
+		last.End = ns.Start
+	}
+	if len(s.Windows) > 0 {
+		if err := ns.addWindowRotations(s.unapplyOverrides(s.Rotations), more, skipped); err != nil {
+			return nil, err
+		}
+		ns.Users = append(s.Users[:0:0], s.Users...)
+		return ns.finishGenerate(len(kept))
+	}
+	if len(s.Regions) > 0 {
+		if err := ns.addRegionRotations(s.unapplyOverrides(s.Rotations), more, skipped); err != nil {
+			return nil, err
+		}
+		ns.Users = append(s.Users[:0:0], s.Users...)
+		return ns.finishGenerate(len(kept))
+	}
+	next := 0
+	order := append([]string{}, s.primaryPool()...)
+	if s.Order == OrderShuffled {
+		if ns.Seed == 0 {
+			ns.Seed = newSeed(ns.now)
+		}
+		order, next = ns.shuffledOrder(s, fresh != nil, skipped[""])
+	} else if len(s.Pattern) > 0 {
+		order = rotateUsers(s.Pattern, s.PatternNext + skipped[""])
+		ns.Users = append(s.Users[:0:0], s.Users...)
+	} else if fresh == nil {
+		order = ns.continueOrder(s.primaryPool(), ns.turns(s.unapplyOverrides(s.Rotations)), "Users")
+		order = rotateUsers(order, skipped[""])
+	}
+	if s.separatePools() {
+		ns.secondaries = &pool{users: append([]string{}, s.secondaryPool()...)}
+	}
+	if s.SecondaryRotationDuration > 0 && len(kept) > 0 {
+		ns.secondaryHolder = s.SecondaryUsers[len(s.SecondaryUsers)-1]
+	}
+	// Add rotations until one starts at or after the horizon, or covers until.
+	// That is about n of them, unless pauses or daylight saving changes make
+	// some longer or shorter. Rotations are added up to any pinned rotations
+	// beyond the horizon too, so as to leave no gap before them.
+	for len(pinned) > 0 || more() {
+		if len(pinned) > 0 && !ns.Start.Before(pinned[0][0].Start) {
+			if order, next, err = ns.addPinned(pinned[0], order, next); err != nil {
+				return nil, err
+			}
+			pinned = pinned[1:]
+			continue
+		}
+		if s.Order == OrderShuffled && next >= len(order) {
+			order, next = ns.nextCycle(order), 0
+		}
+		if next, err = ns.addRotation(order, next); err != nil {
+			return nil, err
+		}
+		if len(pinned) > 0 && ns.Start.After(pinned[0][0].Start) {
+			ns.shortenBefore(pinned[0][0])
+		}
+	}
+	// Leave each pool in the order it is next taken in.
+	ns.Users = append(s.Users[:0:0], s.Users...)
+	ns.PrimaryUsers = append(s.PrimaryUsers[:0:0], s.PrimaryUsers...)
+	ns.SecondaryUsers = append(s.SecondaryUsers[:0:0], s.SecondaryUsers...)
+	last := ""
+	if turns := ns.turns(ns.Rotations); len(turns) > 0 {
+		last = turns[len(turns)-1].Primary
+	}
+	primaries := nextUsers(order, last)
+	if s.Order == OrderShuffled {
+		ns.Users = order
+	} else if len(s.Pattern) > 0 {
+		ns.PatternNext = (s.PatternNext + skipped[""] + next) % len(s.Pattern)
+	} else if len(s.PrimaryUsers) > 0 {
+		ns.PrimaryUsers = primaries
 	} else {
-		ns.Start = ns.Rotations[len(ns.Rotations)-1].Start.Add(ns.RotationDuration)
+		ns.Users = primaries
 	}
+	if q := ns.secondaries; q != nil {
+		secondaries := rotateUsers(q.users, q.p % len(q.users))
+		if len(s.SecondaryUsers) > 0 {
+			ns.SecondaryUsers = secondaries
+		} else {
+			ns.Users = secondaries
+		}
+		ns.secondaries = nil
+		ns.secondaryHolder = ""
+	}
+	return ns.finishGenerate(len(kept))
+}
 
-	if ns.now.IsZero() {
-		ns.now = time.Now()
+// Check and apply overrides to the rotations Generate added to ns after the
+// first kept, and return ns.
+func (ns *Schedule) finishGenerate(kept int) (*Schedule, error) {
+	// Every rotation added above should have distinct users in each tier. Check
+	// the composed result rather than trusting each step that picks users.
+	for _, d := range ns.doubledTiers(ns.Rotations[kept:]) {
+		r := ns.Rotations[kept+d]
+		if r.Pinned {
+			// Pinned rotations are kept as written, and Lint reports them.
+			continue
+		}
+		i, _ := ns.doubledTier(r)
+		return nil, fmt.Errorf("generated rotation starting at %s assigns %s to two tiers (round-robin from Users, with LandmarkShifts and UserEndDates applied); this is a bug", r.Start.Format(time.RFC3339), ns.Assigned(r)[i])
 	}
-	ns.Rotations = truncate(ns.Rotations, ns.now)
 
-	for end := ns.now.Add(s.ScheduleForDuration); end.After(ns.Rotations[len(ns.Rotations)-1].Start); {
-		ns.addRotation()
+	ns.Overrides = dropOverridesBefore(ns.Overrides, ns.Rotations[0].Start)
+	ns.Pauses = dropRangesBefore(ns.Pauses, ns.Rotations[0].Start)
+	ns.Freezes = dropRangesBefore(ns.Freezes, ns.Rotations[0].Start)
+	ns.Unavailable = dropUnavailableBefore(ns.Unavailable, ns.Rotations[0].Start)
+	ns.assignIDs()
+	if err := ns.applyOverrides(); err != nil {
+		return nil, err
 	}
+	ns.Swaps = dropSwapsBefore(ns.Swaps, ns.Rotations[0].Start)
+	ns.applySwaps()
 
 	return ns, nil
 }
 
-// Add a rotation to Rotations and update relevant state.
-func (s *Schedule) addRotation() {
+// Return a copy of pool, which Landmark shifts may reorder, ordered to continue
+// the cycle from the primary of the last of rs rather than from the order of
+// pool, which may not have been kept in sync with edits to Rotations. If that
+// primary has left pool, named by list in the warning Lint gives, pool is
+// followed from the start.
+func (ns *Schedule) continueOrder(pool []string, rs []Rotation, list string) []string {
+	order := append([]string{}, pool...)
+	if last := lastPrimary(rs); last != "" {
+		if containsUser(order, last) {
+			order = nextUsers(order, last)
+		} else {
+			ns.warnings = append(ns.warnings, fmt.Sprintf("%s, the primary of the last rotation before those generated, is no longer among the users to schedule, so the new rotations follow the order of %s instead", last, list))
+		}
+	}
+	return order
+}
+
+// Return the primary of the last of rs that somebody was on, or "" if there is
+// none.
+func lastPrimary(rs []Rotation) string {
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].Primary != "" && rs[i].Primary != Unassigned {
+			return rs[i].Primary
+		}
+	}
+	return ""
+}
+
+// Return users ordered so that the user following last's primary comes first,
+// which keeps the documented "Users[0] is primary next" invariant even when no
+// rotations were added or Users was not kept in sync with hand edits. If last's
+// primary is not in users, the order is returned unchanged.
+func nextUsers(users []string, last string) []string {
+	for i, u := range users {
+		if u == last {
+			return rotateUsers(users, i + 1)
+		}
+	}
+	return rotateUsers(users, 0)
+}
+
+// Add a rotation to Rotations and update relevant state. The primary is the
+// first available user at or after position p in users, or the least loaded
+// with balanced Fairness. The secondary follows SecondaryMode, or is taken
+// from the secondary pool if there is one, or with SecondaryRotationLength is
+// whoever holds the secondary's turn, splitting the rotation where that hands
+// off. Users skipped because they are unavailable move up the order behind the
+// primary, so they are next in line once they are available again. Rotations
+// over landmark shifts may also reorder users. Returns the position to
+// continue the round-robin from.
+func (s *Schedule) addRotation(users []string, p int) (int, error) {
+	p = p % len(users)
+	end := s.NominalEnd(s.Start)
+	if s.gapAt(s.Start) != nil {
+		s.addUnstaffed(end)
+		return p, nil
+	}
+	var primary int
+	if s.Fairness == FairnessBalanced {
+		primary = s.leastLoaded(users, p)
+	} else {
+		primary = s.nextEligible(users, p)
+	}
+	if primary < 0 {
+		if !s.AllowUnassigned {
+			return p, fmt.Errorf("%w for the rotation starting at %s and ending at %s (set AllowUnassigned to leave it unassigned)", ErrNoneAvailable, s.Start.Format(time.RFC3339), end.Format(time.RFC3339))
+		}
+		// The round-robin does not advance past a rotation nobody was on.
+		r := Rotation{Start: s.Start, End: end, Notes: "nobody is available"}
+		for _, tier := range s.tiers() {
+			r = s.withTierUser(r, tier, Unassigned)
+		}
+		s.Rotations = append(s.Rotations, r)
+		s.Start = end
+		return p, nil
+	}
+	if s.tooSoon(users[primary]) {
+		var err error
+		if primary, err = s.nextAfterGap(users, primary); err != nil {
+			return p, err
+		}
+	}
+	// A pattern is kept as written, so its users are skipped rather than
+	// moved up.
+	for s.Fairness != FairnessBalanced && len(s.Pattern) == 0 && primary != p && s.skippedForUnavailability(users, p, primary, end) {
+		prev := mod(primary - 1, len(users))
+		users[primary], users[prev] = users[prev], users[primary]
+		primary = prev
+	}
+	holidays := s.holidaysOverlapping(s.Start, end)
+	if landmarks := s.landmarksOverlapping(s.Start, end); len(landmarks) > 0 {
+		// Give the landmark to whoever has had it least, and let the user the
+		// round-robin reached take their place in the order.
+		fairest := s.fairestForLandmarks(landmarks, users, primary)
+		users[primary], users[fairest] = users[fairest], users[primary]
+		s.recordLandmarks(landmarks, users[primary])
+	} else if len(holidays) > 0 && s.HolidayPolicy == HolidayAvoid {
+		fairest := s.fairestForHolidays(users, primary)
+		users[primary], users[fairest] = users[fairest], users[primary]
+	}
 	r := Rotation{
 		Start: s.Start,
-		Primary: s.Users[0],
-		Secondary: s.Users[1 % len(s.Users)],
+		End: end,
+		Notes: joinNotes(s.pauseNote(s.Start), s.gapNote(s.Start)),
+	}
+	if len(holidays) > 0 {
+		r.Notes = joinNotes(r.Notes, holidayNote(holidays))
+	}
+	r.Shadow = s.nextShadow(end)
+	r = s.withTierUser(r, s.tiers()[0], users[primary])
+	// Each higher tier takes the next available user not on the rotation
+	// already. Once nobody is left, the search comes back round to someone who
+	// is.
+	if s.SecondaryRotationDuration > 0 {
+		if err := s.addSplitBySecondary(r); err != nil {
+			return p, err
+		}
+		s.Start = end
+		return primary + 1, nil
+	}
+	assigned := []string{users[primary]}
+	// With a Pattern, the higher tiers are still taken from Users.
+	tierUsers, at := users, primary
+	if len(s.Pattern) > 0 {
+		tierUsers, at = s.Users, indexOf(s.Users, users[primary])
+	}
+	for _, tier := range s.tiers()[1:] {
+		var next string
+		if len(assigned) == 1 {
+			next = s.secondaryFor(tierUsers, at)
+		} else {
+			next = s.nextUnassigned(tierUsers, assigned)
+		}
+		if containsUser(assigned, next) {
+			if !s.AllowTierCollapse {
+				return p, fmt.Errorf("%d available users cannot fill %d tiers for the rotation starting at %s (set AllowTierCollapse to leave the highest tiers unfilled)", len(assigned), len(s.tiers()), s.Start.Format(time.RFC3339))
+			}
+			break
+		}
+		assigned = append(assigned, next)
+		r = s.withTierUser(r, tier, next)
 	}
 	s.Rotations = append(s.Rotations, r)
-	s.Start = s.Start.Add(s.RotationDuration)
-	s.Users = append(s.Users[1:], s.Users[0])
+	s.Start = end
+	return primary + 1, nil
+}
+
+// Return the position of the first user at or after position p in users,
+// wrapping around, who may be assigned to the rotation starting at s.Start, or
+// -1 if there is none.
+func (s *Schedule) nextEligible(users []string, p int) int {
+	return s.best(users, p, nil)
+}
+
+// Return the first available user in users after the last of assigned,
+// wrapping around, who is not one of assigned. If there is none, one of
+// assigned is returned.
+func (s *Schedule) nextUnassigned(users []string, assigned []string) string {
+	from := indexOf(users, assigned[len(assigned)-1]) + 1
+	return users[s.best(users, from, func(u string) float64 {
+		if containsUser(assigned, u) {
+			return 1
+		}
+		return 0
+	})]
+}
+
+func containsUser(users []string, u string) bool {
+	for _, v := range users {
+		if v == u {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether any user from position p up to, but not including, position q in
+// users, wrapping around, was passed over for the rotation starting at s.Start
+// and ending at end only because they are unavailable.
+func (s *Schedule) skippedForUnavailability(users []string, p, q int, end time.Time) bool {
+	for i := p; i != q; i = (i + 1) % len(users) {
+		if s.eligible(users[i], s.Start) && !s.available(users[i], s.Start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return a copy of users rotated left by n places, so that the user who would
+// be primary after n more rotations comes first.
+func rotateUsers(users []string, n int) []string {
+	n = n % len(users)
+	rotated := make([]string, 0, len(users))
+	rotated = append(rotated, users[n:]...)
+	return append(rotated, users[:n]...)
+}
+
+// Return the time k rotations after t: RotationLength after it k times over.
+// With a Timezone, whole days are added on the calendar, so the wall-clock
+// time is kept across daylight saving changes. k may be negative.
+func (s Schedule) rotationsAfter(t time.Time, k int) time.Time {
+	if s.location == nil {
+		return t.Add(time.Duration(k) * s.RotationDuration)
+	}
+	days := int(s.RotationDuration / Day)
+	rest := s.RotationDuration % Day
+	local := t.In(s.location)
+	if hour, min, err := parseHandoffTime(s.HandoffTime); s.HandoffTime != "" && err == nil {
+		// Count days from the handoff as configured rather than from where a
+		// daylight saving change moved it, so the next handoff moves back.
+		year, month, day := local.Date()
+		if wallClock(year, month, day, hour, min, s.location).Equal(t) {
+			return wallClock(year, month, day + k * days, hour, min, s.location).Add(time.Duration(k) * rest)
+		}
+	}
+	return local.AddDate(0, 0, k * days).Add(time.Duration(k) * rest)
+}
+
+// SetNow pins the schedule's clock to now, so that truncation and the
+// generation horizon are deterministic. A zero now restores the wall clock.
+// The clock is never serialized.
+func (s *Schedule) SetNow(now time.Time) {
+	s.now = now
+}
+
+// The current time according to the schedule's clock.
+func (s Schedule) currentTime() time.Time {
+	if s.now.IsZero() {
+		return time.Now()
+	}
+	return s.now
 }
 
-// Truncate rotations that have elapsed.
+// NominalEnd returns when a rotation starting at start ends if no rotation
+// follows it: RotationLength later (on the calendar, with a Timezone), plus
+// the length of any pauses it covers, moved forward to the next HandoffTime
+// and StartWeekday. A rotation that does not start on StartWeekday instead
+// ends on the next one, so that the rotation after it gets back in step. A
+// rotation ends early as a gap starts, and one starting in a gap ends with it.
+// With Windows, it is instead the start of the next window, and with Regions
+// the start of the next region's shift.
+func (s Schedule) NominalEnd(start time.Time) time.Time {
+	if len(s.Windows) > 0 {
+		return s.nextWindowStart(start)
+	}
+	if len(s.Regions) > 0 {
+		return s.nextRegionStart(start)
+	}
+	end, _ := s.endForGaps(start, s.ungappedEnd(start))
+	return end
+}
+
+// The end of a rotation starting at start, before it is cut short by a gap.
+func (s Schedule) ungappedEnd(start time.Time) time.Time {
+	end, _ := s.extendForPauses(start, s.naturalEnd(start))
+	return s.snapToStartWeekday(s.snapToHandoff(end))
+}
+
+// The end of a rotation starting at start, before pauses and snapping.
+func (s Schedule) naturalEnd(start time.Time) time.Time {
+	if !s.onStartWeekday(start) {
+		return s.nextStartWeekday(start)
+	}
+	return s.rotationsAfter(start, 1)
+}
+
+// RotationEnd returns the end of the i-th rotation in Rotations: its End if it
+// has one, or otherwise the start of the next one, or the NominalEnd of the
+// last one. If the last rotation was split by an override, or where the
+// secondary hands off, that is the NominalEnd of the rotation it was split
+// from.
+func (s Schedule) RotationEnd(i int) time.Time {
+	if end := s.Rotations[i].End; !end.IsZero() {
+		return end
+	}
+	if i + 1 < len(s.Rotations) {
+		return s.Rotations[i+1].Start
+	}
+	return s.NominalEnd(primaryTurnStart(s.Rotations, i))
+}
+
+var (
+	// Returned by At for a time before the first rotation.
+	ErrBeforeRotations = errors.New("no rotation covers the time: it is before the first rotation")
+	// Returned by At for a time at or after the end of the last rotation,
+	// usually because the schedule needs regenerating.
+	ErrAfterRotations = errors.New("no rotation covers the time: it is after the last rotation; regenerate the schedule")
+)
+
+// At returns the rotation covering t: the last one starting at or before t,
+// provided t is before its RotationEnd. A time outside every rotation is
+// ErrBeforeRotations or ErrAfterRotations. With SearchHistory, a time before
+// the first rotation is looked for in History too. A time in one of Gaps is
+// covered by an Unstaffed rotation, with nobody on call.
+func (s Schedule) At(t time.Time, opts ...AtOption) (Rotation, error) {
+	i := sort.Search(len(s.Rotations), func(i int) bool {
+		return s.Rotations[i].Start.After(t)
+	}) - 1
+	if i < 0 {
+		for _, o := range opts {
+			if r, ok := s.historyAt(t); o == SearchHistory && ok {
+				return r, nil
+			}
+		}
+		return Rotation{}, ErrBeforeRotations
+	}
+	if !t.Before(s.RotationEnd(i)) {
+		return Rotation{}, ErrAfterRotations
+	}
+	return s.Rotations[i], nil
+}
+
+// RotationsBetween returns every rotation that overlaps the window from from,
+// inclusive, to to, exclusive, in order: each rotation, from History or
+// Rotations, that starts before to and ends after from, including those only
+// partly in the window. A rotation ending at from, or starting at to, is not
+// included. The rotations are copies, with End filled in, so changing them
+// leaves s as it was. A window outside every rotation, or with to not after
+// from, returns none.
+func (s Schedule) RotationsBetween(from, to time.Time) []Rotation {
+	rs := []Rotation{}
+	if !from.Before(to) {
+		return rs
+	}
+	for _, r := range s.History {
+		if len(s.Rotations) > 0 && !r.Start.Before(s.Rotations[0].Start) {
+			break
+		}
+		if r.Start.Before(to) && r.End.After(from) {
+			rs = append(rs, r.clone())
+		}
+	}
+	for i, r := range s.Rotations {
+		if !r.Start.Before(to) {
+			break
+		}
+		if end := s.RotationEnd(i); end.After(from) {
+			r = r.clone()
+			r.End = end
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// Current returns the rotation covering the current time according to the
+// schedule's clock. See At.
+func (s Schedule) Current() (Rotation, error) {
+	return s.At(s.currentTime())
+}
+
+// Truncate rotations that have elapsed, keeping the one before the current
+// rotation. A rotation has elapsed once its End, or the Start of the next
+// rotation if it has no End, is at or before now.
 func truncate(rs []Rotation, now time.Time) []Rotation {
-	trunc := len(rs) - 1
-	for ;trunc > 0; trunc -= 1 {
-		if rs[trunc].Start.Before(now) {
-			// Truncate all rotations _before_ this once, since it should be the
-			// currently active rotation.
-			trunc -= 1
+	current := len(rs) - 1
+	for i, r := range rs {
+		end := r.End
+		if end.IsZero() && i + 1 < len(rs) {
+			end = rs[i+1].Start
+		}
+		if end.IsZero() || end.After(now) {
+			current = i
 			break
 		}
 	}
-	return rs[trunc:]
+	if current == 0 {
+		return rs
+	}
+	return rs[current-1:]
+}
+
+// The number of rotations Generate may leave in Rotations when MaxRotations
+// is not set: enough for a daily rotation scheduled years out, but far fewer
+// than a typo in RotationLength or ScheduleFor produces.
+const DefaultMaxRotations = 10000
+
+// Return an error if Generate would leave n rotations, counting those kept,
+// which is more than MaxRotations allows. The error gives the durations that
+// produce n, so that a typo in either is obvious. Generating until a time,
+// rather than for ScheduleFor, is checked the same way.
+func (s Schedule) checkRotationCount(n int, until time.Time) error {
+	max := s.MaxRotations
+	if max == 0 {
+		max = DefaultMaxRotations
+	}
+	if n <= max {
+		return nil
+	}
+	length := fmt.Sprintf("RotationLength %s", s.RotationLength)
+	if len(s.Windows) > 0 || len(s.Regions) > 0 {
+		length = fmt.Sprintf("rotations as short as %s", s.typicalLength())
+	}
+	extent := fmt.Sprintf("ScheduleFor %s", s.ScheduleFor)
+	if !until.IsZero() {
+		extent = "until " + until.Format(time.RFC3339)
+	}
+	return fmt.Errorf("%w: generating with %s and %s makes %d rotations, more than MaxRotations %d; check both for a typo (e.g. \"1m\" is a minute), or raise MaxRotations", ErrTooManyRotations, length, extent, n, max)
 }
 
 func numRotations(start, end time.Time, duration time.Duration) int {
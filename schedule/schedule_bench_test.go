@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchSchedule returns a daily schedule with history rotations already
+// generated, and now set to the start of the first one so that none of them
+// are truncated.
+func benchSchedule(users, history int, scheduleFor time.Duration) *Schedule {
+	s := &Schedule{
+		Start: Start,
+		RotationLength: "24h",
+		RotationDuration: 24 * time.Hour,
+		ScheduleFor: scheduleFor.String(),
+		ScheduleForDuration: scheduleFor,
+	}
+	for i := 0; i < users; i++ {
+		s.Users = append(s.Users, fmt.Sprintf("user%d", i))
+	}
+	for i := 0; i < history; i++ {
+		s.Rotations = append(s.Rotations, Rotation{
+			Start: Start.Add(time.Duration(i) * s.RotationDuration),
+			Primary: s.Users[i % users],
+			Secondary: s.Users[(i + 1) % users],
+		})
+	}
+	s.now = Start
+	return s
+}
+
+func BenchmarkGenerateFromEmpty(b *testing.B) {
+	s := benchSchedule(10, 0, 3 * 365 * 24 * time.Hour)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Generate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateLongHistory(b *testing.B) {
+	s := benchSchedule(10, 3 * 365, 3 * 365 * 24 * time.Hour + 90 * 24 * time.Hour)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Generate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// An hourly rotation of 50 users scheduled 10,000 rotations ahead.
+func BenchmarkGenerateHourlyLargeTeam(b *testing.B) {
+	s := benchSchedule(50, 0, 10000 * time.Hour)
+	s.RotationLength, s.RotationDuration = "1h", time.Hour
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Generate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package schedule
 
 import (
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,21 +26,25 @@ const (
 	"Rotations": [
 		{
 			"Start": "2017-02-01T10:00:00Z",
+			"End": "2017-02-08T10:00:00Z",
 			"Primary": "a",
 			"Secondary": "b"
 		},
 		{
 			"Start": "2017-02-08T10:00:00Z",
+			"End": "2017-02-15T10:00:00Z",
 			"Primary": "b",
 			"Secondary": "c"
 		},
 		{
 			"Start": "2017-02-15T10:00:00Z",
+			"End": "2017-02-22T10:00:00Z",
 			"Primary": "c",
 			"Secondary": "a"
 		},
 		{
 			"Start": "2017-02-22T10:00:00Z",
+			"End": "2017-03-01T10:00:00Z",
 			"Primary": "a",
 			"Secondary": "b"
 		}
@@ -51,41 +58,49 @@ var (
 
 func EmptySchedule() *Schedule {
 	return &Schedule{
+		FormatVersion: CurrentFormatVersion,
 		Users: []string{"a", "b", "c"},
 		Start: Start,
 		RotationLength: "168h",
-		rotationLength: 7 * 24 * time.Hour,
+		RotationDuration: 7 * 24 * time.Hour,
 		ScheduleFor: "504h",
-		scheduleFor: 3 * 7 * 24 * time.Hour,
+		ScheduleForDuration: 3 * 7 * 24 * time.Hour,
+		HandoffGraceDuration: DefaultHandoffGrace,
 	}
 }
 
 func FilledSchedule() *Schedule {
 	return &Schedule{
+		FormatVersion: CurrentFormatVersion,
 		Users: []string{"b", "c", "a"},
 		Start: time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC),
 		RotationLength: "168h",
-		rotationLength: 7 * 24 * time.Hour,
+		RotationDuration: 7 * 24 * time.Hour,
 		ScheduleFor: "504h",
-		scheduleFor: 3 * 7 * 24 * time.Hour,
+		ScheduleForDuration: 3 * 7 * 24 * time.Hour,
+		HandoffGraceDuration: DefaultHandoffGrace,
 		Rotations: []Rotation{
 			{
 				Start: Start,
+				End: time.Date(2017, time.February, 8, 10, 0, 0, 0, time.UTC),
 				Primary: "a",
 				Secondary: "b",
 			},
 			{
 				Start: time.Date(2017, time.February, 8, 10, 0, 0, 0, time.UTC),
+				End: time.Date(2017, time.February, 15, 10, 0, 0, 0, time.UTC),
 				Primary: "b",
 				Secondary: "c",
 			},
 			{
 				Start: time.Date(2017, time.February, 15, 10, 0, 0, 0, time.UTC),
+				End: time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC),
 				Primary: "c",
 				Secondary: "a",
 			},
 			{
 				Start: time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC),
+				End: time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC),
 				Primary: "a",
 				Secondary: "b",
 			},
@@ -93,6 +108,35 @@ func FilledSchedule() *Schedule {
 	}
 }
 
+// Return rs with the End of each rotation set to the Start of the next, and
+// the End of the last to end.
+func withEnds(end time.Time, rs []Rotation) []Rotation {
+	for i := range rs {
+		if i + 1 < len(rs) {
+			rs[i].End = rs[i+1].Start
+		} else {
+			rs[i].End = end
+		}
+	}
+	return rs
+}
+
+// Return rs with the IDs Generate gives rotations without one.
+func withIDs(rs []Rotation) []Rotation {
+	id, k := "", 0
+	for i := range rs {
+		if rs[i].Continues {
+			k++
+		} else {
+			id, k = rotationID("", rs[i]), 0
+		}
+		if rs[i].ID == "" {
+			rs[i].ID = segmentID(id, k)
+		}
+	}
+	return rs
+}
+
 func TestParseEmptySchedule(t *testing.T) {
 	s, err := NewSchedule([]byte(EmptyScheduleText))
 	if err != nil {
@@ -120,6 +164,8 @@ func TestGenerateFromEmpty(t *testing.T) {
 	empty.now = Start
 	filled := FilledSchedule()
 	filled.now = empty.now
+	filled.Provenance = expectedProvenance(t, empty)
+	filled.Rotations = withIDs(filled.Rotations)
 	s, err := empty.Generate()
 	if err != nil {
 		t.Error(err)
@@ -136,7 +182,425 @@ func TestGenerateIsIdempotent(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	filled.Provenance = expectedProvenance(t, filled)
+	filled.Rotations = withIDs(filled.Rotations)
 	if !reflect.DeepEqual(filled, s) {
 		t.Errorf("generated schedule does not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", filled, s)
 	}
 }
+
+func TestGenerateLeavesReceiverUnchanged(t *testing.T) {
+	// Each slice has room to grow, so that an append to one in place would
+	// write into the receiver's backing array rather than reallocate.
+	roomy := func(s *Schedule) *Schedule {
+		s.Users = append(make([]string, 0, 16), s.Users...)
+		s.Rotations = append(make([]Rotation, 0, 64), s.Rotations...)
+		for i := range s.Windows {
+			s.Windows[i].Users = append(make([]string, 0, 16), s.Windows[i].Users...)
+		}
+		for i := range s.Regions {
+			s.Regions[i].Users = append(make([]string, 0, 16), s.Regions[i].Users...)
+		}
+		return s
+	}
+	withOverrides := FilledSchedule()
+	withOverrides.Overrides = []Override{{Start: day(30), End: day(32), Role: TierPrimary, User: "c"}}
+	withOverrides.Swaps = []Swap{{Users: [2]string{"a", "b"}, Start: day(36), End: day(43)}}
+	pools := EmptySchedule()
+	pools.Users, pools.PrimaryUsers, pools.SecondaryUsers = nil, []string{"a", "b"}, []string{"c", "d"}
+	// Rotations with Assignments and Overridden maps of their own, and counts
+	// carried over from those dropped.
+	roles := EmptySchedule()
+	roles.Roles = []string{"primary", "secondary", "tertiary"}
+	roles.Fairness = FairnessBalanced
+	roles.now = Start
+	roles, err := roles.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles.Overrides = []Override{{Start: day(16), End: day(17), Role: "tertiary", User: "a", Force: true}, {Start: day(23), End: day(24), Role: "tertiary", User: "b", Force: true}}
+	if roles, err = roles.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]*Schedule{
+		"empty": roomy(EmptySchedule()),
+		"filled": roomy(FilledSchedule()),
+		"overrides": roomy(withOverrides),
+		"pools": roomy(pools),
+		"roles": roomy(roles),
+		"windows": roomy(WindowsSchedule(t)),
+		"regions": roomy(RegionsSchedule(t)),
+	} {
+		s.now = s.Start.Add(2 * Week)
+		before, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Generate(); err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if after, _ := json.Marshal(s); string(after) != string(before) {
+			t.Errorf("%s: Generate modified the schedule\nBefore:\n%s\nAfter:\n%s", name, before, after)
+		}
+		if spare := s.Rotations[len(s.Rotations):cap(s.Rotations)]; !reflect.DeepEqual(spare, make([]Rotation, len(spare))) {
+			t.Errorf("%s: Generate wrote past the end of Rotations", name)
+		}
+		if spare := s.Users[len(s.Users):cap(s.Users)]; !reflect.DeepEqual(spare, make([]string, len(spare))) {
+			t.Errorf("%s: Generate wrote past the end of Users: %v", name, spare)
+		}
+	}
+}
+
+func TestGenerateUsersNextUpWithoutNewRotations(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = Start
+	// Users is stale: it has not been rotated to reflect the last rotation.
+	filled.Users = []string{"a", "b", "c"}
+	s, err := filled.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Rotations) != len(filled.Rotations) {
+		t.Fatalf("expected no rotations to be added, got %d", len(s.Rotations) - len(filled.Rotations))
+	}
+	expected := []string{"b", "c", "a"}
+	if !reflect.DeepEqual(expected, s.Users) {
+		t.Errorf("expected Users %v, got %v", expected, s.Users)
+	}
+}
+
+func TestGenerateUsersNextUpWithManyNewRotations(t *testing.T) {
+	empty := EmptySchedule()
+	empty.now = Start.Add(10 * empty.RotationDuration)
+	s, err := empty.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := s.Rotations[len(s.Rotations)-1]
+	next := map[string]string{"a": "b", "b": "c", "c": "a"}[last.Primary]
+	if s.Users[0] != next {
+		t.Errorf("last rotation has primary %s, expected Users[0] to be %s, got %v", last.Primary, next, s.Users)
+	}
+}
+
+func TestTruncateUsesEnd(t *testing.T) {
+	rs := FilledSchedule().Rotations
+	cases := []struct {
+		now time.Time
+		kept int
+	}{
+		{Start, 4},
+		{time.Date(2017, time.February, 15, 10, 0, 0, 0, time.UTC), 3},
+		{time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC), 3},
+		{time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC), 2},
+	}
+	for _, c := range cases {
+		kept := truncate(rs, c.now)
+		if len(kept) != c.kept {
+			t.Errorf("at %s, expected %d rotations kept, got %d", c.now, c.kept, len(kept))
+		}
+	}
+}
+
+func TestValidateRotations(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2017, time.February, d, 10, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		rotations []Rotation
+		err string
+	}{
+		{[]Rotation{{Start: day(1), End: day(8)}, {Start: day(8), End: day(15)}}, ""},
+		{[]Rotation{{Start: day(1)}, {Start: day(8)}}, ""},
+		{[]Rotation{{Start: day(8), End: day(1)}}, "rotation starting at 2017-02-08T10:00:00Z must end after it starts"},
+		{[]Rotation{{Start: day(8), End: day(15)}, {Start: day(1), End: day(8)}}, "rotation starting at 2017-02-01T10:00:00Z is listed after the rotation starting at 2017-02-08T10:00:00Z"},
+		{[]Rotation{{Start: day(1), End: day(7)}, {Start: day(8), End: day(15)}}, "gap between the rotation from 2017-02-01T10:00:00Z to 2017-02-07T10:00:00Z and the rotation starting at 2017-02-08T10:00:00Z"},
+		{[]Rotation{{Start: day(1), End: day(9)}, {Start: day(8), End: day(15)}}, "rotation from 2017-02-01T10:00:00Z to 2017-02-09T10:00:00Z overlaps the rotation starting at 2017-02-08T10:00:00Z"},
+	}
+	for i, c := range cases {
+		err := validateRotations(c.rotations)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("case %d: expected no error, got %s", i, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("case %d: expected an error containing %q, got %v", i, c.err, err)
+		}
+	}
+}
+
+func TestExtend(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	until := time.Date(2017, time.April, 12, 11, 0, 0, 0, time.UTC)
+	ns, err := s.Extend(until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last rotation, starting on 12 April, covers until, and no more are
+	// added after it.
+	last := len(ns.Rotations) - 1
+	if len(ns.Rotations) != 11 || !ns.Rotations[last].Start.Before(until) || !ns.RotationEnd(last).After(until) {
+		t.Errorf("expected 11 rotations, the last covering %s, got %v", until, ns.Rotations)
+	}
+
+	if _, err := ns.Extend(until.Add(-Week)); !errors.Is(err, ErrAlreadyCovered) {
+		t.Errorf("expected ErrAlreadyCovered, got %v", err)
+	}
+
+	// Generate a week later truncates as usual, and keeps the rotations Extend
+	// added beyond ScheduleFor.
+	ns.now = Start.Add(Week + time.Hour)
+	regenerated, err := ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(regenerated.Rotations, ns.Rotations) {
+		t.Errorf("expected Generate to keep the extended rotations, got %v", regenerated.Rotations)
+	}
+	ns.now = Start.Add(2 * Week + time.Hour)
+	if regenerated, err = ns.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(regenerated.Rotations, ns.Rotations[1:]) {
+		t.Errorf("expected Generate to truncate the first rotation only, got %v", regenerated.Rotations)
+	}
+}
+
+func TestAt(t *testing.T) {
+	s := FilledSchedule()
+	// Hand-edited to be irregular: the second rotation is cut short.
+	s.Rotations[1].End = time.Date(2017, time.February, 10, 10, 0, 0, 0, time.UTC)
+	s.Rotations[2].Start = s.Rotations[1].End
+	feb := func(day, hour int) time.Time {
+		return time.Date(2017, time.February, day, hour, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		at time.Time
+		primary string
+		err error
+	}{
+		{Start.Add(-time.Nanosecond), "", ErrBeforeRotations},
+		{Start, "a", nil},
+		{feb(8, 10).Add(-time.Nanosecond), "a", nil},
+		{feb(8, 10), "b", nil},
+		{feb(10, 10).Add(-time.Nanosecond), "b", nil},
+		{feb(10, 10), "c", nil},
+		{feb(22, 10), "a", nil},
+		{time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC).Add(-time.Nanosecond), "a", nil},
+		{time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC), "", ErrAfterRotations},
+	}
+	for _, c := range cases {
+		r, err := s.At(c.at)
+		if err != c.err {
+			t.Errorf("at %s, expected error %v, got %v", c.at, c.err, err)
+		}
+		if r.Primary != c.primary {
+			t.Errorf("at %s, expected primary %q, got %q", c.at, c.primary, r.Primary)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	s := FilledSchedule()
+	s.SetNow(time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC))
+	r, err := s.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Primary != "c" {
+		t.Errorf("expected c to be primary, got %s", r.Primary)
+	}
+}
+
+func TestRotationsBetween(t *testing.T) {
+	s := FilledSchedule()
+	feb := func(day, hour int) time.Time {
+		return time.Date(2017, time.February, day, hour, 0, 0, 0, time.UTC)
+	}
+	for _, c := range []struct {
+		from, to time.Time
+		primaries string
+	}{
+		// Rotations partly in the window are included.
+		{feb(3, 0), feb(10, 0), "a b"},
+		// The start is inclusive and the end exclusive.
+		{feb(8, 10), feb(15, 10), "b"},
+		{feb(8, 10).Add(-time.Nanosecond), feb(15, 10).Add(time.Nanosecond), "a b c"},
+		// A window between two handoffs is covered by one rotation.
+		{feb(16, 0), feb(17, 0), "c"},
+		{Start.Add(-time.Hour), Start, ""},
+		{time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC), time.Date(2017, time.March, 2, 0, 0, 0, 0, time.UTC), ""},
+		{feb(17, 0), feb(16, 0), ""},
+	} {
+		primaries := []string{}
+		for _, r := range s.RotationsBetween(c.from, c.to) {
+			primaries = append(primaries, r.Primary)
+		}
+		if got := strings.Join(primaries, " "); got != c.primaries {
+			t.Errorf("from %s to %s, expected %q, got %q", c.from, c.to, c.primaries, got)
+		}
+	}
+
+	// End is filled in, and the rotations are copies.
+	s.Rotations[3].End = time.Time{}
+	rs := s.RotationsBetween(feb(23, 0), feb(24, 0))
+	if len(rs) != 1 || !rs[0].End.Equal(s.NominalEnd(s.Rotations[3].Start)) {
+		t.Fatalf("expected the last rotation with its End filled in, got %v", rs)
+	}
+	rs[0].Primary = "z"
+	if s.Rotations[3].Primary != "a" || !s.Rotations[3].End.IsZero() {
+		t.Errorf("expected changing the result to leave the schedule as it was, got %v", s.Rotations[3])
+	}
+}
+
+func TestRotationsBetweenHistory(t *testing.T) {
+	s := FilledSchedule()
+	s.History = []Rotation{{
+		Start: time.Date(2017, time.January, 25, 10, 0, 0, 0, time.UTC),
+		End: Start,
+		Primary: "c",
+		Secondary: "a",
+	}}
+	rs := s.RotationsBetween(time.Date(2017, time.January, 31, 0, 0, 0, 0, time.UTC), time.Date(2017, time.February, 2, 0, 0, 0, 0, time.UTC))
+	if len(rs) != 2 || rs[0].Primary != "c" || rs[1].Primary != "a" {
+		t.Errorf("expected the rotations from History and Rotations, got %v", rs)
+	}
+	if rs := s.RotationsBetween(time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2017, time.January, 2, 0, 0, 0, 0, time.UTC)); len(rs) != 0 {
+		t.Errorf("expected no rotations before History, got %v", rs)
+	}
+}
+
+func TestWithNow(t *testing.T) {
+	now := time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	s, err := NewSchedule([]byte(EmptyScheduleText), WithNow(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotations are added until one starts ScheduleFor after now, not after
+	// the wall clock.
+	if last := ns.Rotations[len(ns.Rotations)-1]; last.Start.Before(now.Add(3 * Week)) || !last.Start.Before(now.Add(4 * Week)) {
+		t.Errorf("expected the last rotation to start in the fourth week after now, got %s", last)
+	}
+	text, err := ns.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.now.IsZero() {
+		t.Errorf("expected the clock not to be written, got %s", again.now)
+	}
+}
+
+func TestGenerateContinuesFromLastRotation(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC)
+	// The last two weeks were swapped by hand, without rotating Users.
+	filled.Rotations[2].Primary, filled.Rotations[3].Primary = "a", "c"
+	filled.Rotations[2].Secondary, filled.Rotations[3].Secondary = "b", "a"
+	s, err := filled.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := s.Rotations[len(filled.Rotations) - 2]; first.Primary != "a" {
+		t.Errorf("expected a to follow c, got %s", first)
+	}
+	expected := []string{"b", "c", "a"}
+	if last := s.Rotations[len(s.Rotations)-1]; last.Primary != "a" || !reflect.DeepEqual(expected, s.Users) {
+		t.Errorf("expected the cycle to continue to a with b next, got %s and Users %v", last, s.Users)
+	}
+	if lint := s.Lint(); len(lint) != 0 {
+		t.Errorf("expected no suggestions, got %v", lint)
+	}
+}
+
+func TestGenerateAfterEveryRotationElapsed(t *testing.T) {
+	filled := FilledSchedule()
+	// Eight weeks after the last rotation ended.
+	filled.now = time.Date(2017, time.April, 26, 12, 0, 0, 0, time.UTC)
+	s, err := filled.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := s.Rotations[0]
+	if !first.Start.Equal(time.Date(2017, time.April, 26, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the first rotation to be the one under way, got %s", first)
+	}
+	// The cycle moves on as if the eight weeks in between had been scheduled.
+	if first.Primary != "a" {
+		t.Errorf("expected a to be primary, got %s", first)
+	}
+	// Only the rotations that actually happened are counted.
+	shifts := 0
+	for _, c := range s.ShiftCounts {
+		shifts += c.Primary
+	}
+	if shifts != len(filled.Rotations) {
+		t.Errorf("expected %d primary shifts counted, got %v", len(filled.Rotations), s.ShiftCounts)
+	}
+}
+
+func TestGenerateLastPrimaryNotInUsers(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC)
+	filled.Rotations[3].Primary = "d"
+	s, err := filled.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The cycle falls back to the order of Users.
+	if first := s.Rotations[len(filled.Rotations) - 2]; first.Primary != "b" {
+		t.Errorf("expected b, first in Users, to be primary next, got %s", first)
+	}
+	lint := s.Lint()
+	if len(lint) != 1 || !strings.Contains(lint[0], "d, the primary of the last rotation before those generated, is no longer among the users to schedule") {
+		t.Errorf("expected a warning about d, got %v", lint)
+	}
+}
+
+func TestGenerateRefusesTooManyRotations(t *testing.T) {
+	s := EmptySchedule()
+	// A minute, where a month was meant, for 90 days.
+	s.RotationLength, s.RotationDuration = "1m", time.Minute
+	s.ScheduleFor, s.ScheduleForDuration = "2160h", 90 * Day
+	s.now = Start
+	started := time.Now()
+	_, err := s.Generate()
+	if !errors.Is(err, ErrTooManyRotations) {
+		t.Fatalf("expected ErrTooManyRotations, got %v", err)
+	}
+	expected := "too many rotations: generating with RotationLength 1m and ScheduleFor 2160h makes 129601 rotations, more than MaxRotations 10000"
+	if !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("expected an error starting %q, got %q", expected, err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected to fail fast, took %s", elapsed)
+	}
+}
+
+func TestMaxRotationsCountsKeptRotations(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	// Three rotations are kept, from the one before the rotation under way,
+	// and three added to cover ScheduleFor.
+	s.MaxRotations = 5
+	if _, err := s.Generate(); !errors.Is(err, ErrTooManyRotations) || !strings.Contains(err.Error(), "makes 6 rotations, more than MaxRotations 5") {
+		t.Errorf("expected the kept rotations to count, got %v", err)
+	}
+	s.MaxRotations = 6
+	if ns, err := s.Generate(); err != nil || len(ns.Rotations) != 6 {
+		t.Errorf("expected 6 rotations within MaxRotations, got %v", err)
+	}
+	s.MaxRotations = -1
+	if err := s.Validate(); err == nil {
+		t.Errorf("expected negative MaxRotations to fail validation")
+	}
+}
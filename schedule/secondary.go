@@ -0,0 +1,158 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// The conventions for who backs up each rotation's primary as secondary.
+const (
+	// The user who will be primary next, so the secondary is warmed up for
+	// their own turn. This is the default.
+	SecondaryNextPrimary = "next-primary"
+	// The user who was primary for the previous rotation, so context carries
+	// over. The first rotation of a new schedule has no previous primary, and
+	// uses whoever precedes its primary in Users instead.
+	SecondaryPreviousPrimary = "previous-primary"
+)
+
+func validateSecondaryMode(mode string) error {
+	switch mode {
+	case "", SecondaryNextPrimary, SecondaryPreviousPrimary:
+		return nil
+	}
+	return fmt.Errorf("unknown SecondaryMode %q: must be %q or %q", mode, SecondaryNextPrimary, SecondaryPreviousPrimary)
+}
+
+// Return the secondary for the rotation starting at s.Start whose primary is
+// at position primary in users: the next from the secondary pool if there is
+// one, or otherwise following SecondaryMode. If the preferred user is
+// unavailable, the next available user after the primary is taken instead. If
+// the primary is the only available user, they are returned.
+func (s *Schedule) secondaryFor(users []string, primary int) string {
+	if s.secondaries != nil {
+		return s.nextSecondary(users[primary])
+	}
+	if s.SecondaryMode != SecondaryPreviousPrimary {
+		return users[s.nextEligible(users, primary + 1)]
+	}
+	previous := users[mod(primary - 1, len(users))]
+	if len(s.Rotations) > 0 {
+		previous = s.Rotations[len(s.Rotations)-1].Primary
+	}
+	return users[s.best(users, primary + 1, func(u string) float64 {
+		switch u {
+		case previous:
+			return 0
+		case users[primary]:
+			return 2
+		}
+		return 1
+	})]
+}
+
+func (s Schedule) validateSecondaryRotation() error {
+	if s.SecondaryRotationLength == "" {
+		return nil
+	}
+	if s.SecondaryRotationDuration <= 0 {
+		return fmt.Errorf("cannot have nonpositive SecondaryRotationLength (got %s)", s.SecondaryRotationDuration)
+	}
+	if len(s.SecondaryUsers) == 0 {
+		return fmt.Errorf("SecondaryRotationLength requires SecondaryUsers, for the secondaries' own round-robin")
+	}
+	if len(s.Windows) > 0 || len(s.Regions) > 0 {
+		return fmt.Errorf("SecondaryRotationLength cannot be used with Windows or Regions")
+	}
+	return nil
+}
+
+// Return SecondaryStart, or nil if it is not set, as written.
+func (s Schedule) secondaryStart() *time.Time {
+	if s.SecondaryStart.IsZero() {
+		return nil
+	}
+	t := s.SecondaryStart
+	return &t
+}
+
+// Return the start of the primary's turn that the rotation at i in rs is part
+// of, going back over the segments split off it by overrides or where the
+// secondary hands off.
+func primaryTurnStart(rs []Rotation, i int) time.Time {
+	for i > 0 && (rs[i].Continues || rs[i].SecondaryHandoff) {
+		i--
+	}
+	return rs[i].Start
+}
+
+// Return when the secondary turn starting at t ends: SecondaryRotationLength
+// later, on the calendar if it is a whole number of days and there is a
+// Timezone, so the wall-clock handoff is kept across daylight saving changes.
+func (s Schedule) secondaryTurnEnd(t time.Time) time.Time {
+	if s.location == nil || s.SecondaryRotationDuration % Day != 0 {
+		return t.Add(s.SecondaryRotationDuration)
+	}
+	return t.In(s.location).AddDate(0, 0, int(s.SecondaryRotationDuration / Day))
+}
+
+// Add r, a rotation whose primary alone is assigned, to Rotations, split
+// wherever a secondary turn starts during it, with the secondary of each part
+// whoever holds the turn. Each turn is taken by the next of the secondary pool
+// still eligible as it starts, so that the pool advances once per turn
+// whoever is primary. A turn that started before the last rotation kept is
+// held by the last of SecondaryUsers. If the holder is the part's primary, or
+// unavailable, the next available user other than the primary stands in for
+// that part alone.
+func (s *Schedule) addSplitBySecondary(r Rotation) error {
+	q := s.secondaries
+	tier := s.tiers()[1]
+	for start := r.Start; start.Before(r.End); {
+		if s.SecondaryStart.IsZero() {
+			s.SecondaryStart = start
+		}
+		for s.secondaryHolder == "" || !start.Before(s.SecondaryStart) {
+			turn := start
+			if !start.Before(s.SecondaryStart) {
+				turn = s.SecondaryStart
+				s.SecondaryStart = s.secondaryTurnEnd(s.SecondaryStart)
+			}
+			i := q.p % len(q.users)
+			for k := 0; k < len(q.users) && !s.eligible(q.users[i], turn); k++ {
+				i = (i + 1) % len(q.users)
+			}
+			s.secondaryHolder = q.users[i]
+			q.p = i + 1
+		}
+		part := r
+		part.Start = start
+		if s.SecondaryStart.Before(r.End) {
+			part.End = s.SecondaryStart
+		}
+		if start.After(r.Start) {
+			part.SecondaryHandoff = true
+			// The rotation's notes are kept on its first part.
+			part.Notes = ""
+		}
+		secondary := s.secondaryHolder
+		if secondary == r.Primary || !s.available(secondary, part.Start, part.End) {
+			i := s.best(q.users, q.p, func(u string) float64 {
+				if u == r.Primary {
+					return 1
+				}
+				return 0
+			})
+			if i < 0 || q.users[i] == r.Primary {
+				if !s.AllowTierCollapse {
+					return fmt.Errorf("nobody but %s is available to be %s from %s (set AllowTierCollapse to leave the tier unfilled)", r.Primary, tier, start.Format(time.RFC3339))
+				}
+				secondary = ""
+			} else {
+				secondary = q.users[i]
+			}
+		}
+		s.Rotations = append(s.Rotations, s.withTierUser(part, tier, secondary))
+		start = part.End
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecondaryPreviousPrimary(t *testing.T) {
+	s := EmptySchedule()
+	s.SecondaryMode = SecondaryPreviousPrimary
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first rotation has no previous primary, so c, who precedes a in
+	// Users, backs a up.
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "c"},
+		{Start: day(8), Primary: "b", Secondary: "a"},
+		{Start: day(15), Primary: "c", Secondary: "b"},
+		{Start: day(22), Primary: "a", Secondary: "c"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+
+	// Regenerating keeps the convention.
+	ns.now = day(16)
+	again, err := ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SecondaryMode != SecondaryPreviousPrimary {
+		t.Errorf("expected SecondaryMode to be kept, got %q", again.SecondaryMode)
+	}
+	for i := 1; i < len(again.Rotations); i++ {
+		if r := again.Rotations[i]; r.Secondary != again.Rotations[i-1].Primary {
+			t.Errorf("rotation starting at %s: expected the previous primary %s as secondary, got %s", r.Start, again.Rotations[i-1].Primary, r.Secondary)
+		}
+	}
+}
+
+func TestSecondaryPreviousPrimaryUnavailable(t *testing.T) {
+	s := EmptySchedule()
+	s.SecondaryMode = SecondaryPreviousPrimary
+	s.now = Start
+	s.Unavailable = []Unavailability{{User: "a", Start: day(8), End: day(15)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a is away for the second rotation, so the next user after b stands in.
+	if r := ns.Rotations[1]; r.Primary != "b" || r.Secondary != "c" {
+		t.Errorf("expected b and c for the second rotation, got %+v", r)
+	}
+}
+
+func TestValidateSecondaryMode(t *testing.T) {
+	s := EmptySchedule()
+	s.SecondaryMode = "same"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), `unknown SecondaryMode "same"`) {
+		t.Errorf("expected an error about the unknown mode, got %v", err)
+	}
+}
+
+// A schedule with weekly primaries and a secondary every 10 days, so that
+// their handoffs only sometimes fall together.
+func secondaryCadenceSchedule() *Schedule {
+	s := EmptySchedule()
+	s.Users = nil
+	s.PrimaryUsers = []string{"a", "b", "c", "d"}
+	s.SecondaryUsers = []string{"x", "y", "z"}
+	s.SecondaryRotationLength, s.SecondaryRotationDuration = "10d", 10 * Day
+	s.now = Start
+	return s
+}
+
+func TestSecondaryRotationLength(t *testing.T) {
+	ns, err := secondaryCadenceSchedule().Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "x"},
+		{Start: day(8), Primary: "b", Secondary: "x"},
+		{Start: day(11), Primary: "b", Secondary: "y", SecondaryHandoff: true},
+		{Start: day(15), Primary: "c", Secondary: "y"},
+		{Start: day(21), Primary: "c", Secondary: "z", SecondaryHandoff: true},
+		{Start: day(22), Primary: "d", Secondary: "z"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	// z holds the turn under way, so comes last.
+	if !reflect.DeepEqual(ns.SecondaryUsers, []string{"x", "y", "z"}) || !ns.SecondaryStart.Equal(day(31)) {
+		t.Errorf("expected z to hold the secondary until %s, got %v until %s", day(31), ns.SecondaryUsers, ns.SecondaryStart)
+	}
+	if end := ns.RotationEnd(len(ns.Rotations) - 1); !end.Equal(day(29)) {
+		t.Errorf("expected the last rotation to end with d's turn at %s, got %s", day(29), end)
+	}
+	if warnings := ns.Warnings(); len(warnings) != 0 {
+		t.Errorf("expected the split rotations not to look hand-edited, got %v", warnings)
+	}
+}
+
+func TestSecondaryRotationLengthSurvivesRegeneration(t *testing.T) {
+	// Generated in one go...
+	long := secondaryCadenceSchedule()
+	long.ScheduleFor, long.ScheduleForDuration = "49d", 49 * Day
+	whole, err := long.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ...and generated, written and regenerated later, the rotations are the
+	// same: neither round-robin starts over.
+	ns, err := secondaryCadenceSchedule().Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), `"SecondaryStart":"2017-03-03T10:00:00Z"`) {
+		t.Errorf("expected SecondaryStart to be written, got %s", text)
+	}
+	reloaded, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = day(16)
+	again, err := reloaded.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := -1
+	for i, r := range whole.Rotations {
+		if r.Start.Equal(again.Rotations[0].Start) {
+			first = i
+		}
+	}
+	if first < 0 || first + len(again.Rotations) > len(whole.Rotations) {
+		t.Fatalf("expected the regenerated rotations among those generated in one go\n%v\n%v", again.Rotations, whole.Rotations)
+	}
+	if expected := whole.Rotations[first:first+len(again.Rotations)]; !reflect.DeepEqual(expected, again.Rotations) {
+		t.Errorf("regenerated rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, again.Rotations)
+	}
+}
+
+func TestSecondaryRotationLengthStandsIn(t *testing.T) {
+	// The same people take both roles, with a secondary for four weeks.
+	s := EmptySchedule()
+	s.Users = []string{"a", "b", "c", "d"}
+	s.SecondaryUsers = []string{"a", "b", "c", "d"}
+	s.SecondaryRotationLength, s.SecondaryRotationDuration = "4w", 4 * Week
+	s.ScheduleFor, s.ScheduleForDuration = "8w", 8 * Week
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondaries := []string{}
+	for _, r := range ns.Rotations {
+		if r.Primary == r.Secondary {
+			t.Errorf("rotation starting at %s has %s as both primary and secondary", r.Start, r.Primary)
+		}
+		secondaries = append(secondaries, r.Secondary)
+	}
+	// a holds the first turn, b the second and c the third, but each stands
+	// down for their own week as primary.
+	if got := strings.Join(secondaries, " "); got != "b a a a b c b b c" {
+		t.Errorf("unexpected secondaries %s", got)
+	}
+}
+
+func TestSecondaryRotationLengthValidation(t *testing.T) {
+	for _, c := range []struct {
+		edit func(s *Schedule)
+		err string
+	}{
+		{func(s *Schedule) { s.SecondaryRotationDuration = 0 }, "nonpositive SecondaryRotationLength"},
+		{func(s *Schedule) { s.SecondaryRotationDuration = -time.Hour }, "nonpositive SecondaryRotationLength"},
+		{func(s *Schedule) { s.Users, s.PrimaryUsers, s.SecondaryUsers = []string{"a", "b", "c"}, nil, nil }, "requires SecondaryUsers"},
+	} {
+		s := secondaryCadenceSchedule()
+		c.edit(s)
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefaultTeam is the name a ScheduleSet gives the schedule of a
+// single-schedule file.
+const DefaultTeam = "default"
+
+// A ScheduleSet is the schedules of several teams kept in one file, e.g.
+//
+//	{
+//		"Timezone": "America/New_York",
+//		"ScheduleFor": "3w",
+//		"Teams": {
+//			"payments": {"Users": ["alice", "bob"], ...},
+//			"search": {"Users": ["carol", "dan"], "ScheduleFor": "6w", ...}
+//		}
+//	}
+//
+// Every field besides Teams is a default for each team's schedule, which a
+// team can set for itself instead, though not to a zero value the schedule
+// file leaves out, like false. Each team is otherwise a schedule of its own,
+// with its own Rotations.
+//
+// A single-schedule file can be read as a ScheduleSet too, as the one team
+// DefaultTeam, and is written back as a single schedule.
+type ScheduleSet struct {
+	Teams map[string]*Schedule
+
+	// The defaults as written, by field.
+	defaults map[string]interface{}
+	// Whether the set was read from a single-schedule file.
+	single bool
+}
+
+// TeamErrors are the errors of some teams of a ScheduleSet, by team.
+type TeamErrors map[string]error
+
+func (e TeamErrors) Error() string {
+	errs := []string{}
+	for team, err := range e {
+		errs = append(errs, fmt.Sprintf("team %s: %s", team, err))
+	}
+	sort.Strings(errs)
+	return strings.Join(errs, "; ")
+}
+
+// NewScheduleSet parses a file of several teams' schedules, or of a single
+// schedule. Every team is parsed, and the errors of any that cannot be are
+// returned together as TeamErrors.
+func NewScheduleSet(text []byte) (*ScheduleSet, error) {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(standardize(text), &doc); err != nil {
+		return nil, fmt.Errorf("error parsing schedule set: %s", locateJSON(text, err))
+	}
+	teams, ok := doc["Teams"]
+	if !ok {
+		s, err := NewSchedule(text)
+		if err != nil {
+			return nil, err
+		}
+		return &ScheduleSet{Teams: map[string]*Schedule{DefaultTeam: s}, single: true}, nil
+	}
+	byTeam, ok := teams.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("error parsing schedule set: Teams must be an object of schedules by team")
+	}
+	delete(doc, "Teams")
+	ss := &ScheduleSet{Teams: map[string]*Schedule{}, defaults: doc}
+	errs := TeamErrors{}
+	for team, t := range byTeam {
+		fields, ok := t.(map[string]interface{})
+		if !ok {
+			errs[team] = fmt.Errorf("error parsing schedule: not an object")
+			continue
+		}
+		for k, v := range doc {
+			if _, ok := fields[k]; !ok {
+				fields[k] = v
+			}
+		}
+		text, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewSchedule(text)
+		if err != nil {
+			errs[team] = err
+			continue
+		}
+		s.team = team
+		ss.Teams[team] = s
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return ss, nil
+}
+
+// Names returns the names of the teams in order.
+func (ss *ScheduleSet) Names() []string {
+	names := []string{}
+	for team := range ss.Teams {
+		names = append(names, team)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateAll generates every team's schedule, returning the generated set.
+// A team that cannot be generated keeps its schedule as it was, and its error
+// is returned in TeamErrors along with the set, so that one team's problem
+// does not hold up the rest.
+func (ss *ScheduleSet) GenerateAll() (*ScheduleSet, error) {
+	ns := &ScheduleSet{Teams: map[string]*Schedule{}, defaults: ss.defaults, single: ss.single}
+	errs := TeamErrors{}
+	for team, s := range ss.Teams {
+		generated, err := s.Generate()
+		if err != nil {
+			errs[team] = err
+			generated = s
+		}
+		ns.Teams[team] = generated
+	}
+	if len(errs) > 0 {
+		return ns, errs
+	}
+	return ns, nil
+}
+
+// MarshalJSON writes the set as it was read: a single schedule as one, and
+// otherwise the defaults and each team's schedule, leaving out the fields of a
+// team that match the defaults. Teams and fields are written in order, so
+// regenerating the set only changes the lines of the teams that changed.
+func (ss *ScheduleSet) MarshalJSON() ([]byte, error) {
+	if ss.single {
+		return json.Marshal(ss.Teams[DefaultTeam])
+	}
+	// Every team is written in the current format, so its version is shared
+	// too.
+	defaults := map[string]interface{}{"FormatVersion": float64(CurrentFormatVersion)}
+	for k, v := range ss.defaults {
+		if k != "FormatVersion" {
+			defaults[k] = v
+		}
+	}
+	teams := map[string]interface{}{}
+	for team, s := range ss.Teams {
+		text, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(text, &fields); err != nil {
+			return nil, err
+		}
+		for k, v := range defaults {
+			if reflect.DeepEqual(fields[k], v) {
+				delete(fields, k)
+			}
+		}
+		teams[team] = fields
+	}
+	doc := map[string]interface{}{"Teams": teams}
+	for k, v := range defaults {
+		doc[k] = v
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,127 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const setText = `{
+	"Timezone": "America/New_York",
+	"RotationLength": "1w",
+	"ScheduleFor": "3w",
+	"Start": "2017-02-01T10:00:00-05:00",
+	"Teams": {
+		"search": {"Users": ["d", "e"], "ScheduleFor": "2w"},
+		"payments": {"Users": ["a", "b", "c"]}
+	}
+}`
+
+func TestScheduleSetDefaults(t *testing.T) {
+	ss, err := NewScheduleSet([]byte(setText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := ss.Names(); strings.Join(names, " ") != "payments search" {
+		t.Errorf("expected the teams in order, got %v", names)
+	}
+	payments, search := ss.Teams["payments"], ss.Teams["search"]
+	if payments.Timezone != "America/New_York" || search.Timezone != "America/New_York" {
+		t.Errorf("expected both teams to share the default Timezone")
+	}
+	if payments.ScheduleForDuration != 3 * Week || search.ScheduleForDuration != 2 * Week {
+		t.Errorf("expected search to keep its own ScheduleFor, got %s and %s", payments.ScheduleForDuration, search.ScheduleForDuration)
+	}
+}
+
+func TestScheduleSetGenerateAll(t *testing.T) {
+	// The third team cannot be generated, with everyone past their end date.
+	text := strings.Replace(setText, `"payments":`, `"departed": {"Users": ["f", "g"], "UserEndDates": {"f": "2017-01-01T00:00:00Z", "g": "2017-01-01T00:00:00Z"}}, "payments":`, 1)
+	ss, err := NewScheduleSet([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range ss.Teams {
+		s.now = Start
+	}
+	ns, err := ss.GenerateAll()
+	errs := TeamErrors{}
+	if !errors.As(err, &errs) || len(errs) != 1 || errs["departed"] == nil {
+		t.Fatalf("expected an error for the departed team alone, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "team departed: ") {
+		t.Errorf("expected the error to name the team, got %q", err)
+	}
+	if ns.Teams["departed"] != ss.Teams["departed"] {
+		t.Errorf("expected the departed team to keep its schedule")
+	}
+	if primaries := primariesOf(ns.Teams["payments"].Rotations); primaries != "a b c a" {
+		t.Errorf("expected payments to be generated on its own, got %s", primaries)
+	}
+	if primaries := primariesOf(ns.Teams["search"].Rotations); primaries != "d e d" {
+		t.Errorf("expected search to be generated on its own, got %s", primaries)
+	}
+
+	// Written back, the teams keep their own fields and share the rest, in a
+	// stable order.
+	out, err := json.MarshalIndent(ns, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.MarshalIndent(ns, "", "  ")
+	if err != nil || string(out) != string(again) {
+		t.Errorf("expected the set to be written the same way every time")
+	}
+	doc := struct {
+		ScheduleFor string
+		Teams map[string]map[string]interface{}
+	}{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.ScheduleFor != "3w" || doc.Teams["search"]["ScheduleFor"] != "2w" {
+		t.Errorf("expected ScheduleFor to stay a default that search overrides, got:\n%s", out)
+	}
+	if _, ok := doc.Teams["payments"]["Timezone"]; ok {
+		t.Errorf("expected payments to share the default Timezone, got:\n%s", out)
+	}
+	reread, err := NewScheduleSet(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reread.Teams["payments"].Rotations) != 4 {
+		t.Errorf("expected the rotations to round-trip, got %d", len(reread.Teams["payments"].Rotations))
+	}
+}
+
+func TestScheduleSetWrapsSingleSchedule(t *testing.T) {
+	ss, err := NewScheduleSet([]byte(EmptyScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := ss.Names(); len(names) != 1 || names[0] != DefaultTeam {
+		t.Errorf("expected the single schedule as the %s team, got %v", DefaultTeam, names)
+	}
+	out, err := json.Marshal(ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "Teams") {
+		t.Errorf("expected a single schedule to be written back as one, got %s", out)
+	}
+	if _, err := NewSchedule(out); err != nil {
+		t.Errorf("expected the output to read as a schedule, got %s", err)
+	}
+}
+
+func TestScheduleSetParseErrors(t *testing.T) {
+	text := strings.Replace(setText, `"ScheduleFor": "2w"`, `"ScheduleFor": "soon"`, 1)
+	_, err := NewScheduleSet([]byte(text))
+	if err == nil || !strings.HasPrefix(err.Error(), "team search: ") {
+		t.Errorf("expected an error naming the team, got %v", err)
+	}
+	if _, err := NewScheduleSet([]byte(`{"Teams": []}`)); err == nil {
+		t.Errorf("expected an error for Teams that is not an object")
+	}
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"strings"
+	"time"
+)
+
+// A Shift is a rotation a user is assigned to, and the tier they fill in it.
+type Shift struct {
+	Rotation Rotation
+	Tier string
+	// When the shift ends: the rotation's RotationEnd.
+	End time.Time
+}
+
+// A MatchOption changes how ShiftsFor and NextShiftFor match user names.
+type MatchOption int
+
+const (
+	// Match user names case-insensitively, e.g. for rotations hand-edited to
+	// say "Alice" rather than "alice".
+	IgnoreCase MatchOption = iota + 1
+)
+
+// ShiftsFor returns the shifts of user that have not ended by the schedule's
+// clock, in order. A user in no rotation, including one not in Users, has no
+// shifts.
+func (s Schedule) ShiftsFor(user string, opts ...MatchOption) []Shift {
+	return s.shiftsFor(user, s.currentTime(), opts)
+}
+
+// NextShiftFor returns the first shift of user that has not ended by after,
+// which is the shift under way at after if there is one.
+func (s Schedule) NextShiftFor(user string, after time.Time, opts ...MatchOption) (Shift, bool) {
+	shifts := s.shiftsFor(user, after, opts)
+	if len(shifts) == 0 {
+		return Shift{}, false
+	}
+	return shifts[0], true
+}
+
+func (s Schedule) shiftsFor(user string, after time.Time, opts []MatchOption) []Shift {
+	match := func(u string) bool {
+		return u == user
+	}
+	for _, o := range opts {
+		if o == IgnoreCase {
+			match = func(u string) bool {
+				return strings.EqualFold(u, user)
+			}
+		}
+	}
+	shifts := []Shift{}
+	for i, r := range s.Rotations {
+		end := s.RotationEnd(i)
+		if !end.After(after) {
+			continue
+		}
+		for j, u := range s.Assigned(r) {
+			if u != "" && match(u) {
+				shifts = append(shifts, Shift{Rotation: r, Tier: s.tiers()[j], End: end})
+			}
+		}
+	}
+	return shifts
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShiftsFor(t *testing.T) {
+	s := FilledSchedule()
+	s.SetNow(time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC))
+	before := FilledSchedule()
+	before.SetNow(s.now)
+
+	// From the third rotation on, b is only ever secondary.
+	shifts := s.ShiftsFor("b")
+	if len(shifts) != 1 {
+		t.Fatalf("expected 1 shift, got %v", shifts)
+	}
+	if shifts[0].Tier != TierSecondary || !shifts[0].Rotation.Start.Equal(s.Rotations[3].Start) {
+		t.Errorf("expected b to be secondary in the last rotation, got %+v", shifts[0])
+	}
+	if !shifts[0].End.Equal(s.RotationEnd(3)) {
+		t.Errorf("expected the shift to end at %s, got %s", s.RotationEnd(3), shifts[0].End)
+	}
+
+	// The rotation under way counts.
+	shifts = s.ShiftsFor("c")
+	if len(shifts) != 1 || shifts[0].Tier != TierPrimary || shifts[0].Rotation.Primary != "c" {
+		t.Errorf("expected c's current primary shift, got %+v", shifts)
+	}
+
+	if shifts := s.ShiftsFor("nobody"); len(shifts) != 0 {
+		t.Errorf("expected no shifts for a user not in the schedule, got %v", shifts)
+	}
+	if !reflect.DeepEqual(before, s) {
+		t.Errorf("expected ShiftsFor to leave the schedule alone")
+	}
+}
+
+func TestShiftsForIgnoreCase(t *testing.T) {
+	s := FilledSchedule()
+	s.SetNow(Start)
+	s.Rotations[3].Primary = "A"
+	if shifts := s.ShiftsFor("a"); len(shifts) != 2 {
+		t.Errorf("expected an exact match to miss the hand-edited A, got %v", shifts)
+	}
+	if shifts := s.ShiftsFor("a", IgnoreCase); len(shifts) != 3 {
+		t.Errorf("expected 3 shifts ignoring case, got %v", shifts)
+	}
+}
+
+func TestNextShiftFor(t *testing.T) {
+	s := FilledSchedule()
+	cases := []struct {
+		user string
+		after time.Time
+		ok bool
+		start time.Time
+		tier string
+	}{
+		{"c", Start, true, time.Date(2017, time.February, 8, 10, 0, 0, 0, time.UTC), TierSecondary},
+		{"c", time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC), true, time.Date(2017, time.February, 15, 10, 0, 0, 0, time.UTC), TierPrimary},
+		{"c", time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC), false, time.Time{}, ""},
+		{"nobody", Start, false, time.Time{}, ""},
+	}
+	for _, c := range cases {
+		shift, ok := s.NextShiftFor(c.user, c.after)
+		if ok != c.ok || !shift.Rotation.Start.Equal(c.start) || shift.Tier != c.tier {
+			t.Errorf("%s after %s: expected %v %s %s, got %v %+v", c.user, c.after, c.ok, c.start, c.tier, ok, shift)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// The orders Generate can take Users in.
+const (
+	// Users in the order written, cycling round. This is the default.
+	OrderRoundRobin = "round-robin"
+	// Users permuted afresh at the start of each cycle through them, so that
+	// who follows whom varies.
+	OrderShuffled = "shuffled"
+)
+
+func (s Schedule) validateOrder() error {
+	switch s.Order {
+	case "", OrderRoundRobin:
+		return nil
+	case OrderShuffled:
+	default:
+		return fmt.Errorf("unknown Order %q: must be %q or %q", s.Order, OrderRoundRobin, OrderShuffled)
+	}
+	switch {
+	case len(s.Pattern) > 0:
+		return fmt.Errorf("Order %q cannot be used with Pattern, which gives the primaries' order", s.Order)
+	case s.separatePools():
+		return fmt.Errorf("Order %q cannot be used with PrimaryUsers or SecondaryUsers", s.Order)
+	case len(s.Windows) > 0 || len(s.Regions) > 0:
+		return fmt.Errorf("Order %q cannot be used with Windows or Regions, which keep their own orders", s.Order)
+	case s.Fairness == FairnessBalanced:
+		return fmt.Errorf("Order %q cannot be used with balanced Fairness, which picks primaries by load rather than in order", s.Order)
+	}
+	return nil
+}
+
+// Return the order of the cycle through Users the rotations of s left off in,
+// and the position in it to continue from, moved on by skipped places. The
+// first time s is shuffled, which is before it has a Seed, or when it is
+// generated afresh, the cycle under way starts with whoever is next.
+func (ns *Schedule) shuffledOrder(s *Schedule, fresh bool, skipped int) ([]string, int) {
+	order := append([]string{}, s.Users...)
+	next := 0
+	if s.Seed == 0 && !fresh {
+		order = ns.continueOrder(s.Users, ns.turns(s.unapplyOverrides(s.Rotations)), "Users")
+	} else if last := lastPrimary(ns.turns(s.unapplyOverrides(s.Rotations))); last != "" && !fresh {
+		if i := indexOf(order, last); i >= 0 {
+			next = i + 1
+		} else {
+			ns.warnings = append(ns.warnings, fmt.Sprintf("%s, the primary of the last rotation before those generated, is no longer among the users to schedule, so the new rotations start the cycle over", last))
+		}
+	}
+	next += skipped
+	for next > len(order) {
+		next -= len(order)
+		order = ns.nextCycle(order)
+	}
+	return order, next
+}
+
+// Return the order of the next cycle through users: a permutation by a PRNG
+// seeded from Seed and the cycle's index, so that every run permutes alike.
+// Whoever was primary last is never first, so that nobody has two shifts
+// running across the start of the cycle.
+func (ns *Schedule) nextCycle(users []string) []string {
+	ns.Cycle++
+	order := append([]string{}, users...)
+	// Sorted first, so that the permutation only depends on who is in the
+	// cycle, not on how the last one was reordered.
+	sort.Strings(order)
+	r := rand.New(rand.NewSource(ns.Seed + int64(ns.Cycle)))
+	r.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	if len(order) > 1 && order[0] == lastPrimary(ns.Rotations) {
+		order[0], order[1] = order[1], order[0]
+	}
+	return order
+}
+
+// Return a new Seed for shuffling from now: any will do, so long as it is
+// written down for later runs to agree on.
+func newSeed(now time.Time) int64 {
+	if seed := now.UnixNano(); seed != 0 {
+		return seed
+	}
+	return 1
+}
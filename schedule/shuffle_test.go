@@ -0,0 +1,146 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Return a schedule of five users shuffled with seed.
+func shuffledSchedule(seed int64) *Schedule {
+	s := EmptySchedule()
+	s.now = Start
+	s.Users = []string{"a", "b", "c", "d", "e"}
+	s.Order, s.Seed = OrderShuffled, seed
+	return s
+}
+
+func TestShuffledOrder(t *testing.T) {
+	s := shuffledSchedule(42)
+	s.ScheduleFor, s.ScheduleForDuration = "20w", 20 * Week
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycles := []string{}
+	for i := 0; i + 5 <= len(ns.Rotations); i += 5 {
+		cycle := []string{}
+		for _, r := range ns.Rotations[i : i+5] {
+			cycle = append(cycle, r.Primary)
+		}
+		sorted := append([]string{}, cycle...)
+		sort.Strings(sorted)
+		if !reflect.DeepEqual(sorted, s.Users) {
+			t.Errorf("expected each cycle to take every user once, got %v", cycle)
+		}
+		cycles = append(cycles, strings.Join(cycle, ""))
+	}
+	if cycles[0] != "abcde" {
+		t.Errorf("expected the first cycle to keep the order of Users, got %s", cycles[0])
+	}
+	if cycles[1] == cycles[2] && cycles[2] == cycles[3] {
+		t.Errorf("expected the cycles to vary, got %v", cycles)
+	}
+	for i := 1; i < len(ns.Rotations); i++ {
+		if ns.Rotations[i].Primary == ns.Rotations[i-1].Primary {
+			t.Errorf("expected nobody to be primary twice running, got %v", ns.Rotations[i-1:i+1])
+		}
+	}
+	if ns.Cycle != 4 || ns.Seed != 42 {
+		t.Errorf("expected 4 cycles permuted with the seed kept, got %d and %d", ns.Cycle, ns.Seed)
+	}
+
+	// The same seed permutes alike, and another differently.
+	for seed, same := range map[int64]bool{42: true, 43: false} {
+		other := shuffledSchedule(seed)
+		other.ScheduleFor, other.ScheduleForDuration = s.ScheduleFor, s.ScheduleForDuration
+		generated, err := other.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(generated.Rotations, ns.Rotations) != same {
+			t.Errorf("expected seed %d to permute alike as seed 42: %v", seed, same)
+		}
+	}
+}
+
+func TestShuffledOrderRegenerates(t *testing.T) {
+	// Generating a week at a time carries each cycle on, and starts the next,
+	// just as generating all at once does.
+	whole := shuffledSchedule(7)
+	whole.ScheduleFor, whole.ScheduleForDuration = "12w", 12 * Week
+	all, err := whole.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := shuffledSchedule(7)
+	s.ScheduleFor, s.ScheduleForDuration = "2w", 2 * Week
+	primaries := map[time.Time]string{}
+	for now := 1; now <= 71; now += 7 {
+		s.now = day(now)
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range ns.Rotations {
+			primaries[r.Start] = r.Primary
+		}
+		text, err := json.Marshal(ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, err = NewSchedule(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range all.Rotations {
+		if p, ok := primaries[r.Start]; ok && p != r.Primary {
+			t.Errorf("expected the rotation starting at %s to have primary %s, as when generated at once, got %s", r.Start.Format(time.RFC3339), r.Primary, p)
+		}
+	}
+}
+
+func TestShuffledOrderWritesSeed(t *testing.T) {
+	s := shuffledSchedule(0)
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.Seed != Start.UnixNano() {
+		t.Errorf("expected a seed to be written on first use, got %d", ns.Seed)
+	}
+	ns.now = day(40)
+	again, err := ns.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Seed != ns.Seed {
+		t.Errorf("expected the seed to be kept, got %d", again.Seed)
+	}
+
+	// The default order is left as it always was.
+	plain := EmptySchedule()
+	plain.now = Start
+	if ns, err = plain.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if ns.Seed != 0 || ns.Cycle != 0 {
+		t.Errorf("expected no seed or cycles without OrderShuffled, got %d and %d", ns.Seed, ns.Cycle)
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	s := shuffledSchedule(1)
+	s.Order = "random"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), `unknown Order "random"`) {
+		t.Errorf("expected an error for an unknown Order, got %v", err)
+	}
+	s = shuffledSchedule(1)
+	s.Pattern = []string{"a", "b"}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "cannot be used with Pattern") {
+		t.Errorf("expected an error for a shuffled Pattern, got %v", err)
+	}
+}
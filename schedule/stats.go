@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Stats is how the on-call load of a schedule was shared out over a span of
+// time.
+type Stats struct {
+	// The span of time covered.
+	Window TimeRange
+	// Each user's load, ordered by user. Every user in the schedule is listed,
+	// even with no load.
+	Users []UserStats
+	// For each landmark, how many times each user has been primary over it, as
+	// in LandmarkCounts.
+	LandmarkCounts map[string]map[string]int `json:",omitempty"`
+
+	// The landmark table, rendered by the schedule it came from.
+	landmarks string
+	// The DisplayTimezone of the schedule it came from, which String renders
+	// the window in.
+	location *time.Location
+}
+
+// UserStats is one user's load within a Stats window.
+type UserStats struct {
+	User string
+	// The rotations overlapping the window the user was primary or secondary
+	// of, as generated. Like ShiftCounts, these count a rotation split by an
+	// override once, for whoever it was generated for.
+	Primary int
+	Secondary int
+	// The hours within the window the user was actually on call as primary or
+	// secondary, after overrides.
+	PrimaryHours float64
+	SecondaryHours float64
+	// The hours within the window the user was on call in any tier, and those
+	// hours weighted by BurdenWindows.
+	Hours float64
+	WeightedHours float64
+}
+
+// Stats returns each user's load within window: from its first time, if
+// given, to its second, if given. It defaults to everything the schedule
+// knows about, which is History and Rotations. Rotations count for the time
+// they actually cover, from their Start to their End or the next rotation's
+// Start, however irregular that is.
+func (s *Schedule) Stats(window ...time.Time) Stats {
+	type span struct {
+		r Rotation
+		end time.Time
+	}
+	spans := []span{}
+	for _, r := range s.History {
+		spans = append(spans, span{r, r.End})
+	}
+	for i, r := range s.Rotations {
+		spans = append(spans, span{r, s.RotationEnd(i)})
+	}
+
+	w := TimeRange{}
+	if len(spans) > 0 {
+		w = TimeRange{Start: spans[0].r.Start, End: spans[len(spans)-1].end}
+	}
+	if len(window) > 0 {
+		w.Start = window[0]
+	}
+	if len(window) > 1 {
+		w.End = window[1]
+	}
+
+	byUser := map[string]*UserStats{}
+	get := func(u string) *UserStats {
+		if byUser[u] == nil {
+			byUser[u] = &UserStats{User: u}
+		}
+		return byUser[u]
+	}
+	for _, u := range s.allUsers() {
+		get(u)
+	}
+	for i, sp := range spans {
+		start, end := sp.r.Start, sp.end
+		if start.Before(w.Start) {
+			start = w.Start
+		}
+		if end.After(w.End) {
+			end = w.End
+		}
+		if !end.After(start) {
+			continue
+		}
+		hours := end.Sub(start).Hours()
+		weighted := s.Burden(start, end)
+		for j, u := range s.Assigned(sp.r) {
+			if u == "" || u == Unassigned {
+				continue
+			}
+			st := get(u)
+			st.Hours += hours
+			st.WeightedHours += weighted
+			switch j {
+			case 0:
+				st.PrimaryHours += hours
+			case 1:
+				st.SecondaryHours += hours
+			}
+		}
+
+		// A rotation is counted at its first segment overlapping the window,
+		// for the users it was generated for.
+		if sp.r.Continues && i > 0 && spans[i-1].end.After(w.Start) {
+			continue
+		}
+		base := sp.r
+		for tier, u := range base.Overridden {
+			base = s.withTierUser(base, tier, u)
+		}
+		for j, u := range s.Assigned(base) {
+			if u == "" || u == Unassigned || j > 1 {
+				continue
+			}
+			if j == 0 {
+				get(u).Primary++
+			} else {
+				get(u).Secondary++
+			}
+		}
+	}
+
+	st := Stats{Window: w, Users: []UserStats{}, location: s.displayLocation}
+	for _, u := range byUser {
+		st.Users = append(st.Users, *u)
+	}
+	sort.Slice(st.Users, func(i, j int) bool {
+		return st.Users[i].User < st.Users[j].User
+	})
+	if len(s.LandmarkShifts) > 0 {
+		st.LandmarkCounts = copyLandmarkCounts(s.LandmarkCounts)
+		st.landmarks = s.LandmarkReport()
+	}
+	return st
+}
+
+// String renders st as a table of users, followed by the table of landmarks
+// from LandmarkReport if the schedule has any.
+func (st Stats) String() string {
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "From %s to %s:\n", formatTime(st.Window.Start, st.location, time.RFC3339), formatTime(st.Window.End, st.location, time.RFC3339))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "USER\tPRIMARY\tSECONDARY\tPRIMARY HOURS\tSECONDARY HOURS\tHOURS\tWEIGHTED HOURS\n")
+	for _, u := range st.Users {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%g\t%g\t%g\t%g\n", u.User, u.Primary, u.Secondary, u.PrimaryHours, u.SecondaryHours, u.Hours, u.WeightedHours)
+	}
+	w.Flush()
+	if st.landmarks != "" {
+		fmt.Fprintf(out, "\n%s", st.landmarks)
+	}
+	return out.String()
+}
+
+// WriteTo writes st to w as rendered by String.
+func (st Stats) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, st.String())
+	return int64(n), err
+}
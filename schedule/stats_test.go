@@ -0,0 +1,120 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func statsFor(st Stats, u string) UserStats {
+	for _, us := range st.Users {
+		if us.User == u {
+			return us
+		}
+	}
+	return UserStats{User: u}
+}
+
+func TestStatsAcrossOverride(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Overrides = []Override{{Start: day(10), End: day(12), Role: TierSecondary, User: "a"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := ns.Stats()
+	if st.Window.Start != day(1) || st.Window.End != day(29) {
+		t.Errorf("expected the window to default to the whole schedule, got %v", st.Window)
+	}
+	// The override moves 48 hours of c's secondary to a, but the rotation is
+	// still c's shift.
+	expected := []UserStats{
+		{User: "a", Primary: 2, Secondary: 1, PrimaryHours: 336, SecondaryHours: 216, Hours: 552, WeightedHours: 552},
+		{User: "b", Primary: 1, Secondary: 2, PrimaryHours: 168, SecondaryHours: 336, Hours: 504, WeightedHours: 504},
+		{User: "c", Primary: 1, Secondary: 1, PrimaryHours: 168, SecondaryHours: 120, Hours: 288, WeightedHours: 288},
+	}
+	if !reflect.DeepEqual(expected, st.Users) {
+		t.Errorf("stats do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, st.Users)
+	}
+
+	// Within the override, only the segments overlapping the window count.
+	st = ns.Stats(day(9), day(11))
+	if b := statsFor(st, "b"); b.Primary != 1 || b.PrimaryHours != 48 {
+		t.Errorf("expected b's 48 primary hours in the window, got %+v", b)
+	}
+	if c := statsFor(st, "c"); c.Secondary != 1 || c.SecondaryHours != 24 {
+		t.Errorf("expected c's shift to be counted with 24 hours, got %+v", c)
+	}
+	if a := statsFor(st, "a"); a.Secondary != 0 || a.SecondaryHours != 24 {
+		t.Errorf("expected a's 24 override hours but no shift, got %+v", a)
+	}
+}
+
+func TestStatsHandEditedLengths(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hand-edit the third rotation to start two days late, dropping the End of
+	// the second, and the last to run a day longer.
+	ns.Rotations[1].End = time.Time{}
+	ns.Rotations[2].Start = day(17)
+	ns.Rotations[len(ns.Rotations) - 1].End = day(30)
+	st := ns.Stats()
+	if b := statsFor(st, "b"); b.PrimaryHours != 9*24 {
+		t.Errorf("expected b's rotation to run until the next starts, got %+v", b)
+	}
+	if c := statsFor(st, "c"); c.PrimaryHours != 5*24 {
+		t.Errorf("expected c's rotation to be shortened, got %+v", c)
+	}
+	if a := statsFor(st, "a"); a.PrimaryHours != 15*24 {
+		t.Errorf("expected a's last rotation to run until its End, got %+v", a)
+	}
+}
+
+func TestStatsIncludesHistory(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.KeepHistory = true
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = regenerateWeekly(t, s, 2)
+	if len(s.History) == 0 {
+		t.Fatal("expected rotations to have been archived")
+	}
+	st := s.Stats()
+	if st.Window.Start != s.History[0].Start {
+		t.Errorf("expected the window to start with History, got %v", st.Window)
+	}
+	total := 0
+	for _, u := range st.Users {
+		total += u.Primary
+	}
+	if n := len(s.History) + len(s.Rotations); total != n {
+		t.Errorf("expected %d primary shifts, got %d", n, total)
+	}
+}
+
+func TestStatsString(t *testing.T) {
+	s := landmarkSchedule()
+	s.LandmarkCounts = map[string]map[string]int{"nye": {"a": 2}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := ns.Stats().String()
+	for _, expected := range []string{"USER  PRIMARY  SECONDARY", "\na     ", ns.LandmarkReport()} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected stats to contain %q, got:\n%s", expected, text)
+		}
+	}
+	if text := EmptySchedule().Stats().String(); strings.Contains(text, "nye") {
+		t.Errorf("expected no landmark table without LandmarkShifts, got:\n%s", text)
+	}
+}
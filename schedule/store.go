@@ -0,0 +1,242 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// A Store holds a schedule for a long-running service, which answers queries
+// about it from many goroutines while regenerating it, e.g. on a timer.
+//
+// A schedule in a Store is never modified: Generate, like every other method
+// that changes a schedule, returns a new one and leaves its receiver as it
+// was, and Replace swaps in the schedule it is given whole. Queries through
+// the Store therefore only hold its lock long enough to find the schedule,
+// and return copies, so that nothing a caller does to a result reaches the
+// schedule other goroutines are reading.
+type Store struct {
+	mu sync.RWMutex
+	s *Schedule
+	// Serializes Generate, so that each regeneration works from the schedule
+	// the last one stored.
+	generating sync.Mutex
+	// The start of the rotation under way when Generate last ran, guarded by
+	// generating.
+	underWay time.Time
+
+	// Guards subscribers, and serializes sending them events.
+	subscribersMu sync.Mutex
+	subscribers map[*subscriber]bool
+}
+
+// ErrEmptyStore is returned by the queries of a Store no schedule has been
+// stored in.
+var ErrEmptyStore = errors.New("no schedule has been stored")
+
+// NewStore returns a Store holding s, which may be nil to store one later.
+// The caller must not modify s once it is stored.
+func NewStore(s *Schedule) *Store {
+	return &Store{s: s}
+}
+
+// Load returns the schedule stored, or nil if there is none. It is shared
+// with every other caller, so must not be modified; call Generate, or modify
+// a copy and Replace it.
+func (st *Store) Load() *Schedule {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.s
+}
+
+// Replace stores s in place of the schedule stored. Queries under way finish
+// against the schedule they started with. The caller must not modify s once it
+// is stored.
+//
+// If s adds overrides to the schedule it replaces, subscribers are sent an
+// EventOverrideAdded, or else if it changes the rotations, an
+// EventRotationReassigned.
+func (st *Store) Replace(s *Schedule) {
+	old := st.swap(s)
+	if old == nil {
+		return
+	}
+	d := NewDiff(old, s)
+	switch {
+	case len(addedOverrides(old.Overrides, s.Overrides)) > 0:
+		st.publish(ChangeEvent{Kind: EventOverrideAdded, Schedule: s, Diff: d})
+	case !d.Empty():
+		st.publish(ChangeEvent{Kind: EventRotationReassigned, Schedule: s, Diff: d})
+	}
+}
+
+// Store s, returning the schedule it replaced.
+func (st *Store) swap(s *Schedule) *Schedule {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	old := st.s
+	st.s = s
+	return old
+}
+
+// Generate regenerates the schedule stored, as GenerateWithDiff does, and
+// stores the result, sending subscribers an EventRegenerated, and an
+// EventHandoffOccurred if another rotation has come under way since it last
+// ran. Queries carry on against the schedule stored until the
+// new one replaces it. Concurrent calls are serialized, so none of them
+// regenerates from a schedule another has replaced.
+func (st *Store) Generate() (*Schedule, Diff, error) {
+	st.generating.Lock()
+	defer st.generating.Unlock()
+	s := st.Load()
+	if s == nil {
+		return nil, Diff{}, ErrEmptyStore
+	}
+	ns, d, err := s.GenerateWithDiff()
+	if err != nil {
+		return nil, Diff{}, err
+	}
+	st.swap(ns)
+	events := []ChangeEvent{{Kind: EventRegenerated, Schedule: ns, Diff: d}}
+	if st.handedOff(ns) {
+		events = append(events, ChangeEvent{Kind: EventHandoffOccurred, Schedule: ns, Diff: d})
+	}
+	st.publish(events...)
+	return ns, d, nil
+}
+
+// At returns a copy of the rotation covering t in the schedule stored, as
+// Schedule.At does.
+func (st *Store) At(t time.Time, opts ...AtOption) (Rotation, error) {
+	s := st.Load()
+	if s == nil {
+		return Rotation{}, ErrEmptyStore
+	}
+	r, err := s.At(t, opts...)
+	return r.clone(), err
+}
+
+// ShiftsFor returns copies of the shifts of user in the schedule stored, as
+// Schedule.ShiftsFor does.
+func (st *Store) ShiftsFor(user string, opts ...MatchOption) ([]Shift, error) {
+	s := st.Load()
+	if s == nil {
+		return nil, ErrEmptyStore
+	}
+	shifts := s.ShiftsFor(user, opts...)
+	for i := range shifts {
+		shifts[i].Rotation = shifts[i].Rotation.clone()
+	}
+	return shifts, nil
+}
+
+type ChangeEventKind string
+
+const (
+	// Generate regenerated the schedule stored.
+	EventRegenerated ChangeEventKind = "regenerated"
+	// A schedule with overrides the one it replaced did not have was stored.
+	EventOverrideAdded ChangeEventKind = "override_added"
+	// A schedule assigning rotations differently from the one it replaced was
+	// stored, e.g. after a swap.
+	EventRotationReassigned ChangeEventKind = "rotation_reassigned"
+	// Generate found a rotation under way that started after the one under
+	// way when it last ran.
+	EventHandoffOccurred ChangeEventKind = "handoff_occurred"
+)
+
+// A ChangeEvent is sent to the subscribers of a Store when the schedule stored
+// changes.
+type ChangeEvent struct {
+	Kind ChangeEventKind
+	// The schedule stored, which must not be modified.
+	Schedule *Schedule
+	// The Diff from the schedule it replaced.
+	Diff Diff
+	// How many events the subscriber had missed by falling behind, since it
+	// subscribed, when this one was sent.
+	Dropped int
+}
+
+// How many events a subscriber may fall behind before the oldest are dropped.
+const subscriberBuffer = 16
+
+type subscriber struct {
+	events chan ChangeEvent
+	dropped int
+}
+
+// Subscribe returns a channel of the events of changes to the schedule stored
+// from now on, which is closed once ctx is done. Events are sent without
+// waiting for the subscriber, so that a slow one cannot hold up regeneration:
+// once it has fallen behind by a few events, the oldest it has not received
+// are dropped to make room, and counted in the Dropped of those sent after.
+func (st *Store) Subscribe(ctx context.Context) <-chan ChangeEvent {
+	sub := &subscriber{events: make(chan ChangeEvent, subscriberBuffer)}
+	st.subscribersMu.Lock()
+	if st.subscribers == nil {
+		st.subscribers = map[*subscriber]bool{}
+	}
+	st.subscribers[sub] = true
+	st.subscribersMu.Unlock()
+	go func() {
+		<-ctx.Done()
+		st.subscribersMu.Lock()
+		defer st.subscribersMu.Unlock()
+		delete(st.subscribers, sub)
+		close(sub.events)
+	}()
+	return sub.events
+}
+
+// Send events to every subscriber, dropping the oldest of those a subscriber
+// has not received to make room if need be.
+func (st *Store) publish(events ...ChangeEvent) {
+	st.subscribersMu.Lock()
+	defer st.subscribersMu.Unlock()
+	for sub := range st.subscribers {
+		for _, e := range events {
+			for sent := false; !sent; {
+				e.Dropped = sub.dropped
+				select {
+				case sub.events <- e:
+					sent = true
+				default:
+					select {
+					case <-sub.events:
+						sub.dropped++
+					default:
+					}
+				}
+			}
+		}
+	}
+}
+
+// Whether the rotation under way in ns, by its clock, started after the one
+// under way when the Store last generated a schedule.
+func (st *Store) handedOff(ns *Schedule) bool {
+	r, err := ns.At(ns.currentTime())
+	if err != nil {
+		return false
+	}
+	handedOff := !st.underWay.IsZero() && r.Start.After(st.underWay)
+	st.underWay = r.Start
+	return handedOff
+}
+
+// Return the overrides in head that are not in base.
+func addedOverrides(base, head []Override) []Override {
+	added := []Override{}
+	for _, o := range head {
+		found := false
+		for _, b := range base {
+			found = found || (o.String() == b.String() && o.Force == b.Force)
+		}
+		if !found {
+			added = append(added, o)
+		}
+	}
+	return added
+}
@@ -0,0 +1,207 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: queries hammer the store while it is regenerated.
+func TestStoreConcurrentQueries(t *testing.T) {
+	s := EmptySchedule()
+	s.Overrides = []Override{{Start: day(3), End: day(4), Role: TierPrimary, User: "c"}}
+	s.now = Start
+	st := NewStore(s)
+	if _, _, err := st.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				r, err := st.At(day(3).Add(time.Hour))
+				if err != nil || r.Primary != "c" {
+					t.Errorf("expected the override to cover day 3, got %v, %v", r, err)
+					return
+				}
+				if _, err := st.ShiftsFor("b"); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := st.Load().Marshal(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		if _, _, err := st.Generate(); err != nil {
+			t.Error(err)
+			break
+		}
+		// Replacing with a schedule generated outside the store is as safe.
+		ns, err := st.Load().Generate()
+		if err != nil {
+			t.Error(err)
+			break
+		}
+		st.Replace(ns)
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestStoreReturnsCopies(t *testing.T) {
+	s := EmptySchedule()
+	s.Roles = []string{TierPrimary, TierSecondary, "incident"}
+	s.Users = []string{"a", "b", "c", "d"}
+	s.now = Start
+	st := NewStore(s)
+	if _, _, err := st.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := st.At(Start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incident := r.Assignments["incident"]
+	r.Assignments["incident"] = "z"
+	if got := st.Load().Rotations[0].Assignments["incident"]; got != incident {
+		t.Errorf("expected the stored rotation to be left as it was, got %s", got)
+	}
+}
+
+func TestStoreEmpty(t *testing.T) {
+	st := NewStore(nil)
+	if _, err := st.At(Start); !errors.Is(err, ErrEmptyStore) {
+		t.Errorf("expected ErrEmptyStore, got %v", err)
+	}
+	if _, _, err := st.Generate(); !errors.Is(err, ErrEmptyStore) {
+		t.Errorf("expected ErrEmptyStore, got %v", err)
+	}
+}
+
+func TestStoreSubscribe(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	st := NewStore(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := st.Subscribe(ctx)
+
+	ns, _, err := st.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overridden := *ns
+	overridden.Overrides = []Override{{Start: day(3), End: day(4), Role: TierPrimary, User: "c"}}
+	st.Replace(&overridden)
+	swapped, err := st.Load().Swap("a", "c", day(15), day(29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Replace(swapped)
+	// Replacing the schedule with the same rotations is no change.
+	st.Replace(swapped)
+	later := *swapped
+	later.now = day(9)
+	st.Replace(&later)
+	if _, _, err := st.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ChangeEventKind{EventRegenerated, EventOverrideAdded, EventRotationReassigned, EventRegenerated, EventHandoffOccurred}
+	for i, kind := range expected {
+		e := nextEvent(t, events)
+		if e.Kind != kind || e.Dropped != 0 {
+			t.Errorf("event %d: expected %s, got %s with %d dropped", i, kind, e.Kind, e.Dropped)
+		}
+		if e.Kind == EventHandoffOccurred && e.Schedule != st.Load() {
+			t.Errorf("expected the handoff to carry the schedule stored")
+		}
+	}
+	select {
+	case e := <-events:
+		t.Errorf("expected no more events, got %s", e.Kind)
+	default:
+	}
+}
+
+func nextEvent(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("expected another event")
+	}
+	return ChangeEvent{}
+}
+
+func TestStoreSubscribeSlowConsumer(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	st := NewStore(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := st.Subscribe(ctx)
+
+	// Regeneration carries on while the subscriber reads nothing.
+	generated := []*Schedule{}
+	for i := 0; i < subscriberBuffer + 5; i++ {
+		ns, _, err := st.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated = append(generated, ns)
+	}
+	// The oldest events were dropped, and the rest are delivered in order,
+	// the last counting every event dropped.
+	var e ChangeEvent
+	for i := 5; i < len(generated); i++ {
+		e = nextEvent(t, events)
+		if e.Schedule != generated[i] {
+			t.Fatalf("expected the event of generation %d", i)
+		}
+	}
+	if e.Dropped != 5 {
+		t.Errorf("expected 5 events to have been dropped, got %d", e.Dropped)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("expected no more events, got %s", e.Kind)
+	default:
+	}
+}
+
+func TestStoreSubscribeCancel(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	st := NewStore(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := st.Subscribe(ctx)
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("expected no events after the context was canceled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the channel to be closed once the context was canceled")
+	}
+	// Changes after unsubscribing are sent to nobody.
+	if _, _, err := st.Generate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,189 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Problem is something ValidateStrict finds wrong with a schedule.
+type Problem struct {
+	// The field the problem is in, e.g. "Users" or "Rotations[3]".
+	Field string
+	Message string
+	// Whether the problem is only worth a warning, and does not make the
+	// schedule invalid.
+	Warning bool
+
+	// The error behind the problem, if it came from Validate.
+	err error
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return p.Message
+	}
+	return p.Field + ": " + p.Message
+}
+
+// ValidationErrors are the problems that make a schedule invalid, in the order
+// they were found.
+type ValidationErrors []Problem
+
+func (e ValidationErrors) Error() string {
+	problems := []string{}
+	for _, p := range e {
+		problems = append(problems, p.String())
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Is reports whether the error behind any of the problems is target, so that
+// errors.Is finds e.g. ErrNoUsers among them.
+func (e ValidationErrors) Is(target error) bool {
+	for _, p := range e {
+		if p.err != nil && errors.Is(p.err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error behind the problems that matches target, as
+// errors.As does.
+func (e ValidationErrors) As(target interface{}) bool {
+	for _, p := range e {
+		if p.err != nil && errors.As(p.err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// A ParseOption changes how NewSchedule reads a schedule.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	strict bool
+	disallowUnknown bool
+	now time.Time
+}
+
+// Strict makes NewSchedule check the schedule with ValidateStrict rather than
+// Validate, so that every problem is reported at once, and keep its warnings
+// for Lint to report, along with any fields of the document it does not know.
+func Strict() ParseOption {
+	return func(o *parseOptions) {
+		o.strict = true
+	}
+}
+
+// DisallowUnknownFields makes NewSchedule reject a document with a field it
+// does not know, e.g. a misspelt one, rather than ignore it. Since documents
+// from newer versions of oncallator may have fields this one does not know,
+// it is not the default; Strict reports unknown fields as warnings instead.
+func DisallowUnknownFields() ParseOption {
+	return func(o *parseOptions) {
+		o.disallowUnknown = true
+	}
+}
+
+// WithNow pins the clock of the schedule NewSchedule reads to now, as SetNow
+// does, so that it is validated and generated as of then.
+func WithNow(now time.Time) ParseOption {
+	return func(o *parseOptions) {
+		o.now = now
+	}
+}
+
+// ValidateStrict checks s for mistakes Validate lets through, which otherwise
+// surface later or quietly produce the wrong schedule:
+//
+//	ScheduleFor is positive, and no shorter than RotationLength
+//	Start is set, if there are no Rotations to continue from
+//	Users, PrimaryUsers and SecondaryUsers list nobody twice, and no empty names
+//	Rotations are in order of Start, with no two starting together
+//	no rotation yet to end has one user in two tiers
+//	no rotation yet to end assigns anyone missing from the users to schedule
+//
+// The last is only a warning, since people who have left the rotation still
+// turn up in the rotations they were assigned before leaving. Rather than
+// stopping at the first problem, ValidateStrict returns every warning, and
+// every other problem as ValidationErrors. Validate's error is included when
+// nothing else is found, since it otherwise usually repeats one of them.
+func (s *Schedule) ValidateStrict() ([]Problem, error) {
+	problems := []Problem{}
+	add := func(field string, warning bool, format string, args ...interface{}) {
+		problems = append(problems, Problem{Field: field, Message: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	if s.ScheduleForDuration <= 0 {
+		add("ScheduleFor", false, "must be positive (got %s)", s.ScheduleForDuration)
+	} else if s.ScheduleForDuration < s.RotationDuration {
+		add("ScheduleFor", false, "must be at least RotationLength %s (got %s)", s.RotationDuration, s.ScheduleForDuration)
+	}
+	if s.Start.IsZero() && len(s.Rotations) == 0 {
+		add("Start", false, "must be set when there are no Rotations")
+	}
+	for _, list := range []struct {
+		name string
+		users []string
+	}{{"Users", s.Users}, {"PrimaryUsers", s.PrimaryUsers}, {"SecondaryUsers", s.SecondaryUsers}} {
+		seen := map[string]bool{}
+		for i, u := range list.users {
+			if u == "" {
+				add(fmt.Sprintf("%s[%d]", list.name, i), false, "is empty")
+			} else if seen[u] {
+				add(fmt.Sprintf("%s[%d]", list.name, i), false, "lists %s more than once", u)
+			}
+			seen[u] = true
+		}
+	}
+	for i := 1; i < len(s.Rotations); i++ {
+		prev, r := s.Rotations[i-1], s.Rotations[i]
+		if r.Start.Equal(prev.Start) {
+			add(fmt.Sprintf("Rotations[%d]", i), false, "starts at %s, as Rotations[%d] does", r.Start.Format(time.RFC3339), i - 1)
+		} else if r.Start.Before(prev.Start) {
+			add(fmt.Sprintf("Rotations[%d]", i), false, "starts at %s, before Rotations[%d]; rotations must be in chronological order", r.Start.Format(time.RFC3339), i - 1)
+		}
+	}
+
+	now := s.currentTime()
+	users := map[string]bool{}
+	for _, u := range s.allUsers() {
+		users[u] = true
+	}
+	for i, r := range s.Rotations {
+		if !s.RotationEnd(i).After(now) {
+			continue
+		}
+		field := fmt.Sprintf("Rotations[%d]", i)
+		if lower, _ := s.doubledTier(r); lower >= 0 {
+			add(field, false, "%s", s.doubledTierMessage(r))
+		}
+		for _, u := range s.Assigned(r) {
+			if u != "" && u != Unassigned && !users[u] {
+				add(field, true, "assigns %s, who is not among the users to schedule", u)
+			}
+		}
+	}
+
+	warnings, errs := []Problem{}, ValidationErrors{}
+	for _, p := range problems {
+		if p.Warning {
+			warnings = append(warnings, p)
+		} else {
+			errs = append(errs, p)
+		}
+	}
+	if len(errs) == 0 {
+		if err := s.Validate(); err != nil {
+			errs = append(errs, Problem{Message: err.Error(), err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, errs
+	}
+	return warnings, nil
+}
@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateStrict(t *testing.T) {
+	s := FilledSchedule()
+	s.now = Start.Add(2 * Week + time.Hour)
+	if warnings, err := s.ValidateStrict(); err != nil || len(warnings) != 0 {
+		t.Fatalf("expected the filled schedule to pass, got %v, %v", warnings, err)
+	}
+
+	s.Users = []string{"b", "", "c", "b"}
+	s.ScheduleForDuration = 3 * 24 * time.Hour
+	s.Rotations = append([]Rotation{}, s.Rotations...)
+	s.Rotations[1].Start = s.Rotations[0].Start
+	// a has left, and is still named in rotations both over and yet to end.
+	s.Rotations[3].Secondary = "c"
+	s.Rotations[3].Primary = "c"
+	warnings, err := s.ValidateStrict()
+	errs := ValidationErrors{}
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	fields := []string{}
+	for _, p := range errs {
+		fields = append(fields, p.Field)
+	}
+	if got, expected := strings.Join(fields, " "), "ScheduleFor Users[1] Users[3] Rotations[1] Rotations[3]"; got != expected {
+		t.Errorf("expected problems with %s, got %s", expected, got)
+	}
+	if len(warnings) != 1 || warnings[0].Field != "Rotations[2]" || !strings.Contains(warnings[0].Message, "assigns a") {
+		t.Errorf("expected a warning about a in the rotation yet to end, got %v", warnings)
+	}
+	if !strings.Contains(err.Error(), "Users[3]: lists b more than once") {
+		t.Errorf("expected each problem to name its field, got %q", err)
+	}
+}
+
+func TestValidateStrictStart(t *testing.T) {
+	s := EmptySchedule()
+	s.Start = time.Time{}
+	_, err := s.ValidateStrict()
+	if err == nil || err.Error() != "Start: must be set when there are no Rotations" {
+		t.Errorf("expected an error about Start, got %v", err)
+	}
+}
+
+func TestNewScheduleStrictOption(t *testing.T) {
+	if _, err := NewSchedule([]byte(EmptyScheduleText), Strict()); err != nil {
+		t.Errorf("expected the empty schedule to pass, got %s", err)
+	}
+	text := strings.Replace(EmptyScheduleText, `"504h"`, `"24h"`, 1)
+	if _, err := NewSchedule([]byte(text)); err != nil {
+		t.Errorf("expected NewSchedule to accept a ScheduleFor shorter than RotationLength, got %s", err)
+	}
+	if _, err := NewSchedule([]byte(text), Strict()); err == nil || !strings.HasPrefix(err.Error(), "ScheduleFor: ") {
+		t.Errorf("expected an error about ScheduleFor, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// A Swap exchanges two users' places in the rotations starting within a span
+// of time, e.g. when they trade weeks. Like overrides, swaps are applied on top
+// of the generated rotations, so they survive regeneration and leave the
+// round-robin as it was: the rotations after the swap are those the users would
+// have had without it.
+type Swap struct {
+	Users [2]string
+	// Rotations starting in [Start, End) are swapped.
+	Start time.Time
+	End time.Time
+	// When the swap was made.
+	Made time.Time
+}
+
+func (sw Swap) String() string {
+	return fmt.Sprintf("swap of %s and %s from %s to %s", sw.Users[0], sw.Users[1], sw.Start.Format(time.RFC3339), sw.End.Format(time.RFC3339))
+}
+
+func validateSwaps(swaps []Swap) error {
+	for _, sw := range swaps {
+		if sw.Users[0] == "" || sw.Users[1] == "" {
+			return fmt.Errorf("%s: must name two Users", sw)
+		}
+		if sw.Users[0] == sw.Users[1] {
+			return fmt.Errorf("%s: must name two different Users", sw)
+		}
+		if !sw.Start.Before(sw.End) {
+			return fmt.Errorf("%s: must end after it starts", sw)
+		}
+	}
+	return nil
+}
+
+// Swap returns a copy of s with the places of users a and b exchanged, in
+// every tier, in the rotations starting from from until to, and the swap
+// recorded in Swaps. It is an error if either user has no shift in those
+// rotations, or cannot take over a shift of the other's, because it is after
+// their end date or they are unavailable for it.
+func (s *Schedule) Swap(a, b string, from, to time.Time) (*Schedule, error) {
+	sw := Swap{Users: [2]string{a, b}, Start: from, End: to, Made: s.currentTime()}
+	if err := validateSwaps([]Swap{sw}); err != nil {
+		return nil, err
+	}
+	ns := *s
+	ns.Rotations = append([]Rotation{}, s.Rotations...)
+	swapped := ns.applySwap(sw)
+	for _, u := range sw.Users {
+		if !swapped[u] {
+			return nil, fmt.Errorf("%s: %s has no shifts in rotations starting in that time", sw, u)
+		}
+	}
+	if err := ns.checkSwapped(sw); err != nil {
+		return nil, err
+	}
+	ns.Swaps = append(append([]Swap{}, s.Swaps...), sw)
+	return &ns, nil
+}
+
+// Check that each user sw put in a rotation of s may be assigned to it: that
+// the rotation starts before their end date, and they are available for it.
+func (s *Schedule) checkSwapped(sw Swap) error {
+	var start time.Time
+	for i, r := range s.Rotations {
+		if !r.Continues {
+			start = r.Start
+		}
+		if start.Before(sw.Start) || !start.Before(sw.End) {
+			continue
+		}
+		for j, u := range s.Assigned(r) {
+			if _, changed := r.Overridden[s.tiers()[j]]; !changed || (u != sw.Users[0] && u != sw.Users[1]) {
+				continue
+			}
+			if !s.eligible(u, start) {
+				return fmt.Errorf("%s: %s cannot take over the rotation starting at %s, after their end date %s", sw, u, r.Start.Format(time.RFC3339), s.UserEndDates[u].Format(time.RFC3339))
+			}
+			if a := s.unavailability(u, r.Start, s.RotationEnd(i)); a != nil {
+				return fmt.Errorf("%s: %s cannot take over the rotation starting at %s, as %s", sw, u, r.Start.Format(time.RFC3339), a)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply swaps to the rotations of s, after overrides.
+func (s *Schedule) applySwaps() {
+	for _, sw := range s.Swaps {
+		s.applySwap(sw)
+	}
+}
+
+// Apply sw to the rotations of s, recording who each changed tier was
+// generated for as overrides do, and return which of its users were swapped.
+// A rotation split by overrides is swapped whole if it started in the span.
+func (s *Schedule) applySwap(sw Swap) map[string]bool {
+	swapped := map[string]bool{}
+	var start time.Time
+	for i, r := range s.Rotations {
+		if !r.Continues {
+			start = r.Start
+		}
+		if start.Before(sw.Start) || !start.Before(sw.End) {
+			continue
+		}
+		overridden := map[string]string{}
+		for tier, u := range r.Overridden {
+			overridden[tier] = u
+		}
+		tiers := s.tiers()
+		for j, u := range s.Assigned(r) {
+			other := ""
+			switch u {
+			case sw.Users[0]:
+				other = sw.Users[1]
+			case sw.Users[1]:
+				other = sw.Users[0]
+			default:
+				continue
+			}
+			swapped[u] = true
+			if _, ok := overridden[tiers[j]]; !ok {
+				overridden[tiers[j]] = u
+			}
+			r = s.withTierUser(r, tiers[j], other)
+		}
+		if len(overridden) > 0 {
+			r.Overridden = overridden
+		}
+		s.Rotations[i] = r
+	}
+	return swapped
+}
+
+// Drop the swaps that ended before cutoff.
+func dropSwapsBefore(swaps []Swap, cutoff time.Time) []Swap {
+	kept := []Swap{}
+	for _, sw := range swaps {
+		if sw.End.After(cutoff) {
+			kept = append(kept, sw)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
@@ -0,0 +1,129 @@
+package schedule
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSwap(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	plain, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b and c are both on call in the first rotation swapped.
+	swapped, err := plain.Swap("b", "c", day(8), day(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "c", Secondary: "b", Overridden: map[string]string{TierPrimary: "b", TierSecondary: "c"}},
+		{Start: day(15), Primary: "b", Secondary: "a", Overridden: map[string]string{TierPrimary: "c"}},
+		{Start: day(22), Primary: "a", Secondary: "b"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), swapped.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, swapped.Rotations)
+	}
+	if plain.Rotations[1].Primary != "b" || len(plain.Swaps) != 0 {
+		t.Errorf("expected the original schedule to be left alone")
+	}
+	if len(swapped.Swaps) != 1 || !swapped.Swaps[0].Made.Equal(Start) {
+		t.Errorf("expected the swap to be recorded, got %+v", swapped.Swaps)
+	}
+
+	// The swap survives regeneration, after a round trip through JSON, and the
+	// rotations after it are those generated without it.
+	text, err := json.Marshal(swapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), `"Swaps":[{"Users":["b","c"]`) {
+		t.Errorf("expected the swap in JSON, got %s", text)
+	}
+	reloaded, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.now = day(9)
+	again, err := reloaded.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.now = day(9)
+	unswapped, err := plain.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected[:3], again.Rotations[:3]) {
+		t.Errorf("expected the swapped rotations to be kept\nExpected:\n%+v\n---\nGot:\n%+v\n", expected[:3], again.Rotations[:3])
+	}
+	if !reflect.DeepEqual(unswapped.Rotations[3:], again.Rotations[3:]) {
+		t.Errorf("expected the rotations after the swap to be unchanged\nExpected:\n%+v\n---\nGot:\n%+v\n", unswapped.Rotations[3:], again.Rotations[3:])
+	}
+}
+
+func TestSwapErrors(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		a, b string
+		err string
+	}{
+		{"b", "z", "z has no shifts"},
+		{"a", "a", "must name two different Users"},
+		// a is only on call from day 15.
+		{"a", "c", ""},
+	} {
+		_, err := ns.Swap(c.a, c.b, day(8), day(15))
+		if c.err == "" {
+			c.err = "a has no shifts"
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("swap of %s and %s: expected an error containing %q, got %v", c.a, c.b, c.err, err)
+		}
+	}
+}
+
+func TestSwapIneligibleUsers(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.UserEndDates = map[string]time.Time{"c": day(20)}
+	if _, err := ns.Swap("b", "c", day(15), day(29)); err == nil || !strings.Contains(err.Error(), "c cannot take over the rotation starting at 2017-02-22T10:00:00Z, after their end date") {
+		t.Errorf("expected an error for c taking over after their end date, got %v", err)
+	}
+	ns.UserEndDates = nil
+	ns.Unavailable = []Unavailability{{User: "a", Start: day(9), End: day(10)}}
+	if _, err := ns.Swap("a", "c", day(8), day(16)); err == nil || !strings.Contains(err.Error(), "a cannot take over the rotation starting at 2017-02-08T10:00:00Z, as a is unavailable") {
+		t.Errorf("expected an error for a taking over while unavailable, got %v", err)
+	}
+}
+
+func TestValidateSwapIneligibleUsers(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns, err = ns.Swap("b", "c", day(15), day(29)); err != nil {
+		t.Fatal(err)
+	}
+	// c has since become unavailable for the rotation they swapped into.
+	ns.Unavailable = []Unavailability{{User: "c", Start: day(23), End: day(24)}}
+	if err := ns.Validate(); err == nil || !strings.Contains(err.Error(), "swap of b and c") || !strings.Contains(err.Error(), "c is unavailable") {
+		t.Errorf("expected an error naming the swap, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package schedule
+
+// best returns the position in users of the user an assignment step should
+// choose for the rotation starting at s.Start, or -1 if no user is available
+// for it. Every step that chooses users does so through best, so that ties are
+// always broken the same way. Among eligible users, best prefers:
+//
+//  1. the lowest score, as given by the step (e.g. fewest landmark shifts, or
+//     fewest hours on call);
+//  2. the first reached by the round-robin from position p in users.
+//
+// Each position is reached exactly once, so the chain always settles on a
+// single user and never depends on map iteration order. New criteria belong in
+// a step's score or as a new link in this chain, not in the steps themselves;
+// TestAssignmentsUseBest fails for code that assigns users any other way.
+//
+// A nil score is a step that only follows the round-robin, so the first
+// eligible user wins without looking at the rest.
+func (s *Schedule) best(users []string, p int, score func(u string) float64) int {
+	best, bestScore := -1, 0.0
+	end := s.NominalEnd(s.Start)
+	for i := 0; i < len(users); i++ {
+		j := (p + i) % len(users)
+		if !s.available(users[j], s.Start, end) {
+			continue
+		}
+		if score == nil {
+			return j
+		}
+		if sc := score(users[j]); best < 0 || sc < bestScore {
+			best, bestScore = j, sc
+		}
+	}
+	return best
+}
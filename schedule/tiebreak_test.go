@@ -0,0 +1,170 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const constrainedScheduleText = `
+{
+	"Users": ["a", "b", "c", "d", "e"],
+	"Start": "2017-12-06T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "2016h",
+	"UserEndDates": {
+		"e": "2018-02-01T00:00:00Z"
+	},
+	"LandmarkShifts": [
+		{"Name": "Christmas", "Date": "12-25"},
+		{"Name": "New Year's Eve", "Date": "12-31"},
+		{"Name": "Valentine's Day", "Date": "02-14"}
+	],
+	"LandmarkCounts": {
+		"Christmas": {"a": 1, "b": 1, "c": 1, "d": 1},
+		"New Year's Eve": {"a": 2, "c": 1, "d": 1, "e": 1}
+	},
+	"BurdenWindows": [
+		{"Name": "Holidays", "Start": "2017-12-20T00:00:00Z", "End": "2018-01-02T00:00:00Z", "Weight": 2}
+	],
+	"Pauses": [
+		{"Start": "2018-03-01T00:00:00Z", "End": "2018-03-08T00:00:00Z"}
+	],
+	"Rotations": [
+		{"Start": "2017-11-22T10:00:00Z", "Primary": "c", "Secondary": "d"},
+		{"Start": "2017-11-29T10:00:00Z", "Primary": "d", "Secondary": "b"}
+	]
+}`
+
+// Regenerating the same input must produce byte-identical output, however
+// many constraints are in play.
+func TestGenerateDeterministic(t *testing.T) {
+	var first []byte
+	for i := 0; i < 100; i++ {
+		s, err := NewSchedule([]byte(constrainedScheduleText))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.now = time.Date(2017, time.December, 1, 0, 0, 0, 0, time.UTC)
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := json.MarshalIndent(ns, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = out
+		} else if !bytes.Equal(first, out) {
+			t.Fatalf("run %d differs from the first\nFirst:\n%s\n---\nGot:\n%s\n", i, first, out)
+		}
+	}
+}
+
+// Functions that may assign users to a rotation, through withTierUser, and
+// how they choose them. withTierUser is the only function that sets a
+// rotation's users itself; clone only copies them.
+var assigners = map[string]string{
+	"addRotation": "chooses every user through best",
+	"addSplitBySecondary": "keeps whoever holds the secondary's turn, or chooses through best",
+	"ReassignUser": "chooses through best, unless given the replacement",
+	"Backfill": "replays the round-robin backwards, without choosing",
+	"applyOverrides": "puts on call the user each override names",
+	"unapplyOverrides": "restores the users overrides replaced",
+	"applySwap": "exchanges the users each swap names",
+	"Stats": "restores the users overrides replaced, to count shifts as generated",
+}
+
+// Every user assigned to a rotation must be chosen through best, or ties could
+// be broken inconsistently. Code that sets Primary, Secondary or Assignments
+// other than through withTierUser, or calls withTierUser outside the functions
+// listed in assigners, fails this test.
+func TestAssignmentsUseBest(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []*ast.File{}
+	for _, f := range pkgs["schedule"].Files {
+		files = append(files, f)
+	}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("schedule", fset, files, info); err != nil {
+		t.Fatal(err)
+	}
+	isRotation := func(e ast.Expr) bool {
+		typ := info.TypeOf(e)
+		if p, ok := typ.(*types.Pointer); ok {
+			typ = p.Elem()
+		}
+		named, ok := typ.(*types.Named)
+		return ok && named.Obj().Name() == "Rotation"
+	}
+	isTier := func(name string) bool {
+		return name == "Primary" || name == "Secondary" || name == "Assignments"
+	}
+	// Whether rhs is a copy of lhs, as clone makes.
+	isCopy := func(lhs, rhs ast.Expr) bool {
+		call, ok := rhs.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return false
+		}
+		fn, ok := call.Fun.(*ast.Ident)
+		return ok && fn.Name == "copyTiers" && types.ExprString(call.Args[0]) == types.ExprString(lhs)
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || fn.Name.Name == "withTierUser" {
+				continue
+			}
+			_, assigner := assigners[fn.Name.Name]
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.AssignStmt:
+					for i, lhs := range n.Lhs {
+						if index, ok := lhs.(*ast.IndexExpr); ok {
+							lhs = index.X
+						}
+						sel, ok := lhs.(*ast.SelectorExpr)
+						if !ok || !isTier(sel.Sel.Name) || !isRotation(sel.X) {
+							continue
+						}
+						if len(n.Rhs) == len(n.Lhs) && isCopy(n.Lhs[i], n.Rhs[i]) {
+							continue
+						}
+						t.Errorf("%s: %s assigns users to a rotation other than through withTierUser", fset.Position(n.Pos()), fn.Name.Name)
+					}
+				case *ast.CallExpr:
+					if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "withTierUser" && !assigner {
+						t.Errorf("%s: %s assigns users to a rotation without going through best; choose them with best, or list it in assigners saying why it need not", fset.Position(n.Pos()), fn.Name.Name)
+					}
+				case *ast.CompositeLit:
+					if !isRotation(n) {
+						break
+					}
+					for _, elt := range n.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok && isTier(kv.Key.(*ast.Ident).Name) {
+							t.Errorf("%s: %s assigns users to a rotation other than through withTierUser", fset.Position(n.Pos()), fn.Name.Name)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
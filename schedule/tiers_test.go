@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTiersWithTooFewUsers(t *testing.T) {
+	three := []string{TierPrimary, TierSecondary, "ic"}
+	for _, c := range []struct {
+		users []string
+		roles []string
+		collapse bool
+		err string
+		first string
+	}{
+		{users: []string{"a"}, err: "1 users cannot fill 2 tiers"},
+		{users: []string{"a"}, collapse: true, first: "a -"},
+		{users: []string{"a", "b"}, first: "a b"},
+		{users: []string{"a", "b"}, collapse: true, first: "a b"},
+		{users: []string{"a", "b", "c"}, first: "a b"},
+		{users: []string{"a", "b", "c"}, collapse: true, first: "a b"},
+		{users: []string{"a"}, roles: three, err: "1 users cannot fill 3 tiers"},
+		{users: []string{"a"}, roles: three, collapse: true, first: "a -"},
+		{users: []string{"a", "b"}, roles: three, err: "2 users cannot fill 3 tiers"},
+		{users: []string{"a", "b"}, roles: three, collapse: true, first: "a b"},
+		{users: []string{"a", "b", "c"}, roles: three, first: "a b ic=c"},
+		{users: []string{"a", "b", "c"}, roles: three, collapse: true, first: "a b ic=c"},
+	} {
+		empty := EmptySchedule()
+		empty.now = Start
+		empty.Users = c.users
+		empty.Roles = c.roles
+		empty.AllowTierCollapse = c.collapse
+		s, err := empty.Generate()
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%d users, %d roles, collapse %v: expected error %q, got %v", len(c.users), len(c.roles), c.collapse, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d users, %d roles, collapse %v: %s", len(c.users), len(c.roles), c.collapse, err)
+			continue
+		}
+		if r := s.Rotations[0]; r.String() != "2017-02-01T10:00:00Z/2017-02-08T10:00:00Z " + c.first {
+			t.Errorf("%d users, %d roles, collapse %v: expected %q, got %q", len(c.users), len(c.roles), c.collapse, c.first, r)
+		}
+	}
+}
+
+func TestTiersCollapseMidSchedule(t *testing.T) {
+	empty := EmptySchedule()
+	empty.now = Start
+	empty.Users = []string{"a", "b"}
+	empty.UserEndDates = map[string]time.Time{"b": time.Date(2017, time.February, 10, 0, 0, 0, 0, time.UTC)}
+	if _, err := empty.Generate(); err == nil || !strings.Contains(err.Error(), "2017-02-15T10:00:00Z") {
+		t.Errorf("expected an error naming the understaffed rotation, got %v", err)
+	}
+
+	empty.AllowTierCollapse = true
+	s, err := empty.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2017-02-01T10:00:00Z/2017-02-08T10:00:00Z a b",
+		"2017-02-08T10:00:00Z/2017-02-15T10:00:00Z b a",
+		"2017-02-15T10:00:00Z/2017-02-22T10:00:00Z a -",
+		"2017-02-22T10:00:00Z/2017-03-01T10:00:00Z a -",
+	}
+	for i, r := range s.Rotations {
+		if r.String() != expected[i] {
+			t.Errorf("rotation %d: expected %q, got %q", i, expected[i], r)
+		}
+	}
+	events := s.HandoffNotifications(time.Date(2017, time.February, 15, 10, 0, 0, 0, time.UTC))
+	if len(events) != 2 || events[1].Outgoing != "a" || events[1].Incoming != "" {
+		t.Errorf("expected the secondary handoff to an unfilled tier, got %+v", events)
+	}
+}
+
+func TestLintDoubledTiers(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 10, 0, 0, 0, 0, time.UTC)
+	// An elapsed rotation no longer matters.
+	filled.Rotations[0].Secondary = "a"
+	filled.Rotations[2].Secondary = "c"
+	suggestions := filled.Lint()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "2017-02-15T10:00:00Z has c as both primary and secondary") {
+		t.Errorf("expected a warning about c's rotation, got %v", suggestions)
+	}
+
+	// Generate keeps the hand edit, and the warning with it.
+	ns, err := filled.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suggestions := ns.Lint(); len(suggestions) != 1 {
+		t.Errorf("expected the warning to survive generation, got %v", suggestions)
+	}
+}
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const newYorkScheduleText = `
+{
+	"Users": ["a", "b", "c"],
+	"Start": "2017-03-01T09:00:00-05:00",
+	"Timezone": "America/New_York",
+	"RotationLength": "1w",
+	"ScheduleFor": "3w"
+}`
+
+func TestTimezoneKeepsWallClockHandoff(t *testing.T) {
+	s, err := NewSchedule([]byte(newYorkScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = time.Date(2017, time.March, 1, 14, 0, 0, 0, time.UTC)
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Daylight saving starts on 2017-03-12, but every handoff stays at 09:00.
+	expected := []string{
+		"2017-03-01T09:00:00-05:00/2017-03-08T09:00:00-05:00 a b",
+		"2017-03-08T09:00:00-05:00/2017-03-15T09:00:00-04:00 b c",
+		"2017-03-15T09:00:00-04:00/2017-03-22T09:00:00-04:00 c a",
+		"2017-03-22T09:00:00-04:00/2017-03-29T09:00:00-04:00 a b",
+		"2017-03-29T09:00:00-04:00/2017-04-05T09:00:00-04:00 b c",
+	}
+	got := []string{}
+	for _, r := range ns.Rotations {
+		got = append(got, r.String())
+	}
+	if strings.Join(expected, "\n") != strings.Join(got, "\n") {
+		t.Errorf("rotations do not match expected\nExpected:\n%s\n---\nGot:\n%s\n", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	out, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"Start":"2017-04-05T09:00:00-04:00"`) {
+		t.Errorf("expected Start to be written in the schedule's zone, got %s", out)
+	}
+}
+
+func TestNoTimezoneKeepsAbsoluteLength(t *testing.T) {
+	s, err := NewSchedule([]byte(strings.Replace(newYorkScheduleText, `"Timezone": "America/New_York",`, "", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = time.Date(2017, time.March, 1, 14, 0, 0, 0, time.UTC)
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ns.Rotations[2].String(); got != "2017-03-15T09:00:00-05:00/2017-03-22T09:00:00-05:00 c a" {
+		t.Errorf("expected rotations of exactly 168h in the original offset, got %s", got)
+	}
+}
+
+func TestTimezoneBackfill(t *testing.T) {
+	s, err := NewSchedule([]byte(strings.Replace(newYorkScheduleText, "2017-03-01T09:00:00-05:00", "2017-03-15T09:00:00-04:00", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := s.Backfill(time.Date(2017, time.March, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].String() != "2017-03-01T09:00:00-05:00/2017-03-08T09:00:00-05:00 b c" {
+		t.Errorf("expected backfilled rotations to keep the 09:00 handoff, got %v", rs)
+	}
+}
+
+func TestTimezoneInvalid(t *testing.T) {
+	_, err := NewSchedule([]byte(strings.Replace(newYorkScheduleText, "America/New_York", "America/Nowhere", 1)))
+	if err == nil || !strings.HasPrefix(err.Error(), "error loading Timezone: ") {
+		t.Errorf("expected an error loading the zone, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+func (s Schedule) validateTrainees() error {
+	if len(s.Trainees) == 0 {
+		return nil
+	}
+	if s.TraineeShifts < 1 {
+		return fmt.Errorf("must set TraineeShifts to how many rotations each of Trainees shadows (got %d)", s.TraineeShifts)
+	}
+	seen := map[string]bool{}
+	for _, u := range s.Trainees {
+		if seen[u] {
+			return fmt.Errorf("Trainees lists %s more than once", u)
+		}
+		seen[u] = true
+		if containsUser(s.allUsers(), u) {
+			return fmt.Errorf("Trainees lists %s, who is already among the users to schedule; remove them from Trainees once they graduate", u)
+		}
+	}
+	return nil
+}
+
+// How many rotations u has shadowed: those counted in ShiftCounts, and those
+// in Rotations.
+func (s *Schedule) shadowed(u string) int {
+	n := s.ShiftCounts[u].Shadow
+	for _, r := range s.Rotations {
+		if r.Shadow == u {
+			n++
+		}
+	}
+	return n
+}
+
+// Return the trainee to shadow the rotation starting at s.Start and ending at
+// end: the first of Trainees, in order, who has shadowed fewer than
+// TraineeShifts rotations and is available, or "" if there is none. Each
+// trainee so shadows TraineeShifts consecutive rotations before the next
+// starts.
+func (s *Schedule) nextShadow(end time.Time) string {
+	for _, u := range s.Trainees {
+		if s.shadowed(u) < s.TraineeShifts && s.available(u, s.Start, end) {
+			return u
+		}
+	}
+	return ""
+}
+
+// Return the trainees who have shadowed every rotation they are to, the last
+// of which has ended by now.
+func (s *Schedule) graduates(now time.Time) []string {
+	graduates := []string{}
+	for _, u := range s.Trainees {
+		if s.shadowed(u) < s.TraineeShifts {
+			continue
+		}
+		shadowing := false
+		for i, r := range s.Rotations {
+			if r.Shadow == u && s.RotationEnd(i).After(now) {
+				shadowing = true
+			}
+		}
+		if !shadowing {
+			graduates = append(graduates, u)
+		}
+	}
+	return graduates
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func shadowsOf(rs []Rotation) []string {
+	shadows := []string{}
+	for _, r := range rs {
+		shadows = append(shadows, r.Shadow)
+	}
+	return shadows
+}
+
+func TestTraineesShadowInTurn(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.ScheduleForDuration = 5 * Week
+	without, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Trainees = []string{"t1", "t2"}
+	s.TraineeShifts = 2
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shadows := shadowsOf(ns.Rotations); !reflect.DeepEqual(shadows, []string{"t1", "t1", "t2", "t2", "", ""}) {
+		t.Errorf("expected each trainee to shadow two rotations in turn, got %q", shadows)
+	}
+	for i, r := range ns.Rotations {
+		if r.Primary != without.Rotations[i].Primary || r.Secondary != without.Rotations[i].Secondary {
+			t.Errorf("rotation %d: expected trainees to leave the round-robin alone, got %s rather than %s", i, r, without.Rotations[i])
+		}
+	}
+	if r := ns.Rotations[0].String(); !strings.HasSuffix(r, " a b shadow=t1") {
+		t.Errorf("expected the rotation to show its shadow, got %q", r)
+	}
+}
+
+func TestTraineesCountedThroughTruncation(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Trainees = []string{"t1", "t2"}
+	s.TraineeShifts = 2
+	s, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = regenerateWeekly(t, s, 3)
+	if n := s.ShiftCounts["t1"].Shadow; n != 2 {
+		t.Errorf("expected t1's 2 elapsed shadow shifts to be counted, got %d", n)
+	}
+	// t1 is not given more once their shifts are dropped, and t2 picks up
+	// where they left off.
+	for _, r := range s.Rotations {
+		if r.Shadow == "t1" {
+			t.Errorf("expected t1 to have finished shadowing, got %s", r)
+		}
+	}
+	if got := s.shadowed("t2"); got != 2 {
+		t.Errorf("expected t2 to shadow 2 rotations, got %d", got)
+	}
+	lint := strings.Join(s.Lint(), "\n")
+	if !strings.Contains(lint, "t1 has shadowed 2 rotations and is ready to graduate") {
+		t.Errorf("expected a suggestion to graduate t1, got:\n%s", lint)
+	}
+	if strings.Contains(lint, "t2 has shadowed") {
+		t.Errorf("expected t2 not to be ready yet, got:\n%s", lint)
+	}
+}
+
+func TestValidateTrainees(t *testing.T) {
+	for _, c := range []struct {
+		trainees []string
+		shifts int
+		err string
+	}{
+		{[]string{"t1"}, 0, "must set TraineeShifts"},
+		{[]string{"t1", "t1"}, 2, "Trainees lists t1 more than once"},
+		{[]string{"a"}, 2, "a, who is already among the users to schedule"},
+	} {
+		s := EmptySchedule()
+		s.Trainees, s.TraineeShifts = c.trainees, c.shifts
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+}
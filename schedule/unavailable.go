@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Unassigned is the placeholder user given to every tier of a rotation nobody
+// is available for, when AllowUnassigned is set.
+const Unassigned = "UNASSIGNED"
+
+// An Unavailability is a span of time a user cannot be on call, e.g. for
+// time off.
+type Unavailability struct {
+	User string
+	Start time.Time
+	End time.Time
+	Reason string `json:",omitempty"`
+}
+
+func (u Unavailability) String() string {
+	text := fmt.Sprintf("%s is unavailable from %s to %s", u.User, u.Start.Format(time.RFC3339), u.End.Format(time.RFC3339))
+	if u.Reason != "" {
+		text += fmt.Sprintf(" (%s)", u.Reason)
+	}
+	return text
+}
+
+func validateUnavailable(unavailable []Unavailability) error {
+	for _, u := range unavailable {
+		if u.User == "" {
+			return fmt.Errorf("unavailability from %s to %s must name a User", u.Start.Format(time.RFC3339), u.End.Format(time.RFC3339))
+		}
+		if !u.Start.Before(u.End) {
+			return fmt.Errorf("%s: must end after it starts", u)
+		}
+	}
+	return nil
+}
+
+// Whether u may be assigned to a rotation from start to end: they have not
+// reached their end date, and are not unavailable at any point in between.
+func (s *Schedule) available(u string, start, end time.Time) bool {
+	if !s.eligible(u, start) {
+		return false
+	}
+	return s.unavailability(u, start, end) == nil
+}
+
+// Return the first of u's unavailabilities overlapping start to end, or nil.
+func (s *Schedule) unavailability(u string, start, end time.Time) *Unavailability {
+	for i, a := range s.Unavailable {
+		if a.User == u && a.Start.Before(end) && a.End.After(start) {
+			return &s.Unavailable[i]
+		}
+	}
+	return nil
+}
+
+// Drop the unavailabilities that ended before cutoff.
+func dropUnavailableBefore(unavailable []Unavailability, cutoff time.Time) []Unavailability {
+	kept := []Unavailability{}
+	for _, u := range unavailable {
+		if u.End.After(cutoff) {
+			kept = append(kept, u)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
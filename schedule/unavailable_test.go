@@ -0,0 +1,98 @@
+package schedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnavailableAcrossRotations(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	s.Unavailable = []Unavailability{{User: "b", Start: day(8), End: day(22), Reason: "vacation"}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b is skipped for both rotations they are away for, and is first in line
+	// when they are back.
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "c", Secondary: "a"},
+		{Start: day(15), Primary: "a", Secondary: "c"},
+		{Start: day(22), Primary: "b", Secondary: "c"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+}
+
+func TestUnavailableClipsRotation(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	// c is away only for the last half day of the second rotation.
+	s.Unavailable = []Unavailability{{User: "c", Start: day(15).Add(-12 * time.Hour), End: day(15)}}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: "b", Secondary: "a"},
+		{Start: day(15), Primary: "c", Secondary: "a"},
+		{Start: day(22), Primary: "a", Secondary: "b"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+}
+
+func TestUnavailableEveryone(t *testing.T) {
+	s := EmptySchedule()
+	s.now = Start
+	for _, u := range s.Users {
+		s.Unavailable = append(s.Unavailable, Unavailability{User: u, Start: day(9), End: day(10)})
+	}
+	_, err := s.Generate()
+	if err == nil || !strings.Contains(err.Error(), "starting at 2017-02-08T10:00:00Z and ending at 2017-02-15T10:00:00Z") {
+		t.Fatalf("expected an error naming the unfillable rotation, got %v", err)
+	}
+
+	s.AllowUnassigned = true
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := withEnds(day(29), []Rotation{
+		{Start: day(1), Primary: "a", Secondary: "b"},
+		{Start: day(8), Primary: Unassigned, Secondary: Unassigned, Notes: "nobody is available"},
+		{Start: day(15), Primary: "b", Secondary: "c"},
+		{Start: day(22), Primary: "c", Secondary: "a"},
+	})
+	if !reflect.DeepEqual(withIDs(expected), ns.Rotations) {
+		t.Errorf("rotations do not match expected\nExpected:\n%+v\n---\nGot:\n%+v\n", expected, ns.Rotations)
+	}
+	suggestions := ns.Lint()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "2017-02-08T10:00:00Z to 2017-02-15T10:00:00Z is UNASSIGNED") {
+		t.Errorf("expected a warning about the unassigned rotation, got %v", suggestions)
+	}
+}
+
+func TestLintUnavailable(t *testing.T) {
+	filled := FilledSchedule()
+	filled.now = time.Date(2017, time.February, 10, 0, 0, 0, 0, time.UTC)
+	filled.Unavailable = []Unavailability{{User: filled.Rotations[2].Primary, Start: filled.Rotations[2].Start, End: filled.Rotations[2].Start.Add(time.Hour)}}
+	suggestions := filled.Lint()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "add an override to cover them") {
+		t.Errorf("expected a warning about the unavailable user, got %v", suggestions)
+	}
+}
+
+func TestValidateUnavailable(t *testing.T) {
+	s := EmptySchedule()
+	s.Unavailable = []Unavailability{{User: "a", Start: day(3), End: day(2)}}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "must end after it starts") {
+		t.Errorf("expected an error about the backwards range, got %v", err)
+	}
+}
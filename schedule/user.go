@@ -0,0 +1,123 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// A User is an entry in Users: a name, as rotations refer to the user by, and
+// optionally how to reach them, so that integrations need no mapping files of
+// their own. In a schedule file, a user with nothing but a Name may be
+// written as just the name, e.g.
+//
+//	"Users": ["alice", {"Name": "bob", "Email": "bob@example.com", "SlackID": "U024BE7LH"}]
+type User struct {
+	Name string
+	Email string `json:",omitempty"`
+	PagerDutyID string `json:",omitempty"`
+	SlackID string `json:",omitempty"`
+	// The user's time zone, as a name from the IANA Time Zone database.
+	TZ string `json:",omitempty"`
+}
+
+func (u *User) UnmarshalJSON(text []byte) error {
+	var name string
+	if err := json.Unmarshal(text, &name); err == nil {
+		*u = User{Name: name}
+		return nil
+	}
+	// An alias has no UnmarshalJSON, so the object is read field by field.
+	type user User
+	v := user{}
+	if err := json.Unmarshal(text, &v); err != nil {
+		return fmt.Errorf("a user must be a name or an object with a Name (got %s)", text)
+	}
+	if v.Name == "" {
+		return fmt.Errorf("a user must have a Name (got %s)", text)
+	}
+	*u = User(v)
+	return nil
+}
+
+// MarshalJSON writes a user with nothing but a Name as just the name, so that
+// files without user details read as they always have.
+func (u User) MarshalJSON() ([]byte, error) {
+	if u == (User{Name: u.Name}) {
+		return json.Marshal(u.Name)
+	}
+	type user User
+	return json.Marshal(user(u))
+}
+
+// UsersNamed returns Users for the given names, with no other details.
+func UsersNamed(names []string) []User {
+	users := make([]User, len(names))
+	for i, name := range names {
+		users[i] = User{Name: name}
+	}
+	return users
+}
+
+// Return the names of users, or nil if users is.
+func namesOf(users []User) []string {
+	if users == nil {
+		return nil
+	}
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return names
+}
+
+// User returns the details of the named user, or just their name if Users
+// gives none, and whether s may assign them at all.
+func (s Schedule) User(name string) (User, bool) {
+	if u, ok := s.userDetails[name]; ok {
+		return u, true
+	}
+	return User{Name: name}, containsUser(s.allUsers(), name)
+}
+
+// Return Users with the details of each.
+func (s Schedule) usersWithDetails() []User {
+	if s.Users == nil {
+		return nil
+	}
+	users := UsersNamed(s.Users)
+	for i, u := range users {
+		if d, ok := s.userDetails[u.Name]; ok {
+			users[i] = d
+		}
+	}
+	return users
+}
+
+// Check the details of users. Once any user has details, every user named in
+// Rotations must be one s may assign, so that integrations can look each of
+// them up.
+func (s Schedule) validateUserDetails() error {
+	if len(s.userDetails) == 0 {
+		return nil
+	}
+	for _, u := range s.Users {
+		if d, ok := s.userDetails[u]; ok && d.TZ != "" {
+			if _, err := time.LoadLocation(d.TZ); err != nil {
+				return fmt.Errorf("error loading TZ of user %s: %s", u, err)
+			}
+		}
+	}
+	known := map[string]bool{}
+	for _, u := range s.allUsers() {
+		known[u] = true
+	}
+	for _, r := range s.Rotations {
+		for _, u := range s.Assigned(r) {
+			if u != "" && u != Unassigned && !known[u] {
+				return fmt.Errorf("rotation starting at %s names %s, who is not among the users", r.Start.Format(time.RFC3339), u)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const UsersScheduleText = `{
+	"Users": ["a", {"Name": "b", "Email": "b@example.com", "PagerDutyID": "PB", "SlackID": "UB", "TZ": "Europe/London"}, {"Name": "c"}],
+	"Start": "2017-02-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h"
+}`
+
+func TestUserDetails(t *testing.T) {
+	s, err := NewSchedule([]byte(UsersScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(s.Users, ",") != "a,b,c" {
+		t.Errorf("expected Users to be the names, got %v", s.Users)
+	}
+	if u, ok := s.User("b"); !ok || u.Email != "b@example.com" || u.PagerDutyID != "PB" || u.TZ != "Europe/London" {
+		t.Errorf("expected b's details, got %+v, %v", u, ok)
+	}
+	if u, ok := s.User("a"); !ok || u != (User{Name: "a"}) {
+		t.Errorf("expected just a's name, got %+v, %v", u, ok)
+	}
+	if _, ok := s.User("z"); ok {
+		t.Errorf("expected z to be unknown")
+	}
+
+	s.now = s.Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.Rotations[1].Primary != "b" {
+		t.Errorf("expected rotations to name users, got %s", ns.Rotations[1])
+	}
+	text, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Generate moves a to the end, and only b is written as an object.
+	expected := `"Users":[{"Name":"b","Email":"b@example.com","PagerDutyID":"PB","SlackID":"UB","TZ":"Europe/London"},"c","a"]`
+	if !strings.Contains(string(text), expected) {
+		t.Errorf("expected %s, got %s", expected, text)
+	}
+	again, err := NewSchedule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := again.User("b"); u.SlackID != "UB" {
+		t.Errorf("expected b's details to round-trip, got %+v", u)
+	}
+}
+
+func TestValidateUserDetails(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		err string
+	}{
+		{strings.Replace(UsersScheduleText, `{"Name": "c"}`, `{"Email": "c@example.com"}`, 1), "a user must have a Name"},
+		{strings.Replace(UsersScheduleText, `{"Name": "c"}`, `7`, 1), "a user must be a name or an object with a Name"},
+		{strings.Replace(UsersScheduleText, `{"Name": "c"}`, `{"Name": "b"}`, 1), "Users lists b more than once"},
+		{strings.Replace(UsersScheduleText, "Europe/London", "Europe/Nowhere", 1), "error loading TZ of user b"},
+		{strings.Replace(UsersScheduleText, `"ScheduleFor": "504h"`, `"ScheduleFor": "504h", "Rotations": [{"Start": "2017-02-01T10:00:00Z", "Primary": "a", "Secondary": "z"}]`, 1), "rotation starting at 2017-02-01T10:00:00Z names z, who is not among the users"},
+	} {
+		if _, err := NewSchedule([]byte(c.text)); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error containing %q, got %v", c.err, err)
+		}
+	}
+	// Without details, a rotation may still name a user since removed.
+	s := FilledSchedule()
+	s.Users = []string{"a", "b"}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected no error without user details, got %s", err)
+	}
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+type WarningKind string
+
+const (
+	// A rotation assigns someone who is not among the users to schedule, e.g.
+	// because their name was mistyped.
+	WarningUnknownUser WarningKind = "unknown_user"
+	// A rotation starts before the one listed before it.
+	WarningOutOfOrder WarningKind = "out_of_order"
+	// A rotation starts as the one listed before it does.
+	WarningDuplicateStart WarningKind = "duplicate_start"
+	// A rotation runs for less than half, or more than twice, as long as
+	// RotationLength, with any pause or gap, makes it.
+	WarningLength WarningKind = "length"
+	// A rotation assigns one user to two tiers.
+	WarningDoubledTier WarningKind = "doubled_tier"
+)
+
+// A Warning is something suspicious about a rotation edited into Rotations by
+// hand. Unlike a problem Validate reports, it does not stop the schedule from
+// being generated.
+type Warning struct {
+	Kind WarningKind `json:"kind"`
+	// The position of the rotation in Rotations.
+	Rotation int `json:"rotation"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("Rotations[%d]: %s", w.Rotation, w.Message)
+}
+
+// GenerateWithWarnings is Generate, also returning the Warnings about the
+// rotations of s as they were before generating.
+func (s *Schedule) GenerateWithWarnings() (*Schedule, []Warning, error) {
+	ns, err := s.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+	return ns, s.Warnings(), nil
+}
+
+// Warnings checks the Rotations of s for signs of a mistaken hand edit, which
+// Generate would otherwise carry into the schedule it generates: rotations out
+// of order or starting together, rotations yet to end that assign someone
+// missing from the users to schedule or one user to two tiers, and rotations
+// yet to end far longer or shorter than RotationLength. Rotations out of order
+// or starting together also fail Validate, but Warnings reports them without
+// stopping, along with everything else.
+func (s *Schedule) Warnings() []Warning {
+	warnings := []Warning{}
+	add := func(kind WarningKind, i int, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Kind: kind, Rotation: i, Message: fmt.Sprintf(format, args...)})
+	}
+	for i := 1; i < len(s.Rotations); i++ {
+		prev, r := s.Rotations[i-1], s.Rotations[i]
+		if r.Start.Equal(prev.Start) {
+			add(WarningDuplicateStart, i, "starts at %s, as Rotations[%d] does", r.Start.Format(time.RFC3339), i - 1)
+		} else if r.Start.Before(prev.Start) {
+			add(WarningOutOfOrder, i, "starts at %s, before Rotations[%d] at %s", r.Start.Format(time.RFC3339), i - 1, prev.Start.Format(time.RFC3339))
+		}
+	}
+
+	now := s.currentTime()
+	users := map[string]bool{}
+	for _, u := range s.allUsers() {
+		users[u] = true
+	}
+	tiers := s.tiers()
+	for i, r := range s.Rotations {
+		if !s.RotationEnd(i).After(now) {
+			continue
+		}
+		for _, u := range s.Assigned(r) {
+			if u != "" && u != Unassigned && !users[u] {
+				add(WarningUnknownUser, i, "assigns %s, who is not among the users to schedule", u)
+			}
+		}
+		// A user forced into two tiers by an override was put there on
+		// purpose.
+		if lower, higher := s.doubledTier(r); lower >= 0 && r.Overridden == nil {
+			add(WarningDoubledTier, i, "assigns %s as both %s and %s", s.Assigned(r)[lower], tiers[lower], tiers[higher])
+		}
+		if length, nominal := s.impliedLength(i), s.NominalEnd(r.Start).Sub(r.Start); !r.Continues && !r.SecondaryHandoff && !r.Pinned && (length < nominal / 2 || length > nominal * 2) {
+			add(WarningLength, i, "runs for %s, but RotationLength makes it %s", length, nominal)
+		}
+	}
+	return warnings
+}
+
+// Return how long the rotation at i runs for, including the rest of it after
+// any override or secondary handoff that split it. A rotation ending where a
+// pinned rotation starts may be cut short on purpose, so is taken to run its
+// nominal length.
+func (s Schedule) impliedLength(i int) time.Duration {
+	j := i
+	for j + 1 < len(s.Rotations) && (s.Rotations[j+1].Continues || s.Rotations[j+1].SecondaryHandoff) {
+		j++
+	}
+	if j + 1 < len(s.Rotations) && s.Rotations[j+1].Pinned {
+		return s.NominalEnd(s.Rotations[i].Start).Sub(s.Rotations[i].Start)
+	}
+	return s.RotationEnd(j).Sub(s.Rotations[i].Start)
+}
@@ -0,0 +1,109 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarnings(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		edit func(s *Schedule)
+		kind WarningKind
+		rotation int
+		message string
+	}{
+		{
+			"unknown user",
+			func(s *Schedule) { s.Rotations[3].Secondary = "bb" },
+			WarningUnknownUser, 3, "assigns bb, who is not among the users to schedule",
+		},
+		{
+			"out of order",
+			func(s *Schedule) {
+				s.Rotations[3].Start = time.Date(2017, time.February, 14, 10, 0, 0, 0, time.UTC)
+				s.Rotations[3].End = time.Date(2017, time.February, 22, 10, 0, 0, 0, time.UTC)
+			},
+			WarningOutOfOrder, 3, "starts at 2017-02-14T10:00:00Z, before Rotations[2] at 2017-02-15T10:00:00Z",
+		},
+		{
+			"duplicate start",
+			func(s *Schedule) { s.Rotations[3].Start = s.Rotations[2].Start },
+			WarningDuplicateStart, 3, "starts at 2017-02-15T10:00:00Z, as Rotations[2] does",
+		},
+		{
+			"long",
+			func(s *Schedule) {
+				s.Rotations[2].End = time.Date(2017, time.March, 8, 10, 0, 0, 0, time.UTC)
+				s.Rotations = s.Rotations[:3]
+			},
+			WarningLength, 2, "runs for 504h0m0s, but RotationLength makes it 168h0m0s",
+		},
+		{
+			"short",
+			func(s *Schedule) {
+				s.Rotations[2].End = time.Date(2017, time.February, 16, 10, 0, 0, 0, time.UTC)
+				s.Rotations[3].Start = s.Rotations[2].End
+			},
+			WarningLength, 2, "runs for 24h0m0s, but RotationLength makes it 168h0m0s",
+		},
+		{
+			"doubled tier",
+			func(s *Schedule) { s.Rotations[3].Secondary = "a" },
+			WarningDoubledTier, 3, "assigns a as both primary and secondary",
+		},
+	} {
+		s := FilledSchedule()
+		s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+		c.edit(s)
+		warnings := s.Warnings()
+		found := false
+		for _, w := range warnings {
+			if w.Kind == c.kind && w.Rotation == c.rotation && w.Message == c.message {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected a %s warning for Rotations[%d]: %q, got %v", c.name, c.kind, c.rotation, c.message, warnings)
+		}
+	}
+}
+
+func TestWarningsIgnoreElapsedRotations(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	// Whoever was on call before leaving stays in the rotations they worked.
+	s.Rotations[0].Primary = "departed"
+	s.Rotations[1].Secondary = "b"
+	if warnings := s.Warnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings about elapsed rotations, got %v", warnings)
+	}
+}
+
+func TestGenerateWithWarnings(t *testing.T) {
+	s := FilledSchedule()
+	s.now = time.Date(2017, time.February, 16, 0, 0, 0, 0, time.UTC)
+	ns, warnings, err := s.GenerateWithWarnings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings for a clean schedule, got %v", warnings)
+	}
+	if _, warnings, _ := ns.GenerateWithWarnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings for a generated schedule, got %v", warnings)
+	}
+
+	// Warnings do not stop generation.
+	s.Rotations[3].Primary = "aa"
+	ns, warnings, err = s.GenerateWithWarnings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Kind != WarningUnknownUser || warnings[0].String() != "Rotations[3]: assigns aa, who is not among the users to schedule" {
+		t.Errorf("expected a warning about aa, got %v", warnings)
+	}
+	if ns.Rotations[2].Primary != "aa" {
+		t.Errorf("expected the rotation to be generated as written, got %v", ns.Rotations)
+	}
+}
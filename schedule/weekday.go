@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Parse a StartWeekday, e.g. "Tuesday", ignoring case.
+func parseWeekday(text string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(text, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("error parsing StartWeekday: must be a day of the week, e.g. %q (got %q)", time.Tuesday.String(), text)
+}
+
+// The location weekdays are judged in: the schedule's Timezone, or t's
+// location without one.
+func (s Schedule) weekdayLocation(t time.Time) *time.Location {
+	if s.location != nil {
+		return s.location
+	}
+	return t.Location()
+}
+
+// Whether t falls on StartWeekday. Always true if StartWeekday is not set.
+func (s Schedule) onStartWeekday(t time.Time) bool {
+	if s.StartWeekday == "" {
+		return true
+	}
+	weekday, err := parseWeekday(s.StartWeekday)
+	if err != nil {
+		return true
+	}
+	return t.In(s.weekdayLocation(t)).Weekday() == weekday
+}
+
+// Return the same time of day on the first StartWeekday after t.
+func (s Schedule) nextStartWeekday(t time.Time) time.Time {
+	if s.StartWeekday == "" {
+		return t
+	}
+	weekday, err := parseWeekday(s.StartWeekday)
+	if err != nil {
+		return t
+	}
+	local := t.In(s.weekdayLocation(t))
+	days := (int(weekday) - int(local.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return local.AddDate(0, 0, days)
+}
+
+// Return t if it falls on StartWeekday, or otherwise the same time of day on
+// the next StartWeekday.
+func (s Schedule) snapToStartWeekday(t time.Time) time.Time {
+	if s.onStartWeekday(t) {
+		return t
+	}
+	return s.nextStartWeekday(t)
+}
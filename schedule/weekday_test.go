@@ -0,0 +1,83 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateStartWeekday(t *testing.T) {
+	s := EmptySchedule()
+	s.StartWeekday = "Tues"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "StartWeekday") {
+		t.Errorf("expected an error parsing StartWeekday, got %v", err)
+	}
+	s.StartWeekday = "tuesday"
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected weekdays to be case-insensitive, got %v", err)
+	}
+}
+
+func TestStartWeekday(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		start int
+		length time.Duration
+		expected []int
+	}{
+		{"weekly from a Tuesday", 7, Week, []int{7, 14, 21, 28}},
+		// The first rotation is short so that the second starts on a Tuesday.
+		{"weekly from a Wednesday", 1, Week, []int{1, 7, 14, 21}},
+		{"fortnightly from a Wednesday", 1, 2 * Week, []int{1, 7, 21, 35}},
+		// A 10d rotation ends on a Friday and is rounded up to the Tuesday.
+		{"10 days from a Tuesday", 7, 10 * Day, []int{7, 21, 35}},
+	} {
+		s := EmptySchedule()
+		s.StartWeekday = "Tuesday"
+		s.Start = day(c.start)
+		s.now = s.Start
+		s.RotationDuration = c.length
+		s.ScheduleForDuration = day(c.expected[len(c.expected)-1]).Sub(s.Start) - time.Hour
+		ns, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []int{}
+		for _, r := range ns.Rotations {
+			got = append(got, r.Start.YearDay() - Start.YearDay() + 1)
+		}
+		if len(got) != len(c.expected) {
+			t.Errorf("%s: expected rotations starting on days %v, got %v", c.name, c.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.expected[i] {
+				t.Errorf("%s: expected rotations starting on days %v, got %v", c.name, c.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestStartWeekdayRecoversFromHandEdit(t *testing.T) {
+	s := EmptySchedule()
+	s.StartWeekday = "Tuesday"
+	s.Start = day(7)
+	s.now = day(7)
+	s.Rotations = []Rotation{
+		{Start: day(7), Primary: "a", Secondary: "b"},
+		// Moved onto a Wednesday by hand.
+		{Start: day(15), Primary: "b", Secondary: "c"},
+	}
+	suggestions := s.Lint()
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0], "2017-02-15T10:00:00Z falls on a Wednesday rather than StartWeekday Tuesday") {
+		t.Errorf("expected a warning about the Wednesday rotation, got %v", suggestions)
+	}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ns.Rotations[2].Start.Equal(day(21)) {
+		t.Errorf("expected the next rotation back on Tuesday %s, got %s", day(21).Format(time.RFC3339), ns.Rotations[2])
+	}
+}
@@ -0,0 +1,245 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A WeekWindow is a span of each week, such as weekdays or weekends, staffed
+// from its own users in its own round-robin.
+type WeekWindow struct {
+	// Identifies the window in the Window of each of its rotations.
+	Name string
+	// When the window starts and ends each week, as a day of the week and a
+	// time on a 24-hour clock in Timezone, e.g. "Friday 17:00".
+	Start string
+	End string
+	// The users to schedule in the window. Like Users, Generate leaves them
+	// ordered so that whoever is next comes first.
+	Users []string
+}
+
+// A time of the week a window starts or ends at.
+type weekTime struct {
+	day time.Weekday
+	hour, min int
+}
+
+// Parse a window's Start or End, e.g. "Friday 17:00".
+func parseWeekTime(text string) (weekTime, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 2 {
+		day, dayErr := parseWeekday(fields[0])
+		hour, min, timeErr := parseHandoffTime(fields[1])
+		if dayErr == nil && timeErr == nil {
+			return weekTime{day, hour, min}, nil
+		}
+	}
+	return weekTime{}, fmt.Errorf("must be a day of the week and a time on a 24-hour clock, e.g. %q (got %q)", "Friday 17:00", text)
+}
+
+// How far into the week, counted from midnight at the start of Sunday, w is.
+func (w weekTime) offset() time.Duration {
+	return time.Duration(w.day) * Day + time.Duration(w.hour) * time.Hour + time.Duration(w.min) * time.Minute
+}
+
+func (s Schedule) validateWindows() error {
+	if len(s.Windows) == 0 {
+		return nil
+	}
+	for _, f := range []struct {
+		name string
+		set bool
+	}{
+		{"RotationLength", s.RotationLength != ""},
+		{"HandoffTime", s.HandoffTime != ""},
+		{"StartWeekday", s.StartWeekday != ""},
+		{"PrimaryUsers or SecondaryUsers", s.separatePools()},
+		{"Pauses", len(s.Pauses) > 0},
+		{"Freezes", len(s.Freezes) > 0},
+		{"Gaps", len(s.Gaps) > 0},
+	} {
+		if f.set {
+			return fmt.Errorf("%s cannot be used with Windows, which set when each rotation starts and ends and who it is taken from", f.name)
+		}
+	}
+	names := map[string]bool{}
+	starts := make([]time.Duration, len(s.Windows))
+	ends := make([]time.Duration, len(s.Windows))
+	for i, w := range s.Windows {
+		if w.Name == "" {
+			return fmt.Errorf("Windows cannot include a window with no Name")
+		}
+		if names[w.Name] {
+			return fmt.Errorf("Windows lists %s more than once", w.Name)
+		}
+		names[w.Name] = true
+		start, err := parseWeekTime(w.Start)
+		if err != nil {
+			return fmt.Errorf("error parsing Start of window %s: %s", w.Name, err)
+		}
+		end, err := parseWeekTime(w.End)
+		if err != nil {
+			return fmt.Errorf("error parsing End of window %s: %s", w.Name, err)
+		}
+		starts[i], ends[i] = start.offset(), end.offset()
+		if len(w.Users) == 0 {
+			return fmt.Errorf("must provide at least 1 user in window %s", w.Name)
+		}
+		seen := map[string]bool{}
+		for _, u := range w.Users {
+			if seen[u] {
+				return fmt.Errorf("window %s lists %s more than once", w.Name, u)
+			}
+			seen[u] = true
+		}
+		if len(w.Users) < len(s.tiers()) && !s.AllowTierCollapse {
+			return fmt.Errorf("%d users in window %s cannot fill %d tiers (set AllowTierCollapse to leave the highest tiers unfilled)", len(w.Users), w.Name, len(s.tiers()))
+		}
+	}
+	// Each window must end exactly where the next to start begins.
+	order := make([]int, len(s.Windows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return starts[order[i]] < starts[order[j]]
+	})
+	for k, i := range order {
+		j := order[(k + 1) % len(order)]
+		w, next := s.Windows[i], s.Windows[j]
+		length := modWeek(ends[i] - starts[i])
+		if length == 0 {
+			length = Week
+		}
+		until := modWeek(starts[j] - starts[i])
+		if until == 0 {
+			until = Week
+		}
+		switch {
+		case length < until:
+			return fmt.Errorf("window %s ends at %s but the next window, %s, starts at %s, leaving a gap; Windows must cover the week", w.Name, w.End, next.Name, next.Start)
+		case length > until:
+			return fmt.Errorf("window %s ends at %s, after the next window, %s, starts at %s; Windows cannot overlap", w.Name, w.End, next.Name, next.Start)
+		}
+	}
+	return nil
+}
+
+func modWeek(d time.Duration) time.Duration {
+	return (d % Week + Week) % Week
+}
+
+// Return the name of the window covering t, or "" if Windows is not set.
+func (s Schedule) windowAt(t time.Time) string {
+	if len(s.Windows) == 0 {
+		return ""
+	}
+	loc := s.weekdayLocation(t)
+	local := t.In(loc)
+	// The window that most recently started, looking back up to a week.
+	for d := 0; d <= 7; d++ {
+		latest, name := time.Time{}, ""
+		for _, w := range s.Windows {
+			start, err := parseWeekTime(w.Start)
+			if err != nil {
+				// Validate rejects this before any rotation is computed.
+				continue
+			}
+			b := wallClock(local.Year(), local.Month(), local.Day() - d, start.hour, start.min, loc)
+			if b.Weekday() == start.day && !b.After(t) && b.After(latest) {
+				latest, name = b, w.Name
+			}
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Return the first time after t at which a window starts.
+func (s Schedule) nextWindowStart(t time.Time) time.Time {
+	loc := s.weekdayLocation(t)
+	local := t.In(loc)
+	for d := 0; d <= 7; d++ {
+		earliest := time.Time{}
+		for _, w := range s.Windows {
+			start, err := parseWeekTime(w.Start)
+			if err != nil {
+				continue
+			}
+			b := wallClock(local.Year(), local.Month(), local.Day() + d, start.hour, start.min, loc)
+			if b.Weekday() == start.day && b.After(t) && (earliest.IsZero() || b.Before(earliest)) {
+				earliest = b
+			}
+		}
+		if !earliest.IsZero() {
+			return earliest
+		}
+	}
+	return t.Add(Week)
+}
+
+// The length of a typical rotation, for estimating how many fit in a span of
+// time: RotationLength, or with Windows the shortest window, or with Regions
+// the shortest region's shift.
+func (s Schedule) typicalLength() time.Duration {
+	if len(s.Regions) > 0 {
+		return s.shortestRegion()
+	}
+	if len(s.Windows) == 0 {
+		return s.RotationDuration
+	}
+	shortest := Week
+	for _, w := range s.Windows {
+		start, err1 := parseWeekTime(w.Start)
+		end, err2 := parseWeekTime(w.End)
+		if length := modWeek(end.offset() - start.offset()); err1 == nil && err2 == nil && length > 0 && length < shortest {
+			shortest = length
+		}
+	}
+	return shortest
+}
+
+// The rotations of rs in the named window.
+func inWindow(rs []Rotation, name string) []Rotation {
+	in := []Rotation{}
+	for _, r := range rs {
+		if r.Window == name {
+			in = append(in, r)
+		}
+	}
+	return in
+}
+
+// Add rotations to ns, each covering one occurrence of a window, for as long
+// as more says to. Each window continues its own round-robin from its last
+// rotation in rs, moved on by the number of its occurrences skipped.
+func (ns *Schedule) addWindowRotations(rs []Rotation, more func() bool, skipped map[string]int) error {
+	orders := map[string][]string{}
+	next := map[string]int{}
+	for _, w := range ns.Windows {
+		order := ns.continueOrder(w.Users, inWindow(rs, w.Name), fmt.Sprintf("Users of window %s", w.Name))
+		orders[w.Name] = rotateUsers(order, skipped[w.Name])
+	}
+	for more() {
+		name := ns.windowAt(ns.Start)
+		p, err := ns.addRotation(orders[name], next[name])
+		if err != nil {
+			return err
+		}
+		next[name] = p
+		ns.Rotations[len(ns.Rotations)-1].Window = name
+	}
+	// Leave each window's users in the order they are next taken in.
+	windows := make([]WeekWindow, len(ns.Windows))
+	for i, w := range ns.Windows {
+		w.Users = nextUsers(orders[w.Name], lastPrimary(inWindow(ns.Rotations, w.Name)))
+		windows[i] = w
+	}
+	ns.Windows = windows
+	return nil
+}
@@ -0,0 +1,141 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const WindowsScheduleText = `{
+	"Start": "2017-02-06T09:00:00Z",
+	"ScheduleFor": "336h",
+	"Windows": [
+		{"Name": "weekday", "Start": "Monday 09:00", "End": "Friday 17:00", "Users": ["a", "b", "c"]},
+		{"Name": "weekend", "Start": "Friday 17:00", "End": "Monday 09:00", "Users": ["d", "e"]}
+	]
+}`
+
+func WindowsSchedule(t *testing.T) *Schedule {
+	s, err := NewSchedule([]byte(WindowsScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWindowsGenerate(t *testing.T) {
+	s := WindowsSchedule(t)
+	s.now = s.Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"2017-02-06T09:00:00Z/2017-02-10T17:00:00Z a b weekday",
+		"2017-02-10T17:00:00Z/2017-02-13T09:00:00Z d e weekend",
+		"2017-02-13T09:00:00Z/2017-02-17T17:00:00Z b c weekday",
+		"2017-02-17T17:00:00Z/2017-02-20T09:00:00Z e d weekend",
+		"2017-02-20T09:00:00Z/2017-02-24T17:00:00Z c a weekday",
+	}
+	if len(ns.Rotations) != len(expected) {
+		t.Fatalf("expected %d rotations, got %v", len(expected), ns.Rotations)
+	}
+	for i, r := range ns.Rotations {
+		if got := r.String() + " " + r.Window; got != expected[i] {
+			t.Errorf("rotation %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+	if users := ns.Windows[0].Users; strings.Join(users, ",") != "a,b,c" {
+		t.Errorf("expected the weekday users to be left with a next, got %v", users)
+	}
+	if users := ns.Windows[1].Users; strings.Join(users, ",") != "d,e" {
+		t.Errorf("expected the weekend users to be left with d next, got %v", users)
+	}
+	if s.Windows[1].Users[0] != "d" || len(s.Rotations) != 0 {
+		t.Errorf("expected Generate to leave the schedule alone")
+	}
+}
+
+func TestWindowsContinueEachRoundRobin(t *testing.T) {
+	s := WindowsSchedule(t)
+	s.now = s.Start
+	first, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A week on, partway through the second weekend.
+	first.now = time.Date(2017, time.February, 18, 12, 0, 0, 0, time.UTC)
+	second, err := first.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := second.Rotations[0]; !r.Start.Equal(first.Rotations[2].Start) {
+		t.Errorf("expected the rotation before the current one to be kept first, got %s", r)
+	}
+	// The three kept are followed by four more, each window taking up where
+	// its own round-robin left off.
+	added := second.Rotations[3:]
+	if len(added) != 4 {
+		t.Fatalf("expected 4 rotations added, got %v", added)
+	}
+	for i, expected := range []string{"d e weekend", "a b weekday", "e d weekend", "b c weekday"} {
+		r := added[i]
+		if got := r.Primary + " " + r.Secondary + " " + r.Window; got != expected {
+			t.Errorf("added rotation %d: expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestWindowsInTimezone(t *testing.T) {
+	s := WindowsSchedule(t)
+	s.Timezone = "America/New_York"
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.location = loc
+	// Clocks go forward on Sunday 12 March 2017, during the weekend.
+	s.Start = time.Date(2017, time.March, 6, 9, 0, 0, 0, loc)
+	s.now = s.Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ns.Rotations {
+		local := r.Start.In(loc)
+		if h, m, _ := local.Clock(); (h != 9 || m != 0) && (h != 17 || m != 0) {
+			t.Errorf("expected rotations to hand off at 09:00 or 17:00 local time, got %s", local)
+		}
+	}
+	if weekend := ns.Rotations[1]; ns.RotationEnd(1).Sub(weekend.Start) != 63 * time.Hour {
+		t.Errorf("expected the weekend over the clocks going forward to be 63 hours, got %s", ns.RotationEnd(1).Sub(weekend.Start))
+	}
+}
+
+func TestValidateWindows(t *testing.T) {
+	for _, c := range []struct {
+		edit func(s *Schedule)
+		err string
+	}{
+		{func(s *Schedule) { s.Windows[0].End = "Friday 16:00" }, "leaving a gap"},
+		{func(s *Schedule) { s.Windows[1].End = "Monday 10:00" }, "Windows cannot overlap"},
+		{func(s *Schedule) { s.Windows[1].Start = "Friday 5pm" }, "error parsing Start of window weekend"},
+		{func(s *Schedule) { s.Windows[1].Name = "weekday" }, "Windows lists weekday more than once"},
+		{func(s *Schedule) { s.Windows[1].Users = []string{"d"} }, "1 users in window weekend cannot fill 2 tiers"},
+		{func(s *Schedule) { s.RotationLength, s.RotationDuration = "168h", Week }, "RotationLength cannot be used with Windows"},
+		{func(s *Schedule) { s.HandoffTime = "09:00" }, "HandoffTime cannot be used with Windows"},
+	} {
+		s := WindowsSchedule(t)
+		c.edit(s)
+		if err := s.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error %q, got %v", c.err, err)
+		}
+	}
+
+	// A single window may cover the whole week.
+	s := WindowsSchedule(t)
+	s.Windows = []WeekWindow{{Name: "all", Start: "Monday 09:00", End: "Monday 09:00", Users: []string{"a", "b"}}}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected a single window covering the week to be valid, got %s", err)
+	}
+}
@@ -0,0 +1,212 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NewScheduleYAML parses a schedule written as YAML. The YAML is read as the
+// equivalent JSON document, so it has the same fields, migrations, defaults
+// and validation as one read by NewSchedule. Errors in the document itself
+// give the line they were found on.
+func NewScheduleYAML(text []byte, opts ...ParseOption) (*Schedule, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(text, &doc); err != nil {
+		return nil, &ParseError{Op: "parsing", Field: "schedule", Err: err}
+	}
+	if doc.Kind == 0 {
+		return nil, &ParseError{Op: "parsing", Field: "schedule", Err: errors.New("the document is empty")}
+	}
+	j := &yamlToJSON{}
+	if err := j.write(&doc); err != nil {
+		return nil, &ParseError{Op: "parsing", Field: "schedule", Err: err}
+	}
+	// A mistyped field is found again in the JSON as written, whose offsets
+	// still correspond to the YAML's lines, unless migrating it fixed the
+	// field.
+	if migrated, _, err := Migrate(j.out.Bytes()); err == nil && json.Unmarshal(migrated, &Input{}) != nil {
+		if err := json.Unmarshal(j.out.Bytes(), &Input{}); err != nil {
+			if bad := walkJSON(j.out.Bytes(), 0, reflect.TypeOf(Input{}), "", nil); bad != nil && bad.path != "" {
+				err := bad.err
+				if line, ok := j.line(bad.offset + 1); ok {
+					err = fmt.Errorf("line %d: %w", line, err)
+				}
+				return nil, &ParseError{Op: "parsing", Field: bad.path, Value: bad.value(), Err: err}
+			}
+			return nil, &ParseError{Op: "parsing", Field: "schedule", Err: j.locate(err)}
+		}
+	}
+	return NewSchedule(j.out.Bytes(), opts...)
+}
+
+// Writes a YAML document as JSON, remembering the YAML line each JSON value
+// came from.
+type yamlToJSON struct {
+	out bytes.Buffer
+	// The offset in out at which each value starts, in order, and its line.
+	offsets []int
+	lines []int
+}
+
+func (j *yamlToJSON) write(n *yaml.Node) error {
+	j.offsets = append(j.offsets, j.out.Len())
+	j.lines = append(j.lines, n.Line)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		return j.write(n.Content[0])
+	case yaml.AliasNode:
+		return j.write(n.Alias)
+	case yaml.MappingNode:
+		j.out.WriteString("{")
+		for i := 0; i + 1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Kind != yaml.ScalarNode || key.Tag == "!!merge" {
+				return fmt.Errorf("line %d: only plain keys are supported", key.Line)
+			}
+			if i > 0 {
+				j.out.WriteString(",")
+			}
+			k, _ := json.Marshal(key.Value)
+			j.out.Write(k)
+			j.out.WriteString(":")
+			if err := j.write(value); err != nil {
+				return err
+			}
+		}
+		j.out.WriteString("}")
+	case yaml.SequenceNode:
+		j.out.WriteString("[")
+		for i, c := range n.Content {
+			if i > 0 {
+				j.out.WriteString(",")
+			}
+			if err := j.write(c); err != nil {
+				return err
+			}
+		}
+		j.out.WriteString("]")
+	case yaml.ScalarNode:
+		var v interface{} = n.Value
+		switch n.ShortTag() {
+		case "!!null", "!!bool", "!!int", "!!float":
+			if err := n.Decode(&v); err != nil {
+				return fmt.Errorf("line %d: %s", n.Line, err)
+			}
+		}
+		text, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", n.Line, err)
+		}
+		j.out.Write(text)
+	}
+	return nil
+}
+
+// Prefix err with the line of the value it is about, if it says where in the
+// JSON that is.
+func (j *yamlToJSON) locate(err error) error {
+	offset := -1
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		offset = int(e.Offset)
+	case *json.SyntaxError:
+		offset = int(e.Offset)
+	}
+	if line, ok := j.line(offset); ok {
+		return fmt.Errorf("line %d: %s", line, err)
+	}
+	return err
+}
+
+// Return the YAML line of the JSON value that ends at offset, or runs past it.
+func (j *yamlToJSON) line(offset int) (int, bool) {
+	// The value is the last to start before offset.
+	i := sort.SearchInts(j.offsets, offset) - 1
+	if offset < 0 || i < 0 {
+		return 0, false
+	}
+	return j.lines[i], true
+}
+
+// MarshalYAML writes s as YAML with the same fields, in the same order, as
+// its JSON.
+func (s *Schedule) MarshalYAML() (interface{}, error) {
+	text, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(text))
+	d.UseNumber()
+	return jsonToYAML(d)
+}
+
+// Read the next JSON value from d as a YAML node.
+func jsonToYAML(d *json.Decoder) (*yaml.Node, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		if t == '[' {
+			n.Kind = yaml.SequenceNode
+		}
+		for d.More() {
+			if n.Kind == yaml.MappingNode {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			c, err := jsonToYAML(d)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, c)
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		if len(n.Content) == 0 {
+			n.Style = yaml.FlowStyle
+		}
+		return n, nil
+	case string:
+		n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}
+		// Times are left unquoted, as YAML reads them back as the same text.
+		if tag := (&yaml.Node{Kind: yaml.ScalarNode, Value: t}).ShortTag(); tag == "!!timestamp" {
+			n.Tag = tag
+		}
+		return n, nil
+	case json.Number:
+		tag := "!!int"
+		if _, err := strconv.ParseInt(t.String(), 10, 64); err != nil {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// WriteYAML writes s to w as YAML, indented by two spaces.
+func (s *Schedule) WriteYAML(w io.Writer) error {
+	e := yaml.NewEncoder(w)
+	e.SetIndent(2)
+	if err := e.Encode(s); err != nil {
+		return err
+	}
+	return e.Close()
+}
@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const EmptyScheduleYAML = `
+# Who is on call for the widget service.
+Users:
+  - a
+  - b
+  - c
+Start: 2017-02-01T10:00:00Z
+RotationLength: 168h
+ScheduleFor: 504h # three weeks
+`
+
+func TestNewScheduleYAML(t *testing.T) {
+	s, err := NewScheduleYAML([]byte(EmptyScheduleYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := NewSchedule([]byte(EmptyScheduleText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, expected, s)
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	s, err := NewScheduleYAML([]byte(EmptyScheduleYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Users whose names YAML would otherwise read as other types.
+	s.Users = []string{"yes", "1", "null"}
+	s.now = Start
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := ns.WriteYAML(out); err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewScheduleYAML(out.Bytes())
+	if err != nil {
+		t.Fatalf("error reading back:\n%s\n%s", out.String(), err)
+	}
+	assertSameJSON(t, ns, again)
+}
+
+func TestWriteYAMLKeepsFieldOrder(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := FilledSchedule().WriteYAML(out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	last := -1
+	for _, field := range []string{"FormatVersion:", "Users:", "Start:", "RotationLength:", "ScheduleFor:", "Rotations:"} {
+		i := strings.Index(text, "\n" + field)
+		if i < 0 && strings.HasPrefix(text, field) {
+			i = 0
+		}
+		if i <= last {
+			t.Errorf("expected %s after the fields before it, got:\n%s", field, text)
+		}
+		last = i
+	}
+	if !strings.Contains(text, "  - Start: 2017-02-01T10:00:00Z\n") {
+		t.Errorf("expected rotation times to be written unquoted, got:\n%s", text)
+	}
+}
+
+func TestNewScheduleYAMLErrorsGiveLine(t *testing.T) {
+	cases := []struct {
+		text string
+		err string
+	}{
+		{"Users:\n  - a\nStart: [\n", "line 3"},
+		{"Users:\n  - a\nRotationLength: 168h\nScheduleFor:\n  weeks: 3\n", "line 5"},
+		{"Users: a\nRotationLength: 168h\n", "line 1"},
+		{"Users: [a, b]\nStart: 2017-02-01T10:00:00Z\nRotationLength: 168h\nScheduleFor: 504h\nRotations:\n  - Start: 2017-02-01T10:00:00Z\n    Primary: [a]\n", "line 7"},
+		{"Users: [a, b]\nStart: 2017-02-01T10:00:00Z\nRotationLength: 168h\nScheduleFor: 504h\nRotations:\n  - Start: 2017-02-01T10:00:00Z\n  - Start: 2017-02-30T10:00:00Z\n", "error parsing Rotations[1].Start: line 7: parsing time"},
+	}
+	for _, c := range cases {
+		_, err := NewScheduleYAML([]byte(c.text))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected an error at %s for:\n%s\ngot %v", c.err, c.text, err)
+		}
+	}
+}
+
+func TestNewScheduleYAMLValidates(t *testing.T) {
+	_, err := NewScheduleYAML([]byte("Users: [a]\nStart: 2017-02-01T10:00:00Z\nRotationLength: often\nScheduleFor: 504h\n"))
+	if err == nil || !strings.Contains(err.Error(), "error parsing RotationLength") {
+		t.Errorf("expected an error about RotationLength, got %v", err)
+	}
+}
+
+func assertSameJSON(t *testing.T, expected, got *Schedule) {
+	e, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(e, g) {
+		t.Errorf("expected:\n%s\ngot:\n%s", e, g)
+	}
+}
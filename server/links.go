@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/websdev/oncallator/links"
+	"github.com/websdev/oncallator/schedule"
+)
+
+var linkPage = template.Must(template.New("link").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>On call</title></head>
+<body><p>{{.}}</p></body>
+</html>
+`))
+
+// Serve a link: verify it, take the action it authorizes and render a page
+// saying what happened.
+func (s *Server) serveLink(w http.ResponseWriter, r *http.Request) {
+	if s.Links == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("%s requires GET", r.URL.Path), http.StatusMethodNotAllowed)
+		return
+	}
+	message, status, err := s.handleLink(r)
+	if err != nil {
+		message = err.Error()
+	} else {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	linkPage.Execute(w, message)
+}
+
+// Take the action a link authorizes, returning a message confirming it, or an
+// error and its status.
+func (s *Server) handleLink(r *http.Request) (string, int, error) {
+	c, err := s.Links.Verify(r.URL.Query())
+	switch err {
+	case nil:
+	case links.ErrBadSignature:
+		return "", http.StatusForbidden, err
+	case links.ErrExpired, links.ErrUsed:
+		return "", http.StatusGone, err
+	default:
+		return "", http.StatusInternalServerError, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, status, err := s.load()
+	if err != nil {
+		return "", status, err
+	}
+	i := sched.RotationByID(c.RotationID)
+	if i < 0 {
+		return "", http.StatusNotFound, fmt.Errorf("the rotation this link is for is no longer in the schedule")
+	}
+	start := sched.Rotations[i].Start.Format(time.RFC3339)
+	var ns *schedule.Schedule
+	var message string
+	switch c.Action {
+	case links.ActionAcknowledge:
+		ns, err = sched.Acknowledge(c.RotationID, c.User)
+		message = fmt.Sprintf("%s acknowledged the rotation starting at %s.", c.User, start)
+	case links.ActionSwap:
+		ns, err = sched.Swap(c.User, c.With, sched.Rotations[i].Start, c.Until)
+		message = fmt.Sprintf("%s and %s swapped places in the rotations from %s to %s.", c.User, c.With, start, c.Until.UTC().Format(time.RFC3339))
+	default:
+		return "", http.StatusBadRequest, fmt.Errorf("unknown action %q", c.Action)
+	}
+	if err != nil {
+		return "", http.StatusUnprocessableEntity, err
+	}
+	if err := s.Storage.Save(ns); err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("error saving schedule: %s", err)
+	}
+	return message, 0, nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/links"
+	"github.com/websdev/oncallator/schedule"
+)
+
+func newLinkServer(t *testing.T) (*Server, *schedule.Schedule) {
+	m, _ := newStorage(t)
+	sched, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.pending = 0
+	signer := &links.Signer{Secret: []byte("secret"), BaseURL: "/link", Nonces: &links.MemoryNonces{}}
+	return &Server{Storage: m, Token: "token", Links: signer}, sched
+}
+
+func TestLinkAcknowledge(t *testing.T) {
+	srv, sched := newLinkServer(t)
+	id := sched.Rotations[1].ID
+	u, err := srv.Links.URL(id, "c", links.ActionAcknowledge, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Links need no bearer token.
+	w := get(t, srv, "GET", u)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "c acknowledged the rotation") {
+		t.Fatalf("expected the link to be confirmed, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("expected an HTML page, got %s", w.Header().Get("Content-Type"))
+	}
+	saved, err := srv.Storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := saved.Rotations[saved.RotationByID(id)]; len(r.Acknowledged) != 1 || r.Acknowledged[0] != "c" {
+		t.Errorf("expected c's acknowledgment to be saved, got %+v", r)
+	}
+
+	if w := get(t, srv, "GET", u); w.Code != http.StatusGone {
+		t.Errorf("expected a used link to be refused, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestLinkSwap(t *testing.T) {
+	srv, sched := newLinkServer(t)
+	u, err := srv.Links.SwapURL(sched.Rotations[1].ID, "a", "c", sched.RotationEnd(2), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := get(t, srv, "GET", u); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "a and c swapped places") {
+		t.Fatalf("expected the swap to be confirmed, got %d: %s", w.Code, w.Body)
+	}
+	saved, err := srv.Storage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Rotations[1].Secondary + saved.Rotations[2].Primary; got != "aa" {
+		t.Errorf("expected a to take c's shifts, got %v", saved.Rotations[1:3])
+	}
+}
+
+func TestLinkRefused(t *testing.T) {
+	srv, sched := newLinkServer(t)
+	id := sched.Rotations[1].ID
+	expired, err := srv.Links.URL(id, "c", links.ActionAcknowledge, -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := srv.Links.URL(id, "c", links.ActionAcknowledge, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := parsed.Query()
+	q.Set("user", "b")
+	tampered := "/link?" + q.Encode()
+	notOnCall, err := srv.Links.URL(id, "a", links.ActionAcknowledge, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name, path string
+		status int
+		expected string
+	}{
+		{"expired", expired, http.StatusGone, "link has expired"},
+		{"tampered", tampered, http.StatusForbidden, "link signature is invalid"},
+		{"not on call", notOnCall, http.StatusUnprocessableEntity, "a is not on call"},
+	} {
+		w := get(t, srv, "GET", c.path)
+		if w.Code != c.status || !strings.Contains(w.Body.String(), c.expected) {
+			t.Errorf("%s: expected %d containing %q, got %d: %s", c.name, c.status, c.expected, w.Code, w.Body)
+		}
+	}
+	if srv.Storage.(*memStorage).saves != 0 {
+		t.Errorf("expected nothing to be saved")
+	}
+
+	srv.Links = nil
+	if w := get(t, srv, "GET", valid); w.Code != http.StatusNotFound {
+		t.Errorf("expected links to be 404 without a Signer, got %d", w.Code)
+	}
+}
@@ -0,0 +1,244 @@
+// Package server serves a schedule over HTTP, for dashboards and bots to ask
+// who is on call, and regenerates it on request. It speaks the API the client
+// package queries.
+//
+// A time no rotation covers, whether before the schedule, after it, or in a
+// gap, is answered 404 Not Found, as is a user with no upcoming shifts.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/websdev/oncallator/links"
+	"github.com/websdev/oncallator/schedule"
+)
+
+// Storage holds the schedule a Server serves.
+type Storage interface {
+	Load() (*schedule.Schedule, error)
+	// Save replaces the schedule atomically: Load returns either the old
+	// schedule or s, never a mix of the two.
+	Save(s *schedule.Schedule) error
+}
+
+// FileStorage stores a schedule as JSON in a file, replacing the file by
+// renaming a new one over it.
+type FileStorage struct {
+	Path string
+}
+
+func (f FileStorage) Load() (*schedule.Schedule, error) {
+	text, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.NewSchedule(text)
+}
+
+func (f FileStorage) Save(s *schedule.Schedule) error {
+	text, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), filepath.Base(f.Path) + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(text); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// A Server is an http.Handler serving the schedule in its Storage at these
+// paths, relative to wherever it is mounted (e.g. with http.StripPrefix):
+//
+//	GET /schedule              the schedule
+//	GET /oncall?at=RFC3339     the rotation covering at, by default now,
+//	                           including elapsed rotations in History
+//	GET /upcoming?n=N          the rotation under way and the ones after it,
+//	                           N in all, by default 1
+//	GET /shifts?user=NAME      the shifts of user that have not ended
+//	POST /generate             regenerate the schedule, save it and return it
+//	GET /link?...              act on the schedule as a signed link from Links
+//	                           says, and confirm it with an HTML page
+//
+// Responses are JSON, except to links, which are opened in a browser.
+// Generation and the actions of links are serialized, so concurrent requests
+// each change the schedule the last one saved.
+type Server struct {
+	Storage Storage
+	// If set, requests must carry it as a bearer token, except to follow a
+	// link, which carries its own signature instead.
+	Token string
+	// Verifies links. If nil, links are not served.
+	Links *links.Signer
+
+	mu sync.Mutex
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSuffix(r.URL.Path, "/") == "/link" {
+		s.serveLink(w, r)
+		return
+	}
+	if s.Token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+	}
+	method, handle := "GET", handler(nil)
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/schedule":
+		handle = s.handleSchedule
+	case "/oncall":
+		handle = s.handleOnCall
+	case "/upcoming":
+		handle = s.handleUpcoming
+	case "/shifts":
+		handle = s.handleShifts
+	case "/generate":
+		method, handle = "POST", s.handleGenerate
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, fmt.Sprintf("%s requires %s", r.URL.Path, method), http.StatusMethodNotAllowed)
+		return
+	}
+	v, status, err := handle(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	text, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(text)
+}
+
+// A handler returns the value to respond with, or an error and its status.
+type handler func(r *http.Request) (interface{}, int, error)
+
+// Load the schedule, or return an error and its status.
+func (s *Server) load() (*schedule.Schedule, int, error) {
+	sched, err := s.Storage.Load()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("error loading schedule: %s", err)
+	}
+	return sched, 0, nil
+}
+
+func (s *Server) handleSchedule(r *http.Request) (interface{}, int, error) {
+	sched, status, err := s.load()
+	if err != nil {
+		return nil, status, err
+	}
+	return sched, 0, nil
+}
+
+func (s *Server) handleOnCall(r *http.Request) (interface{}, int, error) {
+	at := time.Now()
+	if a := r.URL.Query().Get("at"); a != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, a); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("error parsing at: %s", err)
+		}
+	}
+	sched, status, err := s.load()
+	if err != nil {
+		return nil, status, err
+	}
+	rotation, err := sched.At(at, schedule.SearchHistory)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	return rotation, 0, nil
+}
+
+func (s *Server) handleUpcoming(r *http.Request) (interface{}, int, error) {
+	n := 1
+	if q := r.URL.Query().Get("n"); q != "" {
+		var err error
+		if n, err = strconv.Atoi(q); err != nil || n < 1 {
+			return nil, http.StatusBadRequest, fmt.Errorf("n must be a positive integer (got %q)", q)
+		}
+	}
+	sched, status, err := s.load()
+	if err != nil {
+		return nil, status, err
+	}
+	t := time.Now()
+	i := len(sched.Rotations)
+	for j, rotation := range sched.Rotations {
+		if !rotation.Start.After(t) && sched.RotationEnd(j).After(t) {
+			i = j
+			break
+		}
+	}
+	if i == len(sched.Rotations) {
+		return nil, http.StatusNotFound, errors.New("no rotation is under way")
+	}
+	rs := sched.Rotations[i:]
+	if len(rs) > n {
+		rs = rs[:n]
+	}
+	return rs, 0, nil
+}
+
+func (s *Server) handleShifts(r *http.Request) (interface{}, int, error) {
+	user := r.URL.Query().Get("user")
+	if user == "" {
+		return nil, http.StatusBadRequest, errors.New("user is required")
+	}
+	sched, status, err := s.load()
+	if err != nil {
+		return nil, status, err
+	}
+	shifts := sched.ShiftsFor(user)
+	if len(shifts) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("%s has no upcoming shifts", user)
+	}
+	return shifts, 0, nil
+}
+
+func (s *Server) handleGenerate(r *http.Request) (interface{}, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, status, err := s.load()
+	if err != nil {
+		return nil, status, err
+	}
+	ns, err := sched.Generate()
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, fmt.Errorf("error generating schedule: %s", err)
+	}
+	if err := s.Storage.Save(ns); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("error saving schedule: %s", err)
+	}
+	return ns, 0, nil
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/websdev/oncallator/client"
+	"github.com/websdev/oncallator/schedule"
+)
+
+// A Storage in memory, noting whether generation ever overlapped.
+type memStorage struct {
+	mu sync.Mutex
+	text []byte
+	saves int
+	// How many loads have not yet been followed by a save, and whether that
+	// was ever more than one.
+	pending int
+	overlapped bool
+}
+
+func (m *memStorage) Load() (*schedule.Schedule, error) {
+	m.mu.Lock()
+	m.pending++
+	if m.pending > 1 {
+		m.overlapped = true
+	}
+	text := m.text
+	m.mu.Unlock()
+	// Give a concurrent request the chance to load the same schedule.
+	time.Sleep(time.Millisecond)
+	return schedule.NewSchedule(text)
+}
+
+func (m *memStorage) Save(s *schedule.Schedule) error {
+	text, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text = text
+	m.saves++
+	m.pending--
+	return nil
+}
+
+// A schedule whose first rotation started two days ago.
+func newStorage(t *testing.T) (*memStorage, time.Time) {
+	start := time.Now().UTC().Truncate(time.Hour).Add(-48 * time.Hour)
+	s, err := schedule.NewSchedule([]byte(fmt.Sprintf(`{
+		"Users": ["a", "b", "c"],
+		"Start": %q,
+		"RotationLength": "168h",
+		"ScheduleFor": "504h"
+	}`, start.Format(time.RFC3339))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &memStorage{}
+	m.Save(ns)
+	m.saves, m.pending = 0, 0
+	return m, start
+}
+
+func get(t *testing.T, h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestOnCall(t *testing.T) {
+	m, start := newStorage(t)
+	srv := &Server{Storage: m}
+	for _, c := range []struct {
+		path string
+		status int
+		primary string
+	}{
+		{"/oncall", http.StatusOK, "a"},
+		{"/oncall?at=" + start.Add(8 * 24 * time.Hour).Format(time.RFC3339), http.StatusOK, "b"},
+		{"/oncall?at=" + start.Add(-time.Hour).Format(time.RFC3339), http.StatusNotFound, ""},
+		{"/oncall?at=" + start.Add(365 * 24 * time.Hour).Format(time.RFC3339), http.StatusNotFound, ""},
+		{"/oncall?at=tomorrow", http.StatusBadRequest, ""},
+	} {
+		w := get(t, srv, "GET", c.path)
+		if w.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d: %s", c.path, c.status, w.Code, w.Body)
+			continue
+		}
+		if c.primary == "" {
+			continue
+		}
+		r := schedule.Rotation{}
+		if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil || r.Primary != c.primary {
+			t.Errorf("%s: expected primary %s, got %s (%v)", c.path, c.primary, w.Body, err)
+		}
+	}
+}
+
+func TestUpcomingAndShifts(t *testing.T) {
+	m, _ := newStorage(t)
+	srv := &Server{Storage: m}
+	rs := []schedule.Rotation{}
+	w := get(t, srv, "GET", "/upcoming?n=2")
+	if err := json.Unmarshal(w.Body.Bytes(), &rs); err != nil || len(rs) != 2 || rs[0].Primary != "a" || rs[1].Primary != "b" {
+		t.Errorf("expected the current and next rotations, got %s (%v)", w.Body, err)
+	}
+	if w := get(t, srv, "GET", "/upcoming?n=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected n=0 to be rejected, got %d", w.Code)
+	}
+
+	shifts := []schedule.Shift{}
+	w = get(t, srv, "GET", "/shifts?user=c")
+	if err := json.Unmarshal(w.Body.Bytes(), &shifts); err != nil || len(shifts) == 0 || shifts[0].Tier != schedule.TierSecondary {
+		t.Errorf("expected c's shifts, starting as secondary, got %s (%v)", w.Body, err)
+	}
+	if w := get(t, srv, "GET", "/shifts?user=nobody"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a user without shifts, got %d", w.Code)
+	}
+	if w := get(t, srv, "GET", "/shifts"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a user, got %d", w.Code)
+	}
+}
+
+func TestMethodsAndAuth(t *testing.T) {
+	m, _ := newStorage(t)
+	srv := &Server{Storage: m, Token: "secret"}
+	if w := get(t, srv, "GET", "/schedule"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", w.Code)
+	}
+	for _, c := range []struct {
+		method, path string
+		status int
+	}{
+		{"GET", "/schedule", http.StatusOK},
+		{"POST", "/schedule", http.StatusMethodNotAllowed},
+		{"GET", "/generate", http.StatusMethodNotAllowed},
+		{"GET", "/nope", http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(c.method, c.path, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		srv.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, w.Code)
+		}
+	}
+}
+
+func TestGenerateConcurrently(t *testing.T) {
+	m, _ := newStorage(t)
+	// Drop the later rotations for generation to restore.
+	s, _ := schedule.NewSchedule(m.text)
+	n := len(s.Rotations)
+	s.Rotations = s.Rotations[:1]
+	m.Save(s)
+	m.saves, m.pending = 0, 0
+
+	srv := &Server{Storage: m}
+	const requests = 10
+	var wg sync.WaitGroup
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- get(t, srv, "POST", "/generate").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("expected every generation to succeed, got %d", code)
+		}
+	}
+	if m.overlapped {
+		t.Errorf("expected generations to be serialized")
+	}
+	if m.saves != requests {
+		t.Errorf("expected %d saves, got %d", requests, m.saves)
+	}
+	ns, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns.Rotations) != n {
+		t.Errorf("expected %d rotations to be restored, got %d", n, len(ns.Rotations))
+	}
+}
+
+func TestClient(t *testing.T) {
+	m, start := newStorage(t)
+	httpServer := httptest.NewServer(http.StripPrefix("/api", &Server{Storage: m, Token: "secret"}))
+	defer httpServer.Close()
+	c := client.New(httpServer.URL + "/api", client.WithToken("secret"))
+	ctx := context.Background()
+	if r, err := c.OnCallAt(ctx, start.Add(time.Hour)); err != nil || r.Primary != "a" {
+		t.Errorf("expected a to be on call, got %s, %v", r, err)
+	}
+	if _, err := c.OnCallAt(ctx, start.Add(-time.Hour)); err != client.ErrNoRotation {
+		t.Errorf("expected ErrNoRotation, got %v", err)
+	}
+	if rs, err := c.Upcoming(ctx, 3); err != nil || len(rs) != 3 {
+		t.Errorf("expected 3 rotations, got %v, %v", rs, err)
+	}
+	if s, err := c.Schedule(ctx); err != nil || !s.Rotations[0].Start.Equal(start) {
+		t.Errorf("expected the schedule, got %v", err)
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	m, _ := newStorage(t)
+	s, _ := m.Load()
+	f := FileStorage{Path: filepath.Join(dir, "schedule.json")}
+	if err := f.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Rotations) != len(s.Rotations) {
+		t.Errorf("expected the schedule to round-trip, got %d rotations", len(loaded.Rotations))
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 || strings.Contains(files[0].Name(), ".tmp") {
+		t.Errorf("expected only the schedule to be left, got %v", files)
+	}
+}
@@ -0,0 +1,183 @@
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+// The default prefix of resource names.
+const DefaultName = "oncall"
+
+type HCLConfig struct {
+	// Maps schedule user names to PagerDuty user IDs. Every user assigned to a
+	// rotation must have an entry.
+	Users map[string]string
+	// The prefix of resource names, and of the names of the PagerDuty
+	// schedules. Defaults to DefaultName.
+	Name string
+}
+
+// WriteHCL writes pagerduty_schedule resources for each tier of the rotations
+// of s, for the PagerDuty Terraform provider.
+//
+// A tier whose rotations hand over in a fixed cycle of users, each turn
+// RotationLength long, is written as one schedule, e.g. oncall_primary, with a
+// layer cycling through the users from the first rotation. Any other tier,
+// e.g. one with hand-edited or overridden rotations, is written as a schedule
+// per rotation, e.g. oncall_primary_20170301t100000z, each with a layer
+// covering just that rotation. Since these are named after their start,
+// regenerating the schedule only adds and removes the schedules of the
+// rotations it adds and drops.
+func WriteHCL(w io.Writer, s *schedule.Schedule, c HCLConfig) error {
+	name := c.Name
+	if name == "" {
+		name = DefaultName
+	}
+	missing := map[string]bool{}
+	ids := func(users []string) []string {
+		ids := []string{}
+		for _, u := range users {
+			id, ok := c.Users[u]
+			if !ok {
+				missing[u] = true
+			}
+			ids = append(ids, fmt.Sprintf("%q", id))
+		}
+		return ids
+	}
+	timeZone := s.Timezone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	resources := []resource{}
+	for tier, tierName := range s.Tiers() {
+		if cycle, ok := regularCycle(s, tier); ok {
+			start := s.Rotations[0].Start.Format(time.RFC3339)
+			resources = append(resources, resource{
+				name: name + "_" + tierName,
+				title: name + " " + tierName,
+				timeZone: timeZone,
+				layer: [][2]string{
+					{"name", fmt.Sprintf("%q", tierName)},
+					{"start", fmt.Sprintf("%q", start)},
+					{"rotation_virtual_start", fmt.Sprintf("%q", start)},
+					{"rotation_turn_length_seconds", fmt.Sprint(int(s.RotationDuration.Seconds()))},
+					{"users", "[" + strings.Join(ids(cycle), ", ") + "]"},
+				},
+			})
+			continue
+		}
+		for i, r := range s.Rotations {
+			u := s.Assigned(r)[tier]
+			if u == "" || u == schedule.Unassigned {
+				continue
+			}
+			start, end := r.Start.Format(time.RFC3339), s.RotationEnd(i).Format(time.RFC3339)
+			resources = append(resources, resource{
+				name: name + "_" + tierName + "_" + strings.ToLower(r.Start.UTC().Format("20060102T150405Z")),
+				title: name + " " + tierName + " " + start,
+				timeZone: timeZone,
+				layer: [][2]string{
+					{"name", fmt.Sprintf("%q", tierName)},
+					{"start", fmt.Sprintf("%q", start)},
+					{"end", fmt.Sprintf("%q", end)},
+					{"rotation_virtual_start", fmt.Sprintf("%q", start)},
+					{"rotation_turn_length_seconds", fmt.Sprint(int(s.RotationEnd(i).Sub(r.Start).Seconds()))},
+					{"users", "[" + strings.Join(ids([]string{u}), ", ") + "]"},
+				},
+			})
+		}
+	}
+	if len(missing) > 0 {
+		users := []string{}
+		for u := range missing {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+		return fmt.Errorf("no PagerDuty user ID for users: %s", strings.Join(users, ", "))
+	}
+
+	b := bufio.NewWriter(w)
+	for i, r := range resources {
+		if i > 0 {
+			fmt.Fprintln(b)
+		}
+		r.write(b)
+	}
+	return b.Flush()
+}
+
+type resource struct {
+	name string
+	title string
+	timeZone string
+	// The attributes of the layer, as HCL.
+	layer [][2]string
+}
+
+// Write r as terraform fmt would.
+func (r resource) write(w io.Writer) {
+	fmt.Fprintf(w, "resource \"pagerduty_schedule\" %q {\n", r.name)
+	writeAttributes(w, "  ", [][2]string{{"name", fmt.Sprintf("%q", r.title)}, {"time_zone", fmt.Sprintf("%q", r.timeZone)}})
+	fmt.Fprintf(w, "\n  layer {\n")
+	writeAttributes(w, "    ", r.layer)
+	fmt.Fprintf(w, "  }\n}\n")
+}
+
+// Write attributes with their equals signs aligned.
+func writeAttributes(w io.Writer, indent string, attributes [][2]string) {
+	width := 0
+	for _, a := range attributes {
+		if len(a[0]) > width {
+			width = len(a[0])
+		}
+	}
+	for _, a := range attributes {
+		fmt.Fprintf(w, "%s%-*s = %s\n", indent, width, a[0], a[1])
+	}
+}
+
+// Return the cycle of users the given tier of the rotations of s follows, if
+// every rotation is RotationLength long, has no override, and is filled by the
+// user a fixed cycle of distinct users calls for.
+func regularCycle(s *schedule.Schedule, tier int) ([]string, bool) {
+	if len(s.Rotations) == 0 || len(s.Windows) > 0 {
+		return nil, false
+	}
+	users := []string{}
+	for i, r := range s.Rotations {
+		u := s.Assigned(r)[tier]
+		if u == "" || u == schedule.Unassigned || r.Continues || len(r.Overridden) > 0 || s.RotationEnd(i).Sub(r.Start) != s.RotationDuration {
+			return nil, false
+		}
+		users = append(users, u)
+	}
+	// The cycle runs until the first user comes round again.
+	cycle := users
+	for i, u := range users {
+		if i > 0 && u == users[0] {
+			cycle = users[:i]
+			break
+		}
+	}
+	seen := map[string]bool{}
+	for _, u := range cycle {
+		if seen[u] {
+			return nil, false
+		}
+		seen[u] = true
+	}
+	for i, u := range users {
+		if u != cycle[i % len(cycle)] {
+			return nil, false
+		}
+	}
+	return cycle, true
+}
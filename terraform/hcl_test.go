@@ -0,0 +1,110 @@
+package terraform
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/websdev/oncallator/schedule"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var users = map[string]string{"a": "PA", "b": "PB", "c": "PC"}
+
+const regularText = `
+{
+	"Users": ["a", "b", "c"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{"Start": "2017-03-01T10:00:00Z", "Primary": "a", "Secondary": "b"},
+		{"Start": "2017-03-08T10:00:00Z", "Primary": "b", "Secondary": "c"},
+		{"Start": "2017-03-15T10:00:00Z", "Primary": "c", "Secondary": "a"},
+		{"Start": "2017-03-22T10:00:00Z", "End": "2017-03-29T10:00:00Z", "Primary": "a", "Secondary": "b"}
+	]
+}`
+
+// The second rotation was hand-edited to hand over two days late, and its
+// secondary swapped.
+const irregularText = `
+{
+	"Users": ["a", "b", "c"],
+	"Start": "2017-03-01T10:00:00Z",
+	"RotationLength": "168h",
+	"ScheduleFor": "504h",
+	"Rotations": [
+		{"Start": "2017-03-01T10:00:00Z", "Primary": "a", "Secondary": "b"},
+		{"Start": "2017-03-10T10:00:00Z", "Primary": "b", "Secondary": "a"},
+		{"Start": "2017-03-15T10:00:00Z", "End": "2017-03-22T10:00:00Z", "Primary": "c", "Secondary": "a"}
+	]
+}`
+
+func writeHCL(t *testing.T, text string, c HCLConfig) (string, error) {
+	s, err := schedule.NewSchedule([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	err = WriteHCL(out, s, c)
+	return out.String(), err
+}
+
+func TestWriteHCL(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		text string
+	}{
+		{"regular", regularText},
+		{"irregular", irregularText},
+	} {
+		got, err := writeHCL(t, c.text, HCLConfig{Users: users})
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join("testdata", c.name + ".tf")
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(expected) {
+			t.Errorf("HCL does not match %s\nExpected:\n%s\n---\nGot:\n%s\n", golden, expected, got)
+		}
+	}
+}
+
+func TestWriteHCLNamesStableAcrossRegeneration(t *testing.T) {
+	before, err := writeHCL(t, irregularText, HCLConfig{Users: users, Name: "team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Regeneration drops the first rotation and adds one at the end.
+	regenerated := strings.Replace(irregularText, `{"Start": "2017-03-01T10:00:00Z", "Primary": "a", "Secondary": "b"},`, "", 1)
+	regenerated = strings.Replace(regenerated, `"End": "2017-03-22T10:00:00Z", "Primary": "c", "Secondary": "a"}`, `"Primary": "c", "Secondary": "a"},
+		{"Start": "2017-03-22T10:00:00Z", "End": "2017-03-29T10:00:00Z", "Primary": "a", "Secondary": "b"}`, 1)
+	after, err := writeHCL(t, regenerated, HCLConfig{Users: users, Name: "team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"team_primary_20170310t100000z", "team_secondary_20170315t100000z"} {
+		if !strings.Contains(before, name) || !strings.Contains(after, name) {
+			t.Errorf("expected %s to keep its name", name)
+		}
+	}
+}
+
+func TestWriteHCLMissingUsers(t *testing.T) {
+	_, err := writeHCL(t, regularText, HCLConfig{Users: map[string]string{"a": "PA"}})
+	if err == nil || err.Error() != "no PagerDuty user ID for users: b, c" {
+		t.Errorf("expected an error naming the missing users, got %v", err)
+	}
+}
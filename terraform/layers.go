@@ -20,20 +20,29 @@ type Layers struct {
 
 func NewLayers(s *schedule.Schedule) Layers {
 	l := Layers{}
-	for _, r := range s.Rotations {
+	for i, r := range s.Rotations {
 		start := r.Start.Format(time.RFC3339)
+		length := s.RotationDuration
+		if len(s.Windows) > 0 {
+			// Each window has its own length.
+			length = s.RotationEnd(i).Sub(r.Start)
+		}
 		primary := Layer{
 			Start: start,
 			Users: []string{r.Primary},
 			RotationVirtualStart: start,
-			RotationTurnLengthSeconds: int(s.RotationDuration.Seconds()),
+			RotationTurnLengthSeconds: int(length.Seconds()),
 		}
 		l.Primary = append(l.Primary, primary)
+		if r.Secondary == "" {
+			// The secondary tier is unfilled for this rotation.
+			continue
+		}
 		secondary := Layer{
 			Start: start,
 			Users: []string{r.Secondary},
 			RotationVirtualStart: start,
-			RotationTurnLengthSeconds: int(s.RotationDuration.Seconds()),
+			RotationTurnLengthSeconds: int(length.Seconds()),
 		}
 		l.Secondary = append(l.Secondary, secondary)
 	}
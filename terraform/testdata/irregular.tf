@@ -0,0 +1,83 @@
+resource "pagerduty_schedule" "oncall_primary_20170301t100000z" {
+  name      = "oncall primary 2017-03-01T10:00:00Z"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "primary"
+    start                        = "2017-03-01T10:00:00Z"
+    end                          = "2017-03-10T10:00:00Z"
+    rotation_virtual_start       = "2017-03-01T10:00:00Z"
+    rotation_turn_length_seconds = 777600
+    users                        = ["PA"]
+  }
+}
+
+resource "pagerduty_schedule" "oncall_primary_20170310t100000z" {
+  name      = "oncall primary 2017-03-10T10:00:00Z"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "primary"
+    start                        = "2017-03-10T10:00:00Z"
+    end                          = "2017-03-15T10:00:00Z"
+    rotation_virtual_start       = "2017-03-10T10:00:00Z"
+    rotation_turn_length_seconds = 432000
+    users                        = ["PB"]
+  }
+}
+
+resource "pagerduty_schedule" "oncall_primary_20170315t100000z" {
+  name      = "oncall primary 2017-03-15T10:00:00Z"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "primary"
+    start                        = "2017-03-15T10:00:00Z"
+    end                          = "2017-03-22T10:00:00Z"
+    rotation_virtual_start       = "2017-03-15T10:00:00Z"
+    rotation_turn_length_seconds = 604800
+    users                        = ["PC"]
+  }
+}
+
+resource "pagerduty_schedule" "oncall_secondary_20170301t100000z" {
+  name      = "oncall secondary 2017-03-01T10:00:00Z"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "secondary"
+    start                        = "2017-03-01T10:00:00Z"
+    end                          = "2017-03-10T10:00:00Z"
+    rotation_virtual_start       = "2017-03-01T10:00:00Z"
+    rotation_turn_length_seconds = 777600
+    users                        = ["PB"]
+  }
+}
+
+resource "pagerduty_schedule" "oncall_secondary_20170310t100000z" {
+  name      = "oncall secondary 2017-03-10T10:00:00Z"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "secondary"
+    start                        = "2017-03-10T10:00:00Z"
+    end                          = "2017-03-15T10:00:00Z"
+    rotation_virtual_start       = "2017-03-10T10:00:00Z"
+    rotation_turn_length_seconds = 432000
+    users                        = ["PA"]
+  }
+}
+
+resource "pagerduty_schedule" "oncall_secondary_20170315t100000z" {
+  name      = "oncall secondary 2017-03-15T10:00:00Z"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "secondary"
+    start                        = "2017-03-15T10:00:00Z"
+    end                          = "2017-03-22T10:00:00Z"
+    rotation_virtual_start       = "2017-03-15T10:00:00Z"
+    rotation_turn_length_seconds = 604800
+    users                        = ["PA"]
+  }
+}
@@ -0,0 +1,25 @@
+resource "pagerduty_schedule" "oncall_primary" {
+  name      = "oncall primary"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "primary"
+    start                        = "2017-03-01T10:00:00Z"
+    rotation_virtual_start       = "2017-03-01T10:00:00Z"
+    rotation_turn_length_seconds = 604800
+    users                        = ["PA", "PB", "PC"]
+  }
+}
+
+resource "pagerduty_schedule" "oncall_secondary" {
+  name      = "oncall secondary"
+  time_zone = "UTC"
+
+  layer {
+    name                         = "secondary"
+    start                        = "2017-03-01T10:00:00Z"
+    rotation_virtual_start       = "2017-03-01T10:00:00Z"
+    rotation_turn_length_seconds = 604800
+    users                        = ["PB", "PC", "PA"]
+  }
+}